@@ -0,0 +1,182 @@
+package benchmarks
+
+import (
+	"io"
+	"testing"
+
+	tls "github.com/refraction-networking/utls"
+)
+
+// presets are the ClientHelloIDs benchmarked, covering GREASE, padding and
+// post-quantum key shares.
+var presets = []struct {
+	name string
+	id   tls.ClientHelloID
+}{
+	{"Golang", tls.HelloGolang},
+	{"Chrome", tls.HelloChrome_Auto},
+	{"Firefox", tls.HelloFirefox_Auto},
+	{"Safari", tls.HelloSafari_Auto},
+	{"iOS", tls.HelloIOS_Auto},
+}
+
+func newServer(b *testing.B, config *tls.Config) *tls.UTLSTestServer {
+	b.Helper()
+	s, err := tls.NewUTLSServer(config)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(s.Close)
+	return s
+}
+
+// BenchmarkClientHello measures building and marshaling a ClientHello,
+// including key share generation.
+func BenchmarkClientHello(b *testing.B) {
+	config := &tls.Config{ServerName: "example.com"}
+	for _, p := range presets {
+		b.Run(p.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				uconn := tls.UClient(nil, config, p.id)
+				if err := uconn.BuildHandshakeState(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkClientHelloWithoutGREASEOrPadding is like BenchmarkClientHello with
+// the GREASE and padding extensions removed from the spec, to tell their cost
+// apart from the rest of the marshaling.
+func BenchmarkClientHelloWithoutGREASEOrPadding(b *testing.B) {
+	config := &tls.Config{ServerName: "example.com"}
+	for _, p := range presets {
+		if p.id == tls.HelloGolang {
+			// Built by crypto/tls code, without a spec.
+			continue
+		}
+		b.Run(p.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				spec, err := tls.UTLSIdToSpec(p.id)
+				if err != nil {
+					b.Fatal(err)
+				}
+				extensions := spec.Extensions[:0]
+				for _, e := range spec.Extensions {
+					switch e.(type) {
+					case *tls.UtlsGREASEExtension, *tls.UtlsPaddingExtension:
+					default:
+						extensions = append(extensions, e)
+					}
+				}
+				spec.Extensions = extensions
+				uconn := tls.UClient(nil, config, tls.HelloCustom)
+				if err := uconn.ApplyPreset(&spec); err != nil {
+					b.Fatal(err)
+				}
+				if err := uconn.BuildHandshakeState(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkHandshake measures full handshakes, on both ends of in-memory
+// connections.
+func BenchmarkHandshake(b *testing.B) {
+	s := newServer(b, nil)
+	for _, p := range presets {
+		b.Run(p.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				c, err := tls.NewUTLSClient(s, nil, p.id)
+				if err != nil {
+					b.Fatal(err)
+				}
+				c.Close()
+			}
+		})
+	}
+}
+
+// BenchmarkResumption measures handshakes resuming a session, with TLS 1.3
+// tickets.
+func BenchmarkResumption(b *testing.B) {
+	s := newServer(b, nil)
+	for _, p := range []struct {
+		name string
+		id   tls.ClientHelloID
+	}{
+		{"Golang", tls.HelloGolang},
+		{"Chrome_PSK", tls.HelloChrome_100_PSK},
+	} {
+		b.Run(p.name, func(b *testing.B) {
+			config := &tls.Config{
+				ClientSessionCache: tls.NewLRUClientSessionCache(1),
+				OmitEmptyPsk:       true,
+			}
+			connect := func() bool {
+				c, err := tls.NewUTLSClient(s, config, p.id)
+				if err != nil {
+					b.Fatal(err)
+				}
+				defer c.Close()
+				// Read the echo, and the session ticket sent before it.
+				if _, err := c.Write([]byte{0}); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := io.ReadFull(c, make([]byte, 1)); err != nil {
+					b.Fatal(err)
+				}
+				return c.ConnectionState().DidResume
+			}
+			connect()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if !connect() {
+					b.Fatal("session was not resumed")
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRecordEncryption measures the throughput of writing 16 KiB records,
+// which the server decrypts and discards.
+func BenchmarkRecordEncryption(b *testing.B) {
+	for _, suite := range []struct {
+		name    string
+		version uint16
+		suites  []uint16
+	}{
+		{"TLS12_AES_128_GCM", tls.VersionTLS12, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}},
+		{"TLS12_CHACHA20_POLY1305", tls.VersionTLS12, []uint16{tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256, tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256}},
+		{"TLS13", tls.VersionTLS13, nil},
+	} {
+		b.Run(suite.name, func(b *testing.B) {
+			s := newServer(b, &tls.Config{MaxVersion: suite.version, CipherSuites: suite.suites})
+			s.Handler = func(c *tls.Conn) { io.Copy(io.Discard, c) }
+			c, err := tls.NewUTLSClient(s, nil, tls.HelloChrome_Auto)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer c.Close()
+
+			buf := make([]byte, 16<<10)
+			b.SetBytes(int64(len(buf)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.Write(buf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+// Package benchmarks holds the uTLS benchmark suite, covering ClientHello
+// construction, full and resumed handshakes, and record encryption across
+// presets. It has no API: it exists so that performance-motivated changes can
+// be evaluated consistently, with
+//
+//	go test -run '^$' -bench . -count 10 ./benchmarks > old.txt
+//
+// run before and after the change, and the results compared with benchstat.
+//
+// Record encryption and key share generation use the assembly of the Go
+// crypto packages, e.g. AES-NI and AVX2 on amd64, and the AES and NEON
+// instructions on arm64, so results should only be compared on the same
+// GOARCH and CPU.
+package benchmarks
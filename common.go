@@ -302,6 +302,21 @@ type ConnectionState struct {
 	// resumed connections that don't support Extended Master Secret (RFC 7627).
 	TLSUnique []byte
 
+	// TranscriptHash is the digest of the handshake transcript, once the
+	// handshake is complete. It is intended for channel binding in protocols
+	// such as SASL/SCRAM.
+	//
+	// For TLS 1.3 it covers the messages from ClientHello up to and including
+	// the server Finished, which is the transcript the exporter master secret
+	// is derived from. For TLS 1.0–1.2 it covers all handshake messages,
+	// including both Finished messages. Both peers observe the same value.
+	//
+	// For TLS 1.2 and 1.3 it's computed with the hash of the negotiated cipher
+	// suite. For TLS 1.0 and 1.1 it's the MD5 digest followed by the SHA-1
+	// digest, 36 bytes in total, which their Finished messages are computed
+	// over.
+	TranscriptHash []byte // [uTLS]
+
 	// ECHAccepted indicates if Encrypted Client Hello was offered by the client
 	// and accepted by the server. Currently, ECH is supported only on the
 	// client side.
@@ -310,6 +325,13 @@ type ConnectionState struct {
 	// ekm is a closure exposed via ExportKeyingMaterial.
 	ekm func(label string, context []byte, length int) ([]byte, error)
 
+	// serverCertificate is the raw leaf certificate of the server, used by
+	// ChannelBinding.
+	serverCertificate []byte // [uTLS]
+
+	// ctx is the context of the handshake in progress, exposed via Context.
+	ctx context.Context // [uTLS]
+
 	// testingOnlyDidHRR is true if a HelloRetryRequest was sent/received.
 	testingOnlyDidHRR bool
 
@@ -478,11 +500,22 @@ type ClientHelloInfo struct {
 
 	// ctx is the context of the handshake that is in progress.
 	ctx context.Context
+
+	// [UTLS SECTION START]
+	// hello is the ClientHello, and echAccepted reports whether it's an
+	// inner ClientHello, for GetCertificateForClientHello.
+	hello       *clientHelloMsg
+	echAccepted bool
+	// [UTLS SECTION END]
 }
 
 // Context returns the context of the handshake that is in progress.
 // This context is a child of the context passed to HandshakeContext,
 // if any, and is canceled when the handshake concludes.
+//
+// [uTLS] If Config.CertificateLookupTimeout is set, it also expires after
+// that long. Callbacks doing remote lookups should stop and return an error
+// once it's done.
 func (c *ClientHelloInfo) Context() context.Context {
 	return c.ctx
 }
@@ -592,6 +625,31 @@ type Config struct {
 	// Once a Certificate is returned it should not be modified.
 	GetCertificate func(*ClientHelloInfo) (*Certificate, error)
 
+	// GetCertificateForClientHello, if not nil, is called before
+	// GetCertificate with the parsed ClientHello, including the decrypted
+	// inner ClientHello if Encrypted Client Hello was accepted, to select
+	// the server certificate. If it returns a nil certificate and error,
+	// the certificate is selected as if it was nil.
+	GetCertificateForClientHello func(*CertificateSelectionInfo) (*Certificate, error) // [uTLS]
+
+	// CertificateLookupTimeout, if positive, bounds the context returned by
+	// ClientHelloInfo.Context for each call to GetConfigForClient,
+	// GetCertificate and GetCertificateForClientHello, so that lookups in a
+	// remote certificate store give up in time. The context never outlives
+	// the handshake context.
+	CertificateLookupTimeout time.Duration // [uTLS]
+
+	// GetACMEKeyAuthorization, if not nil, enables the ACME TLS-ALPN-01
+	// challenge (RFC 8737). When a client offers only the acme-tls/1
+	// application protocol, the server negotiates it and presents the
+	// validation certificate returned by NewACMEChallengeCertificate for the
+	// server name of the ClientHello, and the key authorization returned by
+	// GetACMEKeyAuthorization for it, instead of the configured certificates.
+	//
+	// The application must close connections whose NegotiatedProtocol is
+	// ACMETLSALPNProtocol after the handshake.
+	GetACMEKeyAuthorization func(serverName string) (string, error) // [uTLS]
+
 	// GetClientCertificate, if not nil, is called when a server requests a
 	// certificate from a client. If set, the contents of Certificates will
 	// be ignored.
@@ -656,6 +714,20 @@ type Config struct {
 	// settings.
 	VerifyConnection func(ConnectionState) error
 
+	// OnPeerCertificates, if not nil, is called by a TLS client with the raw
+	// ASN.1 certificates provided by the server, before they are parsed and
+	// before normal certificate verification. If it returns a non-nil error,
+	// the handshake is aborted with a bad_certificate alert and that error
+	// results.
+	//
+	// It allows early rejection of chains, for example based on an issuer
+	// blocklist or on their size, without disabling normal verification. It is
+	// called regardless of InsecureSkipVerify, and is not invoked on resumed
+	// connections.
+	//
+	// rawCerts and its contents should not be modified.
+	OnPeerCertificates func(rawCerts [][]byte) error // [uTLS]
+
 	// RootCAs defines the set of root certificate authorities
 	// that clients use when verifying server certificates.
 	// If RootCAs is nil, TLS uses the host's root CA set.
@@ -719,6 +791,101 @@ type Config struct {
 	// This field is ignored when InsecureSkipVerify is true.
 	InsecureServerNameToVerify string // [uTLS]
 
+	// InsecureSkipVerifyHosts lists the server names for which a client skips
+	// certificate verification as if InsecureSkipVerify was set, e.g. for
+	// internal endpoints behind a proxy. Names are matched against
+	// ServerName, case-insensitively. An entry starting with "*." matches
+	// any subdomain of the rest, at any depth, but not the domain itself.
+	//
+	// Connections to other server names are verified normally.
+	InsecureSkipVerifyHosts []string // [uTLS]
+
+	// InsecureSkipVerifyHost, if not nil, is called with ServerName by
+	// clients, and certificate verification is skipped as if
+	// InsecureSkipVerify was set if it returns true. It's consulted in
+	// addition to InsecureSkipVerifyHosts.
+	InsecureSkipVerifyHost func(serverName string) bool // [uTLS]
+
+	// AllowServerCertificateChange, if true, allows the server to present a
+	// different certificate when renegotiating a TLS 1.2 connection, which is
+	// then verified again instead of aborting the handshake. It's intended
+	// for intercepting proxies that rotate the certificates they forge, and
+	// weakens protection against the triple handshake attack.
+	AllowServerCertificateChange bool // [uTLS]
+
+	// VerifyCertSignatureAlgorithms makes clients reject server certificate
+	// chains signed with algorithms the ClientHello didn't offer in
+	// signature_algorithms_cert, or in signature_algorithms if it has no
+	// signature_algorithms_cert extension. Browsers don't enforce this, so
+	// it's off by default. It has no effect if verification is skipped.
+	VerifyCertSignatureAlgorithms bool // [uTLS]
+
+	// UnsolicitedExtensions is how clients react to extensions sent by the
+	// server that the ClientHello didn't offer. The default ignores those
+	// unknown to crypto/tls, unlike browsers.
+	UnsolicitedExtensions UnsolicitedExtensionPolicy // [uTLS]
+
+	// OnUnsolicitedExtension, if not nil, is called by clients for every
+	// extension sent by the server that the ClientHello didn't offer, with
+	// the TLS HandshakeType of the message and the extension type. If it
+	// returns an error, the handshake is aborted with an
+	// unsupported_extension alert. It takes precedence over
+	// UnsolicitedExtensions.
+	OnUnsolicitedExtension func(handshakeType uint8, extension uint16) error // [uTLS]
+
+	// DecryptErrors is how connections react to records that fail
+	// authentication. The default sends a bad_record_mac alert.
+	DecryptErrors DecryptErrorPolicy // [uTLS]
+
+	// DecryptErrorCloseDelay is how long DecryptErrorDelayedClose waits
+	// before closing the connection.
+	DecryptErrorCloseDelay time.Duration // [uTLS]
+
+	// TransformPskIdentity, if not nil, is called by clients resuming a
+	// TLS 1.3 session with the identity of the pre_shared_key extension,
+	// which holds the session ticket, and returns the identity to send
+	// instead, e.g. to carry a covert signal to a server that reverses the
+	// transformation in UnwrapSession. Binders are computed over the
+	// transformed identity, which is sent again after a HelloRetryRequest,
+	// with its age updated. If it returns an error, the handshake fails.
+	TransformPskIdentity func(identity PskIdentity, session *SessionState) (PskIdentity, error) // [uTLS]
+
+	// TicketAge, if not nil, is called by clients resuming a TLS 1.3 session
+	// with the time elapsed since the session was established, and returns
+	// the age to report in the obfuscated_ticket_age of the pre_shared_key
+	// extension, e.g. TicketAgeJitter to blur the exact age like browsers
+	// do. Negative results are reported as zero. The adjustment is made once
+	// per connection, and kept after a HelloRetryRequest.
+	TicketAge func(age time.Duration) time.Duration // [uTLS]
+
+	// OnNewSessionTicket, if not nil, is called by clients with every session
+	// ticket received from the server and the session it resumes, before
+	// the session is stored in ClientSessionCache under key. It may store the
+	// session elsewhere, e.g. to share it between clients, and returns
+	// whether to also store it in ClientSessionCache. It's called even if
+	// ClientSessionCache is nil. If it returns an error, the connection fails.
+	OnNewSessionTicket func(key string, ticket *NewSessionTicket, session *ClientSessionState) (store bool, err error) // [uTLS]
+
+	// OnGREASESelected, if not nil, is called by clients when the handshake
+	// fails because the server negotiated a GREASE value, e.g. to alert on
+	// possible interference. The handshake returns the same error.
+	OnGREASESelected func(err *GREASESelectedError) // [uTLS]
+
+	// GREASESeed, if not nil, is used by clients to derive the GREASE values
+	// of the ClientHello, instead of drawing them from Rand for each
+	// connection. Connections sharing a GREASESeed send the same GREASE
+	// values, like the connections of a Chrome browsing session, e.g. those
+	// of an IdentityManager persona.
+	GREASESeed *PRNGSeed // [uTLS]
+
+	// RandomizedPerHost makes clients using a HelloRandomized ClientHelloID
+	// derive the randomized ClientHello from the server name, and the Seed of
+	// the ID or PersistentRandomizedSeed if it has none. Each host then always
+	// sees the same randomized ClientHello, as if visited by the same
+	// browser, while different hosts see different ones. Connections without
+	// a server name share a single ClientHello.
+	RandomizedPerHost bool // [uTLS]
+
 	// PreferSkipResumptionOnNilExtension controls the behavior when session resumption is enabled but the corresponding session extensions are nil.
 	//
 	// To successfully use session resumption, ensure that the following requirements are met:
@@ -732,6 +899,101 @@ type Config struct {
 	// By default, utls throws an exception in such scenarios. Set this to true to skip the resumption and suppress the exception.
 	PreferSkipResumptionOnNilExtension bool // [uTLS]
 
+	// HandshakeSizeLimits, if not nil, overrides the maximum sizes of the
+	// handshake messages accepted from the peer. Messages exceeding a limit
+	// abort the handshake with a *HandshakeSizeError.
+	//
+	// If nil, the defaults described in HandshakeSizeLimits are used.
+	HandshakeSizeLimits *HandshakeSizeLimits // [uTLS]
+
+	// HandshakeTarpit, if not nil, makes servers respond to a failed
+	// handshake, e.g. a probe sending an invalid ClientHello, by discarding
+	// what the client sends without replying with an alert, within the
+	// limits it sets, instead of sending an alert right away.
+	HandshakeTarpit *HandshakeTarpit // [uTLS]
+
+	// AIAFetcher, if not nil, is used by clients to fetch intermediate
+	// certificates missing from the chain sent by the server, when the
+	// chain can't otherwise be verified. Leave it nil to disable fetching.
+	AIAFetcher *AIAFetcher // [uTLS]
+
+	// VerifiedChainCache, if not nil, is used by clients to reuse the
+	// verification of server certificate chains seen recently, instead of
+	// verifying them on every connection. Leave it nil to disable caching.
+	VerifiedChainCache *VerifiedChainCache // [uTLS]
+
+	// HandshakeLimiter, if not nil, limits the number of handshakes running
+	// concurrently, on both clients and servers. Handshakes beyond the limit
+	// wait for a slot, or fail if its queue is full. Share one between
+	// Configs for a process-wide limit.
+	HandshakeLimiter *HandshakeLimiter // [uTLS]
+
+	// ResumptionMonitor, if not nil, is used by clients to count session
+	// resumptions, and report the sessions servers reject with a full
+	// handshake.
+	ResumptionMonitor *ResumptionMonitor // [uTLS]
+
+	// RecordAEADProvider, if not nil, supplies the AEAD implementations
+	// protecting the records of the connection, instead of the built-in
+	// ones. See RecordAEADProvider.
+	RecordAEADProvider RecordAEADProvider // [uTLS]
+
+	// ExplicitNonce is how the explicit nonces of TLS 1.2 AES-GCM records
+	// are generated. The default, ExplicitNonceSequence, should only be
+	// changed to emulate other stacks.
+	ExplicitNonce ExplicitNoncePolicy // [uTLS]
+
+	// WritePacing, if not nil, limits the rate at which connections write
+	// records. See WritePacing.
+	WritePacing *WritePacing // [uTLS]
+
+	// NoBackgroundTasks, if true, guarantees that connections spawn no
+	// goroutines and don't fill the global ECDHE key cache, for embedders
+	// running under seccomp or similar sandboxes. ECDHE keys are then
+	// generated for each connection, and QUIC connections, whose handshake
+	// runs in a goroutine, fail to start.
+	//
+	// HandshakeContext honors the deadline of its context by setting the
+	// deadline of the underlying connection for the duration of the
+	// handshake; those set with Conn.SetDeadline are restored afterwards.
+	// A context without a deadline is only checked when the handshake has
+	// read from the network, so canceling it doesn't interrupt a blocked
+	// read. CertificateLookupTimeout is reported by the Deadline and Err
+	// methods of ClientHelloInfo.Context, but doesn't close its Done channel.
+	// Contexts should come from the context package, as the ones derived
+	// from other implementations are watched by a goroutine.
+	//
+	// Utilities that are concurrent by design, such as Prober, ShadowDialer
+	// or SpecWatcher, are not affected, and the embedded fingerprint
+	// database is still parsed on the first use of a CapturedHelloID.
+	NoBackgroundTasks bool // [uTLS]
+
+	// ZeroizeSecrets, if true, overwrites the TLS 1.3 traffic and resumption
+	// secrets of a connection with zeros when it's closed, and automatically
+	// rotated session ticket keys once they expire. See UConn.Close for the
+	// client handshake secrets also covered. Close then waits for in-flight
+	// Reads and Writes, which fail once the connection is closed, to return.
+	ZeroizeSecrets bool // [uTLS]
+
+	// ZeroizeHook, if not nil, is called after every secret is zeroized,
+	// with a label describing it and the now zeroed secret, which is nil for
+	// private keys that can only be released. It's intended to verify
+	// zeroization in tests and must not retain secret.
+	ZeroizeHook func(label string, secret []byte) // [uTLS]
+
+	// HandshakeForensics, if true, captures the records, handshake messages,
+	// alerts and timings of every handshake, and wraps the error of a failed
+	// one in a *HandshakeForensicsError carrying them. It's intended for
+	// debugging and costs a copy of every handshake record.
+	HandshakeForensics bool // [uTLS]
+
+	// FalseStart, if true, makes clients whose ClientHelloID is the preset
+	// of a client that False Starts by default, such as Chrome and Firefox,
+	// False Start like it, see ClientHelloSpec.FalseStart. Handshake then
+	// returns before the server's Finished is verified, and the exporters
+	// of ConnectionState fail until the first Read verifies it.
+	FalseStart bool // [uTLS]
+
 	// CipherSuites is a list of enabled TLS 1.0–1.2 cipher suites. The order of
 	// the list is ignored. Note that TLS 1.3 ciphersuites are not configurable.
 	//
@@ -987,7 +1249,7 @@ func (c *Config) Clone() *Config {
 	}
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
-	return &Config{
+	clone := &Config{
 		Rand:                                c.Rand,
 		Time:                                c.Time,
 		Certificates:                        c.Certificates,
@@ -997,6 +1259,7 @@ func (c *Config) Clone() *Config {
 		GetConfigForClient:                  c.GetConfigForClient,
 		VerifyPeerCertificate:               c.VerifyPeerCertificate,
 		VerifyConnection:                    c.VerifyConnection,
+		OnPeerCertificates:                  c.OnPeerCertificates, // [uTLS]
 		RootCAs:                             c.RootCAs,
 		NextProtos:                          c.NextProtos,
 		ApplicationSettings:                 c.ApplicationSettings,
@@ -1006,12 +1269,30 @@ func (c *Config) Clone() *Config {
 		InsecureSkipVerify:                  c.InsecureSkipVerify,
 		InsecureSkipTimeVerify:              c.InsecureSkipTimeVerify,
 		InsecureServerNameToVerify:          c.InsecureServerNameToVerify,
+		InsecureSkipVerifyHosts:             c.InsecureSkipVerifyHosts,       // [UTLS]
+		InsecureSkipVerifyHost:              c.InsecureSkipVerifyHost,        // [UTLS]
+		AllowServerCertificateChange:        c.AllowServerCertificateChange,  // [UTLS]
+		VerifyCertSignatureAlgorithms:       c.VerifyCertSignatureAlgorithms, // [UTLS]
+		UnsolicitedExtensions:               c.UnsolicitedExtensions,         // [UTLS]
+		DecryptErrors:                       c.DecryptErrors,                 // [UTLS]
+		DecryptErrorCloseDelay:              c.DecryptErrorCloseDelay,        // [UTLS]
+		OnUnsolicitedExtension:              c.OnUnsolicitedExtension,        // [UTLS]
+		TransformPskIdentity:                c.TransformPskIdentity,          // [UTLS]
+		TicketAge:                           c.TicketAge,                     // [UTLS]
+		OnNewSessionTicket:                  c.OnNewSessionTicket,            // [UTLS]
+		OnGREASESelected:                    c.OnGREASESelected,              // [UTLS]
+		GREASESeed:                          c.GREASESeed,                    // [UTLS]
+		RandomizedPerHost:                   c.RandomizedPerHost,             // [UTLS]
+		GetCertificateForClientHello:        c.GetCertificateForClientHello,  // [UTLS]
+		CertificateLookupTimeout:            c.CertificateLookupTimeout,      // [UTLS]
+		GetACMEKeyAuthorization:             c.GetACMEKeyAuthorization,       // [UTLS]
 		OmitEmptyPsk:                        c.OmitEmptyPsk,
 		CipherSuites:                        c.CipherSuites,
 		PreferServerCipherSuites:            c.PreferServerCipherSuites,
 		SessionTicketsDisabled:              c.SessionTicketsDisabled,
 		SessionTicketKey:                    c.SessionTicketKey,
 		ClientSessionCache:                  c.ClientSessionCache,
+		PreciseSessionCache:                 c.PreciseSessionCache, // [UTLS]
 		UnwrapSession:                       c.UnwrapSession,
 		WrapSession:                         c.WrapSession,
 		MinVersion:                          c.MinVersion,
@@ -1025,10 +1306,26 @@ func (c *Config) Clone() *Config {
 		EncryptedClientHelloRejectionVerify: c.EncryptedClientHelloRejectionVerify,
 		EncryptedClientHelloKeys:            c.EncryptedClientHelloKeys,
 		sessionTicketKeys:                   c.sessionTicketKeys,
-		autoSessionTicketKeys:               c.autoSessionTicketKeys,
+		autoSessionTicketKeys:               c.shareAutoTicketKeys(), // [uTLS]
 
 		PreferSkipResumptionOnNilExtension: c.PreferSkipResumptionOnNilExtension, // [UTLS]
+		HandshakeSizeLimits:                c.HandshakeSizeLimits,                // [UTLS]
+		HandshakeTarpit:                    c.HandshakeTarpit,                    // [UTLS]
+		AIAFetcher:                         c.AIAFetcher,                         // [UTLS]
+		VerifiedChainCache:                 c.VerifiedChainCache,                 // [UTLS]
+		HandshakeLimiter:                   c.HandshakeLimiter,                   // [UTLS]
+		ResumptionMonitor:                  c.ResumptionMonitor,                  // [UTLS]
+		RecordAEADProvider:                 c.RecordAEADProvider,                 // [UTLS]
+		ExplicitNonce:                      c.ExplicitNonce,                      // [UTLS]
+		WritePacing:                        c.WritePacing,                        // [UTLS]
+		NoBackgroundTasks:                  c.NoBackgroundTasks,                  // [UTLS]
+		ZeroizeSecrets:                     c.ZeroizeSecrets,                     // [UTLS]
+		ZeroizeHook:                        c.ZeroizeHook,                        // [UTLS]
+		HandshakeForensics:                 c.HandshakeForensics,                 // [UTLS]
+		FalseStart:                         c.FalseStart,                         // [UTLS]
 	}
+	clone.deepCopyUTLSFields() // [uTLS]
+	return clone
 }
 
 // deprecatedSessionTicketKey is set as the prefix of SessionTicketKey if it was
@@ -1102,7 +1399,7 @@ func (c *Config) ticketKeys(configForClient *Config) []ticketKey {
 	}
 	// Fast path for the common case where the key is fresh enough.
 	if len(c.autoSessionTicketKeys) > 0 && c.time().Sub(c.autoSessionTicketKeys[0].created) < ticketKeyRotation {
-		return c.autoSessionTicketKeys
+		return c.shareAutoTicketKeys() // [uTLS]
 	}
 
 	// autoSessionTicketKeys are managed by auto-rotation.
@@ -1118,15 +1415,23 @@ func (c *Config) ticketKeys(configForClient *Config) []ticketKey {
 		}
 		valid := make([]ticketKey, 0, len(c.autoSessionTicketKeys)+1)
 		valid = append(valid, c.ticketKeyFromBytes(newKey))
-		for _, k := range c.autoSessionTicketKeys {
+		for i, k := range c.autoSessionTicketKeys {
 			// While rotating the current key, also remove any expired ones.
 			if c.time().Sub(k.created) < ticketKeyLifetime {
 				valid = append(valid, k)
+			} else if c.ZeroizeSecrets { // [uTLS]
+				c.zeroizeTicketKey(&c.autoSessionTicketKeys[i])
 			}
 		}
+		if c.ZeroizeSecrets { // [uTLS]
+			// Nothing else references the old keys, see shareAutoTicketKeys,
+			// and the retained ones were copied to valid.
+			clear(c.autoSessionTicketKeys)
+			c.zeroize("session ticket key seed", newKey[:])
+		}
 		c.autoSessionTicketKeys = valid
 	}
-	return c.autoSessionTicketKeys
+	return c.shareAutoTicketKeys() // [uTLS]
 }
 
 // SetSessionTicketKeys updates the session ticket keys for a server.
@@ -1319,6 +1624,18 @@ var errNoCertificates = errors.New("tls: no certificates configured")
 // getCertificate returns the best certificate for the given ClientHelloInfo,
 // defaulting to the first element of c.Certificates.
 func (c *Config) getCertificate(clientHello *ClientHelloInfo) (*Certificate, error) {
+	// [UTLS SECTION START]
+	if c.GetACMEKeyAuthorization != nil && isACMEChallenge(clientHello.SupportedProtos) {
+		return c.acmeChallengeCertificate(clientHello)
+	}
+	if c.GetCertificateForClientHello != nil && clientHello.hello != nil {
+		cert, err := c.GetCertificateForClientHello(clientHello.certificateSelectionInfo())
+		if cert != nil || err != nil {
+			return cert, err
+		}
+	}
+	// [UTLS SECTION END]
+
 	if c.GetCertificate != nil &&
 		(len(c.Certificates) == 0 || len(clientHello.ServerName) > 0) {
 		cert, err := c.GetCertificate(clientHello)
@@ -1351,6 +1668,14 @@ func (c *Config) getCertificate(clientHello *ClientHelloInfo) (*Certificate, err
 		}
 	}
 
+	// [UTLS SECTION START]
+	// Prefer a certificate whose chain is signed with algorithms offered in
+	// signature_algorithms_cert.
+	if cert := clientHello.certificateForClientHello(c.Certificates); cert != nil {
+		return cert, nil
+	}
+	// [UTLS SECTION END]
+
 	for _, cert := range c.Certificates {
 		if err := clientHello.SupportsCertificate(&cert); err == nil {
 			return &cert, nil
@@ -132,38 +132,44 @@ type Conn struct {
 
 // LocalAddr returns the local network address.
 func (c *Conn) LocalAddr() net.Addr {
-	return c.conn.LocalAddr()
+	return c.NetConn().LocalAddr() // [uTLS]
 }
 
 // RemoteAddr returns the remote network address.
 func (c *Conn) RemoteAddr() net.Addr {
-	return c.conn.RemoteAddr()
+	return c.NetConn().RemoteAddr() // [uTLS]
 }
 
 // SetDeadline sets the read and write deadlines associated with the connection.
 // A zero value for t means [Conn.Read] and [Conn.Write] will not time out.
 // After a Write has timed out, the TLS state is corrupt and all future writes will return the same error.
 func (c *Conn) SetDeadline(t time.Time) error {
-	return c.conn.SetDeadline(t)
+	c.utls.pacer.setDeadline(t)                             // [uTLS]
+	return c.utls.deadlines.set(c.NetConn(), true, true, t) // [uTLS]
 }
 
 // SetReadDeadline sets the read deadline on the underlying connection.
 // A zero value for t means [Conn.Read] will not time out.
 func (c *Conn) SetReadDeadline(t time.Time) error {
-	return c.conn.SetReadDeadline(t)
+	return c.utls.deadlines.set(c.NetConn(), true, false, t) // [uTLS]
 }
 
 // SetWriteDeadline sets the write deadline on the underlying connection.
 // A zero value for t means [Conn.Write] will not time out.
 // After a [Conn.Write] has timed out, the TLS state is corrupt and all future writes will return the same error.
 func (c *Conn) SetWriteDeadline(t time.Time) error {
-	return c.conn.SetWriteDeadline(t)
+	c.utls.pacer.setDeadline(t)                              // [uTLS]
+	return c.utls.deadlines.set(c.NetConn(), false, true, t) // [uTLS]
 }
 
 // NetConn returns the underlying connection that is wrapped by c.
 // Note that writing to or reading from this connection directly will corrupt the
 // TLS session.
 func (c *Conn) NetConn() net.Conn {
+	// [UTLS SECTION START]
+	c.utls.connMu.RLock()
+	defer c.utls.connMu.RUnlock()
+	// [UTLS SECTION END]
 	return c.conn
 }
 
@@ -185,6 +191,9 @@ type halfConn struct {
 
 	level         QUICEncryptionLevel // current QUIC encryption level
 	trafficSecret []byte              // current TLS 1.3 traffic secret
+
+	aeadProvider  RecordAEADProvider  // [uTLS]
+	explicitNonce ExplicitNoncePolicy // [uTLS]
 }
 
 type permanentError struct {
@@ -229,14 +238,21 @@ func (hc *halfConn) changeCipherSpec() error {
 	return nil
 }
 
-func (hc *halfConn) setTrafficSecret(suite *cipherSuiteTLS13, level QUICEncryptionLevel, secret []byte) {
+func (hc *halfConn) setTrafficSecret(suite *cipherSuiteTLS13, level QUICEncryptionLevel, secret []byte) error { // [uTLS] returns an error
+	key, iv := suite.trafficKey(secret)
+	// [UTLS SECTION START]
+	cipher, err := hc.recordAEAD(suite.id, suite.aead, key, iv)
+	if err != nil {
+		return err
+	}
+	// [UTLS SECTION END]
 	hc.trafficSecret = secret
 	hc.level = level
-	key, iv := suite.trafficKey(secret)
-	hc.cipher = suite.aead(key, iv)
+	hc.cipher = cipher
 	for i := range hc.seq {
 		hc.seq[i] = 0
 	}
+	return nil
 }
 
 // incSeq increments the sequence number.
@@ -488,7 +504,7 @@ func (hc *halfConn) encrypt(record, payload []byte, rand io.Reader) ([]byte, err
 	var explicitNonce []byte
 	if explicitNonceLen := hc.explicitNonceLen(); explicitNonceLen > 0 {
 		record, explicitNonce = sliceForAppend(record, explicitNonceLen)
-		if _, isCBC := hc.cipher.(cbcMode); !isCBC && explicitNonceLen < 16 {
+		if _, isCBC := hc.cipher.(cbcMode); !isCBC && explicitNonceLen < 16 && hc.explicitNonce != ExplicitNonceRandom { // [uTLS]
 			// The AES-GCM construction in TLS has an explicit nonce so that the
 			// nonce can be random. However, the nonce is only 8 bytes which is
 			// too small for a secure, random nonce. Therefore we use the
@@ -687,10 +703,15 @@ func (c *Conn) readRecordOrCCS(expectChangeCipherSpec bool) error {
 
 	// Process message.
 	record := c.rawInput.Next(recordHeaderLen + n)
+	c.forensicsRecord(false, record) // [uTLS]
 	data, typ, err := c.in.decrypt(record)
 	if err != nil {
-		return c.in.setErrorLocked(c.sendAlert(err.(alert)))
+		return c.in.setErrorLocked(c.decryptError(err.(alert))) // [uTLS]
 	}
+	// [UTLS SECTION START]
+	c.utls.stats.recordsRead.Add(1)
+	c.utls.stats.bytesRead.Add(uint64(recordHeaderLen + n))
+	// [UTLS SECTION END]
 	if len(data) > maxPlaintext {
 		return c.in.setErrorLocked(c.sendAlert(alertRecordOverflow))
 	}
@@ -721,6 +742,7 @@ func (c *Conn) readRecordOrCCS(expectChangeCipherSpec bool) error {
 		if len(data) != 2 {
 			return c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
 		}
+		c.forensicsAlert(false, alert(data[1])) // [uTLS]
 		if alert(data[1]) == alertCloseNotify {
 			return c.in.setErrorLocked(io.EOF)
 		}
@@ -829,15 +851,26 @@ func (c *Conn) readFromUntil(r io.Reader, n int) error {
 	// attempt to fetch it so that it can be used in (*Conn).Read to
 	// "predict" closeNotify alerts.
 	c.rawInput.Grow(needs + bytes.MinRead)
+	c.pauseHandshakeSlot() // [uTLS]
 	_, err := c.rawInput.ReadFrom(&atLeastReader{r, int64(needs)})
+	if err == nil {
+		err = c.resumeHandshakeSlot() // [uTLS]
+	}
+	if err == nil {
+		err = c.handshakeContextErr() // [uTLS]
+	}
 	return err
 }
 
 // sendAlertLocked sends a TLS alert message.
 func (c *Conn) sendAlertLocked(err alert) error {
+	c.forensicsAlert(true, err) // [uTLS]
 	if c.quic != nil {
 		return c.out.setErrorLocked(&net.OpError{Op: "local error", Err: err})
 	}
+	if c.tarpitting() { // [uTLS] failed handshakes are not answered
+		return c.out.setErrorLocked(&net.OpError{Op: "local error", Err: err})
+	}
 
 	switch err {
 	case alertNoRenegotiation, alertCloseNotify:
@@ -946,6 +979,13 @@ func (c *Conn) write(data []byte) (int, error) {
 		return len(data), nil
 	}
 
+	// [UTLS SECTION START]
+	c.utls.pacer.spend(len(data))
+	if len(c.utls.sentFirstFlight) > 0 {
+		return c.writeAfterFirstFlight(data)
+	}
+	// [UTLS SECTION END]
+
 	n, err := c.conn.Write(data)
 	c.bytesSent += int64(n)
 	return n, err
@@ -956,6 +996,8 @@ func (c *Conn) flush() (int, error) {
 		return 0, nil
 	}
 
+	c.utls.pacer.spend(len(c.sendBuf)) // [uTLS]
+
 	n, err := c.conn.Write(c.sendBuf)
 	c.bytesSent += int64(n)
 	c.sendBuf = nil
@@ -1030,6 +1072,8 @@ func (c *Conn) writeRecordLocked(typ recordType, data []byte) (int, error) {
 		if _, err := c.write(outBuf); err != nil {
 			return n, err
 		}
+		c.countRecordWritten(outBuf, m) // [uTLS]
+		c.forensicsRecord(true, outBuf) // [uTLS]
 		n += m
 		data = data[m:]
 	}
@@ -1057,6 +1101,7 @@ func (c *Conn) writeHandshakeRecord(msg handshakeMessage, transcript transcriptH
 	if transcript != nil {
 		transcript.Write(data)
 	}
+	c.forensicsMessage(true, data[0]) // [uTLS]
 
 	return c.writeRecordLocked(recordTypeHandshake, data)
 }
@@ -1092,26 +1137,18 @@ func (c *Conn) readHandshake(transcript transcriptHash) (any, error) {
 	}
 	data := c.hand.Bytes()
 
-	maxHandshakeSize := maxHandshake
-	// hasVers indicates we're past the first message, forcing someone trying to
-	// make us just allocate a large buffer to at least do the initial part of
-	// the handshake first.
-	if c.haveVers && data[0] == typeCertificate {
-		// Since certificate messages are likely to be the only messages that
-		// can be larger than maxHandshake, we use a special limit for just
-		// those messages.
-		maxHandshakeSize = maxHandshakeCertificateMsg
-	}
+	maxHandshakeSize := c.maxHandshakeSize(data[0]) // [uTLS]
 
 	n := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
 	if n > maxHandshakeSize {
 		c.sendAlertLocked(alertInternalError)
-		return nil, c.in.setErrorLocked(fmt.Errorf("tls: handshake message of length %d bytes exceeds maximum of %d bytes", n, maxHandshakeSize))
+		return nil, c.in.setErrorLocked(&HandshakeSizeError{Kind: LimitMessageSize, MsgType: data[0], Size: n, Limit: maxHandshakeSize}) // [uTLS]
 	}
 	if err := c.readHandshakeBytes(4 + n); err != nil {
 		return nil, err
 	}
 	data = c.hand.Next(4 + n)
+	c.forensicsMessage(false, data[0]) // [uTLS]
 	return c.unmarshalHandshakeMessage(data, transcript)
 }
 
@@ -1186,6 +1223,13 @@ func (c *Conn) unmarshalHandshakeMessage(data []byte, transcript transcriptHash)
 		return nil, c.in.setErrorLocked(c.sendAlert(alertUnexpectedMessage))
 	}
 
+	// [UTLS SECTION BEGINS]
+	if err := c.checkHandshakeSizeLimits(m, data); err != nil {
+		c.sendAlert(alertInternalError)
+		return nil, c.in.setErrorLocked(err)
+	}
+	// [UTLS SECTION ENDS]
+
 	if transcript != nil {
 		transcript.Write(data)
 	}
@@ -1220,6 +1264,17 @@ func (c *Conn) Write(b []byte) (int, error) {
 		return 0, err
 	}
 
+	// [UTLS SECTION START]
+	if c.utls.pacer.active() {
+		return c.pacedWrite(b)
+	}
+	return c.writeUnpaced(b)
+}
+
+// writeUnpaced implements Write once the handshake is complete, without
+// pacing.
+func (c *Conn) writeUnpaced(b []byte) (int, error) {
+	// [UTLS SECTION END]
 	c.out.Lock()
 	defer c.out.Unlock()
 
@@ -1300,6 +1355,7 @@ func (c *Conn) handleRenegotiation() error {
 	c.isHandshakeComplete.Store(false)
 	if c.handshakeErr = c.clientHandshake(context.Background()); c.handshakeErr == nil {
 		c.handshakes++
+		c.utls.stats.renegotiations.Add(1) // [uTLS]
 	}
 	return c.handshakeErr
 }
@@ -1347,7 +1403,11 @@ func (c *Conn) handleKeyUpdate(keyUpdate *keyUpdateMsg) error {
 	}
 
 	newSecret := cipherSuite.nextTrafficSecret(c.in.trafficSecret)
-	c.in.setTrafficSecret(cipherSuite, QUICEncryptionLevelInitial, newSecret)
+	if err := c.in.setTrafficSecret(cipherSuite, QUICEncryptionLevelInitial, newSecret); err != nil { // [uTLS]
+		c.sendAlert(alertInternalError)
+		return c.in.setErrorLocked(err)
+	}
+	c.utls.stats.keyUpdatesReceived.Add(1) // [uTLS]
 
 	if keyUpdate.updateRequested {
 		c.out.Lock()
@@ -1366,7 +1426,11 @@ func (c *Conn) handleKeyUpdate(keyUpdate *keyUpdateMsg) error {
 		}
 
 		newSecret := cipherSuite.nextTrafficSecret(c.out.trafficSecret)
-		c.out.setTrafficSecret(cipherSuite, QUICEncryptionLevelInitial, newSecret)
+		if err := c.out.setTrafficSecret(cipherSuite, QUICEncryptionLevelInitial, newSecret); err != nil { // [uTLS]
+			c.sendAlertLocked(alertInternalError)
+			return c.out.setErrorLocked(err)
+		}
+		c.utls.stats.keyUpdatesSent.Add(1) // [uTLS]
 	}
 
 	return nil
@@ -1391,6 +1455,10 @@ func (c *Conn) Read(b []byte) (int, error) {
 	c.in.Lock()
 	defer c.in.Unlock()
 
+	if err := c.completeFalseStart(); err != nil { // [uTLS]
+		return 0, err
+	}
+
 	for c.input.Len() == 0 {
 		if err := c.readRecord(); err != nil {
 			return 0, err
@@ -1434,6 +1502,7 @@ func (c *Conn) Close() error {
 			break
 		}
 	}
+	c.utls.pacer.close() // [uTLS] fail the paced Writes waiting outside c.out
 	if x != 0 {
 		// io.Writer and io.Closer should not be used concurrently.
 		// If Close is called while a Write is currently in-flight,
@@ -1441,7 +1510,15 @@ func (c *Conn) Close() error {
 		// being used to break the Write and/or clean up resources and
 		// avoid sending the alertCloseNotify, which may block
 		// waiting on handshakeMutex or the c.out mutex.
-		return c.conn.Close()
+		// [UTLS SECTION START]
+		err := c.closeNetConn()
+		if c.config.ZeroizeSecrets {
+			// The Write fails now that the connection is closed, and
+			// releases c.out.
+			c.zeroizeSecrets()
+		}
+		return err
+		// [UTLS SECTION END]
 	}
 
 	var alertErr error
@@ -1451,7 +1528,13 @@ func (c *Conn) Close() error {
 		}
 	}
 
-	if err := c.conn.Close(); err != nil {
+	err := c.closeNetConn() // [uTLS]
+	// [UTLS SECTION START]
+	if c.config.ZeroizeSecrets {
+		c.zeroizeSecrets()
+	}
+	// [UTLS SECTION END]
+	if err != nil {
 		return err
 	}
 	return alertErr
@@ -1535,6 +1618,12 @@ func (c *Conn) handshakeContext(ctx context.Context) (ret error) {
 	if c.quic != nil {
 		c.quic.cancelc = handshakeCtx.Done()
 		c.quic.cancel = cancel
+	} else if ctx.Done() != nil && c.config.NoBackgroundTasks { // [uTLS]
+		done, err := c.contextDeadline(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() { ret = done(ret) }()
 	} else if ctx.Done() != nil {
 		// Start the "interrupter" goroutine, if this context might be canceled.
 		// (The background context cannot).
@@ -1575,14 +1664,30 @@ func (c *Conn) handshakeContext(ctx context.Context) (ret error) {
 	c.in.Lock()
 	defer c.in.Unlock()
 
+	// [UTLS SECTION START]
+	c.utls.handshakeCtx = handshakeCtx
+	defer func() { c.utls.handshakeCtx = nil }()
+	release, err := c.acquireHandshakeSlot(handshakeCtx)
+	if err != nil {
+		c.handshakeErr = err
+		return err
+	}
+	defer release()
+	c.configureRecordLayer()
+	// [UTLS SECTION END]
+
+	c.startForensics() // [uTLS]
 	c.handshakeErr = c.handshakeFn(handshakeCtx)
 	if c.handshakeErr == nil {
 		c.handshakes++
 	} else {
+		c.tarpit() // [uTLS]
 		// If an error occurred during the handshake try to flush the
 		// alert that might be left in the buffer.
 		c.flush()
+		c.reportGREASESelected(c.handshakeErr) // [uTLS]
 	}
+	c.handshakeErr = c.finishForensics(c.handshakeErr) // [uTLS]
 
 	if c.handshakeErr == nil && !c.isHandshakeComplete.Load() {
 		c.handshakeErr = errors.New("tls: internal error: handshake should have had a result")
@@ -1650,7 +1755,9 @@ func (c *Conn) connectionStateLocked() ConnectionState {
 			state.TLSUnique = c.serverFinished[:]
 		}
 	}
-	if c.config.Renegotiation != RenegotiateNever {
+	// [uTLS] TLS 1.3 has no renegotiation, so the exporter stays available
+	// to parrots that enable it for TLS 1.2.
+	if c.config.Renegotiation != RenegotiateNever && c.vers != VersionTLS13 {
 		state.ekm = noEKMBecauseRenegotiation
 	} else if c.vers != VersionTLS13 && !c.extMasterSecret {
 		state.ekm = func(label string, context []byte, length int) ([]byte, error) {
@@ -0,0 +1,138 @@
+//go:build ignore
+
+// Rebuild fingerprints/fingerprints.json, the embedded fingerprint database,
+// from the ClientHello files in fingerprints/captures.
+//
+// Each file is named <name>.hex and holds the hex of the TLS record carrying
+// a ClientHello, as copied from a packet capture. Whitespace is ignored. The
+// file starts with "# key: value" header lines:
+//
+//	# browser: Chrome 131
+//	# captured: 2024-11
+//	# source: Wireshark capture, Windows 11
+//	# notes: optional deviations from the real client
+//
+// The source header is the only record of provenance: this tool checks that
+// the ClientHello parses, not that it came from the named browser. Entries
+// that weren't captured from the real client, like the firefox_133 seed entry
+// generated from the HelloFirefox_133 preset, must say so there.
+//
+// Run it with go generate.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tls "github.com/refraction-networking/utls"
+)
+
+var (
+	captures = flag.String("captures", "fingerprints/captures", "Directory of the ClientHello captures")
+	output   = flag.String("o", "fingerprints/fingerprints.json", "Output file")
+)
+
+// fingerprint must match capturedFingerprintJSON in u_fingerprint_db.go.
+type fingerprint struct {
+	Name        string `json:"name"`
+	Browser     string `json:"browser"`
+	Captured    string `json:"captured"`
+	Source      string `json:"source"`
+	Notes       string `json:"notes,omitempty"`
+	ClientHello string `json:"client_hello"`
+}
+
+func main() {
+	flag.Parse()
+
+	files, err := filepath.Glob(filepath.Join(*captures, "*.hex"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fingerprints := make([]fingerprint, 0, len(files))
+	for _, file := range files {
+		fp, err := readCapture(file)
+		if err != nil {
+			log.Fatalf("%s: %v", file, err)
+		}
+		fingerprints = append(fingerprints, fp)
+	}
+	sort.Slice(fingerprints, func(i, j int) bool {
+		return fingerprints[i].Name < fingerprints[j].Name
+	})
+
+	out, err := json.MarshalIndent(fingerprints, "", "\t")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, append(out, '\n'), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func readCapture(file string) (fingerprint, error) {
+	fp := fingerprint{Name: strings.TrimSuffix(filepath.Base(file), ".hex")}
+	f, err := os.Open(file)
+	if err != nil {
+		return fp, err
+	}
+	defer f.Close()
+
+	var data bytes.Buffer
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if header, ok := strings.CutPrefix(line, "#"); ok {
+			key, value, ok := strings.Cut(header, ":")
+			if !ok {
+				return fp, fmt.Errorf("malformed header %q", line)
+			}
+			value = strings.TrimSpace(value)
+			switch strings.TrimSpace(key) {
+			case "browser":
+				fp.Browser = value
+			case "captured":
+				fp.Captured = value
+			case "source":
+				fp.Source = value
+			case "notes":
+				fp.Notes = value
+			default:
+				return fp, fmt.Errorf("unknown header %q", key)
+			}
+			continue
+		}
+		data.WriteString(strings.Join(strings.Fields(line), ""))
+	}
+	if err := s.Err(); err != nil {
+		return fp, err
+	}
+
+	if fp.Browser == "" || fp.Captured == "" || fp.Source == "" {
+		return fp, fmt.Errorf("missing browser, captured or source header")
+	}
+	if _, err := time.Parse("2006-01", fp.Captured); err != nil {
+		return fp, fmt.Errorf("captured must be formatted as YYYY-MM: %v", err)
+	}
+	raw, err := hex.DecodeString(data.String())
+	if err != nil {
+		return fp, err
+	}
+	var spec tls.ClientHelloSpec
+	if err := spec.FromRaw(raw, true); err != nil {
+		return fp, fmt.Errorf("invalid ClientHello: %v", err)
+	}
+	fp.ClientHello = hex.EncodeToString(raw)
+	return fp, nil
+}
@@ -40,7 +40,8 @@ type clientHandshakeState struct {
 	session      *SessionState // the session being resumed
 	ticket       []byte        // a fresh ticket received during this handshake
 
-	uconn *UConn // [uTLS]
+	uconn     *UConn               // [uTLS]
+	ticketMsg *newSessionTicketMsg // [uTLS] the message ticket was received in
 }
 
 var testingOnlyForceClientHelloSignatureAlgorithms []SignatureScheme
@@ -164,7 +165,7 @@ func (c *Conn) makeClientHello() (*clientHelloMsg, *keySharePrivateKeys, *echCli
 		// Note that if X25519MLKEM768 is supported, it will be first because
 		// the preference order is fixed.
 		if curveID == X25519MLKEM768 {
-			keyShareKeys.ecdhe, err = generateECDHEKey(config.rand(), X25519)
+			keyShareKeys.ecdhe, err = config.generateECDHEKey(X25519) // [uTLS]
 			if err != nil {
 				return nil, nil, nil, err
 			}
@@ -191,7 +192,7 @@ func (c *Conn) makeClientHello() (*clientHelloMsg, *keySharePrivateKeys, *echCli
 			if _, ok := curveForCurveID(curveID); !ok {
 				return nil, nil, nil, errors.New("tls: CurvePreferences includes unsupported curve")
 			}
-			keyShareKeys.ecdhe, err = generateECDHEKey(config.rand(), curveID)
+			keyShareKeys.ecdhe, err = config.generateECDHEKey(curveID) // [uTLS]
 			if err != nil {
 				return nil, nil, nil, err
 			}
@@ -326,6 +327,7 @@ func (c *Conn) clientHandshake(ctx context.Context) (err error) {
 
 	c.serverName = hello.serverName
 
+	c.utls.certSignatureAlgorithms = hello.certSignatureAlgorithms() // [uTLS]
 	if _, err := c.writeHandshakeRecord(hello, nil); err != nil {
 		return err
 	}
@@ -356,6 +358,12 @@ func (c *Conn) clientHandshake(ctx context.Context) (err error) {
 		return err
 	}
 
+	// [UTLS SECTION START]
+	if err := c.checkUnsolicitedServerHello(serverHello, hello, ech); err != nil {
+		return err
+	}
+	// [UTLS SECTION END]
+
 	// If we are negotiating a protocol version that's lower than what we
 	// support, check for the server downgrade canaries.
 	// See RFC 8446, Section 4.1.3.
@@ -401,7 +409,7 @@ func (c *Conn) loadSession(hello *clientHelloMsg) (
 		defer c.utls.sessionController.onLoadSessionReturn()
 	}
 	// [UTLS SECTION END]
-	if c.config.SessionTicketsDisabled || c.config.ClientSessionCache == nil {
+	if c.config.SessionTicketsDisabled || c.config.ClientSessionCache == nil && c.config.OnNewSessionTicket == nil { // [uTLS]
 		return nil, nil, nil, nil
 	}
 
@@ -426,7 +434,7 @@ func (c *Conn) loadSession(hello *clientHelloMsg) (
 
 	// Try to resume a previously negotiated TLS session, if available.
 	cacheKey := c.clientSessionCacheKey()
-	if cacheKey == "" {
+	if cacheKey == "" || c.config.ClientSessionCache == nil { // [uTLS]
 		return nil, nil, nil, nil
 	}
 	cs, ok := c.config.ClientSessionCache.Get(cacheKey)
@@ -459,7 +467,7 @@ func (c *Conn) loadSession(hello *clientHelloMsg) (
 		}
 	}
 	// [UTLS SECTION END]
-	if !c.config.InsecureSkipVerify {
+	if !c.config.skipVerify() { // [uTLS]
 		if len(session.verifiedChains) == 0 {
 			// The original connection had InsecureSkipVerify, while this doesn't.
 			return nil, nil, nil, nil
@@ -488,11 +496,9 @@ func (c *Conn) loadSession(hello *clientHelloMsg) (
 
 		// [uTLS] Support SessionID-based resumption
 		// Use the resumeType to determine resumption mechanism
-		// [uTLS] Check if session has extra fields for TLS 1.2 resumption
-		utlsData := GetSessionExtraFields(session)
-		if utlsData != nil && utlsData.ResumeType == ResumeSessionID {
+		if session.resumeType == ResumeSessionID {
 			// SessionID resumption: use sessionId field
-			hello.sessionId = utlsData.SessionID
+			hello.sessionId = session.sessionId
 			hello.sessionTicket = nil
 		} else {
 			// Session Ticket resumption: use ticket field
@@ -543,11 +549,15 @@ func (c *Conn) loadSession(hello *clientHelloMsg) (
 	}
 
 	// Set the pre_shared_key extension. See RFC 8446, Section 4.2.11.1.
-	ticketAge := c.config.time().Sub(time.Unix(int64(session.createdAt), 0))
 	identity := pskIdentity{
 		label:               session.ticket,
-		obfuscatedTicketAge: uint32(ticketAge/time.Millisecond) + session.ageAdd,
+		obfuscatedTicketAge: c.obfuscatedTicketAge(session), // [uTLS]
+	}
+	// [UTLS SECTION START]
+	if identity, err = c.transformPskIdentity(identity, session); err != nil {
+		return nil, nil, nil, err
 	}
+	// [UTLS SECTION END]
 	hello.pskIdentities = []pskIdentity{identity}
 	hello.pskBinders = [][]byte{make([]byte, cipherSuite.hash.Size())}
 
@@ -573,11 +583,22 @@ func (c *Conn) pickTLSVersion(serverHello *serverHelloMsg) error {
 		peerVersion = serverHello.supportedVersion
 	}
 
+	// [UTLS SECTION START]
+	if isGREASEUint16(peerVersion) {
+		return c.rejectGREASE(GREASEVersion, peerVersion)
+	}
+	// [UTLS SECTION END]
 	vers, ok := c.config.mutualVersion(roleClient, []uint16{peerVersion})
 	if !ok {
 		c.sendAlert(alertProtocolVersion)
 		return fmt.Errorf("tls: server selected unsupported protocol version %x", peerVersion)
 	}
+	// [UTLS SECTION START]
+	if err := c.checkAcceptedVersion(vers); err != nil {
+		c.sendAlert(alertProtocolVersion)
+		return err
+	}
+	// [UTLS SECTION END]
 
 	c.vers = vers
 	c.haveVers = true
@@ -656,6 +677,15 @@ func (hs *clientHandshakeState) handshake() error {
 			return err
 		}
 		c.clientFinishedIsFirst = true
+		// [UTLS SECTION START]
+		if hs.canFalseStart() {
+			// The first Read completes the handshake.
+			c.utls.falseStart = hs.finishFalseStart
+			c.ekm = noEKMBeforeFalseStartCompletes
+			c.isHandshakeComplete.Store(true)
+			return nil
+		}
+		// [UTLS SECTION END]
 		if err := hs.readSessionTicket(); err != nil {
 			return err
 		}
@@ -663,17 +693,26 @@ func (hs *clientHandshakeState) handshake() error {
 			return err
 		}
 	}
+	// [UTLS SECTION START]
+	if err := c.checkResumption(hs.session); err != nil {
+		return err
+	}
+	// [UTLS SECTION END]
 	if err := hs.saveSessionTicket(); err != nil {
 		return err
 	}
 
 	c.ekm = ekmFromMasterSecret(c.vers, hs.suite, hs.masterSecret, hs.hello.random, hs.serverHello.random)
+	c.utls.transcriptHash = hs.finishedHash.Sum() // [uTLS]
 	c.isHandshakeComplete.Store(true)
 
 	return nil
 }
 
 func (hs *clientHandshakeState) pickCipherSuite() error {
+	if isGREASEUint16(hs.serverHello.cipherSuite) { // [uTLS]
+		return hs.c.rejectGREASE(GREASECipherSuite, hs.serverHello.cipherSuite)
+	}
 	if hs.suite = mutualCipherSuite(hs.hello.cipherSuites, hs.serverHello.cipherSuite); hs.suite == nil {
 		hs.c.sendAlert(alertHandshakeFailure)
 		return errors.New("tls: server chose an unconfigured cipher suite")
@@ -748,8 +787,16 @@ func (hs *clientHandshakeState) doFullHandshake() error {
 		// See https://mitls.org/pages/attacks/3SHAKE for the
 		// motivation behind this requirement.
 		if !bytes.Equal(c.peerCertificates[0].Raw, certMsg.certificates[0]) {
-			c.sendAlert(alertBadCertificate)
-			return errors.New("tls: server's identity changed during renegotiation")
+			// [UTLS SECTION START]
+			if c.config.AllowServerCertificateChange {
+				if err := c.verifyServerCertificate(certMsg.certificates); err != nil {
+					return err
+				}
+			} else {
+				c.sendAlert(alertBadCertificate)
+				return errors.New("tls: server's identity changed during renegotiation")
+			}
+			// [UTLS SECTION END]
 		}
 	}
 
@@ -896,8 +943,17 @@ func (hs *clientHandshakeState) establishKeys() error {
 		serverCipher = hs.suite.cipher(serverKey, serverIV, true /* for reading */)
 		serverHash = hs.suite.mac(serverMAC)
 	} else {
-		clientCipher = hs.suite.aead(clientKey, clientIV)
-		serverCipher = hs.suite.aead(serverKey, serverIV)
+		// [UTLS SECTION START]
+		var err error
+		if clientCipher, err = c.out.recordAEAD(hs.suite.id, hs.suite.aead, clientKey, clientIV); err != nil {
+			c.sendAlert(alertInternalError)
+			return err
+		}
+		if serverCipher, err = c.in.recordAEAD(hs.suite.id, hs.suite.aead, serverKey, serverIV); err != nil {
+			c.sendAlert(alertInternalError)
+			return err
+		}
+		// [UTLS SECTION END]
 	}
 
 	c.in.prepareCipherSpec(c.vers, serverCipher, serverHash)
@@ -920,6 +976,12 @@ func (hs *clientHandshakeState) processServerHello() (bool, error) {
 	}
 
 	if hs.serverHello.compressionMethod != compressionNone {
+		// [UTLS SECTION START]
+		if slices.Contains(hs.hello.compressionMethods, hs.serverHello.compressionMethod) {
+			c.sendAlert(alertHandshakeFailure)
+			return false, fmt.Errorf("tls: server selected compression method %d, which was offered by the ClientHelloSpec but is not supported", hs.serverHello.compressionMethod)
+		}
+		// [UTLS SECTION END]
 		c.sendAlert(alertUnexpectedMessage)
 		return false, errors.New("tls: server selected unsupported compression format")
 	}
@@ -999,6 +1061,9 @@ func checkALPN(clientProtos []string, serverProto string, quic bool) error {
 	if len(clientProtos) == 0 {
 		return errors.New("tls: server advertised unrequested ALPN extension")
 	}
+	if isGREASEALPN(serverProto) { // [uTLS]
+		return &GREASESelectedError{Field: GREASEALPNProtocol, Value: uint16(serverProto[0])<<8 | uint16(serverProto[1])}
+	}
 	for _, proto := range clientProtos {
 		if proto == serverProto {
 			return nil
@@ -1062,8 +1127,10 @@ func (hs *clientHandshakeState) readSessionTicket() error {
 		c.sendAlert(alertUnexpectedMessage)
 		return unexpectedMessageError(sessionTicketMsg, msg)
 	}
+	c.utls.serverIssuedTicket.Store(true) // [uTLS]
 
 	hs.ticket = sessionTicketMsg.ticket
+	hs.ticketMsg = sessionTicketMsg // [uTLS]
 	return nil
 }
 
@@ -1118,7 +1185,14 @@ func (hs *clientHandshakeState) saveSessionTicket() error {
 
 	cs := &ClientSessionState{session: session}
 	// [UTLS BEGIN]
-	if c.config.ClientSessionCache != nil { // skip saving session if cache is nil
+	store := c.config.ClientSessionCache != nil // skip saving session if cache is nil
+	if hs.ticketMsg != nil {
+		var err error
+		if store, err = c.onNewSessionTicket(newSessionTicketFromTLS12(hs.ticketMsg), cs); err != nil {
+			return err
+		}
+	}
+	if store {
 		c.config.ClientSessionCache.Put(cacheKey, cs)
 	}
 	// [UTLS END]
@@ -1165,6 +1239,18 @@ func checkKeySize(n int) (max int, ok bool) {
 // verifyServerCertificate parses and verifies the provided chain, setting
 // c.verifiedChains and c.peerCertificates or sending the appropriate alert.
 func (c *Conn) verifyServerCertificate(certificates [][]byte) error {
+	// [UTLS SECTION START]
+	if c.config.OnPeerCertificates != nil {
+		if err := c.config.OnPeerCertificates(certificates); err != nil {
+			c.sendAlert(alertBadCertificate)
+			return err
+		}
+	}
+	if ok, err := c.verifyCachedServerCertificate(certificates); ok {
+		return err
+	}
+	// [UTLS SECTION END]
+
 	activeHandles := make([]*activeCert, len(certificates))
 	certs := make([]*x509.Certificate, len(certificates))
 	for i, asn1Data := range certificates {
@@ -1193,7 +1279,7 @@ func (c *Conn) verifyServerCertificate(certificates [][]byte) error {
 			}
 		} else {
 			opts := x509.VerifyOptions{
-				Roots:       c.config.RootCAs,
+				Roots:       c.config.rootCAs(), // [uTLS]
 				CurrentTime: c.config.time(),
 				// DNSName:       c.serverName, // [uTLS]
 				Intermediates: x509.NewCertPool(),
@@ -1226,10 +1312,10 @@ func (c *Conn) verifyServerCertificate(certificates [][]byte) error {
 				return &CertificateVerificationError{UnverifiedCertificates: certs, Err: err}
 			}
 		}
-	} else if !c.config.InsecureSkipVerify {
+	} else if !c.config.skipVerify() { // [uTLS]
 		// [UTLS SECTION START]
 		opts := x509.VerifyOptions{
-			Roots:       c.config.RootCAs,
+			Roots:       c.config.rootCAs(), // [uTLS]
 			CurrentTime: c.config.time(),
 			// DNSName:       c.serverName, // [uTLS]
 			Intermediates: x509.NewCertPool(),
@@ -1250,6 +1336,11 @@ func (c *Conn) verifyServerCertificate(certificates [][]byte) error {
 			opts.Intermediates.AddCert(cert)
 		}
 		chains, err := certs[0].Verify(opts)
+		// [UTLS SECTION START]
+		if err != nil && c.config.AIAFetcher != nil {
+			chains, err = c.config.AIAFetcher.verifyWithAIA(c.handshakeContextOrBackground(), certs[0], opts, err)
+		}
+		// [UTLS SECTION END]
 		if err != nil {
 			c.sendAlert(alertBadCertificate)
 			return &CertificateVerificationError{UnverifiedCertificates: certs, Err: err}
@@ -1270,8 +1361,15 @@ func (c *Conn) verifyServerCertificate(certificates [][]byte) error {
 		return fmt.Errorf("tls: server's certificate contains an unsupported type of public key: %T", certs[0].PublicKey)
 	}
 
+	// [UTLS SECTION START]
+	if err := c.checkCertSignatureAlgorithms(); err != nil {
+		return err
+	}
+	// [UTLS SECTION END]
+
 	c.activeCertHandles = activeHandles
 	c.peerCertificates = certs
+	c.cacheVerifiedServerCertificate(certificates) // [uTLS]
 
 	if c.config.VerifyPeerCertificate != nil && !echRejected {
 		if err := c.config.VerifyPeerCertificate(certificates, c.verifiedChains); err != nil {
@@ -156,6 +156,9 @@ func (hs *clientHandshakeStateTLS13) handshake() error {
 	if err := hs.serverFinishedReceived(); err != nil {
 		return err
 	}
+	if err := c.checkResumption(hs.session); err != nil {
+		return err
+	}
 	// [UTLS SECTION END]
 	if err := hs.sendClientCertificate(); err != nil {
 		return err
@@ -218,6 +221,9 @@ func (hs *clientHandshakeStateTLS13) checkServerHelloOrHRR() error {
 		return errors.New("tls: server selected unsupported compression format")
 	}
 
+	if isGREASEUint16(hs.serverHello.cipherSuite) { // [uTLS]
+		return c.rejectGREASE(GREASECipherSuite, hs.serverHello.cipherSuite)
+	}
 	selectedSuite := mutualCipherSuiteTLS13(hs.hello.cipherSuites, hs.serverHello.cipherSuite)
 	if hs.suite != nil && selectedSuite != hs.suite {
 		c.sendAlert(alertIllegalParameter)
@@ -326,6 +332,9 @@ func (hs *clientHandshakeStateTLS13) processHelloRetryRequest() error {
 	// a group we advertised but did not send a key share for, and send a key
 	// share for it this time.
 	if curveID := hs.serverHello.selectedGroup; curveID != 0 {
+		if isGREASEUint16(uint16(curveID)) { // [uTLS]
+			return c.rejectGREASE(GREASEGroup, uint16(curveID))
+		}
 		if !slices.Contains(hello.supportedCurves, curveID) {
 			c.sendAlert(alertIllegalParameter)
 			return errors.New("tls: server selected unsupported group")
@@ -345,7 +354,7 @@ func (hs *clientHandshakeStateTLS13) processHelloRetryRequest() error {
 			c.sendAlert(alertInternalError)
 			return errors.New("tls: CurvePreferences includes unsupported curve")
 		}
-		key, err := generateECDHEKey(c.config.rand(), curveID)
+		key, err := c.config.generateECDHEKey(curveID) // [uTLS]
 		if err != nil {
 			c.sendAlert(alertInternalError)
 			return err
@@ -361,8 +370,9 @@ func (hs *clientHandshakeStateTLS13) processHelloRetryRequest() error {
 		}
 		if pskSuite.hash == hs.suite.hash {
 			// Update binders and obfuscated_ticket_age.
-			ticketAge := c.config.time().Sub(time.Unix(int64(hs.session.createdAt), 0))
-			hello.pskIdentities[0].obfuscatedTicketAge = uint32(ticketAge/time.Millisecond) + hs.session.ageAdd
+			// [uTLS] The identity stays as transformed by TransformPskIdentity,
+			// and so does the offset it added to the age.
+			hello.pskIdentities[0].obfuscatedTicketAge = c.obfuscatedTicketAge(hs.session) + c.utls.pskAgeTransform
 
 			transcript := hs.suite.hash.New()
 			transcript.Write([]byte{typeMessageHash, 0, 0, uint8(len(chHash))})
@@ -499,7 +509,14 @@ func (hs *clientHandshakeStateTLS13) processHelloRetryRequest() error {
 		return err
 	}
 
+	// [UTLS SECTION START]
+	if err := c.checkUnsolicitedServerHello(serverHello, hs.hello, hs.echContext); err != nil {
+		return err
+	}
+	// [UTLS SECTION END]
+
 	c.didHRR = true
+	c.utls.stats.helloRetryRequests.Add(1) // [uTLS]
 	return nil
 }
 
@@ -525,6 +542,9 @@ func (hs *clientHandshakeStateTLS13) processServerHello() error {
 		c.sendAlert(alertIllegalParameter)
 		return errors.New("tls: server did not send a key share")
 	}
+	if group := hs.serverHello.serverShare.group; isGREASEUint16(uint16(group)) { // [uTLS]
+		return c.rejectGREASE(GREASEGroup, uint16(group))
+	}
 	if !slices.ContainsFunc(hs.hello.keyShares, func(ks keyShare) bool {
 		return ks.group == hs.serverHello.serverShare.group
 	}) {
@@ -654,9 +674,15 @@ func (hs *clientHandshakeStateTLS13) establishHandshakeKeys() error {
 	handshakeSecret := earlySecret.HandshakeSecret(sharedKey)
 
 	clientSecret := handshakeSecret.ClientHandshakeTrafficSecret(hs.transcript)
-	c.out.setTrafficSecret(hs.suite, QUICEncryptionLevelHandshake, clientSecret)
+	if err := c.out.setTrafficSecret(hs.suite, QUICEncryptionLevelHandshake, clientSecret); err != nil { // [uTLS]
+		c.sendAlert(alertInternalError)
+		return err
+	}
 	serverSecret := handshakeSecret.ServerHandshakeTrafficSecret(hs.transcript)
-	c.in.setTrafficSecret(hs.suite, QUICEncryptionLevelHandshake, serverSecret)
+	if err := c.in.setTrafficSecret(hs.suite, QUICEncryptionLevelHandshake, serverSecret); err != nil { // [uTLS]
+		c.sendAlert(alertInternalError)
+		return err
+	}
 
 	if c.quic != nil {
 		if c.hand.Len() != 0 {
@@ -696,6 +722,12 @@ func (hs *clientHandshakeStateTLS13) readServerParameters() error {
 		return unexpectedMessageError(encryptedExtensions, msg)
 	}
 
+	// [UTLS SECTION START]
+	if err := c.checkUnsolicitedEncryptedExtensions(encryptedExtensions, hs.hello, hs.echContext); err != nil {
+		return err
+	}
+	// [UTLS SECTION END]
+
 	if err := checkALPN(hs.hello.alpnProtocols, encryptedExtensions.alpnProtocol, c.quic != nil); err != nil {
 		// RFC 8446 specifies that no_application_protocol is sent by servers, but
 		// does not specify how clients handle the selection of an incompatible protocol.
@@ -907,7 +939,10 @@ func (hs *clientHandshakeStateTLS13) readServerFinished() error {
 
 	hs.trafficSecret = hs.masterSecret.ClientApplicationTrafficSecret(hs.transcript)
 	serverSecret := hs.masterSecret.ServerApplicationTrafficSecret(hs.transcript)
-	c.in.setTrafficSecret(hs.suite, QUICEncryptionLevelApplication, serverSecret)
+	if err := c.in.setTrafficSecret(hs.suite, QUICEncryptionLevelApplication, serverSecret); err != nil { // [uTLS]
+		c.sendAlert(alertInternalError)
+		return err
+	}
 
 	err = c.config.writeKeyLog(keyLogLabelClientTraffic, hs.hello.random, hs.trafficSecret)
 	if err != nil {
@@ -921,6 +956,7 @@ func (hs *clientHandshakeStateTLS13) readServerFinished() error {
 	}
 
 	c.ekm = hs.suite.exportKeyingMaterial(hs.masterSecret, hs.transcript)
+	c.utls.transcriptHash = hs.transcript.Sum(nil) // [uTLS]
 
 	return nil
 }
@@ -1010,9 +1046,12 @@ func (hs *clientHandshakeStateTLS13) sendClientFinished() error {
 		return err
 	}
 
-	c.out.setTrafficSecret(hs.suite, QUICEncryptionLevelApplication, hs.trafficSecret)
+	if err := c.out.setTrafficSecret(hs.suite, QUICEncryptionLevelApplication, hs.trafficSecret); err != nil { // [uTLS]
+		c.sendAlert(alertInternalError)
+		return err
+	}
 
-	if !c.config.SessionTicketsDisabled && c.config.ClientSessionCache != nil {
+	if !c.config.SessionTicketsDisabled && (c.config.ClientSessionCache != nil || c.config.OnNewSessionTicket != nil) { // [uTLS]
 		c.resumptionSecret = hs.masterSecret.ResumptionMasterSecret(hs.transcript)
 	}
 
@@ -1032,7 +1071,14 @@ func (c *Conn) handleNewSessionTicket(msg *newSessionTicketMsgTLS13) error {
 		return errors.New("tls: received new session ticket from a client")
 	}
 
-	if c.config.SessionTicketsDisabled || c.config.ClientSessionCache == nil {
+	// [UTLS SECTION START]
+	c.utls.serverIssuedTicket.Store(true)
+	if msg.maxEarlyData > 0 {
+		c.utls.serverAllowsEarlyData.Store(true)
+	}
+	// [UTLS SECTION END]
+
+	if c.config.SessionTicketsDisabled || c.config.ClientSessionCache == nil && c.config.OnNewSessionTicket == nil { // [uTLS]
 		return nil
 	}
 
@@ -1071,6 +1117,11 @@ func (c *Conn) handleNewSessionTicket(msg *newSessionTicketMsgTLS13) error {
 		return nil
 	}
 	cs := &ClientSessionState{session: session}
+	// [UTLS SECTION START]
+	if store, err := c.onNewSessionTicket(newSessionTicketFromTLS13(msg), cs); err != nil || !store {
+		return err
+	}
+	// [UTLS SECTION END]
 	if cacheKey := c.clientSessionCacheKey(); cacheKey != "" {
 		c.config.ClientSessionCache.Put(cacheKey, cs)
 	}
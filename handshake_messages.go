@@ -183,9 +183,16 @@ func (m *clientHelloMsg) marshalMsgReorderOuterExts(echInner bool, outerExts []u
 	// be added to the following block, so that they can be properly
 	// decompressed on the other side.
 	var echOuterExts []uint16
+	// [uTLS SECTION BEGIN]
+	// compress reports whether ext is referenced through ech_outer_extensions
+	// rather than repeated in the inner ClientHello.
+	compress := func(ext uint16) bool {
+		return echInner && (outerExts == nil || slices.Contains(outerExts, ext))
+	}
+	// [uTLS SECTION END]
 	if m.ocspStapling {
 		// RFC 4366, Section 3.6
-		if echInner {
+		if compress(extensionStatusRequest) { // uTLS
 			echOuterExts = append(echOuterExts, extensionStatusRequest)
 		} else {
 			exts.AddUint16(extensionStatusRequest)
@@ -198,7 +205,7 @@ func (m *clientHelloMsg) marshalMsgReorderOuterExts(echInner bool, outerExts []u
 	}
 	if len(m.supportedCurves) > 0 {
 		// RFC 4492, sections 5.1.1 and RFC 8446, Section 4.2.7
-		if echInner {
+		if compress(extensionSupportedCurves) { // uTLS
 			echOuterExts = append(echOuterExts, extensionSupportedCurves)
 		} else {
 			exts.AddUint16(extensionSupportedCurves)
@@ -213,7 +220,7 @@ func (m *clientHelloMsg) marshalMsgReorderOuterExts(echInner bool, outerExts []u
 	}
 	if len(m.supportedSignatureAlgorithms) > 0 {
 		// RFC 5246, Section 7.4.1.4.1
-		if echInner {
+		if compress(extensionSignatureAlgorithms) { // uTLS
 			echOuterExts = append(echOuterExts, extensionSignatureAlgorithms)
 		} else {
 			exts.AddUint16(extensionSignatureAlgorithms)
@@ -228,7 +235,7 @@ func (m *clientHelloMsg) marshalMsgReorderOuterExts(echInner bool, outerExts []u
 	}
 	if len(m.supportedSignatureAlgorithmsCert) > 0 {
 		// RFC 8446, Section 4.2.3
-		if echInner {
+		if compress(extensionSignatureAlgorithmsCert) { // uTLS
 			echOuterExts = append(echOuterExts, extensionSignatureAlgorithmsCert)
 		} else {
 			exts.AddUint16(extensionSignatureAlgorithmsCert)
@@ -243,7 +250,7 @@ func (m *clientHelloMsg) marshalMsgReorderOuterExts(echInner bool, outerExts []u
 	}
 	if len(m.alpnProtocols) > 0 {
 		// RFC 7301, Section 3.1
-		if echInner {
+		if compress(extensionALPN) { // uTLS
 			echOuterExts = append(echOuterExts, extensionALPN)
 		} else {
 			exts.AddUint16(extensionALPN)
@@ -275,7 +282,7 @@ func (m *clientHelloMsg) marshalMsgReorderOuterExts(echInner bool, outerExts []u
 	}
 	if len(m.cookie) > 0 {
 		// RFC 8446, Section 4.2.2
-		if echInner {
+		if compress(extensionCookie) { // uTLS
 			echOuterExts = append(echOuterExts, extensionCookie)
 		} else {
 			exts.AddUint16(extensionCookie)
@@ -288,7 +295,7 @@ func (m *clientHelloMsg) marshalMsgReorderOuterExts(echInner bool, outerExts []u
 	}
 	if len(m.keyShares) > 0 {
 		// RFC 8446, Section 4.2.8
-		if echInner {
+		if compress(extensionKeyShare) { // uTLS
 			echOuterExts = append(echOuterExts, extensionKeyShare)
 		} else {
 			exts.AddUint16(extensionKeyShare)
@@ -306,7 +313,7 @@ func (m *clientHelloMsg) marshalMsgReorderOuterExts(echInner bool, outerExts []u
 	}
 	if len(m.pskModes) > 0 {
 		// RFC 8446, Section 4.2.9
-		if echInner {
+		if compress(extensionPSKModes) { // uTLS
 			echOuterExts = append(echOuterExts, extensionPSKModes)
 		} else {
 			exts.AddUint16(extensionPSKModes)
@@ -1107,6 +1114,7 @@ func (m *encryptedExtensionsMsg) unmarshal(data []byte) bool {
 			!extensions.ReadUint16LengthPrefixed(&extData) {
 			return false
 		}
+		m.utls.extensions = append(m.utls.extensions, extension) // [uTLS]
 
 		switch extension {
 		case extensionALPN:
@@ -1922,7 +1930,8 @@ func (m *certificateVerifyMsg) unmarshal(data []byte) bool {
 }
 
 type newSessionTicketMsg struct {
-	ticket []byte
+	lifetimeHint uint32 // [uTLS]
+	ticket       []byte
 }
 
 func (m *newSessionTicketMsg) marshal() ([]byte, error) {
@@ -1934,6 +1943,12 @@ func (m *newSessionTicketMsg) marshal() ([]byte, error) {
 	x[1] = uint8(length >> 16)
 	x[2] = uint8(length >> 8)
 	x[3] = uint8(length)
+	// [UTLS SECTION START]
+	x[4] = uint8(m.lifetimeHint >> 24)
+	x[5] = uint8(m.lifetimeHint >> 16)
+	x[6] = uint8(m.lifetimeHint >> 8)
+	x[7] = uint8(m.lifetimeHint)
+	// [UTLS SECTION END]
 	x[8] = uint8(ticketLen >> 8)
 	x[9] = uint8(ticketLen)
 	copy(x[10:], m.ticket)
@@ -1956,6 +1971,7 @@ func (m *newSessionTicketMsg) unmarshal(data []byte) bool {
 		return false
 	}
 
+	m.lifetimeHint = uint32(data[4])<<24 | uint32(data[5])<<16 | uint32(data[6])<<8 | uint32(data[7]) // [uTLS]
 	m.ticket = data[10:]
 
 	return true
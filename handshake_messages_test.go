@@ -100,6 +100,8 @@ func TestMarshalUnmarshal(t *testing.T) {
 					t.original = nil
 				case *certificateRequestMsgTLS13: // [UTLS]
 					t.original = nil // [UTLS]
+				case *encryptedExtensionsMsg: // [UTLS]
+					t.utls.extensions = nil // [UTLS]
 				}
 
 				if !reflect.DeepEqual(m1, m) {
@@ -359,6 +361,7 @@ func (*finishedMsg) Generate(rand *rand.Rand, size int) reflect.Value {
 
 func (*newSessionTicketMsg) Generate(rand *rand.Rand, size int) reflect.Value {
 	m := &newSessionTicketMsg{}
+	m.lifetimeHint = rand.Uint32() // [uTLS]
 	m.ticket = randomBytes(rand.Intn(4), rand)
 	return reflect.ValueOf(m)
 }
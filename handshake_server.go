@@ -127,6 +127,7 @@ func (hs *serverHandshakeState) handshake() error {
 	}
 
 	c.ekm = ekmFromMasterSecret(c.vers, hs.suite, hs.masterSecret, hs.clientHello.random, hs.hello.random)
+	c.utls.transcriptHash = hs.finishedHash.Sum() // [uTLS]
 	c.isHandshakeComplete.Store(true)
 
 	return nil
@@ -165,6 +166,7 @@ func (c *Conn) readClientHello(ctx context.Context) (*clientHelloMsg, *echServer
 			return nil, nil, err
 		} else if configForClient != nil {
 			c.config = configForClient
+			c.configureRecordLayer() // [uTLS]
 		}
 	}
 	c.ticketKeys = originalConfig.ticketKeys(configForClient)
@@ -255,7 +257,7 @@ func (hs *serverHandshakeState) processClientHello() error {
 		c.serverName = hs.clientHello.serverName
 	}
 
-	selectedProto, err := negotiateALPN(c.config.NextProtos, hs.clientHello.alpnProtocols, false)
+	selectedProto, err := negotiateALPN(c.config.serverNextProtos(hs.clientHello.alpnProtocols), hs.clientHello.alpnProtocols, false) // [uTLS]
 	if err != nil {
 		c.sendAlert(alertNoApplicationProtocol)
 		return err
@@ -317,6 +319,7 @@ func (hs *serverHandshakeState) processClientHello() error {
 // preference order. If ALPN is not configured or the peer doesn't support it,
 // it returns "" and no error.
 func negotiateALPN(serverProtos, clientProtos []string, quic bool) (string, error) {
+	clientProtos = withoutGREASEALPN(clientProtos) // [uTLS]
 	if len(serverProtos) == 0 || len(clientProtos) == 0 {
 		if quic && len(serverProtos) != 0 {
 			// RFC 9001, Section 8.1
@@ -607,6 +610,7 @@ func (hs *serverHandshakeState) doFullHandshake() error {
 	if _, err := hs.c.writeHandshakeRecord(certMsg, &hs.finishedHash); err != nil {
 		return err
 	}
+	c.utls.localCertificate = hs.cert.Certificate[0] // [uTLS]
 
 	if hs.hello.ocspStapling {
 		certStatus := new(certificateStatusMsg)
@@ -798,8 +802,17 @@ func (hs *serverHandshakeState) establishKeys() error {
 		serverCipher = hs.suite.cipher(serverKey, serverIV, false /* not for reading */)
 		serverHash = hs.suite.mac(serverMAC)
 	} else {
-		clientCipher = hs.suite.aead(clientKey, clientIV)
-		serverCipher = hs.suite.aead(serverKey, serverIV)
+		// [UTLS SECTION START]
+		var err error
+		if clientCipher, err = c.in.recordAEAD(hs.suite.id, hs.suite.aead, clientKey, clientIV); err != nil {
+			c.sendAlert(alertInternalError)
+			return err
+		}
+		if serverCipher, err = c.out.recordAEAD(hs.suite.id, hs.suite.aead, serverKey, serverIV); err != nil {
+			c.sendAlert(alertInternalError)
+			return err
+		}
+		// [UTLS SECTION END]
 	}
 
 	c.in.prepareCipherSpec(c.vers, clientCipher, clientHash)
@@ -1004,6 +1017,7 @@ func clientHelloInfo(ctx context.Context, c *Conn, clientHello *clientHelloMsg)
 		Extensions:        clientHello.extensions,
 		Conn:              c.conn,
 		config:            c.config,
-		ctx:               ctx,
+		ctx:               c.config.certificateLookupContext(ctx), // [uTLS]
+		hello:             clientHello,                            // [uTLS]
 	}
 }
@@ -259,7 +259,7 @@ func (hs *serverHandshakeStateTLS13) processClientHello() error {
 		c.sendAlert(alertInternalError)
 		return errors.New("tls: CurvePreferences includes unsupported curve")
 	}
-	key, err := generateECDHEKey(c.config.rand(), ecdhGroup)
+	key, err := c.config.generateECDHEKey(ecdhGroup) // [uTLS]
 	if err != nil {
 		c.sendAlert(alertInternalError)
 		return err
@@ -295,7 +295,7 @@ func (hs *serverHandshakeStateTLS13) processClientHello() error {
 		hs.hello.serverShare.data = append(ciphertext, hs.hello.serverShare.data...)
 	}
 
-	selectedProto, err := negotiateALPN(c.config.NextProtos, hs.clientHello.alpnProtocols, c.quic != nil)
+	selectedProto, err := negotiateALPN(c.config.serverNextProtos(hs.clientHello.alpnProtocols), hs.clientHello.alpnProtocols, c.quic != nil) // [uTLS]
 	if err != nil {
 		c.sendAlert(alertNoApplicationProtocol)
 		return err
@@ -508,7 +508,11 @@ func (hs *serverHandshakeStateTLS13) pickCertificate() error {
 		return c.sendAlert(alertMissingExtension)
 	}
 
-	certificate, err := c.config.getCertificate(clientHelloInfo(hs.ctx, c, hs.clientHello))
+	// [UTLS SECTION START]
+	chi := clientHelloInfo(hs.ctx, c, hs.clientHello)
+	chi.echAccepted = hs.echContext != nil && !hs.echContext.inner
+	certificate, err := c.config.getCertificate(chi)
+	// [UTLS SECTION END]
 	if err != nil {
 		if err == errNoCertificates {
 			c.sendAlert(alertUnrecognizedName)
@@ -663,6 +667,7 @@ func (hs *serverHandshakeStateTLS13) doHelloRetryRequest(selectedGroup CurveID)
 	}
 
 	c.didHRR = true
+	c.utls.stats.helloRetryRequests.Add(1) // [uTLS]
 	hs.clientHello = clientHello
 	return ks, nil
 }
@@ -764,9 +769,15 @@ func (hs *serverHandshakeStateTLS13) sendServerParameters() error {
 	hs.handshakeSecret = earlySecret.HandshakeSecret(hs.sharedKey)
 
 	clientSecret := hs.handshakeSecret.ClientHandshakeTrafficSecret(hs.transcript)
-	c.in.setTrafficSecret(hs.suite, QUICEncryptionLevelHandshake, clientSecret)
+	if err := c.in.setTrafficSecret(hs.suite, QUICEncryptionLevelHandshake, clientSecret); err != nil { // [uTLS]
+		c.sendAlert(alertInternalError)
+		return err
+	}
 	serverSecret := hs.handshakeSecret.ServerHandshakeTrafficSecret(hs.transcript)
-	c.out.setTrafficSecret(hs.suite, QUICEncryptionLevelHandshake, serverSecret)
+	if err := c.out.setTrafficSecret(hs.suite, QUICEncryptionLevelHandshake, serverSecret); err != nil { // [uTLS]
+		c.sendAlert(alertInternalError)
+		return err
+	}
 
 	if c.quic != nil {
 		if c.hand.Len() != 0 {
@@ -852,6 +863,7 @@ func (hs *serverHandshakeStateTLS13) sendServerCertificate() error {
 	if _, err := hs.c.writeHandshakeRecord(certMsg, hs.transcript); err != nil {
 		return err
 	}
+	c.utls.localCertificate = hs.cert.Certificate[0] // [uTLS]
 
 	certVerifyMsg := new(certificateVerifyMsg)
 	certVerifyMsg.hasSignatureAlgorithm = true
@@ -904,7 +916,10 @@ func (hs *serverHandshakeStateTLS13) sendServerFinished() error {
 
 	hs.trafficSecret = hs.masterSecret.ClientApplicationTrafficSecret(hs.transcript)
 	serverSecret := hs.masterSecret.ServerApplicationTrafficSecret(hs.transcript)
-	c.out.setTrafficSecret(hs.suite, QUICEncryptionLevelApplication, serverSecret)
+	if err := c.out.setTrafficSecret(hs.suite, QUICEncryptionLevelApplication, serverSecret); err != nil { // [uTLS]
+		c.sendAlert(alertInternalError)
+		return err
+	}
 
 	if c.quic != nil {
 		if c.hand.Len() != 0 {
@@ -926,6 +941,7 @@ func (hs *serverHandshakeStateTLS13) sendServerFinished() error {
 	}
 
 	c.ekm = hs.suite.exportKeyingMaterial(hs.masterSecret, hs.transcript)
+	c.utls.transcriptHash = hs.transcript.Sum(nil) // [uTLS]
 
 	// If we did not request client certificates, at this point we can
 	// precompute the client finished and roll the transcript forward to send
@@ -1142,7 +1158,10 @@ func (hs *serverHandshakeStateTLS13) readClientFinished() error {
 		return errors.New("tls: invalid client finished hash")
 	}
 
-	c.in.setTrafficSecret(hs.suite, QUICEncryptionLevelApplication, hs.trafficSecret)
+	if err := c.in.setTrafficSecret(hs.suite, QUICEncryptionLevelApplication, hs.trafficSecret); err != nil { // [uTLS]
+		c.sendAlert(alertInternalError)
+		return err
+	}
 
 	return nil
 }
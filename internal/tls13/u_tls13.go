@@ -29,3 +29,18 @@ func (s *MasterSecret) Secret() []byte {
 	}
 	return nil
 }
+
+func NewExporterMasterSecretFromSecret[H fips140.Hash](hash func() H, secret []byte) *ExporterMasterSecret {
+	return &ExporterMasterSecret{
+		secret: secret,
+		hash:   func() fips140.Hash { return hash() },
+	}
+}
+
+const externalBinderLabel = "ext binder"
+
+// ExternalBinderKey derives the binder_key of an external PSK, as opposed to
+// ResumptionBinderKey for resumption PSKs.
+func (s *EarlySecret) ExternalBinderKey() []byte {
+	return deriveSecret(s.hash, s.secret, externalBinderLabel, nil)
+}
@@ -182,7 +182,7 @@ func (ka *ecdheKeyAgreement) generateServerKeyExchange(config *Config, cert *Cer
 		return nil, errors.New("tls: CurvePreferences includes unsupported curve")
 	}
 
-	key, err := generateECDHEKey(config.rand(), curveID)
+	key, err := config.generateECDHEKey(curveID) // [uTLS]
 	if err != nil {
 		return nil, err
 	}
@@ -297,7 +297,7 @@ func (ka *ecdheKeyAgreement) processServerKeyExchange(config *Config, clientHell
 		return errors.New("tls: server selected unsupported curve")
 	}
 
-	key, err := generateECDHEKey(config.rand(), curveID)
+	key, err := config.generateECDHEKey(curveID) // [uTLS]
 	if err != nil {
 		return err
 	}
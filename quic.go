@@ -208,6 +208,9 @@ func (q *QUICConn) Start(ctx context.Context) error {
 	if q.conn.config.MinVersion < VersionTLS13 {
 		return quicError(errors.New("tls: Config MinVersion must be at least TLS 1.3"))
 	}
+	if q.conn.config.NoBackgroundTasks { // [uTLS]
+		return quicError(errQUICBackgroundTasks)
+	}
 	go q.conn.HandshakeContext(ctx)
 	if _, ok := <-q.conn.quic.blockedc; !ok {
 		return q.conn.handshakeErr
@@ -274,8 +277,8 @@ func (q *QUICConn) HandleData(level QUICEncryptionLevel, data []byte) error {
 	for q.conn.hand.Len() >= 4 && q.conn.handshakeErr == nil {
 		b := q.conn.hand.Bytes()
 		n := int(b[1])<<16 | int(b[2])<<8 | int(b[3])
-		if n > maxHandshake {
-			q.conn.handshakeErr = fmt.Errorf("tls: handshake message of length %d bytes exceeds maximum of %d bytes", n, maxHandshake)
+		if limit := q.conn.maxHandshakeSize(b[0]); n > limit { // [uTLS]
+			q.conn.handshakeErr = &HandshakeSizeError{Kind: LimitMessageSize, MsgType: b[0], Size: n, Limit: limit}
 			break
 		}
 		if len(b) < 4+n {
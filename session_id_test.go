@@ -2,6 +2,7 @@ package tls
 
 import (
 	"bytes"
+	"io"
 	"testing"
 )
 
@@ -168,3 +169,105 @@ func TestSessionIDResumeType(t *testing.T) {
 		})
 	}
 }
+
+func TestSessionStateResumeTypeAccessors(t *testing.T) {
+	session := &SessionState{
+		version:     VersionTLS12,
+		cipherSuite: TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		secret:      make([]byte, 48),
+		Extra:       [][]byte{[]byte("other layer")},
+	}
+	sessionID := []byte{1, 2, 3, 4}
+	session.SetResumeType(ResumeSessionID)
+	session.SetSessionID(sessionID)
+	sessionID[0] = 0
+	if session.ResumeType() != ResumeSessionID || !bytes.Equal(session.SessionID(), []byte{1, 2, 3, 4}) {
+		t.Fatalf("got resumeType %v and session ID %x", session.ResumeType(), session.SessionID())
+	}
+
+	b, err := session.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseSessionState(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.ResumeType() != ResumeSessionID || !bytes.Equal(parsed.SessionID(), session.SessionID()) {
+		t.Errorf("parsed resumeType %v and session ID %x", parsed.ResumeType(), parsed.SessionID())
+	}
+	if len(parsed.Extra) != 2 || string(parsed.Extra[0]) != "other layer" {
+		t.Errorf("unexpected Extra %q", parsed.Extra)
+	}
+
+	ClearSessionExtraFields(parsed)
+	if parsed.ResumeType() != ResumeUnknown || parsed.SessionID() != nil || len(parsed.Extra) != 1 {
+		t.Errorf("fields not cleared: %v, %x, %q", parsed.ResumeType(), parsed.SessionID(), parsed.Extra)
+	}
+}
+
+func TestClientSessionStateImport(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	cache := NewLRUClientSessionCache(0)
+	c, err := NewUTLSClient(s, &Config{ClientSessionCache: cache, OmitEmptyPsk: true}, HelloChrome_100_PSK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Read the echo, and the session ticket sent before it.
+	if _, err := c.Write([]byte{0}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(c, make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	cs, ok := cache.Get("localhost")
+	if !ok || cs.SessionTicket() == nil || cs.SessionState() == nil {
+		t.Fatal("no session was cached")
+	}
+
+	// Export the session, as another process would, and import it into an
+	// empty ClientSessionState.
+	ticket := bytes.Clone(cs.SessionTicket())
+	state, err := cs.SessionState().Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseSessionState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	imported := new(ClientSessionState)
+	imported.SetSessionTicket(ticket)
+	imported.SetSessionState(parsed)
+	if !bytes.Equal(imported.SessionTicket(), ticket) {
+		t.Fatalf("SessionTicket = %x, want %x", imported.SessionTicket(), ticket)
+	}
+
+	cache = NewLRUClientSessionCache(0)
+	cache.Put("localhost", imported)
+	c, err = NewUTLSClient(s, &Config{ClientSessionCache: cache}, HelloChrome_100_PSK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if !c.ConnectionState().DidResume {
+		t.Error("imported session was not resumed")
+	}
+}
+
+func TestClientSessionStateSessionID(t *testing.T) {
+	cs := new(ClientSessionState)
+	cs.SetSessionState(&SessionState{version: VersionTLS12})
+	sessionID := []byte{1, 2, 3, 4}
+	cs.SetSessionID(sessionID)
+	sessionID[0] = 0
+	if !bytes.Equal(cs.SessionID(), []byte{1, 2, 3, 4}) || cs.SessionState().ResumeType() != ResumeSessionID {
+		t.Errorf("got session ID %x and resumeType %v", cs.SessionID(), cs.SessionState().ResumeType())
+	}
+}
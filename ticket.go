@@ -106,6 +106,11 @@ type SessionState struct {
 	useBy  uint64 // seconds since UNIX epoch
 	ageAdd uint32
 	ticket []byte
+
+	// [uTLS] Client-side TLS 1.0–1.2-only fields, also encoded in Extra, see
+	// SetSessionExtraFields.
+	resumeType ResumeMechanism
+	sessionId  []byte
 }
 
 // Bytes encodes the session, including any private fields, so that it can be
@@ -214,6 +219,7 @@ func ParseSessionState(data []byte) (*SessionState, error) {
 		}
 		ss.Extra = append(ss.Extra, e)
 	}
+	ss.loadSessionExtraFields() // [uTLS]
 	switch extMasterSecret {
 	case 0:
 		ss.extMasterSecret = false
@@ -783,7 +783,7 @@ func TestWarningAlertFlood(t *testing.T) {
 }
 
 func TestCloneFuncFields(t *testing.T) {
-	const expectedCount = 9
+	const expectedCount = 19
 	called := 0
 
 	c1 := Config{
@@ -823,6 +823,44 @@ func TestCloneFuncFields(t *testing.T) {
 			called |= 1 << 8
 			return nil
 		},
+		OnPeerCertificates: func(rawCerts [][]byte) error { // [uTLS]
+			called |= 1 << 9
+			return nil
+		},
+		ZeroizeHook: func(label string, secret []byte) { // [uTLS]
+			called |= 1 << 10
+		},
+		InsecureSkipVerifyHost: func(serverName string) bool { // [uTLS]
+			called |= 1 << 11
+			return false
+		},
+		OnUnsolicitedExtension: func(handshakeType uint8, extension uint16) error { // [uTLS]
+			called |= 1 << 12
+			return nil
+		},
+		TransformPskIdentity: func(identity PskIdentity, session *SessionState) (PskIdentity, error) { // [uTLS]
+			called |= 1 << 13
+			return identity, nil
+		},
+		TicketAge: func(age time.Duration) time.Duration { // [uTLS]
+			called |= 1 << 14
+			return age
+		},
+		OnNewSessionTicket: func(key string, ticket *NewSessionTicket, session *ClientSessionState) (bool, error) { // [uTLS]
+			called |= 1 << 15
+			return true, nil
+		},
+		OnGREASESelected: func(err *GREASESelectedError) { // [uTLS]
+			called |= 1 << 16
+		},
+		GetCertificateForClientHello: func(*CertificateSelectionInfo) (*Certificate, error) { // [uTLS]
+			called |= 1 << 17
+			return nil, nil
+		},
+		GetACMEKeyAuthorization: func(serverName string) (string, error) { // [uTLS]
+			called |= 1 << 18
+			return "", nil
+		},
 	}
 
 	c2 := c1.Clone()
@@ -836,6 +874,16 @@ func TestCloneFuncFields(t *testing.T) {
 	c2.UnwrapSession(nil, ConnectionState{})
 	c2.WrapSession(ConnectionState{}, nil)
 	c2.EncryptedClientHelloRejectionVerify(ConnectionState{})
+	c2.OnPeerCertificates(nil)
+	c2.ZeroizeHook("", nil)
+	c2.InsecureSkipVerifyHost("")
+	c2.OnUnsolicitedExtension(0, 0)
+	c2.TransformPskIdentity(PskIdentity{}, nil)
+	c2.TicketAge(0)
+	c2.OnNewSessionTicket("", nil, nil)
+	c2.OnGREASESelected(nil)
+	c2.GetCertificateForClientHello(nil)
+	c2.GetACMEKeyAuthorization("")
 
 	if called != (1<<expectedCount)-1 {
 		t.Fatalf("expected %d calls but saw calls %b", expectedCount, called)
@@ -854,7 +902,7 @@ func TestCloneNonFuncFields(t *testing.T) {
 		switch fn := typ.Field(i).Name; fn {
 		case "Rand":
 			f.Set(reflect.ValueOf(io.Reader(os.Stdin)))
-		case "Time", "GetCertificate", "GetConfigForClient", "VerifyPeerCertificate", "VerifyConnection", "GetClientCertificate", "WrapSession", "UnwrapSession", "EncryptedClientHelloRejectionVerify":
+		case "Time", "GetCertificate", "GetConfigForClient", "VerifyPeerCertificate", "VerifyConnection", "GetClientCertificate", "WrapSession", "UnwrapSession", "EncryptedClientHelloRejectionVerify", "OnPeerCertificates", "ZeroizeHook", "InsecureSkipVerifyHost", "OnUnsolicitedExtension", "TransformPskIdentity", "TicketAge", "OnNewSessionTicket", "OnGREASESelected", "GetCertificateForClientHello", "GetACMEKeyAuthorization":
 			// DeepEqual can't compare functions. If you add a
 			// function field to this list, you must also change
 			// TestCloneFuncFields to ensure that the func field is
@@ -877,7 +925,7 @@ func TestCloneNonFuncFields(t *testing.T) {
 			f.Set(reflect.ValueOf("b"))
 		case "ClientAuth":
 			f.Set(reflect.ValueOf(VerifyClientCertIfGiven))
-		case "InsecureSkipVerify", "InsecureSkipTimeVerify", "SessionTicketsDisabled", "DynamicRecordSizingDisabled", "PreferServerCipherSuites", "OmitEmptyPsk", "PreferSkipResumptionOnNilExtension":
+		case "InsecureSkipVerify", "InsecureSkipTimeVerify", "SessionTicketsDisabled", "DynamicRecordSizingDisabled", "PreferServerCipherSuites", "OmitEmptyPsk", "PreferSkipResumptionOnNilExtension", "ZeroizeSecrets", "VerifyCertSignatureAlgorithms", "AllowServerCertificateChange", "HandshakeForensics", "PreciseSessionCache", "RandomizedPerHost", "FalseStart":
 			f.Set(reflect.ValueOf(true))
 		case "InsecureServerNameToVerify":
 			f.Set(reflect.ValueOf("c"))
@@ -903,6 +951,38 @@ func TestCloneNonFuncFields(t *testing.T) {
 			continue // these are unexported fields that are handled separately
 		case "ApplicationSettings": // [UTLS] ALPS (Application Settings)
 			f.Set(reflect.ValueOf(map[string][]byte{"a": {1}}))
+		case "HandshakeSizeLimits": // [UTLS]
+			f.Set(reflect.ValueOf(&HandshakeSizeLimits{MaxMessageSize: 1}))
+		case "HandshakeTarpit": // [UTLS]
+			f.Set(reflect.ValueOf(&HandshakeTarpit{MaxBytes: 1}))
+		case "AIAFetcher": // [UTLS]
+			f.Set(reflect.ValueOf(NewAIAFetcher()))
+		case "VerifiedChainCache": // [UTLS]
+			f.Set(reflect.ValueOf(NewVerifiedChainCache()))
+		case "HandshakeLimiter": // [UTLS]
+			f.Set(reflect.ValueOf(NewHandshakeLimiter(1, 0)))
+		case "ResumptionMonitor": // [UTLS]
+			f.Set(reflect.ValueOf(NewResumptionMonitor(nil)))
+		case "RecordAEADProvider": // [UTLS]
+			f.Set(reflect.ValueOf(&testRecordAEADProvider{}))
+		case "ExplicitNonce": // [UTLS]
+			f.Set(reflect.ValueOf(ExplicitNonceRandom))
+		case "WritePacing": // [UTLS]
+			f.Set(reflect.ValueOf(&WritePacing{BytesPerSecond: 1}))
+		case "NoBackgroundTasks": // [UTLS]
+			f.Set(reflect.ValueOf(true))
+		case "InsecureSkipVerifyHosts": // [UTLS]
+			f.Set(reflect.ValueOf([]string{"a"}))
+		case "UnsolicitedExtensions": // [UTLS]
+			f.Set(reflect.ValueOf(UnsolicitedExtensionAbort))
+		case "DecryptErrors": // [UTLS]
+			f.Set(reflect.ValueOf(DecryptErrorClose))
+		case "DecryptErrorCloseDelay": // [UTLS]
+			f.Set(reflect.ValueOf(time.Second))
+		case "CertificateLookupTimeout": // [UTLS]
+			f.Set(reflect.ValueOf(time.Second))
+		case "GREASESeed": // [UTLS]
+			f.Set(reflect.ValueOf(&PRNGSeed{1}))
 		default:
 			t.Errorf("all fields must be accounted for, but saw unknown field %q", fn)
 		}
@@ -2160,11 +2240,19 @@ func testECHSpec(t *testing.T, spec *ClientHelloSpec, expectSuccess bool) {
 	}
 
 	// [uTLS SECTION BEGIN]
+	var selection *CertificateSelectionInfo
+	serverConfig.GetCertificateForClientHello = func(info *CertificateSelectionInfo) (*Certificate, error) {
+		selection = info
+		return nil, nil
+	}
 	ss, cs, err := testUtlsHandshake(t, clientConfig, serverConfig, spec)
 	if expectSuccess {
 		if err != nil {
 			t.Fatalf("unexpected failure: %s", err)
 		}
+		if selection == nil || !selection.ECHAccepted || selection.Hello.ServerName != "secret.example" {
+			t.Fatalf("GetCertificateForClientHello called with %+v, want the inner ClientHello", selection)
+		}
 		if !ss.ECHAccepted {
 			t.Fatal("server ConnectionState shows ECH not accepted")
 		}
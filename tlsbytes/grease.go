@@ -0,0 +1,26 @@
+package tlsbytes
+
+// GREASEPlaceholder stands for any GREASE value, see RFC 8701. uTLS replaces it
+// with a random GREASE value when building a ClientHello.
+const GREASEPlaceholder = 0x0a0a
+
+// IsGREASE reports whether v is a GREASE value of the form 0x?a?a, with both
+// bytes equal.
+func IsGREASE(v uint16) bool {
+	return v>>8 == v&0xff && v&0xf == 0xa
+}
+
+// UnGREASE returns GREASEPlaceholder if v is a GREASE value, and v otherwise.
+func UnGREASE(v uint16) uint16 {
+	if IsGREASE(v) {
+		return GREASEPlaceholder
+	}
+	return v
+}
+
+// GREASEValue derives a GREASE value from a random seed the way BoringSSL
+// does, keeping the high nibble of its low byte.
+func GREASEValue(seed uint16) uint16 {
+	v := seed&0xf0 | 0x0a
+	return v | v<<8
+}
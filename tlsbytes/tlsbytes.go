@@ -0,0 +1,104 @@
+// Package tlsbytes provides the helpers uTLS uses to encode and decode TLS
+// messages with golang.org/x/crypto/cryptobyte, for implementations of custom
+// extensions that need to get length prefixes and GREASE values right.
+package tlsbytes
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// MarshalingFunction is an adapter to allow the use of ordinary functions as
+// cryptobyte.MarshalingValue.
+type MarshalingFunction func(b *cryptobyte.Builder) error
+
+func (f MarshalingFunction) Marshal(b *cryptobyte.Builder) error {
+	return f(b)
+}
+
+// AddBytesWithLength appends v to b. If the length of v is not n, it produces
+// an error.
+func AddBytesWithLength(b *cryptobyte.Builder, v []byte, n int) {
+	b.AddValue(MarshalingFunction(func(b *cryptobyte.Builder) error {
+		if len(v) != n {
+			return fmt.Errorf("invalid value length: expected %d, got %d", n, len(v))
+		}
+		b.AddBytes(v)
+		return nil
+	}))
+}
+
+// AddUint64 appends a big-endian, 64-bit value to b.
+func AddUint64(b *cryptobyte.Builder, v uint64) {
+	b.AddUint32(uint32(v >> 32))
+	b.AddUint32(uint32(v))
+}
+
+// ReadUint64 decodes a big-endian, 64-bit value into out and advances over it.
+// It reports whether the read was successful.
+func ReadUint64(s *cryptobyte.String, out *uint64) bool {
+	var hi, lo uint32
+	if !s.ReadUint32(&hi) || !s.ReadUint32(&lo) {
+		return false
+	}
+	*out = uint64(hi)<<32 | uint64(lo)
+	return true
+}
+
+// AddUint8LengthPrefixedBytes appends v with an 8-bit length prefix. If v is
+// too long, it produces an error.
+func AddUint8LengthPrefixedBytes(b *cryptobyte.Builder, v []byte) {
+	b.AddUint8LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(v) })
+}
+
+// AddUint16LengthPrefixedBytes appends v with a 16-bit length prefix. If v is
+// too long, it produces an error.
+func AddUint16LengthPrefixedBytes(b *cryptobyte.Builder, v []byte) {
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(v) })
+}
+
+// AddUint24LengthPrefixedBytes appends v with a 24-bit length prefix. If v is
+// too long, it produces an error.
+func AddUint24LengthPrefixedBytes(b *cryptobyte.Builder, v []byte) {
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(v) })
+}
+
+// ReadUint8LengthPrefixed acts like s.ReadUint8LengthPrefixed, but targets a
+// []byte instead of a cryptobyte.String.
+func ReadUint8LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+	return s.ReadUint8LengthPrefixed((*cryptobyte.String)(out))
+}
+
+// ReadUint16LengthPrefixed acts like s.ReadUint16LengthPrefixed, but targets a
+// []byte instead of a cryptobyte.String.
+func ReadUint16LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+	return s.ReadUint16LengthPrefixed((*cryptobyte.String)(out))
+}
+
+// ReadUint24LengthPrefixed acts like s.ReadUint24LengthPrefixed, but targets a
+// []byte instead of a cryptobyte.String.
+func ReadUint24LengthPrefixed(s *cryptobyte.String, out *[]byte) bool {
+	return s.ReadUint24LengthPrefixed((*cryptobyte.String)(out))
+}
+
+// AddExtension appends a TLS extension: its 16-bit type followed by the body
+// written by f with a 16-bit length prefix.
+func AddExtension(b *cryptobyte.Builder, extType uint16, f cryptobyte.BuilderContinuation) {
+	b.AddUint16(extType)
+	b.AddUint16LengthPrefixed(f)
+}
+
+// MarshalExtension returns the encoding of a TLS extension, as written by
+// AddExtension, for implementing TLSExtension.Read.
+func MarshalExtension(extType uint16, f cryptobyte.BuilderContinuation) ([]byte, error) {
+	var b cryptobyte.Builder
+	AddExtension(&b, extType, f)
+	return b.Bytes()
+}
+
+// ReadExtension reads a TLS extension written by AddExtension, returning its
+// type and body. It reports whether the read was successful.
+func ReadExtension(s *cryptobyte.String, extType *uint16, body *[]byte) bool {
+	return s.ReadUint16(extType) && ReadUint16LengthPrefixed(s, body)
+}
@@ -0,0 +1,100 @@
+package tlsbytes
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func TestLengthPrefixed(t *testing.T) {
+	var b cryptobyte.Builder
+	AddUint8LengthPrefixedBytes(&b, []byte{1})
+	AddUint16LengthPrefixedBytes(&b, []byte{2, 2})
+	AddUint24LengthPrefixedBytes(&b, []byte{3, 3, 3})
+	AddUint64(&b, 0x0102030405060708)
+	AddBytesWithLength(&b, []byte{4, 4}, 2)
+	out, err := b.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{1, 1, 0, 2, 2, 2, 0, 0, 3, 3, 3, 3, 1, 2, 3, 4, 5, 6, 7, 8, 4, 4}
+	if !bytes.Equal(out, want) {
+		t.Fatalf("got %x, want %x", out, want)
+	}
+
+	s := cryptobyte.String(out)
+	var v8, v16, v24, fixed []byte
+	var v64 uint64
+	if !ReadUint8LengthPrefixed(&s, &v8) || !ReadUint16LengthPrefixed(&s, &v16) ||
+		!ReadUint24LengthPrefixed(&s, &v24) || !ReadUint64(&s, &v64) || !s.ReadBytes(&fixed, 2) || !s.Empty() {
+		t.Fatal("failed to read back values")
+	}
+	if len(v8) != 1 || len(v16) != 2 || len(v24) != 3 || v64 != 0x0102030405060708 {
+		t.Errorf("read back %x %x %x %x", v8, v16, v24, v64)
+	}
+}
+
+func TestLengthErrors(t *testing.T) {
+	var b cryptobyte.Builder
+	AddBytesWithLength(&b, []byte{1, 2, 3}, 2)
+	if _, err := b.Bytes(); err == nil {
+		t.Error("expected an error for a value of the wrong length")
+	}
+
+	b = cryptobyte.Builder{}
+	AddUint8LengthPrefixedBytes(&b, make([]byte, 256))
+	if _, err := b.Bytes(); err == nil {
+		t.Error("expected an error for a value too long for its length prefix")
+	}
+}
+
+func TestExtension(t *testing.T) {
+	ext, err := MarshalExtension(0x1234, func(b *cryptobyte.Builder) {
+		AddUint8LengthPrefixedBytes(b, []byte("h2"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x12, 0x34, 0, 3, 2, 'h', '2'}; !bytes.Equal(ext, want) {
+		t.Fatalf("got %x, want %x", ext, want)
+	}
+	s := cryptobyte.String(ext)
+	var typ uint16
+	var body []byte
+	if !ReadExtension(&s, &typ, &body) || typ != 0x1234 || !bytes.Equal(body, ext[4:]) || !s.Empty() {
+		t.Errorf("ReadExtension returned %x %x", typ, body)
+	}
+}
+
+func TestGREASE(t *testing.T) {
+	for i := 0; i < 16; i++ {
+		v := uint16(i)<<12 | 0x0a00 | uint16(i)<<4 | 0x0a
+		if !IsGREASE(v) || UnGREASE(v) != GREASEPlaceholder {
+			t.Errorf("%04x not recognized as GREASE", v)
+		}
+		if g := GREASEValue(uint16(i) << 4); g != v {
+			t.Errorf("GREASEValue(%x) = %04x, want %04x", i<<4, g, v)
+		}
+	}
+	for _, v := range []uint16{0x0a0b, 0x1a0a, 0x0000, 0x1301} {
+		if IsGREASE(v) || UnGREASE(v) != v {
+			t.Errorf("%04x recognized as GREASE", v)
+		}
+	}
+}
+
+func ExampleMarshalExtension() {
+	// An application_settings extension for "h2".
+	ext, err := MarshalExtension(17513, func(b *cryptobyte.Builder) {
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			AddUint8LengthPrefixedBytes(b, []byte("h2"))
+		})
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%x\n", ext)
+	// Output: 446900050003026832
+}
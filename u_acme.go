@@ -0,0 +1,97 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"slices"
+	"time"
+)
+
+// ACMETLSALPNProtocol is the ALPN protocol of the ACME TLS-ALPN-01 challenge,
+// defined in RFC 8737.
+const ACMETLSALPNProtocol = "acme-tls/1"
+
+// oidACMEIdentifier is id-pe-acmeIdentifier, RFC 8737, Section 6.1.
+var oidACMEIdentifier = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 31}
+
+// NewACMEChallengeCertificate returns the self-signed validation certificate
+// of a TLS-ALPN-01 challenge for domain, as described in RFC 8737, Section 3:
+// its only subjectAltName is domain, and its critical acmeIdentifier extension
+// holds the SHA-256 digest of keyAuthorization. The key is a new ECDSA P-256
+// key.
+//
+// Servers don't usually need to call it, see Config.GetACMEKeyAuthorization.
+func NewACMEChallengeCertificate(domain, keyAuthorization string) (*Certificate, error) {
+	if domain == "" {
+		return nil, errors.New("tls: ACME challenge certificate requires a domain")
+	}
+	digest := sha256.Sum256([]byte(keyAuthorization))
+	value, err := asn1.Marshal(digest[:])
+	if err != nil {
+		return nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "ACME TLS-ALPN-01 challenge"},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(24 * time.Hour),
+		DNSNames:     []string{domain},
+		ExtraExtensions: []pkix.Extension{
+			{Id: oidACMEIdentifier, Critical: true, Value: value},
+		},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		return nil, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// isACMEChallenge reports whether a client offering protos is an ACME server
+// validating a TLS-ALPN-01 challenge, which offers acme-tls/1 only.
+func isACMEChallenge(protos []string) bool {
+	return slices.Equal(withoutGREASEALPN(protos), []string{ACMETLSALPNProtocol})
+}
+
+// serverNextProtos returns the ALPN protocols the server negotiates with a
+// client offering clientProtos: only acme-tls/1 for a TLS-ALPN-01 challenge,
+// NextProtos otherwise.
+func (c *Config) serverNextProtos(clientProtos []string) []string {
+	if c.GetACMEKeyAuthorization == nil || !isACMEChallenge(clientProtos) {
+		return c.NextProtos
+	}
+	return []string{ACMETLSALPNProtocol}
+}
+
+// acmeChallengeCertificate returns the validation certificate of the
+// TLS-ALPN-01 challenge for the server name of clientHello.
+func (c *Config) acmeChallengeCertificate(clientHello *ClientHelloInfo) (*Certificate, error) {
+	if clientHello.ServerName == "" {
+		return nil, errors.New("tls: ACME TLS-ALPN-01 challenge without a server name")
+	}
+	keyAuthorization, err := c.GetACMEKeyAuthorization(clientHello.ServerName)
+	if err != nil {
+		return nil, err
+	}
+	return NewACMEChallengeCertificate(clientHello.ServerName, keyAuthorization)
+}
@@ -0,0 +1,72 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/asn1"
+	"testing"
+)
+
+func TestACMETLSALPNChallenge(t *testing.T) {
+	s, err := NewUTLSServer(&Config{
+		NextProtos: []string{"h2"},
+		GetACMEKeyAuthorization: func(serverName string) (string, error) {
+			return "token." + serverName, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	c, err := NewUTLSClient(s, &Config{
+		ServerName:         "example.com",
+		NextProtos:         []string{ACMETLSALPNProtocol},
+		InsecureSkipVerify: true,
+	}, HelloGolang)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	cs := c.ConnectionState()
+	if cs.NegotiatedProtocol != ACMETLSALPNProtocol {
+		t.Errorf("negotiated %q, want %q", cs.NegotiatedProtocol, ACMETLSALPNProtocol)
+	}
+	leaf := cs.PeerCertificates[0]
+	if len(leaf.DNSNames) != 1 || leaf.DNSNames[0] != "example.com" {
+		t.Errorf("DNSNames = %q, want [example.com]", leaf.DNSNames)
+	}
+	digest := sha256.Sum256([]byte("token.example.com"))
+	var found bool
+	for _, ext := range leaf.Extensions {
+		if !ext.Id.Equal(oidACMEIdentifier) {
+			continue
+		}
+		found = true
+		var value []byte
+		if _, err := asn1.Unmarshal(ext.Value, &value); err != nil {
+			t.Fatal(err)
+		}
+		if !ext.Critical || !bytes.Equal(value, digest[:]) {
+			t.Errorf("acmeIdentifier extension = %+v, want the critical digest of the key authorization", ext)
+		}
+	}
+	if !found {
+		t.Error("validation certificate has no acmeIdentifier extension")
+	}
+
+	// Other clients get the configured certificate.
+	c2, err := NewUTLSClient(s, &Config{
+		ServerName:         "example.com",
+		NextProtos:         []string{"h2", ACMETLSALPNProtocol},
+		InsecureSkipVerify: true,
+	}, HelloGolang)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	if cs := c2.ConnectionState(); cs.NegotiatedProtocol != "h2" || !cs.PeerCertificates[0].Equal(s.Certificate) {
+		t.Errorf("client offering h2 negotiated %q with certificate %v", cs.NegotiatedProtocol, cs.PeerCertificates[0].Subject)
+	}
+}
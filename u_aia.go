@@ -0,0 +1,184 @@
+package tls
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultAIAMaxDepth     = 4
+	defaultAIATimeout      = 5 * time.Second
+	defaultAIACacheSize    = 256
+	aiaNegativeCacheTTL    = 5 * time.Minute
+	maxAIACertificateBytes = 64 << 10
+)
+
+// AIAFetcher fetches missing intermediate certificates from the CA Issuers URLs
+// of the Authority Information Access extension, like browsers do for servers
+// that send incomplete chains. Set Config.AIAFetcher to enable it.
+//
+// Intermediates sent by the server are used regardless of their order, so only
+// missing certificates are fetched. Fetched certificates are cached until they
+// expire, and failed fetches for a few minutes.
+//
+// An AIAFetcher is safe for concurrent use and should be shared between
+// connections so that its cache is effective.
+type AIAFetcher struct {
+	// Client is used to fetch certificates. If nil, http.DefaultClient is
+	// used.
+	Client *http.Client
+
+	// Timeout bounds each fetch. If zero, 5 seconds is used.
+	Timeout time.Duration
+
+	// MaxDepth is the maximum number of certificates fetched to complete a
+	// single chain. If zero, 4 is used.
+	MaxDepth int
+
+	// CacheSize is the maximum number of cached URLs. If zero, 256 is used.
+	CacheSize int
+
+	mu    sync.Mutex
+	cache map[string]*aiaCacheEntry
+}
+
+type aiaCacheEntry struct {
+	cert    *x509.Certificate
+	err     error
+	expires time.Time
+}
+
+// NewAIAFetcher returns an AIAFetcher with default settings.
+func NewAIAFetcher() *AIAFetcher {
+	return &AIAFetcher{}
+}
+
+// Fetch returns the certificate at url, from the cache if possible.
+func (f *AIAFetcher) Fetch(ctx context.Context, url string) (*x509.Certificate, error) {
+	now := time.Now()
+	f.mu.Lock()
+	if e, ok := f.cache[url]; ok && now.Before(e.expires) {
+		f.mu.Unlock()
+		return e.cert, e.err
+	}
+	f.mu.Unlock()
+
+	cert, err := f.fetch(ctx, url)
+	if err != nil && ctx.Err() != nil {
+		// The caller gave up, which says nothing about url.
+		return nil, err
+	}
+	e := &aiaCacheEntry{cert: cert, err: err, expires: now.Add(aiaNegativeCacheTTL)}
+	if err == nil {
+		e.expires = cert.NotAfter
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cache == nil {
+		f.cache = make(map[string]*aiaCacheEntry)
+	}
+	if len(f.cache) >= f.cacheSize() {
+		// Drop expired entries first, then arbitrary ones.
+		for k, v := range f.cache {
+			if !now.Before(v.expires) {
+				delete(f.cache, k)
+			}
+		}
+		for k := range f.cache {
+			if len(f.cache) < f.cacheSize() {
+				break
+			}
+			delete(f.cache, k)
+		}
+	}
+	f.cache[url] = e
+	return cert, err
+}
+
+func (f *AIAFetcher) fetch(ctx context.Context, url string) (*x509.Certificate, error) {
+	timeout := f.Timeout
+	if timeout == 0 {
+		timeout = defaultAIATimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tls: fetching %s: unexpected status %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAIACertificateBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxAIACertificateBytes {
+		return nil, fmt.Errorf("tls: fetching %s: certificate too large", url)
+	}
+	// CA Issuers are usually DER, but some serve PEM.
+	if block, _ := pem.Decode(body); block != nil && block.Type == "CERTIFICATE" {
+		body = block.Bytes
+	}
+	return x509.ParseCertificate(body)
+}
+
+func (f *AIAFetcher) cacheSize() int {
+	if f.CacheSize > 0 {
+		return f.CacheSize
+	}
+	return defaultAIACacheSize
+}
+
+// verifyWithAIA retries a verification of leaf that failed with verifyErr,
+// fetching missing issuers into opts.Intermediates.
+func (f *AIAFetcher) verifyWithAIA(ctx context.Context, leaf *x509.Certificate, opts x509.VerifyOptions, verifyErr error) ([][]*x509.Certificate, error) {
+	var unknownAuthority x509.UnknownAuthorityError
+	if !errors.As(verifyErr, &unknownAuthority) {
+		return nil, verifyErr
+	}
+	maxDepth := f.MaxDepth
+	if maxDepth == 0 {
+		maxDepth = defaultAIAMaxDepth
+	}
+
+	next := leaf
+	for depth := 0; depth < maxDepth && len(next.IssuingCertificateURL) > 0; depth++ {
+		var issuer *x509.Certificate
+		for _, url := range next.IssuingCertificateURL {
+			cert, err := f.Fetch(ctx, url)
+			if err == nil && next.CheckSignatureFrom(cert) == nil {
+				issuer = cert
+				break
+			}
+		}
+		if issuer == nil {
+			break
+		}
+		opts.Intermediates.AddCert(issuer)
+		chains, err := leaf.Verify(opts)
+		if err == nil || !errors.As(err, &unknownAuthority) {
+			return chains, err
+		}
+		next = issuer
+	}
+	return nil, verifyErr
+}
@@ -0,0 +1,124 @@
+package tls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAIAFetcher(t *testing.T) {
+	var fetches atomic.Int32
+	var stall atomic.Bool
+	var intermediateDER []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if stall.Load() {
+			<-r.Context().Done()
+			return
+		}
+		fetches.Add(1)
+		w.Header().Set("Content-Type", "application/pkix-cert")
+		w.Write(intermediateDER)
+	}))
+	defer srv.Close()
+
+	newCert := func(template, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		template.SerialNumber = big.NewInt(time.Now().UnixNano())
+		template.NotBefore = time.Now().Add(-time.Hour)
+		template.NotAfter = time.Now().Add(time.Hour)
+		if parent == nil {
+			parent, parentKey = template, key
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cert, key
+	}
+	ca := func(name string) *x509.Certificate {
+		return &x509.Certificate{
+			Subject:               pkix.Name{CommonName: name},
+			KeyUsage:              x509.KeyUsageCertSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+	}
+	root, rootKey := newCert(ca("root"), nil, nil)
+	intermediate, intermediateKey := newCert(ca("intermediate"), root, rootKey)
+	intermediateDER = intermediate.Raw
+	leaf, leafKey := newCert(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "leaf"},
+		DNSNames:              []string{"example.com"},
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IssuingCertificateURL: []string{srv.URL + "/intermediate.crt"},
+	}, intermediate, intermediateKey)
+
+	serverConfig := testConfig.Clone()
+	serverConfig.Certificates = []Certificate{{Certificate: [][]byte{leaf.Raw}, PrivateKey: leafKey}}
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	clientConfig := testConfig.Clone()
+	clientConfig.InsecureSkipVerify = false
+	clientConfig.ServerName = "example.com"
+	clientConfig.RootCAs = roots
+	clientConfig.Time = time.Now
+
+	if _, _, err := testHandshake(t, clientConfig, serverConfig); err == nil {
+		t.Fatal("incomplete chain verified without AIA fetching")
+	}
+	if fetches.Load() != 0 {
+		t.Fatal("fetched intermediate without an AIAFetcher")
+	}
+
+	// A stalled fetch is abandoned when the handshake context expires, and
+	// the failure is not cached.
+	stall.Store(true)
+	clientConfig.AIAFetcher = NewAIAFetcher()
+	clientEnd, serverEnd := memPipe()
+	go func() {
+		server := Server(serverEnd, serverConfig)
+		server.Handshake()
+		server.Close()
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	start := time.Now()
+	err := Client(clientEnd, clientConfig).HandshakeContext(ctx)
+	cancel()
+	if err == nil {
+		t.Fatal("handshake succeeded with a stalled AIA fetch")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("handshake took %v after its context expired", d)
+	}
+	stall.Store(false)
+
+	for i := 0; i < 2; i++ {
+		_, state, err := testHandshake(t, clientConfig, serverConfig)
+		if err != nil {
+			t.Fatalf("handshake with AIA fetching failed: %v", err)
+		}
+		if len(state.VerifiedChains) != 1 || len(state.VerifiedChains[0]) != 3 {
+			t.Errorf("unexpected verified chains %v", state.VerifiedChains)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("intermediate fetched %d times, want 1", n)
+	}
+}
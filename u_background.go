@@ -0,0 +1,131 @@
+package tls
+
+import (
+	"context"
+	"crypto/ecdh"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// generateECDHEKey returns a new ECDHE key for curveID. Unless
+// NoBackgroundTasks is set, it's taken from the global key cache, which is
+// filled on first use.
+func (c *Config) generateECDHEKey(curveID CurveID) (*ecdh.PrivateKey, error) {
+	if !c.NoBackgroundTasks {
+		return generateECDHEKey(c.rand(), curveID)
+	}
+	curve, ok := curveForCurveID(curveID)
+	if !ok {
+		return nil, errors.New("tls: internal error: unsupported curve")
+	}
+	return curve.GenerateKey(c.rand())
+}
+
+// errQUICBackgroundTasks is returned by QUICConn.Start and UQUICConn.Start
+// if NoBackgroundTasks is set, as the QUIC handshake runs in a goroutine.
+var errQUICBackgroundTasks = errors.New("tls: QUIC connections run the handshake in a goroutine, which Config.NoBackgroundTasks forbids")
+
+// connDeadlines are the deadlines set through Conn.SetDeadline and its
+// variants, so that contextDeadline can tighten them for the duration of a
+// handshake and restore them afterwards. Deadlines set directly on the
+// underlying connection can't be seen, and are cleared instead.
+type connDeadlines struct {
+	mu          sync.Mutex
+	read, write time.Time
+
+	// ctx is the deadline of the handshake context while it is in effect.
+	ctx time.Time
+}
+
+// earliestDeadline returns the earlier of a and b, where zero means none.
+func earliestDeadline(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+// set records t as the read and/or write deadline, and applies it to conn,
+// bounded by the deadline of the handshake context in effect, if any.
+func (d *connDeadlines) set(conn net.Conn, read, write bool, t time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if read {
+		d.read = t
+	}
+	if write {
+		d.write = t
+	}
+	t = earliestDeadline(t, d.ctx)
+	switch {
+	case read && write:
+		return conn.SetDeadline(t)
+	case read:
+		return conn.SetReadDeadline(t)
+	default:
+		return conn.SetWriteDeadline(t)
+	}
+}
+
+// bound applies ctx, the deadline of a handshake context, to conn until it is
+// called again with a zero ctx.
+func (d *connDeadlines) bound(conn net.Conn, ctx time.Time) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ctx = ctx
+	if err := conn.SetReadDeadline(earliestDeadline(d.read, ctx)); err != nil {
+		return err
+	}
+	return conn.SetWriteDeadline(earliestDeadline(d.write, ctx))
+}
+
+// contextDeadline bounds a handshake by the deadline of ctx through the
+// deadline of the underlying connection, instead of the goroutine closing it
+// when ctx is done, which NoBackgroundTasks forbids. The deadlines set with
+// Conn.SetDeadline are restored afterwards. A ctx without a deadline is
+// checked whenever the handshake has read from the network, see
+// handshakeContextErr. The returned function must be called with the error of
+// the handshake, and returns the one to report.
+func (c *Conn) contextDeadline(ctx context.Context) (func(error) error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return func(err error) error {
+			if err != nil && ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}, nil
+	}
+	if err := c.utls.deadlines.bound(c.NetConn(), deadline); err != nil {
+		return nil, err
+	}
+	return func(err error) error {
+		c.utls.deadlines.bound(c.NetConn(), time.Time{})
+		if err == nil {
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		// The connection may time out before the timer of ctx fires.
+		if !time.Now().Before(deadline) {
+			return context.DeadlineExceeded
+		}
+		return err
+	}, nil
+}
+
+// handshakeContextErr returns the error of the context of the handshake in
+// progress, if NoBackgroundTasks is set, as no goroutine interrupts the
+// handshake when it is done.
+func (c *Conn) handshakeContextErr() error {
+	if !c.config.NoBackgroundTasks || c.utls.handshakeCtx == nil {
+		return nil
+	}
+	return c.utls.handshakeCtx.Err()
+}
@@ -0,0 +1,146 @@
+package tls
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestNoBackgroundTasksKeys(t *testing.T) {
+	cached := keyCacheX25519.initialized.Load()
+	config := &Config{NoBackgroundTasks: true}
+	key, err := config.generateECDHEKey(X25519)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyCacheX25519.initialized.Load() != cached {
+		t.Error("the global key cache was filled")
+	}
+	if slices.Contains(keyCacheX25519.keys, key) {
+		t.Error("key taken from the global key cache")
+	}
+
+	quic := UQUICClient(&QUICConfig{TLSConfig: &Config{NoBackgroundTasks: true, ServerName: "example.com", MinVersion: VersionTLS13}}, HelloChrome_131)
+	if err := quic.Start(context.Background()); !errors.Is(err, errQUICBackgroundTasks) {
+		t.Errorf("QUIC Start = %v, want an error", err)
+	}
+}
+
+func TestNoBackgroundTasksHandshakeContext(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	config := &Config{NoBackgroundTasks: true, InsecureSkipVerify: true}
+
+	// A server that never answers.
+	clientEnd, serverEnd := memPipe()
+	defer serverEnd.Close()
+	client := UClient(clientEnd, config, HelloChrome_131)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := client.HandshakeContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("HandshakeContext = %v, want %v", err, context.DeadlineExceeded)
+	}
+	client.Close()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	clientEnd, serverEnd = memPipe()
+	defer serverEnd.Close()
+	client = UClient(clientEnd, config, HelloChrome_131)
+	if err := client.HandshakeContext(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("HandshakeContext = %v, want %v", err, context.Canceled)
+	}
+	client.Close()
+
+	// The deadline of the context doesn't outlive the handshake.
+	clientEnd, serverEnd = memPipe()
+	server := Server(serverEnd, s.Config)
+	defer server.Close()
+	go func() {
+		if server.Handshake() == nil {
+			io.Copy(server, server)
+		}
+	}()
+	client = UClient(clientEnd, config, HelloChrome_131)
+	defer client.Close()
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := client.HandshakeContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	<-ctx.Done()
+	if _, err := client.Write([]byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(client, make([]byte, 2)); err != nil {
+		t.Errorf("Read after the context expired: %v", err)
+	}
+}
+
+func TestNoBackgroundTasksRestoresDeadline(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	config := &Config{NoBackgroundTasks: true, InsecureSkipVerify: true}
+
+	clientEnd, serverEnd := memPipe()
+	server := Server(serverEnd, s.Config)
+	defer server.Close()
+	go server.Handshake()
+	client := UClient(clientEnd, config, HelloChrome_131)
+	defer client.Close()
+	if err := client.SetReadDeadline(time.Now().Add(200 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := client.HandshakeContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// The server doesn't write, so the Read waits for the deadline set before
+	// the handshake.
+	if _, err := client.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Read = %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+}
+
+func TestNoBackgroundTasksCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lookupCtx context.Context
+	serverConfig := testConfig.Clone()
+	serverConfig.NoBackgroundTasks = true
+	serverConfig.CertificateLookupTimeout = time.Minute
+	serverConfig.GetCertificate = func(chi *ClientHelloInfo) (*Certificate, error) {
+		lookupCtx = chi.Context()
+		// Cancel the client's context while it waits for the server.
+		cancel()
+		return &serverConfig.Certificates[0], nil
+	}
+
+	clientEnd, serverEnd := memPipe()
+	server := Server(serverEnd, serverConfig)
+	defer server.Close()
+	go server.Handshake()
+	client := UClient(clientEnd, &Config{NoBackgroundTasks: true, InsecureSkipVerify: true, ServerName: "example.com"}, HelloChrome_131)
+	defer client.Close()
+	if err := client.HandshakeContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("HandshakeContext = %v, want %v", err, context.Canceled)
+	}
+
+	if _, ok := lookupCtx.(*timerlessDeadlineContext); !ok {
+		t.Errorf("certificate lookup context is a %T, which may start a goroutine", lookupCtx)
+	}
+	if _, ok := lookupCtx.Deadline(); !ok {
+		t.Error("certificate lookup context has no deadline")
+	}
+}
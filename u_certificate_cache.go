@@ -0,0 +1,172 @@
+package tls
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// certificateLookupContext bounds ctx by CertificateLookupTimeout, if set.
+func (c *Config) certificateLookupContext(ctx context.Context) context.Context {
+	if c == nil || c.CertificateLookupTimeout <= 0 || ctx == nil {
+		return ctx
+	}
+	if c.NoBackgroundTasks {
+		deadline := time.Now().Add(c.CertificateLookupTimeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			return ctx
+		}
+		return &timerlessDeadlineContext{Context: ctx, deadline: deadline}
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.CertificateLookupTimeout)
+	// The timer is released at the latest when the handshake context is
+	// canceled, at the end of the handshake.
+	context.AfterFunc(ctx, cancel)
+	return ctx
+}
+
+// timerlessDeadlineContext bounds the deadline of its parent without the timer
+// of context.WithDeadline, whose expiry runs in a goroutine, for
+// NoBackgroundTasks. Deadline and Err report the deadline, but Done is only
+// closed with the parent.
+type timerlessDeadlineContext struct {
+	context.Context
+	deadline time.Time
+}
+
+func (ctx *timerlessDeadlineContext) Deadline() (time.Time, bool) {
+	return ctx.deadline, true
+}
+
+func (ctx *timerlessDeadlineContext) Err() error {
+	if err := ctx.Context.Err(); err != nil {
+		return err
+	}
+	if !time.Now().Before(ctx.deadline) {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+// CertificateCache caches the certificates returned by a slow lookup, e.g. in
+// a remote certificate store, by server name. Concurrent handshakes for the
+// same name share a single lookup. Its GetCertificate method is meant to be
+// used as Config.GetCertificate.
+//
+// It is safe for concurrent use.
+type CertificateCache struct {
+	// Lookup returns the certificate for the ClientHello. It should give up
+	// when the context returned by ClientHelloInfo.Context is done. Errors
+	// are not cached.
+	//
+	// The lookup is shared by all the handshakes waiting for it, so that
+	// context is not the one of the handshake that started it: it keeps its
+	// values, but is only bounded by Timeout.
+	//
+	// The certificate is cached by server name only, so it must not depend
+	// on other properties of the ClientHello.
+	Lookup func(*ClientHelloInfo) (*Certificate, error)
+
+	// TTL is how long a certificate is cached. Zero means until Invalidate
+	// is called.
+	TTL time.Duration
+
+	// Timeout bounds each lookup. Zero means 30s.
+	Timeout time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*certificateCacheEntry
+}
+
+type certificateCacheEntry struct {
+	done    chan struct{} // closed when the lookup is complete
+	cert    *Certificate
+	err     error
+	expires time.Time
+}
+
+// GetCertificate returns the cached certificate for the server name of the
+// ClientHello, starting a lookup if there is none, or waits for the lookup
+// already in progress. It returns early with the error of the ClientHello's
+// context if it's done while waiting, without canceling the lookup, which
+// the other waiting handshakes and the cache still get the result of.
+//
+// If Config.NoBackgroundTasks is set, the lookup runs in the handshake that
+// starts it instead of a goroutine, so that handshake waits for it until
+// Timeout regardless of its own context.
+func (c *CertificateCache) GetCertificate(chi *ClientHelloInfo) (*Certificate, error) {
+	name := strings.ToLower(chi.ServerName)
+	ctx := chi.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	c.mu.Lock()
+	e, ok := c.entries[name]
+	if ok && !e.expires.IsZero() && time.Now().After(e.expires) {
+		delete(c.entries, name)
+		ok = false
+	}
+	if !ok {
+		if c.entries == nil {
+			c.entries = make(map[string]*certificateCacheEntry)
+		}
+		e = &certificateCacheEntry{done: make(chan struct{})}
+		c.entries[name] = e
+		c.mu.Unlock()
+
+		lookupInfo := *chi
+		timeout := c.Timeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		if chi.config != nil && chi.config.NoBackgroundTasks {
+			lookupInfo.ctx = &timerlessDeadlineContext{
+				Context:  context.WithoutCancel(ctx),
+				deadline: time.Now().Add(timeout),
+			}
+			c.lookup(name, e, &lookupInfo)
+			return e.cert, e.err
+		} else {
+			lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+			lookupInfo.ctx = lookupCtx
+			go func() {
+				defer cancel()
+				c.lookup(name, e, &lookupInfo)
+			}()
+		}
+	} else {
+		c.mu.Unlock()
+	}
+
+	select {
+	case <-e.done:
+		return e.cert, e.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// lookup calls Lookup for the entry e of name and completes it.
+func (c *CertificateCache) lookup(name string, e *certificateCacheEntry, chi *ClientHelloInfo) {
+	e.cert, e.err = c.Lookup(chi)
+	c.mu.Lock()
+	if e.err != nil {
+		if c.entries[name] == e {
+			delete(c.entries, name)
+		}
+	} else if c.TTL > 0 {
+		e.expires = time.Now().Add(c.TTL)
+	}
+	c.mu.Unlock()
+	close(e.done)
+}
+
+// Invalidate removes the certificate of serverName from the cache, e.g. after
+// it was renewed. Handshakes already waiting for its lookup are not affected.
+func (c *CertificateCache) Invalidate(serverName string) {
+	c.mu.Lock()
+	delete(c.entries, strings.ToLower(serverName))
+	c.mu.Unlock()
+}
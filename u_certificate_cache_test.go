@@ -0,0 +1,153 @@
+package tls
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCertificateCache(t *testing.T) {
+	cert, err := selfSignedTestCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lookups atomic.Int32
+	release := make(chan struct{})
+	fail := false
+	cache := &CertificateCache{
+		Lookup: func(chi *ClientHelloInfo) (*Certificate, error) {
+			lookups.Add(1)
+			<-release
+			if fail {
+				return nil, errors.New("store unavailable")
+			}
+			return &cert, nil
+		},
+	}
+	chi := func(name string) *ClientHelloInfo {
+		return &ClientHelloInfo{ServerName: name, ctx: context.Background()}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c, err := cache.GetCertificate(chi("Example.com")); err != nil || c != &cert {
+				t.Errorf("GetCertificate = %v, %v", c, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := lookups.Load(); n != 1 {
+		t.Errorf("%d lookups for concurrent handshakes, want 1", n)
+	}
+	if _, err := cache.GetCertificate(chi("example.com")); err != nil || lookups.Load() != 1 {
+		t.Errorf("cached certificate not used: %v, %d lookups", err, lookups.Load())
+	}
+
+	cache.Invalidate("EXAMPLE.com")
+	fail = true
+	if _, err := cache.GetCertificate(chi("example.com")); err == nil {
+		t.Error("lookup error not returned")
+	}
+	fail = false
+	if _, err := cache.GetCertificate(chi("example.com")); err != nil {
+		t.Errorf("lookup error was cached: %v", err)
+	}
+	if n := lookups.Load(); n != 3 {
+		t.Errorf("%d lookups, want 3", n)
+	}
+
+	cache.TTL = time.Millisecond
+	cache.Invalidate("example.com")
+	cache.GetCertificate(chi("example.com"))
+	time.Sleep(5 * time.Millisecond)
+	cache.GetCertificate(chi("example.com"))
+	if n := lookups.Load(); n != 5 {
+		t.Errorf("%d lookups, want 5 after expiry", n)
+	}
+}
+
+func TestCertificateLookupTimeout(t *testing.T) {
+	var hasDeadline bool
+	serverConfig := &Config{
+		CertificateLookupTimeout: 20 * time.Millisecond,
+		GetCertificate: func(chi *ClientHelloInfo) (*Certificate, error) {
+			_, hasDeadline = chi.Context().Deadline()
+			<-chi.Context().Done()
+			return nil, chi.Context().Err()
+		},
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		Client(client, &Config{ServerName: "example.com", InsecureSkipVerify: true}).Handshake()
+	}()
+	start := time.Now()
+	err := Server(server, serverConfig).Handshake()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("handshake error = %v, want DeadlineExceeded", err)
+	}
+	if !hasDeadline {
+		t.Error("lookup context has no deadline")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("lookup took %v", d)
+	}
+}
+
+func TestCertificateCacheDetachedLookup(t *testing.T) {
+	cert, err := selfSignedTestCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	lookupErr := make(chan error, 1)
+	cache := &CertificateCache{
+		Lookup: func(chi *ClientHelloInfo) (*Certificate, error) {
+			close(started)
+			select {
+			case <-release:
+			case <-chi.Context().Done():
+			}
+			lookupErr <- chi.Context().Err()
+			return &cert, nil
+		},
+		Timeout: 5 * time.Second,
+	}
+
+	// The handshake that starts the lookup gives up early, but the lookup
+	// carries on for the other one.
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := cache.GetCertificate(&ClientHelloInfo{ServerName: "example.com", ctx: ctx})
+		firstErr <- err
+	}()
+	<-started
+	secondCert := make(chan *Certificate, 1)
+	go func() {
+		c, _ := cache.GetCertificate(&ClientHelloInfo{ServerName: "example.com", ctx: context.Background()})
+		secondCert <- c
+	}()
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled handshake got %v, want Canceled", err)
+	}
+	close(release)
+	if err := <-lookupErr; err != nil {
+		t.Errorf("lookup context done with %v after the first handshake was canceled", err)
+	}
+	if c := <-secondCert; c != &cert {
+		t.Errorf("waiting handshake got %v, want the certificate", c)
+	}
+}
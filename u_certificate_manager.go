@@ -0,0 +1,70 @@
+package tls
+
+import (
+	stdtls "crypto/tls"
+)
+
+// StdCertificateManager adapts a certificate manager written for crypto/tls,
+// such as autocert.Manager from golang.org/x/crypto/acme/autocert, to uTLS
+// servers:
+//
+//	m := &autocert.Manager{Prompt: autocert.AcceptTOS, HostPolicy: autocert.HostWhitelist("example.com")}
+//	config := StdCertificateManager(m.GetCertificate).TLSConfig()
+//
+// ACME TLS-ALPN-01 challenges are answered by the manager: the ClientHello of
+// the ACME server offers only the acme-tls/1 protocol, which the manager
+// recognizes to return the validation certificate. Managers that expose the
+// key authorization of challenges instead can be plugged into
+// Config.GetACMEKeyAuthorization.
+type StdCertificateManager func(*stdtls.ClientHelloInfo) (*stdtls.Certificate, error)
+
+// GetCertificate is suitable for Config.GetCertificate.
+func (m StdCertificateManager) GetCertificate(chi *ClientHelloInfo) (*Certificate, error) {
+	cert, err := m(stdClientHelloInfo(chi))
+	if err != nil || cert == nil {
+		return nil, err
+	}
+	return &Certificate{
+		Certificate:                  cert.Certificate,
+		PrivateKey:                   cert.PrivateKey,
+		SupportedSignatureAlgorithms: convertSlice[SignatureScheme](cert.SupportedSignatureAlgorithms),
+		OCSPStaple:                   cert.OCSPStaple,
+		SignedCertificateTimestamps:  cert.SignedCertificateTimestamps,
+		Leaf:                         cert.Leaf,
+	}, nil
+}
+
+// TLSConfig returns a server Config that gets its certificates from m and
+// negotiates h2, http/1.1 and, for TLS-ALPN-01 challenges, acme-tls/1. It is
+// the equivalent of autocert.Manager.TLSConfig.
+func (m StdCertificateManager) TLSConfig() *Config {
+	return &Config{
+		GetCertificate: m.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1", ACMETLSALPNProtocol},
+	}
+}
+
+func stdClientHelloInfo(chi *ClientHelloInfo) *stdtls.ClientHelloInfo {
+	return &stdtls.ClientHelloInfo{
+		CipherSuites:      chi.CipherSuites,
+		ServerName:        chi.ServerName,
+		SupportedCurves:   convertSlice[stdtls.CurveID](chi.SupportedCurves),
+		SupportedPoints:   chi.SupportedPoints,
+		SignatureSchemes:  convertSlice[stdtls.SignatureScheme](chi.SignatureSchemes),
+		SupportedProtos:   withoutGREASEALPN(chi.SupportedProtos),
+		SupportedVersions: chi.SupportedVersions,
+		Extensions:        chi.Extensions,
+		Conn:              chi.Conn,
+	}
+}
+
+func convertSlice[To, From ~uint16](s []From) []To {
+	if s == nil {
+		return nil
+	}
+	out := make([]To, len(s))
+	for i, v := range s {
+		out[i] = To(v)
+	}
+	return out
+}
@@ -0,0 +1,67 @@
+package tls
+
+import (
+	stdtls "crypto/tls"
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestStdCertificateManager(t *testing.T) {
+	cert, err := selfSignedTestCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge, err := NewACMEChallengeCertificate("example.com", "token.thumbprint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hellos []*stdtls.ClientHelloInfo
+	manager := StdCertificateManager(func(hello *stdtls.ClientHelloInfo) (*stdtls.Certificate, error) {
+		hellos = append(hellos, hello)
+		c := cert
+		if slices.Equal(hello.SupportedProtos, []string{ACMETLSALPNProtocol}) {
+			c = *challenge
+		}
+		return &stdtls.Certificate{Certificate: c.Certificate, PrivateKey: c.PrivateKey, Leaf: c.Leaf}, nil
+	})
+
+	handshake := func(id ClientHelloID, protos []string) ConnectionState {
+		t.Helper()
+		client, server := net.Pipe()
+		defer client.Close()
+		errc := make(chan error, 1)
+		go func() {
+			errc <- Server(server, manager.TLSConfig()).Handshake()
+			server.Close()
+		}()
+		uconn := UClient(client, &Config{ServerName: "example.com", NextProtos: protos, InsecureSkipVerify: true}, id)
+		if err := uconn.Handshake(); err != nil {
+			t.Fatal(err)
+		}
+		client.Close()
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+		return uconn.ConnectionState()
+	}
+
+	cs := handshake(HelloChrome_Auto, nil)
+	if cs.NegotiatedProtocol != "h2" || !cs.PeerCertificates[0].Equal(cert.Leaf) {
+		t.Errorf("negotiated %q with certificate %v", cs.NegotiatedProtocol, cs.PeerCertificates[0].Subject)
+	}
+	hello := hellos[len(hellos)-1]
+	if hello.ServerName != "example.com" || len(hello.SupportedCurves) == 0 || len(hello.Extensions) == 0 {
+		t.Errorf("unexpected ClientHelloInfo %+v", hello)
+	}
+	for _, proto := range hello.SupportedProtos {
+		if isGREASEALPN(proto) {
+			t.Errorf("GREASE ALPN protocol %q passed to the manager", proto)
+		}
+	}
+
+	cs = handshake(HelloGolang, []string{ACMETLSALPNProtocol})
+	if cs.NegotiatedProtocol != ACMETLSALPNProtocol || !cs.PeerCertificates[0].Equal(challenge.Leaf) {
+		t.Errorf("challenge negotiated %q with certificate %v", cs.NegotiatedProtocol, cs.PeerCertificates[0].Subject)
+	}
+}
@@ -0,0 +1,82 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"slices"
+)
+
+// CertificateSelectionInfo is passed to Config.GetCertificateForClientHello.
+type CertificateSelectionInfo struct {
+	// Hello is the ClientHello the certificate is selected for. If the
+	// server accepted Encrypted Client Hello, it's the decrypted inner
+	// ClientHello, whose ServerName is the name the client connects to.
+	// It must not be modified.
+	Hello *PubClientHelloMsg
+
+	// ECHAccepted reports whether Hello is an inner ClientHello.
+	ECHAccepted bool
+
+	// Info is the ClientHelloInfo of Hello, e.g. to call SupportsCertificate
+	// and SupportsCertificateChain.
+	Info *ClientHelloInfo
+}
+
+// SupportsCertificateChain returns nil if the signatures of the certificate
+// chain of c use algorithms offered by the client in signature_algorithms_cert,
+// or in signature_algorithms if it didn't send signature_algorithms_cert (RFC
+// 8446, Section 4.2.3). Self-signed certificates are not checked, as they are
+// trust anchors. It returns an error describing the first unsupported
+// signature otherwise.
+//
+// It only knows the offered algorithms if this ClientHelloInfo was passed to a
+// GetConfigForClient, GetCertificate or GetCertificateForClientHello callback.
+func (chi *ClientHelloInfo) SupportsCertificateChain(c *Certificate) error {
+	offered := chi.SignatureSchemes
+	if chi.hello != nil {
+		offered = chi.hello.certSignatureAlgorithms()
+	}
+	if len(offered) == 0 {
+		return nil
+	}
+	for i, der := range c.Certificate {
+		cert := c.Leaf
+		if i > 0 || cert == nil {
+			var err error
+			if cert, err = x509.ParseCertificate(der); err != nil {
+				return fmt.Errorf("failed to parse certificate: %w", err)
+			}
+		}
+		if bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+			continue
+		}
+		if !slices.ContainsFunc(x509SignatureSchemes(cert.SignatureAlgorithm), func(s SignatureScheme) bool {
+			return slices.Contains(offered, s)
+		}) {
+			return fmt.Errorf("certificate %d of the chain is signed with %v, which was not offered", i, cert.SignatureAlgorithm)
+		}
+	}
+	return nil
+}
+
+// certificateSelectionInfo returns the argument of
+// Config.GetCertificateForClientHello.
+func (chi *ClientHelloInfo) certificateSelectionInfo() *CertificateSelectionInfo {
+	return &CertificateSelectionInfo{
+		Hello:       chi.hello.getPublicPtr(),
+		ECHAccepted: chi.echAccepted,
+		Info:        chi,
+	}
+}
+
+// certificateForClientHello returns the first of certs supported by the client
+// including its chain, or nil.
+func (chi *ClientHelloInfo) certificateForClientHello(certs []Certificate) *Certificate {
+	for i := range certs {
+		if chi.SupportsCertificate(&certs[i]) == nil && chi.SupportsCertificateChain(&certs[i]) == nil {
+			return &certs[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// testChainSignedWith returns a certificate for localhost issued by a new CA
+// with sigAlg.
+func testChainSignedWith(t *testing.T, sigAlg x509.SignatureAlgorithm) Certificate {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "uTLS test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:       big.NewInt(2),
+		Subject:            pkix.Name{CommonName: "localhost"},
+		DNSNames:           []string{"localhost"},
+		NotBefore:          time.Now().Add(-time.Hour),
+		NotAfter:           time.Now().Add(time.Hour),
+		SignatureAlgorithm: sigAlg,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, key.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Certificate{Certificate: [][]byte{der, caDER}, PrivateKey: key}
+}
+
+func TestCertificateSelectionBySignatureAlgorithmsCert(t *testing.T) {
+	sha512Chain := testChainSignedWith(t, x509.ECDSAWithSHA512)
+	sha256Chain := testChainSignedWith(t, x509.ECDSAWithSHA256)
+	s, err := NewUTLSServer(&Config{Certificates: []Certificate{sha512Chain, sha256Chain}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var selection *CertificateSelectionInfo
+	s.Config.GetCertificateForClientHello = func(info *CertificateSelectionInfo) (*Certificate, error) {
+		selection = info
+		return nil, nil
+	}
+
+	spec, err := UTLSIdToSpec(HelloFirefox_120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.Extensions = append(spec.Extensions, &SignatureAlgorithmsCertExtension{
+		SupportedSignatureAlgorithms: []SignatureScheme{ECDSAWithP256AndSHA256},
+	})
+	c := NewUnstartedUTLSClient(s, &Config{InsecureSkipVerify: true}, HelloCustom)
+	if err := c.ApplyPreset(&spec); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if got := c.ConnectionState().PeerCertificates[0].SignatureAlgorithm; got != x509.ECDSAWithSHA256 {
+		t.Errorf("server selected a certificate signed with %v, want %v", got, x509.ECDSAWithSHA256)
+	}
+	if selection == nil || selection.ECHAccepted || selection.Hello.ServerName != "localhost" ||
+		len(selection.Hello.SupportedSignatureAlgorithmsCert) != 1 {
+		t.Errorf("GetCertificateForClientHello called with %+v", selection)
+	}
+	if err := selection.Info.SupportsCertificateChain(&sha512Chain); err == nil {
+		t.Error("SupportsCertificateChain accepted a chain signed with an algorithm not offered")
+	}
+	if err := selection.Info.SupportsCertificateChain(&sha256Chain); err != nil {
+		t.Errorf("SupportsCertificateChain: %v", err)
+	}
+}
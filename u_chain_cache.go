@@ -0,0 +1,235 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	defaultChainCacheTTL  = 10 * time.Minute
+	defaultChainCacheSize = 256
+)
+
+// ChainCachePolicy controls whether the OCSP response and Signed Certificate
+// Timestamps stapled by the server must match for a VerifiedChainCache entry to
+// be reused.
+type ChainCachePolicy uint8
+
+const (
+	// ChainCacheRequireSameStaples only reuses a verified chain if the server
+	// staples the same OCSP response and SCTs it did when the chain was
+	// verified. Otherwise the chain is verified again and the entry replaced.
+	ChainCacheRequireSameStaples ChainCachePolicy = iota
+
+	// ChainCacheIgnoreStaples reuses a verified chain whatever the server
+	// staples, e.g. if stapled data is checked by VerifyConnection, which
+	// runs on every connection anyway.
+	ChainCacheIgnoreStaples
+)
+
+// VerifiedChainCache caches the server certificate chains verified by clients,
+// keyed by a hash of the certificates sent by the server, so that reconnecting
+// to the same origin doesn't parse and verify the same chain again. Set
+// Config.VerifiedChainCache to enable it.
+//
+// Entries are only reused for the same verification parameters: RootCAs,
+// verified server name and InsecureSkipTimeVerify. They expire after TTL, or
+// when a certificate of the chain does. VerifyPeerCertificate and
+// VerifyConnection are still called for every connection.
+//
+// A VerifiedChainCache is safe for concurrent use and should be shared between
+// connections so that it is effective.
+type VerifiedChainCache struct {
+	// TTL is how long a verified chain is reused. If zero, 10 minutes is
+	// used.
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of cached chains. If zero, 256 is
+	// used.
+	MaxEntries int
+
+	// Policy is how stapled OCSP responses and SCTs are handled.
+	Policy ChainCachePolicy
+
+	mu      sync.Mutex
+	entries map[verifiedChainKey]*verifiedChainEntry
+}
+
+type verifiedChainKey struct {
+	chainHash      [sha256.Size]byte
+	roots          *x509.CertPool
+	dnsName        string
+	skipTimeVerify bool
+}
+
+type verifiedChainEntry struct {
+	certs        []*x509.Certificate
+	chains       [][]*x509.Certificate
+	ocspResponse []byte
+	scts         [][]byte
+	expires      time.Time
+}
+
+// NewVerifiedChainCache returns a VerifiedChainCache with default settings.
+func NewVerifiedChainCache() *VerifiedChainCache {
+	return &VerifiedChainCache{}
+}
+
+// Len returns the number of cached chains, including expired ones not yet
+// evicted.
+func (cc *VerifiedChainCache) Len() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return len(cc.entries)
+}
+
+// Flush removes all cached chains, e.g. after RootCAs changed.
+func (cc *VerifiedChainCache) Flush() {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.entries = nil
+}
+
+func (cc *VerifiedChainCache) get(key verifiedChainKey, now time.Time, ocspResponse []byte, scts [][]byte) *verifiedChainEntry {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	e, ok := cc.entries[key]
+	if !ok {
+		return nil
+	}
+	if !now.Before(e.expires) {
+		delete(cc.entries, key)
+		return nil
+	}
+	if cc.Policy == ChainCacheRequireSameStaples &&
+		(!bytes.Equal(e.ocspResponse, ocspResponse) || !slices.EqualFunc(e.scts, scts, bytes.Equal)) {
+		return nil
+	}
+	return e
+}
+
+func (cc *VerifiedChainCache) put(key verifiedChainKey, now time.Time, e *verifiedChainEntry) {
+	ttl := cc.TTL
+	if ttl == 0 {
+		ttl = defaultChainCacheTTL
+	}
+	e.expires = now.Add(ttl)
+	for _, chain := range e.chains {
+		for _, cert := range chain {
+			if cert.NotAfter.Before(e.expires) {
+				e.expires = cert.NotAfter
+			}
+		}
+	}
+
+	maxEntries := cc.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = defaultChainCacheSize
+	}
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.entries == nil {
+		cc.entries = make(map[verifiedChainKey]*verifiedChainEntry)
+	}
+	if _, ok := cc.entries[key]; !ok && len(cc.entries) >= maxEntries {
+		// Drop expired entries first, then arbitrary ones.
+		for k, v := range cc.entries {
+			if !now.Before(v.expires) {
+				delete(cc.entries, k)
+			}
+		}
+		for k := range cc.entries {
+			if len(cc.entries) < maxEntries {
+				break
+			}
+			delete(cc.entries, k)
+		}
+	}
+	cc.entries[key] = e
+}
+
+// verifiedChainCacheKey returns the cache key of the chain sent by the server,
+// and false if the cache doesn't apply to this connection.
+func (c *Conn) verifiedChainCacheKey(certificates [][]byte) (verifiedChainKey, bool) {
+	config := c.config
+	if config.VerifiedChainCache == nil || config.skipVerify() ||
+		(config.EncryptedClientHelloConfigList != nil && !c.echAccepted) {
+		return verifiedChainKey{}, false
+	}
+	h := sha256.New()
+	for _, cert := range certificates {
+		h.Write([]byte{byte(len(cert) >> 16), byte(len(cert) >> 8), byte(len(cert))})
+		h.Write(cert)
+	}
+	key := verifiedChainKey{
+		roots:          config.rootCAs(),
+		skipTimeVerify: config.InsecureSkipTimeVerify,
+	}
+	h.Sum(key.chainHash[:0])
+	if len(config.InsecureServerNameToVerify) == 0 {
+		key.dnsName = config.ServerName
+	} else if config.InsecureServerNameToVerify != "*" {
+		key.dnsName = config.InsecureServerNameToVerify
+	}
+	return key, true
+}
+
+// verifyCachedServerCertificate sets c.peerCertificates and c.verifiedChains
+// from config.VerifiedChainCache and runs the verification callbacks. It
+// returns false if the chain is not cached, and must then be verified.
+func (c *Conn) verifyCachedServerCertificate(certificates [][]byte) (bool, error) {
+	key, ok := c.verifiedChainCacheKey(certificates)
+	if !ok {
+		return false, nil
+	}
+	e := c.config.VerifiedChainCache.get(key, c.config.time(), c.ocspResponse, c.scts)
+	if e == nil {
+		return false, nil
+	}
+	c.peerCertificates = e.certs
+	c.verifiedChains = e.chains
+	if err := c.checkCertSignatureAlgorithms(); err != nil {
+		return true, err
+	}
+
+	if c.config.VerifyPeerCertificate != nil {
+		if err := c.config.VerifyPeerCertificate(certificates, c.verifiedChains); err != nil {
+			c.sendAlert(alertBadCertificate)
+			return true, err
+		}
+	}
+	if c.config.VerifyConnection != nil {
+		if err := c.config.VerifyConnection(c.connectionStateLocked()); err != nil {
+			c.sendAlert(alertBadCertificate)
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// cacheVerifiedServerCertificate stores the chain verified for certificates in
+// config.VerifiedChainCache.
+func (c *Conn) cacheVerifiedServerCertificate(certificates [][]byte) {
+	key, ok := c.verifiedChainCacheKey(certificates)
+	if !ok || len(c.verifiedChains) == 0 {
+		return
+	}
+	c.config.VerifiedChainCache.put(key, c.config.time(), &verifiedChainEntry{
+		certs:        c.peerCertificates,
+		chains:       c.verifiedChains,
+		ocspResponse: bytes.Clone(c.ocspResponse),
+		scts:         cloneSCTs(c.scts),
+	})
+}
+
+func cloneSCTs(scts [][]byte) [][]byte {
+	clone := make([][]byte, len(scts))
+	for i, sct := range scts {
+		clone[i] = bytes.Clone(sct)
+	}
+	return clone
+}
@@ -0,0 +1,89 @@
+package tls
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+)
+
+func TestVerifiedChainCache(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var staple []byte
+	s.Config.GetCertificate = func(*ClientHelloInfo) (*Certificate, error) {
+		cert := s.Config.Certificates[0]
+		cert.OCSPStaple = staple
+		return &cert, nil
+	}
+
+	now := time.Now()
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate)
+	verifyConnectionCalls := 0
+	cache := &VerifiedChainCache{TTL: time.Minute}
+	config := &Config{
+		RootCAs:            roots,
+		Time:               func() time.Time { return now },
+		VerifiedChainCache: cache,
+		VerifyConnection: func(cs ConnectionState) error {
+			verifyConnectionCalls++
+			return nil
+		},
+	}
+
+	var prevChains [][]*x509.Certificate
+	connect := func(name string, wantHit bool) {
+		t.Helper()
+		c, err := NewUTLSClient(s, config, HelloChrome_Auto)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		defer c.Close()
+		chains := c.ConnectionState().VerifiedChains
+		if len(chains) == 0 || len(c.ConnectionState().PeerCertificates) == 0 {
+			t.Fatalf("%s: connection has no verified chains", name)
+		}
+		// Cached chains are reused as is.
+		hit := prevChains != nil && &chains[0] == &prevChains[0]
+		if hit != wantHit {
+			t.Errorf("%s: cache hit = %v, want %v", name, hit, wantHit)
+		}
+		prevChains = chains
+	}
+
+	connect("first", false)
+	connect("second", true)
+	if verifyConnectionCalls != 2 {
+		t.Errorf("VerifyConnection was called %d times, want 2", verifyConnectionCalls)
+	}
+	if n := cache.Len(); n != 1 {
+		t.Errorf("cache has %d entries, want 1", n)
+	}
+
+	staple = []byte("ocsp response")
+	connect("changed staple", false)
+	connect("same staple", true)
+	cache.Policy = ChainCacheIgnoreStaples
+	staple = nil
+	connect("ignored staple", true)
+
+	now = now.Add(2 * time.Minute)
+	connect("expired", false)
+
+	config.RootCAs = x509.NewCertPool()
+	config.RootCAs.AddCert(s.Certificate)
+	connect("other roots", false)
+
+	config.RootCAs = x509.NewCertPool()
+	if _, err := NewUTLSClient(s, config, HelloChrome_Auto); err == nil {
+		t.Error("cached chain was used with roots that don't trust it")
+	}
+
+	cache.Flush()
+	if n := cache.Len(); n != 0 {
+		t.Errorf("cache has %d entries after Flush", n)
+	}
+}
@@ -0,0 +1,84 @@
+package tls
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// ChannelBindingType is a channel binding type, as registered in the IANA
+// "Channel-Binding Types" registry.
+type ChannelBindingType string
+
+const (
+	// ChannelBindingTLSUnique is defined in RFC 5929, Section 3. It is not
+	// available for TLS 1.3 connections.
+	ChannelBindingTLSUnique ChannelBindingType = "tls-unique"
+	// ChannelBindingTLSServerEndPoint is defined in RFC 5929, Section 4.
+	ChannelBindingTLSServerEndPoint ChannelBindingType = "tls-server-end-point"
+	// ChannelBindingTLSExporter is defined in RFC 9266.
+	ChannelBindingTLSExporter ChannelBindingType = "tls-exporter"
+)
+
+// channelBindingExporterLabel and channelBindingExporterLength are defined in
+// RFC 9266, Section 2.
+const (
+	channelBindingExporterLabel  = "EXPORTER-Channel-Binding"
+	channelBindingExporterLength = 32
+)
+
+// ChannelBinding returns the channel binding data of type t for the
+// connection, as used by authentication mechanisms such as SASL SCRAM-*-PLUS.
+// Both peers obtain the same value.
+//
+// An error is returned if the binding type is not defined for the connection,
+// for example tls-unique with TLS 1.3, or tls-exporter when
+// ExportKeyingMaterial is unavailable.
+func (cs *ConnectionState) ChannelBinding(t ChannelBindingType) ([]byte, error) {
+	if !cs.HandshakeComplete {
+		return nil, errors.New("tls: ChannelBinding called before the handshake completed")
+	}
+
+	switch t {
+	case ChannelBindingTLSUnique:
+		if cs.Version == VersionTLS13 {
+			return nil, errors.New("tls: tls-unique channel binding is not defined for TLS 1.3")
+		}
+		if len(cs.TLSUnique) == 0 {
+			return nil, errors.New("tls: tls-unique channel binding is unavailable for resumed connections without Extended Master Secret")
+		}
+		return append([]byte(nil), cs.TLSUnique...), nil
+	case ChannelBindingTLSServerEndPoint:
+		if len(cs.serverCertificate) == 0 {
+			return nil, errors.New("tls: tls-server-end-point channel binding is unavailable without a server certificate")
+		}
+		cert, err := x509.ParseCertificate(cs.serverCertificate)
+		if err != nil {
+			return nil, fmt.Errorf("tls: failed to parse server certificate: %w", err)
+		}
+		h := serverEndPointHash(cert.SignatureAlgorithm).New()
+		h.Write(cert.Raw)
+		return h.Sum(nil), nil
+	case ChannelBindingTLSExporter:
+		return cs.ExportKeyingMaterial(channelBindingExporterLabel, nil, channelBindingExporterLength)
+	default:
+		return nil, fmt.Errorf("tls: unsupported channel binding type %q", t)
+	}
+}
+
+// serverEndPointHash returns the hash function used by the tls-server-end-point
+// channel binding for a certificate signed with alg. See RFC 5929, Section 4.1.
+func serverEndPointHash(alg x509.SignatureAlgorithm) crypto.Hash {
+	switch alg {
+	case x509.SHA384WithRSA, x509.ECDSAWithSHA384, x509.SHA384WithRSAPSS:
+		return crypto.SHA384
+	case x509.SHA512WithRSA, x509.ECDSAWithSHA512, x509.SHA512WithRSAPSS:
+		return crypto.SHA512
+	default:
+		// MD5 and SHA-1 are upgraded to SHA-256. Algorithms without a
+		// distinguished hash function, such as Ed25519, also use SHA-256, as
+		// is common practice.
+		return crypto.SHA256
+	}
+}
@@ -0,0 +1,44 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestChannelBinding(t *testing.T) {
+	for _, version := range []uint16{VersionTLS12, VersionTLS13} {
+		clientConfig := testConfig.Clone()
+		clientConfig.MaxVersion = version
+		serverState, clientState, err := testHandshake(t, clientConfig, testConfig.Clone())
+		if err != nil {
+			t.Fatalf("%x: handshake failed: %v", version, err)
+		}
+
+		for _, cbType := range []ChannelBindingType{ChannelBindingTLSUnique, ChannelBindingTLSServerEndPoint, ChannelBindingTLSExporter} {
+			clientCB, clientErr := clientState.ChannelBinding(cbType)
+			serverCB, serverErr := serverState.ChannelBinding(cbType)
+			if cbType == ChannelBindingTLSUnique && version == VersionTLS13 {
+				if clientErr == nil || serverErr == nil {
+					t.Errorf("%x: expected %s to be unavailable", version, cbType)
+				}
+				continue
+			}
+			if clientErr != nil || serverErr != nil {
+				t.Fatalf("%x: %s: %v, %v", version, cbType, clientErr, serverErr)
+			}
+			if len(clientCB) == 0 || !bytes.Equal(clientCB, serverCB) {
+				t.Errorf("%x: %s: client and server bindings differ: %x != %x", version, cbType, clientCB, serverCB)
+			}
+		}
+
+		endPoint, _ := clientState.ChannelBinding(ChannelBindingTLSServerEndPoint)
+		if want := sha256.Sum256(clientState.PeerCertificates[0].Raw); !bytes.Equal(endPoint, want[:]) {
+			t.Errorf("%x: unexpected tls-server-end-point binding %x", version, endPoint)
+		}
+	}
+
+	if _, err := (&ConnectionState{HandshakeComplete: true}).ChannelBinding("tls-bogus"); err == nil {
+		t.Error("expected error for unknown channel binding type")
+	}
+}
@@ -0,0 +1,58 @@
+package tls
+
+import "golang.org/x/crypto/cryptobyte"
+
+// RawExtension is an extension of a ClientHello, as sent on the wire.
+type RawExtension struct {
+	ID   uint16
+	Data []byte // without the type and length prefixes
+}
+
+// RawClientHello returns the ClientHello handshake message, including its
+// four bytes header, or nil if not available.
+func (chi *ClientHelloInfo) RawClientHello() []byte {
+	if chi.hello == nil {
+		return nil
+	}
+	return chi.hello.original
+}
+
+// RawExtensions returns the extensions of the ClientHello in the order the
+// client sent them, GREASE and unknown extensions included, so that
+// GetConfigForClient can check a fingerprint without parsing the ClientHello
+// again. The IDs are the same as Extensions.
+func (chi *ClientHelloInfo) RawExtensions() []RawExtension {
+	s := cryptobyte.String(chi.RawClientHello())
+	var random, sessionID, cipherSuites, compressionMethods, extensions cryptobyte.String
+	var vers uint16
+	if !s.Skip(4) || !s.ReadUint16(&vers) || !s.ReadBytes((*[]byte)(&random), 32) ||
+		!s.ReadUint8LengthPrefixed(&sessionID) ||
+		!s.ReadUint16LengthPrefixed(&cipherSuites) ||
+		!s.ReadUint8LengthPrefixed(&compressionMethods) ||
+		!s.ReadUint16LengthPrefixed(&extensions) {
+		return nil
+	}
+
+	var exts []RawExtension
+	for !extensions.Empty() {
+		var ext RawExtension
+		var extData cryptobyte.String
+		if !extensions.ReadUint16(&ext.ID) || !extensions.ReadUint16LengthPrefixed(&extData) {
+			return nil
+		}
+		ext.Data = extData
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+// RawExtension returns the data of the first extension of the ClientHello with
+// the given ID, and whether it was sent.
+func (chi *ClientHelloInfo) RawExtension(id uint16) ([]byte, bool) {
+	for _, ext := range chi.RawExtensions() {
+		if ext.ID == id {
+			return ext.Data, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,59 @@
+package tls
+
+import (
+	"bytes"
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestClientHelloInfoRawExtensions(t *testing.T) {
+	cert, err := selfSignedTestCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var chi *ClientHelloInfo
+	serverConfig := &Config{
+		Certificates: []Certificate{cert},
+		GetConfigForClient: func(info *ClientHelloInfo) (*Config, error) {
+			chi = info
+			return nil, nil
+		},
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- Server(server, serverConfig).Handshake()
+		server.Close()
+	}()
+	uconn := UClient(client, &Config{ServerName: "example.com", InsecureSkipVerify: true}, HelloChrome_Auto)
+	if err := uconn.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	<-errc
+
+	if !bytes.Equal(chi.RawClientHello(), uconn.HandshakeState.Hello.Raw) {
+		t.Error("RawClientHello doesn't match the sent ClientHello")
+	}
+	exts := chi.RawExtensions()
+	var ids []uint16
+	for _, ext := range exts {
+		ids = append(ids, ext.ID)
+	}
+	if !slices.Equal(ids, chi.Extensions) {
+		t.Errorf("RawExtensions IDs = %v, want %v", ids, chi.Extensions)
+	}
+	if !isGREASEUint16(ids[0]) {
+		t.Errorf("first extension is %d, want GREASE", ids[0])
+	}
+	sni, ok := chi.RawExtension(extensionServerName)
+	if !ok || !bytes.HasSuffix(sni, []byte("example.com")) {
+		t.Errorf("server_name extension = %q, %v", sni, ok)
+	}
+	if _, ok := chi.RawExtension(0xfefe); ok {
+		t.Error("found an extension that wasn't sent")
+	}
+}
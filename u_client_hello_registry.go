@@ -0,0 +1,63 @@
+package tls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// autoClientHelloIDs maps clients to the ClientHelloID selected by an "auto"
+// version, e.g. HelloChrome_Auto.
+var autoClientHelloIDs = map[string]ClientHelloID{
+	helloGolang:           HelloGolang,
+	helloRandomized:       HelloRandomized,
+	helloRandomizedALPN:   HelloRandomizedALPN,
+	helloRandomizedNoALPN: HelloRandomizedNoALPN,
+	helloFirefox:          HelloFirefox_Auto,
+	helloChrome:           HelloChrome_Auto,
+	helloIOS:              HelloIOS_Auto,
+	helloEdge:             HelloEdge_Auto,
+	helloSafari:           HelloSafari_Auto,
+	helloElectron:         HelloElectron_Auto,
+	hello360:              Hello360_Auto,
+	helloQQ:               HelloQQ_Auto,
+	helloCurl:             HelloCurl_Auto,
+	helloWget:             HelloWget_Auto,
+	helloJava:             HelloJava_Auto,
+	helloPythonRequests:   HelloPythonRequests_Auto,
+	helloDotNet:           HelloDotNet_Auto,
+	helloOkHttp:           HelloOkHttp_Auto,
+}
+
+// ListClientHelloIDs returns the ClientHelloIDs that ClientHelloIDByName can
+// look up: HelloGolang, the HelloRandomized variants, then all the parroted
+// presets grouped by client. Aliases such as HelloChrome_Auto are not listed
+// separately, and neither is HelloCustom, which requires a spec.
+func ListClientHelloIDs() []ClientHelloID {
+	ids := []ClientHelloID{HelloGolang, HelloRandomized, HelloRandomizedALPN, HelloRandomizedNoALPN}
+	for _, m := range presetMetadata {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
+
+// ClientHelloIDByName returns the ClientHelloID with the given Client and
+// Version fields, e.g. "Chrome" and "131", so that applications can select
+// fingerprints from their own configuration files. client is matched
+// case-insensitively. An empty or "auto" version selects the current preset of
+// the client, e.g. HelloChrome_Auto for "Chrome".
+func ClientHelloIDByName(client, version string) (ClientHelloID, error) {
+	if version == "" || strings.EqualFold(version, "auto") {
+		for c, id := range autoClientHelloIDs {
+			if strings.EqualFold(c, client) {
+				return id, nil
+			}
+		}
+	} else {
+		for _, id := range ListClientHelloIDs() {
+			if strings.EqualFold(id.Client, client) && id.Version == version {
+				return id, nil
+			}
+		}
+	}
+	return ClientHelloID{}, fmt.Errorf("%w: %s %s", ErrUnknownClientHelloID, client, version)
+}
@@ -0,0 +1,49 @@
+package tls
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClientHelloIDByName(t *testing.T) {
+	for _, id := range ListClientHelloIDs() {
+		got, err := ClientHelloIDByName(id.Client, id.Version)
+		if err != nil || got != id {
+			t.Errorf("ClientHelloIDByName(%q, %q) = %s, %v", id.Client, id.Version, got.Str(), err)
+		}
+	}
+
+	for _, tt := range []struct {
+		client, version string
+		want            ClientHelloID
+	}{
+		{"chrome", "131", HelloChrome_131},
+		{"Chrome", "", HelloChrome_Auto},
+		{"FIREFOX", "auto", HelloFirefox_Auto},
+		{"golang", "", HelloGolang},
+		{"Randomized-NoALPN", "auto", HelloRandomizedNoALPN},
+	} {
+		got, err := ClientHelloIDByName(tt.client, tt.version)
+		if err != nil || got != tt.want {
+			t.Errorf("ClientHelloIDByName(%q, %q) = %s, %v, want %s", tt.client, tt.version, got.Str(), err, tt.want.Str())
+		}
+	}
+
+	for _, name := range [][2]string{{"Chrome", "1"}, {"Netscape", ""}, {"Custom", "0"}} {
+		if _, err := ClientHelloIDByName(name[0], name[1]); !errors.Is(err, ErrUnknownClientHelloID) {
+			t.Errorf("ClientHelloIDByName(%q, %q) error = %v", name[0], name[1], err)
+		}
+	}
+}
+
+func TestAutoClientHelloIDsListed(t *testing.T) {
+	listed := map[ClientHelloID]bool{}
+	for _, id := range ListClientHelloIDs() {
+		listed[id] = true
+	}
+	for client, id := range autoClientHelloIDs {
+		if !listed[id] {
+			t.Errorf("auto ClientHelloID %s of %s is not listed", id.Str(), client)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package tls
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// tlsFingerprintFields lists the fields of the client.tlsfingerprint.io format,
+// in the order they are exported.
+var tlsFingerprintFields = []string{
+	"cipher_suites",
+	"compression_methods",
+	"extensions",
+	"pt_fmts",
+	"sig_algs",
+	"supported_versions",
+	"curves",
+	"alpn",
+	"key_share",
+	"psk_key_exchange_modes",
+	"cert_compression_algs",
+	"record_size_limit",
+}
+
+// ImportTLSClientHelloFromText imports ClientHelloSpec from the text format
+// produced by ExportTLSClientHelloText, which is the client.tlsfingerprint.io
+// format with one hex encoded field per line:
+//
+//	cipher_suites: 0a0a 1301 1302 1303 c02b c02f
+//	compression_methods: 00
+//	extensions: 0a0a 0000 0017 ff01 000a 000b
+//	...
+//
+// Whitespace, commas and colons inside values are ignored, as are blank lines
+// and lines starting with '#', so that values can be pasted directly from
+// fingerprint databases. Field names are case insensitive and may use spaces
+// instead of underscores.
+//
+// It calls ImportTLSClientHello internally after decoding the text.
+func (chs *ClientHelloSpec) ImportTLSClientHelloFromText(text []byte) error {
+	data := make(map[string][]byte)
+	scanner := bufio.NewScanner(bytes.NewReader(text))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return fmt.Errorf("line %d: missing ':' separator", lineNum)
+		}
+		key = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), " ", "_")
+		value = strings.Map(func(r rune) rune {
+			switch r {
+			case ' ', '\t', ',', ':':
+				return -1
+			}
+			return r
+		}, value)
+		b, err := hex.DecodeString(value)
+		if err != nil {
+			return fmt.Errorf("line %d: invalid hex value for %s: %w", lineNum, key, err)
+		}
+		data[key] = b
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return chs.ImportTLSClientHello(data)
+}
+
+// ExportTLSClientHello is the inverse of ImportTLSClientHello: it marshals chs
+// and returns the resulting ClientHello in the client.tlsfingerprint.io format.
+// GREASE values are normalized to GREASE_PLACEHOLDER.
+//
+// The spec is applied to a HelloCustom UConn in the process, so like with
+// ApplyPreset its extensions must not be shared with another connection.
+func (chs *ClientHelloSpec) ExportTLSClientHello() (map[string][]byte, error) {
+	// A server name is needed for the server_name extension to be sent.
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com", InsecureSkipVerify: true}, HelloCustom)
+	if err := uconn.ApplyPreset(chs); err != nil {
+		return nil, err
+	}
+	if err := uconn.BuildHandshakeState(); err != nil {
+		return nil, err
+	}
+	return exportTLSClientHello(uconn.HandshakeState.Hello.Raw)
+}
+
+// ExportTLSClientHelloText is like ExportTLSClientHello, but returns the text
+// format accepted by ImportTLSClientHelloFromText.
+func (chs *ClientHelloSpec) ExportTLSClientHelloText() ([]byte, error) {
+	data, err := chs.ExportTLSClientHello()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, field := range tlsFingerprintFields {
+		value, ok := data[field]
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&buf, "%s: %s\n", field, hex.EncodeToString(value))
+	}
+	return buf.Bytes(), nil
+}
+
+// exportTLSClientHello converts a marshaled ClientHello handshake message into
+// the client.tlsfingerprint.io format.
+func exportTLSClientHello(raw []byte) (map[string][]byte, error) {
+	s := cryptobyte.String(raw)
+	var handshakeType uint8
+	var sessionID, cipherSuites, compressionMethods, extensions cryptobyte.String
+	if !s.ReadUint8(&handshakeType) || handshakeType != typeClientHello ||
+		!s.Skip(3+2+32) || // length, version and random
+		!s.ReadUint8LengthPrefixed(&sessionID) ||
+		!s.ReadUint16LengthPrefixed(&cipherSuites) ||
+		!s.ReadUint8LengthPrefixed(&compressionMethods) {
+		return nil, errors.New("tls: unable to parse ClientHello")
+	}
+
+	data := map[string][]byte{
+		"cipher_suites":       unGREASEUint16List(cipherSuites),
+		"compression_methods": append([]byte(nil), compressionMethods...),
+		"extensions":          {},
+	}
+	if s.Empty() {
+		return data, nil
+	}
+	if !s.ReadUint16LengthPrefixed(&extensions) {
+		return nil, errors.New("tls: unable to parse ClientHello extensions")
+	}
+
+	for !extensions.Empty() {
+		var extType uint16
+		var extData cryptobyte.String
+		if !extensions.ReadUint16(&extType) || !extensions.ReadUint16LengthPrefixed(&extData) {
+			return nil, errors.New("tls: unable to parse ClientHello extensions")
+		}
+		extType = unGREASEUint16(extType)
+		data["extensions"] = append(data["extensions"], byte(extType>>8), byte(extType))
+
+		switch extType {
+		case extensionSupportedPoints:
+			data["pt_fmts"] = append([]byte(nil), extData...)
+		case extensionSignatureAlgorithms:
+			data["sig_algs"] = append([]byte(nil), extData...)
+		case extensionSupportedCurves:
+			var curves cryptobyte.String
+			if !extData.ReadUint16LengthPrefixed(&curves) {
+				return nil, errors.New("tls: unable to parse supported_groups")
+			}
+			list := unGREASEUint16List(curves)
+			data["curves"] = append([]byte{byte(len(list) >> 8), byte(len(list))}, list...)
+		case extensionALPN:
+			data["alpn"] = unGREASEALPNList(extData)
+		case extensionSupportedVersions:
+			var versions cryptobyte.String
+			if !extData.ReadUint8LengthPrefixed(&versions) {
+				return nil, errors.New("tls: unable to parse supported_versions")
+			}
+			data["supported_versions"] = unGREASEUint16List(versions)
+		case extensionKeyShare:
+			var shares cryptobyte.String
+			if !extData.ReadUint16LengthPrefixed(&shares) {
+				return nil, errors.New("tls: unable to parse key_share")
+			}
+			keyShares := []byte{}
+			for !shares.Empty() {
+				var group uint16
+				var keyData cryptobyte.String
+				if !shares.ReadUint16(&group) || !shares.ReadUint16LengthPrefixed(&keyData) {
+					return nil, errors.New("tls: unable to parse key_share")
+				}
+				group = unGREASEUint16(group)
+				keyShares = append(keyShares, byte(group>>8), byte(group), byte(len(keyData)>>8), byte(len(keyData)))
+			}
+			data["key_share"] = keyShares
+		case extensionPSKModes:
+			var modes cryptobyte.String
+			if !extData.ReadUint8LengthPrefixed(&modes) {
+				return nil, errors.New("tls: unable to parse psk_key_exchange_modes")
+			}
+			data["psk_key_exchange_modes"] = append([]byte(nil), modes...)
+		case utlsExtensionCompressCertificate:
+			var algs cryptobyte.String
+			if !extData.ReadUint8LengthPrefixed(&algs) {
+				return nil, errors.New("tls: unable to parse compress_certificate")
+			}
+			data["cert_compression_algs"] = append([]byte(nil), algs...)
+		case fakeRecordSizeLimit:
+			data["record_size_limit"] = append([]byte(nil), extData...)
+		}
+	}
+	return data, nil
+}
+
+// unGREASEALPNList normalizes the GREASE protocol names of the ALPN extension
+// data b.
+func unGREASEALPNList(b []byte) []byte {
+	out := bytes.Clone(b)
+	s := cryptobyte.String(b)
+	var list cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&list) {
+		return out
+	}
+	for offset := 2; !list.Empty(); {
+		var proto cryptobyte.String
+		if !list.ReadUint8LengthPrefixed(&proto) {
+			break
+		}
+		if isGREASEALPN(string(proto)) {
+			copy(out[offset+1:], GREASE_ALPN_PLACEHOLDER)
+		}
+		offset += 1 + len(proto)
+	}
+	return out
+}
+
+func unGREASEUint16List(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	s := cryptobyte.String(b)
+	var v uint16
+	for s.ReadUint16(&v) {
+		v = unGREASEUint16(v)
+		out = append(out, byte(v>>8), byte(v))
+	}
+	return out
+}
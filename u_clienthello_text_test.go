@@ -0,0 +1,65 @@
+package tls
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestClientHelloTextRoundTrip(t *testing.T) {
+	for _, id := range []ClientHelloID{HelloChrome_120, HelloFirefox_120, HelloSafari_16_0} {
+		spec, err := UTLSIdToSpec(id)
+		if err != nil {
+			t.Fatalf("%s: %v", id.Str(), err)
+		}
+		text, err := spec.ExportTLSClientHelloText()
+		if err != nil {
+			t.Fatalf("%s: export failed: %v", id.Str(), err)
+		}
+
+		var imported ClientHelloSpec
+		if err := imported.ImportTLSClientHelloFromText(text); err != nil {
+			t.Fatalf("%s: import failed: %v\n%s", id.Str(), err, text)
+		}
+		reexported, err := imported.ExportTLSClientHelloText()
+		if err != nil {
+			t.Fatalf("%s: re-export failed: %v", id.Str(), err)
+		}
+		if !bytes.Equal(text, reexported) {
+			t.Errorf("%s: round trip mismatch:\n%s\n!=\n%s", id.Str(), text, reexported)
+		}
+	}
+}
+
+func TestImportTLSClientHelloFromText(t *testing.T) {
+	text := []byte(`# captured from client.tlsfingerprint.io
+Cipher Suites: 0a0a, 1301, 1302, c02b
+compression_methods: 00
+extensions: 0a0a 0000 000a 000d 002b 0033
+
+curves: 0006 0a0a 001d 0017
+sig_algs: 0006 0403 0804 0401
+supported_versions: 0a:0a:03:04:03:03
+key_share: 0a0a 0001 001d 0020
+`)
+	var spec ClientHelloSpec
+	if err := spec.ImportTLSClientHelloFromText(text); err != nil {
+		t.Fatal(err)
+	}
+	if len(spec.CipherSuites) != 4 || spec.CipherSuites[3] != TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256 {
+		t.Errorf("unexpected cipher suites %x", spec.CipherSuites)
+	}
+	if len(spec.Extensions) != 6 {
+		t.Fatalf("got %d extensions, want 6", len(spec.Extensions))
+	}
+	ks, ok := spec.Extensions[5].(*KeyShareExtension)
+	if !ok || len(ks.KeyShares) != 2 || ks.KeyShares[1].Group != X25519 {
+		t.Errorf("unexpected key_share extension %+v", spec.Extensions[5])
+	}
+
+	if err := spec.ImportTLSClientHelloFromText([]byte("cipher_suites 1301")); err == nil {
+		t.Error("expected error for line without separator")
+	}
+	if err := spec.ImportTLSClientHelloFromText([]byte("cipher_suites: 13zz")); err == nil {
+		t.Error("expected error for invalid hex")
+	}
+}
@@ -5,6 +5,7 @@
 package tls
 
 import (
+	"bytes"
 	"crypto/hmac"
 	"crypto/sha512"
 	"encoding/json"
@@ -12,8 +13,10 @@ import (
 	"fmt"
 	"hash"
 	"log"
+	"slices"
 
 	"github.com/refraction-networking/utls/internal/helper"
+	"github.com/refraction-networking/utls/tlsbytes"
 	"golang.org/x/crypto/cryptobyte"
 )
 
@@ -132,6 +135,14 @@ const (
 	CertCompressionZstd   CertCompressionAlgo = 0x0003
 )
 
+// Compression methods of the ClientHello. Only CompressionNone is supported,
+// others may be listed in ClientHelloSpec.CompressionMethods for mimicry.
+const (
+	CompressionNone    uint8 = compressionNone
+	CompressionDeflate uint8 = 0x01
+	CompressionLZS     uint8 = 0x40
+)
+
 const (
 	PskModePlain uint8 = pskModePlain
 	PskModeDHE   uint8 = pskModeDHE
@@ -174,8 +185,17 @@ const (
 	helloAndroid          = "Android"
 	helloEdge             = "Edge"
 	helloSafari           = "Safari"
+	helloElectron         = "Electron"
 	hello360              = "360Browser"
 	helloQQ               = "QQBrowser"
+	helloCurl             = "curl"
+	helloWget             = "Wget"
+	helloJava             = "Java"
+	helloPythonRequests   = "Python-requests"
+	helloDotNet           = ".NET"
+	helloOkHttp           = "OkHttp"
+	helloConscrypt        = "Conscrypt"
+	helloCaptured         = "Captured"
 
 	// versions
 	helloAutoVers = "0"
@@ -183,7 +203,7 @@ const (
 
 type ClientHelloSpec struct {
 	CipherSuites       []uint16       // nil => default
-	CompressionMethods []uint8        // nil => no compression
+	CompressionMethods []uint8        // nil => no compression; must include CompressionNone
 	Extensions         []TLSExtension // nil => no extensions
 
 	TLSVersMin uint16 // [1.0-1.3] default: parse from .Extensions, if SupportedVersions ext is not present => 1.0
@@ -193,9 +213,72 @@ type ClientHelloSpec struct {
 	// sessionID may or may not depend on ticket; nil => random
 	GetSessionID func(ticket []byte) [32]byte
 
+	// SessionIDPolicy controls the legacy_session_id of ClientHellos that
+	// don't resume a session by ID. A session ID is always sent when
+	// offering a TLS 1.2 session ticket, as servers echo it to accept the
+	// ticket (RFC 5077, Section 3.4).
+	SessionIDPolicy SessionIDPolicy
+
+	// ECHOuterExtensions, if not nil, lists the extensions of the inner
+	// ClientHello that are compressed when using Encrypted Client Hello, i.e.
+	// referenced from the outer ClientHello through ech_outer_extensions
+	// instead of being repeated. Other extensions are repeated in the inner
+	// ClientHello. An empty, non-nil list disables compression.
+	//
+	// If nil, all extensions that can be compressed are, except
+	// supported_versions. The order of the outer ClientHello is always kept.
+	ECHOuterExtensions []uint16
+
+	// Malformations are the deviations from the TLS specifications the
+	// ClientHello may contain, to emulate buggy clients. By default, none are
+	// allowed and building a malformed ClientHello fails.
+	Malformations Malformations
+
+	// ExtensionCodepoints, if not nil, renumbers extensions when the
+	// ClientHello is marshaled: an extension whose type is a key is sent
+	// with the type it maps to. It lets an extension follow a draft that
+	// changed its codepoint, e.g. ECH or ALPS, without a new TLSExtension.
+	// Only the ClientHello is affected: extensions of the server's
+	// response are still handled by their original codepoints.
+	ExtensionCodepoints map[uint16]uint16
+
+	// KeyExchangePreference, if set, moves the TLS 1.2 cipher suites of the
+	// given key exchange family ahead of the others, keeping the order
+	// within each family and the positions of GREASE, TLS 1.3 and unknown
+	// cipher suites. It steers servers that honor the client's preference;
+	// those using their own choose regardless. See
+	// ConnectionState.KeyExchange for the family used.
+	KeyExchangePreference KeyExchange
+
+	// FalseStart makes the client send application data right after its
+	// Finished in full TLS 1.2 handshakes with a forward secret AEAD cipher
+	// suite and a negotiated ALPN protocol, without waiting for the
+	// server's Finished (RFC 7918), which changes the observable sequence
+	// of records. The server's flight is then read and verified by the
+	// first Read, which also stores the new session ticket. Until then,
+	// ConnectionState has no TranscriptHash, and ExportKeyingMaterial,
+	// ExportNamespacedKey and ConnectionID fail, as their keys aren't
+	// authenticated yet. It's off by default; see Config.FalseStart for
+	// preset ClientHelloIDs.
+	FalseStart bool
+
 	// TLSFingerprintLink string // ?? link to tlsfingerprint.io for informational purposes
 }
 
+// SessionIDPolicy is the legacy_session_id sent by a ClientHelloSpec on a full
+// handshake.
+type SessionIDPolicy uint8
+
+const (
+	// SessionIDRandom sends 32 random bytes, like browsers do, also for
+	// TLS 1.3 middlebox compatibility (RFC 8446, Appendix D.4).
+	SessionIDRandom SessionIDPolicy = iota
+
+	// SessionIDEmpty sends an empty session ID, like many TLS 1.2 only
+	// clients do.
+	SessionIDEmpty
+)
+
 // ReadCipherSuites is a helper function to construct a list of cipher suites from
 // a []byte into []uint16.
 //
@@ -238,30 +321,34 @@ func (chs *ClientHelloSpec) ReadTLSExtensions(b []byte, allowBluntMimicry bool,
 		ext := ExtensionFromID(extension)
 		extWriter, ok := ext.(TLSExtensionWriter)
 		if ext != nil && ok { // known extension and implements TLSExtensionWriter properly
-			switch extension {
-			case extensionPreSharedKey:
+			if extension == extensionPreSharedKey {
 				// PSK extension, need to see if we do real or fake PSK
 				if realPSK {
 					extWriter = &UtlsPreSharedKeyExtension{}
 				} else {
 					extWriter = &FakePreSharedKeyExtension{}
 				}
-			case extensionSupportedVersions:
-				chs.TLSVersMin = 0
-				chs.TLSVersMax = 0
 			}
 
-			if _, err := extWriter.Write(extData); err != nil {
+			_, err := extWriter.Write(extData)
+			if err == nil {
+				if extension == extensionSupportedVersions {
+					chs.TLSVersMin = 0
+					chs.TLSVersMax = 0
+				}
+				chs.Extensions = append(chs.Extensions, extWriter)
+				continue
+			}
+			if !allowBluntMimicry {
 				return err
 			}
-
-			chs.Extensions = append(chs.Extensions, extWriter)
+			// With blunt mimicry, extensions that can't be parsed
+			// are kept as is, like unknown ones
+		}
+		if allowBluntMimicry {
+			chs.Extensions = append(chs.Extensions, &GenericExtension{extension, bytes.Clone(extData)})
 		} else {
-			if allowBluntMimicry {
-				chs.Extensions = append(chs.Extensions, &GenericExtension{extension, extData})
-			} else {
-				return fmt.Errorf("unsupported extension %d", extension)
-			}
+			return fmt.Errorf("unsupported extension %d", extension)
 		}
 	}
 	return nil
@@ -399,7 +486,8 @@ func (chs *ClientHelloSpec) ImportTLSClientHello(data map[string][]byte) error {
 				fixedData := make([]byte, 0)
 				for i := 0; i < len(data["key_share"]); i += 4 {
 					fixedData = append(fixedData, data["key_share"][i:i+4]...)
-					for j := 0; j < int(data["key_share"][i+3]); j++ {
+					keyShareLen := int(data["key_share"][i+2])<<8 | int(data["key_share"][i+3])
+					for j := 0; j < keyShareLen; j++ {
 						fixedData = append(fixedData, 0)
 					}
 				}
@@ -450,6 +538,12 @@ func (chs *ClientHelloSpec) ImportTLSClientHello(data map[string][]byte) error {
 				extWriter.(*ApplicationSettingsExtension).SupportedProtocols = []string{"h2"}
 			case utlsExtensionApplicationSettingsNew:
 				extWriter.(*ApplicationSettingsExtensionNew).SupportedProtocols = []string{"h2"}
+			case utlsExtensionPadding:
+				// padding length is not recorded, use BoringSSL style like the JSON importer
+				_, err = extWriter.Write(nil)
+				if err != nil {
+					return err
+				}
 			case extensionPreSharedKey:
 				log.Printf("[Warning] PSK extension added without data")
 			default:
@@ -523,10 +617,13 @@ func (chs *ClientHelloSpec) FromRaw(raw []byte, ctrlFlags ...bool) error {
 	chs.TLSVersMin = recordVersion
 	chs.TLSVersMax = handshakeVersion
 
-	var ignoredSessionID cryptobyte.String
-	if !s.ReadUint8LengthPrefixed(&ignoredSessionID) {
+	var sessionID cryptobyte.String
+	if !s.ReadUint8LengthPrefixed(&sessionID) {
 		return errors.New("unable to read session id")
 	}
+	if len(sessionID) == 0 {
+		chs.SessionIDPolicy = SessionIDEmpty
+	}
 
 	// CipherSuites
 	var cipherSuitesBytes cryptobyte.String
@@ -558,6 +655,12 @@ func (chs *ClientHelloSpec) FromRaw(raw []byte, ctrlFlags ...bool) error {
 		return errors.New("unable to read extensions data")
 	}
 
+	extTypes, ok := readExtensionTypes(extensions)
+	if !ok {
+		return errors.New("unable to read extensions data")
+	}
+	chs.Malformations = malformationsOf(extTypes)
+
 	if err := chs.ReadTLSExtensions(extensions, bluntMimicry, realPSK); err != nil {
 		return err
 	}
@@ -602,7 +705,7 @@ var (
 	HelloRandomizedNoALPN = ClientHelloID{helloRandomizedNoALPN, helloAutoVers, nil, nil}
 
 	// The rest will will parrot given browser.
-	HelloFirefox_Auto = HelloFirefox_120
+	HelloFirefox_Auto = HelloFirefox_133
 	HelloFirefox_55   = ClientHelloID{helloFirefox, "55", nil, nil}
 	HelloFirefox_56   = ClientHelloID{helloFirefox, "56", nil, nil}
 	HelloFirefox_63   = ClientHelloID{helloFirefox, "63", nil, nil}
@@ -611,6 +714,7 @@ var (
 	HelloFirefox_102  = ClientHelloID{helloFirefox, "102", nil, nil}
 	HelloFirefox_105  = ClientHelloID{helloFirefox, "105", nil, nil}
 	HelloFirefox_120  = ClientHelloID{helloFirefox, "120", nil, nil}
+	HelloFirefox_133  = ClientHelloID{helloFirefox, "133", nil, nil}
 
 	HelloChrome_Auto        = HelloChrome_133
 	HelloChrome_58          = ClientHelloID{helloChrome, "58", nil, nil}
@@ -644,21 +748,32 @@ var (
 	HelloChrome_131 = ClientHelloID{helloChrome, "131", nil, nil}
 	// Chrome w/ New ALPS codepoint
 	HelloChrome_133 = ClientHelloID{helloChrome, "133", nil, nil}
+	// chrome-headless-shell 131, as used by automation frameworks, w/o ALPS
+	// and GREASE ECH
+	HelloChrome_Headless = ClientHelloID{helloChrome, "131_Headless", nil, nil}
 
-	HelloIOS_Auto = HelloIOS_14
+	HelloIOS_Auto = HelloIOS_18
 	HelloIOS_11_1 = ClientHelloID{helloIOS, "111", nil, nil} // legacy "111" means 11.1
 	HelloIOS_12_1 = ClientHelloID{helloIOS, "12.1", nil, nil}
 	HelloIOS_13   = ClientHelloID{helloIOS, "13", nil, nil}
 	HelloIOS_14   = ClientHelloID{helloIOS, "14", nil, nil}
+	HelloIOS_18   = ClientHelloID{helloIOS, "18", nil, nil}
 
 	HelloAndroid_11_OkHttp = ClientHelloID{helloAndroid, "11", nil, nil}
 
-	HelloEdge_Auto = HelloEdge_85 // HelloEdge_106 seems to be incompatible with this library
+	HelloEdge_Auto = HelloEdge_130
 	HelloEdge_85   = ClientHelloID{helloEdge, "85", nil, nil}
 	HelloEdge_106  = ClientHelloID{helloEdge, "106", nil, nil}
+	HelloEdge_130  = ClientHelloID{helloEdge, "130", nil, nil}
 
-	HelloSafari_Auto = HelloSafari_16_0
+	HelloSafari_Auto = HelloSafari_18
 	HelloSafari_16_0 = ClientHelloID{helloSafari, "16.0", nil, nil}
+	HelloSafari_18   = ClientHelloID{helloSafari, "18", nil, nil}
+
+	// Electron embeds Chromium's network stack, but without Chrome's field
+	// trials, so it doesn't send ALPS or GREASE ECH.
+	HelloElectron_Auto = HelloElectron_33
+	HelloElectron_33   = ClientHelloID{helloElectron, "33", nil, nil} // Chromium 130
 
 	Hello360_Auto = Hello360_7_5 // Hello360_11_0 seems to be incompatible with this library
 	Hello360_7_5  = ClientHelloID{hello360, "7.5", nil, nil}
@@ -666,6 +781,35 @@ var (
 
 	HelloQQ_Auto = HelloQQ_11_1
 	HelloQQ_11_1 = ClientHelloID{helloQQ, "11.1", nil, nil}
+
+	// Non-browser clients that servers commonly allowlist. These follow the
+	// defaults of the underlying TLS library rather than a browser.
+	HelloCurl_Auto = HelloCurl_8
+	HelloCurl_8    = ClientHelloID{helloCurl, "8", nil, nil} // OpenSSL 3
+
+	// HelloCurl_OpenSSL3 is curl with the defaults of OpenSSL 3.x: its
+	// cipher list and signature algorithm order, and no GREASE.
+	HelloCurl_OpenSSL3 = HelloCurl_8
+
+	HelloWget_Auto = HelloWget_1_21
+	HelloWget_1_21 = ClientHelloID{helloWget, "1.21", nil, nil} // GnuTLS 3.7
+
+	HelloJava_Auto = HelloJava_17
+	HelloJava_11   = ClientHelloID{helloJava, "11", nil, nil} // java.net.http.HttpClient
+	HelloJava_17   = ClientHelloID{helloJava, "17", nil, nil} // java.net.http.HttpClient
+
+	HelloPythonRequests_Auto = HelloPythonRequests_2_31
+	HelloPythonRequests_2_31 = ClientHelloID{helloPythonRequests, "2.31", nil, nil} // urllib3 2, OpenSSL 3
+
+	HelloDotNet_Auto = HelloDotNet_6
+	HelloDotNet_6    = ClientHelloID{helloDotNet, "6", nil, nil} // HttpClient on Windows 10 SChannel
+
+	HelloOkHttp_Auto = HelloOkHttp_4
+	HelloOkHttp_4    = ClientHelloID{helloOkHttp, "4", nil, nil} // Android, over Conscrypt
+
+	// HelloConscrypt is a bare Conscrypt SSLSocket on Android with its default
+	// settings, as used by apps that don't go through OkHttp.
+	HelloConscrypt = ClientHelloID{helloConscrypt, "2", nil, nil}
 )
 
 type Weights struct {
@@ -712,20 +856,41 @@ var DefaultWeights = Weights{
 
 // based on spec's GreaseStyle, GREASE_PLACEHOLDER may be replaced by another GREASE value
 // https://tools.ietf.org/html/draft-ietf-tls-grease-01
-const GREASE_PLACEHOLDER = 0x0a0a
+const GREASE_PLACEHOLDER = tlsbytes.GREASEPlaceholder
 
 func isGREASEUint16(v uint16) bool {
-	// First byte is same as second byte
-	// and lowest nibble is 0xa
-	return ((v >> 8) == v&0xff) && v&0xf == 0xa
+	return tlsbytes.IsGREASE(v)
 }
 
 func unGREASEUint16(v uint16) uint16 {
-	if isGREASEUint16(v) {
-		return GREASE_PLACEHOLDER
-	} else {
-		return v
+	return tlsbytes.UnGREASE(v)
+}
+
+// GREASE_ALPN_PLACEHOLDER is a GREASE protocol name for ALPNExtension, as sent
+// by Chrome. Like GREASE_PLACEHOLDER, it's replaced by a random GREASE value
+// when the spec is applied. See RFC 8701, Section 3.1.
+const GREASE_ALPN_PLACEHOLDER = "\x0a\x0a"
+
+// isGREASEALPN reports whether proto is a GREASE protocol name, which is never
+// negotiated.
+func isGREASEALPN(proto string) bool {
+	return len(proto) == 2 && isGREASEUint16(uint16(proto[0])<<8|uint16(proto[1]))
+}
+
+// withoutGREASEALPN returns protos without GREASE protocol names, which don't
+// count as requesting ALPN.
+func withoutGREASEALPN(protos []string) []string {
+	if !slices.ContainsFunc(protos, isGREASEALPN) {
+		return protos
+	}
+	return slices.DeleteFunc(slices.Clone(protos), isGREASEALPN)
+}
+
+func unGREASEALPN(proto string) string {
+	if isGREASEALPN(proto) {
+		return GREASE_ALPN_PLACEHOLDER
 	}
+	return proto
 }
 
 // utlsMacSHA384 returns a SHA-384 based MAC. These are only supported in TLS 1.2
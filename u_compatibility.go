@@ -0,0 +1,191 @@
+package tls
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ServerCapabilities are the capabilities of a server observed during a
+// handshake, as returned by Conn.ServerCapabilities. They may be stored per
+// destination and passed to CheckPresetCompatibility before the next
+// connection.
+type ServerCapabilities struct {
+	// Version, CipherSuite and CurveID are the negotiated parameters. CurveID
+	// is zero for TLS 1.2 resumptions and RSA key exchanges.
+	Version     uint16
+	CipherSuite uint16
+	CurveID     CurveID
+
+	// HelloRetryRequest reports whether the server asked for a key share it
+	// prefers, which it does for CurveID on every connection that doesn't
+	// offer one.
+	HelloRetryRequest bool
+
+	// Resumed reports whether the handshake resumed a session.
+	Resumed bool
+
+	// SessionTickets reports whether the server issued a session ticket,
+	// and EarlyData whether a TLS 1.3 ticket allowed 0-RTT data.
+	SessionTickets bool
+	EarlyData      bool
+
+	// ECHAccepted reports whether the server accepted Encrypted Client
+	// Hello.
+	ECHAccepted bool
+}
+
+// ServerCapabilities returns the capabilities of the server observed so far.
+// TLS 1.3 servers send session tickets after the handshake, so
+// SessionTickets and EarlyData are only reliable once data has been read from
+// the connection.
+func (c *Conn) ServerCapabilities() (ServerCapabilities, error) {
+	c.handshakeMutex.Lock()
+	defer c.handshakeMutex.Unlock()
+	if !c.isClient {
+		return ServerCapabilities{}, errors.New("tls: ServerCapabilities called on a server connection")
+	}
+	if !c.isHandshakeComplete.Load() {
+		return ServerCapabilities{}, errors.New("tls: handshake has not yet been performed")
+	}
+	return ServerCapabilities{
+		Version:           c.vers,
+		CipherSuite:       c.cipherSuite,
+		CurveID:           c.curveID,
+		HelloRetryRequest: c.didHRR,
+		Resumed:           c.didResume,
+		SessionTickets:    c.utls.serverIssuedTicket.Load(),
+		EarlyData:         c.utls.serverAllowsEarlyData.Load(),
+		ECHAccepted:       c.echAccepted,
+	}, nil
+}
+
+// PresetCompatibility is what a ClientHelloSpec will achieve with a server, as
+// predicted by CheckPresetCompatibility.
+type PresetCompatibility struct {
+	// VersionSupported reports whether the spec offers the version the
+	// server negotiated. If not, the other predictions don't apply.
+	VersionSupported bool
+
+	// Resumption reports whether sessions issued by the server can be
+	// resumed.
+	Resumption bool
+
+	// EarlyData reports whether the server will accept the early_data
+	// extension of resumed sessions. uTLS doesn't send 0-RTT data over TCP,
+	// so such handshakes fail.
+	EarlyData bool
+
+	// PostQuantum reports whether a post-quantum key exchange will be used.
+	PostQuantum bool
+
+	// HelloRetryRequest reports whether the server will ask for another key
+	// share, costing a round trip.
+	HelloRetryRequest bool
+
+	// ECH reports whether Encrypted Client Hello will be accepted, provided
+	// Config.EncryptedClientHelloConfigList is set.
+	ECH bool
+
+	// Suggestions are the adjustments of the spec that would improve the
+	// predictions. Applying them changes the fingerprint of the ClientHello,
+	// which must then be weighed against the benefit.
+	Suggestions []string
+}
+
+// CheckPresetCompatibility predicts which features will be used when
+// connecting with spec to a server with the given capabilities, e.g. observed
+// with a previous connection, and suggests adjustments of the spec. Specs of
+// presets are obtained with UTLSIdToSpec.
+func CheckPresetCompatibility(spec *ClientHelloSpec, caps *ServerCapabilities) *PresetCompatibility {
+	var (
+		versions          []uint16
+		groups, keyShares []CurveID
+		sessionTicket     bool
+		psk, pskModes     bool
+		earlyData         bool
+		ech               bool
+	)
+	for _, ext := range spec.Extensions {
+		switch e := ext.(type) {
+		case *SupportedVersionsExtension:
+			for _, v := range e.Versions {
+				if !isGREASEUint16(v) {
+					versions = append(versions, v)
+				}
+			}
+		case *SupportedCurvesExtension:
+			groups = e.Curves
+		case *KeyShareExtension:
+			for _, ks := range e.KeyShares {
+				keyShares = append(keyShares, ks.Group)
+			}
+		case *SessionTicketExtension:
+			sessionTicket = true
+		case *UtlsPreSharedKeyExtension:
+			psk = true
+		case *PSKKeyExchangeModesExtension:
+			pskModes = true
+		case *GenericExtension:
+			earlyData = earlyData || e.Id == extensionEarlyData
+		case EncryptedClientHelloExtension:
+			// Replaced by the real extension when
+			// Config.EncryptedClientHelloConfigList is set.
+			ech = true
+		}
+	}
+	if len(versions) == 0 {
+		for v := spec.TLSVersMin; v != 0 && v <= spec.TLSVersMax; v++ {
+			versions = append(versions, v)
+		}
+	}
+
+	p := &PresetCompatibility{VersionSupported: slices.Contains(versions, caps.Version)}
+	if !p.VersionSupported {
+		p.Suggestions = append(p.Suggestions, fmt.Sprintf("offer %s, the version the server negotiated", VersionName(caps.Version)))
+		return p
+	}
+
+	if caps.Version == VersionTLS13 {
+		p.Resumption = psk && pskModes && caps.SessionTickets
+		switch {
+		case caps.SessionTickets && !psk:
+			p.Suggestions = append(p.Suggestions, "add a pre_shared_key extension to resume sessions")
+		case caps.SessionTickets && !pskModes:
+			p.Suggestions = append(p.Suggestions, "add a psk_key_exchange_modes extension to resume sessions")
+		}
+		p.EarlyData = p.Resumption && earlyData && caps.EarlyData
+		if p.EarlyData {
+			p.Suggestions = append(p.Suggestions, "remove the early_data extension, which the server accepts but uTLS can't follow with 0-RTT data")
+		}
+
+		switch {
+		case caps.CurveID == 0:
+		case slices.Contains(keyShares, caps.CurveID):
+			p.PostQuantum = isPostQuantumGroup(caps.CurveID)
+		case slices.Contains(groups, caps.CurveID):
+			p.PostQuantum = isPostQuantumGroup(caps.CurveID)
+			p.HelloRetryRequest = true
+			p.Suggestions = append(p.Suggestions, fmt.Sprintf("add a key share for %v, the group the server prefers, to avoid a HelloRetryRequest", caps.CurveID))
+		case isPostQuantumGroup(caps.CurveID):
+			p.Suggestions = append(p.Suggestions, fmt.Sprintf("offer %v and a key share for it to use a post-quantum key exchange", caps.CurveID))
+		}
+
+		p.ECH = ech && caps.ECHAccepted
+		if caps.ECHAccepted && !ech {
+			p.Suggestions = append(p.Suggestions, "add an ECH extension, e.g. GREASEEncryptedClientHelloExtension, to use the server's Encrypted Client Hello configuration")
+		}
+	} else {
+		p.Resumption = sessionTicket && caps.SessionTickets
+		if caps.SessionTickets && !sessionTicket {
+			p.Suggestions = append(p.Suggestions, "add a session_ticket extension to resume sessions")
+		}
+	}
+	return p
+}
+
+// isPostQuantumGroup reports whether group is a hybrid post-quantum key
+// exchange.
+func isPostQuantumGroup(group CurveID) bool {
+	return isPQKeyExchange(group) || group == X25519Kyber768Draft00
+}
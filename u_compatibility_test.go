@@ -0,0 +1,80 @@
+package tls
+
+import (
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestServerCapabilities(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c := NewUnstartedUTLSClient(s, &Config{ClientSessionCache: NewLRUClientSessionCache(1)}, HelloChrome_Auto)
+	if _, err := c.ServerCapabilities(); err == nil {
+		t.Error("ServerCapabilities succeeded before the handshake")
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	// Read the echo, and the session ticket sent before it.
+	if _, err := c.Write([]byte{0}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(c, make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	caps, err := c.ServerCapabilities()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ServerCapabilities{
+		Version:        VersionTLS13,
+		CipherSuite:    c.ConnectionState().CipherSuite,
+		CurveID:        X25519MLKEM768,
+		SessionTickets: true,
+	}
+	if caps != want {
+		t.Errorf("got capabilities %+v, want %+v", caps, want)
+	}
+	if _, err := c.ServerConn.ServerCapabilities(); err == nil {
+		t.Error("ServerCapabilities succeeded on a server connection")
+	}
+}
+
+func TestCheckPresetCompatibility(t *testing.T) {
+	spec := func(id ClientHelloID) *ClientHelloSpec {
+		spec, err := UTLSIdToSpec(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &spec
+	}
+	pq := &ServerCapabilities{Version: VersionTLS13, CurveID: X25519MLKEM768, SessionTickets: true}
+
+	for _, test := range []struct {
+		name        string
+		spec        *ClientHelloSpec
+		caps        *ServerCapabilities
+		want        PresetCompatibility
+		suggestions int
+	}{
+		{"post-quantum", spec(HelloChrome_Auto), pq, PresetCompatibility{VersionSupported: true, PostQuantum: true}, 1},
+		{"resumption", spec(HelloChrome_100_PSK), pq, PresetCompatibility{VersionSupported: true, Resumption: true}, 1},
+		{"retry", spec(HelloChrome_Auto), &ServerCapabilities{Version: VersionTLS13, CurveID: CurveP256}, PresetCompatibility{VersionSupported: true, HelloRetryRequest: true}, 1},
+		{"tls12", spec(HelloChrome_Auto), &ServerCapabilities{Version: VersionTLS12, SessionTickets: true}, PresetCompatibility{VersionSupported: true, Resumption: true}, 0},
+		{"version", &ClientHelloSpec{TLSVersMin: VersionTLS13, TLSVersMax: VersionTLS13}, &ServerCapabilities{Version: VersionTLS12}, PresetCompatibility{}, 1},
+	} {
+		got := CheckPresetCompatibility(test.spec, test.caps)
+		if len(got.Suggestions) != test.suggestions {
+			t.Errorf("%s: got suggestions %q, want %d", test.name, got.Suggestions, test.suggestions)
+		}
+		got.Suggestions = nil
+		if !reflect.DeepEqual(*got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, *got, test.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package tls
+
+import (
+	"bytes"
+	"slices"
+)
+
+// deepCopyUTLSFields gives c its own copy of the settings that Clone would
+// otherwise share with the original through slices, maps and pointers, so
+// that adjusting a clone, e.g. per connection, doesn't change the original.
+//
+// Fields holding shared state, like AIAFetcher, VerifiedChainCache,
+// HandshakeLimiter and ResumptionMonitor, stay shared on purpose, like
+// ClientSessionCache. Of the standard fields, only the ECH configuration list
+// is copied.
+func (c *Config) deepCopyUTLSFields() {
+	c.InsecureSkipVerifyHosts = slices.Clone(c.InsecureSkipVerifyHosts)
+	c.EncryptedClientHelloConfigList = bytes.Clone(c.EncryptedClientHelloConfigList)
+	if c.ApplicationSettings != nil {
+		settings := make(map[string][]byte, len(c.ApplicationSettings))
+		for proto, value := range c.ApplicationSettings {
+			settings[proto] = bytes.Clone(value)
+		}
+		c.ApplicationSettings = settings
+	}
+	if c.HandshakeSizeLimits != nil {
+		limits := *c.HandshakeSizeLimits
+		c.HandshakeSizeLimits = &limits
+	}
+	if c.HandshakeTarpit != nil {
+		tarpit := *c.HandshakeTarpit
+		c.HandshakeTarpit = &tarpit
+	}
+	if c.WritePacing != nil {
+		pacing := *c.WritePacing
+		c.WritePacing = &pacing
+	}
+	if c.GREASESeed != nil {
+		seed := *c.GREASESeed
+		c.GREASESeed = &seed
+	}
+}
@@ -0,0 +1,93 @@
+package tls
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCloneDeepCopiesUTLSFields(t *testing.T) {
+	c1 := &Config{
+		InsecureSkipVerifyHosts:        []string{"a.example"},
+		ApplicationSettings:            map[string][]byte{"h2": {1}},
+		HandshakeSizeLimits:            &HandshakeSizeLimits{MaxMessageSize: 1024},
+		EncryptedClientHelloConfigList: []byte{1, 2, 3},
+		AIAFetcher:                     NewAIAFetcher(),
+		ClientSessionCache:             NewLRUClientSessionCache(1),
+		PreciseSessionCache:            true,
+	}
+	c2 := c1.Clone()
+
+	c2.InsecureSkipVerifyHosts[0] = "b.example"
+	c2.ApplicationSettings["h2"][0] = 2
+	c2.ApplicationSettings["http/1.1"] = nil
+	c2.HandshakeSizeLimits.MaxMessageSize = 2048
+	c2.EncryptedClientHelloConfigList[0] = 9
+
+	if c1.InsecureSkipVerifyHosts[0] != "a.example" {
+		t.Error("InsecureSkipVerifyHosts is shared with the clone")
+	}
+	if len(c1.ApplicationSettings) != 1 || c1.ApplicationSettings["h2"][0] != 1 {
+		t.Error("ApplicationSettings is shared with the clone")
+	}
+	if c1.HandshakeSizeLimits.MaxMessageSize != 1024 {
+		t.Error("HandshakeSizeLimits is shared with the clone")
+	}
+	if c1.EncryptedClientHelloConfigList[0] != 1 {
+		t.Error("EncryptedClientHelloConfigList is shared with the clone")
+	}
+
+	// Shared state must stay shared.
+	if c2.AIAFetcher != c1.AIAFetcher || c2.ClientSessionCache != c1.ClientSessionCache {
+		t.Error("caches are not shared with the clone")
+	}
+	if !c2.PreciseSessionCache {
+		t.Error("PreciseSessionCache was not cloned")
+	}
+
+	// Unset fields stay unset.
+	c3 := (&Config{}).Clone()
+	if c3.InsecureSkipVerifyHosts != nil || c3.ApplicationSettings != nil || c3.HandshakeSizeLimits != nil || c3.EncryptedClientHelloConfigList != nil {
+		t.Errorf("zero Config clone has non-nil fields: %+v", c3)
+	}
+}
+
+// TestCloneDeepCopiesPointerFields fails when a pointer field is added to
+// Config without either a deep copy in deepCopyUTLSFields or an entry in
+// sharedPointerFields.
+func TestCloneDeepCopiesPointerFields(t *testing.T) {
+	// Pointers to state that Clone shares on purpose.
+	sharedPointerFields := map[string]bool{
+		"RootCAs":            true,
+		"ClientCAs":          true,
+		"AIAFetcher":         true,
+		"VerifiedChainCache": true,
+		"HandshakeLimiter":   true,
+		"ResumptionMonitor":  true,
+	}
+
+	c1 := &Config{}
+	v1 := reflect.ValueOf(c1).Elem()
+	typ := v1.Type()
+	var fields []string
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.IsExported() || f.Type.Kind() != reflect.Pointer || sharedPointerFields[f.Name] {
+			continue
+		}
+		v1.Field(i).Set(reflect.New(f.Type.Elem()))
+		fields = append(fields, f.Name)
+	}
+	if len(fields) == 0 {
+		t.Fatal("no pointer fields found")
+	}
+
+	v2 := reflect.ValueOf(c1.Clone()).Elem()
+	for _, name := range fields {
+		p1, p2 := v1.FieldByName(name), v2.FieldByName(name)
+		if p2.IsNil() {
+			t.Errorf("%s was not cloned", name)
+		} else if p1.Pointer() == p2.Pointer() {
+			t.Errorf("%s is shared with the clone: copy it in deepCopyUTLSFields, or list it in sharedPointerFields if that's intended", name)
+		}
+	}
+}
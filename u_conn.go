@@ -13,9 +13,13 @@ import (
 	"errors"
 	"fmt"
 	"hash"
+	"io"
 	"net"
 	"slices"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/crypto/cryptobyte"
 )
@@ -39,6 +43,9 @@ type UConn struct {
 	HandshakeState PubClientHandshakeState
 
 	greaseSeed [ssl_grease_last_index]uint16
+	// greaseALPN seeds the GREASE protocol name of ALPN. It's drawn from the
+	// same source as greaseSeed, after it.
+	greaseALPN uint16
 
 	omitSNIExtension bool
 
@@ -57,6 +64,24 @@ type UConn struct {
 
 	// echCtx is the echContex returned by makeClientHello()
 	echCtx *echClientContext
+
+	// echOuterExtensions is copied from ClientHelloSpec.ECHOuterExtensions.
+	echOuterExtensions []uint16
+
+	// malformations is copied from ClientHelloSpec.Malformations.
+	malformations Malformations
+
+	// firstFlight is the first flight returned by FirstFlight.
+	firstFlight []byte
+
+	// extensionCodepoints is copied from ClientHelloSpec.ExtensionCodepoints.
+	extensionCodepoints map[uint16]uint16
+
+	// falseStart is copied from ClientHelloSpec.FalseStart.
+	falseStart bool
+
+	// rawClientHello is set by ApplyClientHelloBytes.
+	rawClientHello bool
 }
 
 // UClient returns a new uTLS client, with behavior depending on clientHelloID.
@@ -106,6 +131,12 @@ func (uconn *UConn) BuildHandshakeStateWithoutSession() error {
 }
 
 func (uconn *UConn) buildHandshakeState(loadSession bool) error {
+	if uconn.rawClientHello {
+		if uconn.handshakes > 0 {
+			return errors.New("tls: renegotiation is not supported with ApplyClientHelloBytes")
+		}
+		return nil
+	}
 	if uconn.ClientHelloID == HelloGolang {
 		if uconn.clientHelloBuildStatus == BuildByGoTLS {
 			return nil
@@ -170,6 +201,7 @@ func (uconn *UConn) uLoadSession() error {
 	case shouldReturn:
 	case shouldSetTicket:
 		uconn.sessionController.setSessionTicketToUConn()
+		return uconn.ensureSessionIDForTicket()
 	case shouldSetPsk:
 		uconn.sessionController.setPskToUConn()
 	case shouldLoad:
@@ -183,17 +215,16 @@ func (uconn *UConn) uLoadSession() error {
 			// We use the session ticket extension for tls 1.2 session resumption
 			// [uTLS] Support both SessionID and Session Ticket resumption
 			var ticketData []byte
-			// [uTLS] Check if session has extra fields for TLS 1.2 resumption
-			utlsData := GetSessionExtraFields(session)
-			if utlsData != nil && utlsData.ResumeType == ResumeSessionID {
+			if session.resumeType == ResumeSessionID {
 				// SessionID resumption: use sessionId field
-				ticketData = utlsData.SessionID
+				ticketData = session.sessionId
 			} else {
 				// Session Ticket resumption: use ticket field
 				ticketData = session.ticket
 			}
 			uconn.sessionController.initSessionTicketExt(session, ticketData)
 			uconn.sessionController.setSessionTicketToUConn()
+			return uconn.ensureSessionIDForTicket()
 		} else {
 			uconn.sessionController.initPskExt(session, earlySecret, binderKey, hello.pskIdentities)
 		}
@@ -202,6 +233,22 @@ func (uconn *UConn) uLoadSession() error {
 	return nil
 }
 
+// ensureSessionIDForTicket generates a session ID if a TLS 1.2 session ticket is
+// offered without one, as with SessionIDEmpty: the server accepts the ticket by
+// echoing the session ID (RFC 5077, Section 3.4).
+func (uconn *UConn) ensureSessionIDForTicket() error {
+	hello := uconn.HandshakeState.Hello
+	if len(hello.SessionTicket) == 0 || len(hello.SessionId) > 0 || uconn.quic != nil {
+		return nil
+	}
+	sessionID := make([]byte, 32)
+	if _, err := io.ReadFull(uconn.config.rand(), sessionID); err != nil {
+		return err
+	}
+	hello.SessionId = sessionID
+	return nil
+}
+
 func (uconn *UConn) uApplyPatch() {
 	helloLen := len(uconn.HandshakeState.Hello.Raw)
 	if uconn.sessionController.shouldUpdateBinders() {
@@ -332,6 +379,12 @@ func (c *UConn) handshakeContext(ctx context.Context) (ret error) {
 	if c.isHandshakeComplete.Load() {
 		return nil
 	}
+	if c.conn == nil {
+		return errors.New("tls: UConn has no connection, see UConn.Attach")
+	}
+	if err := c.waitHandshakeJitter(ctx); err != nil {
+		return err
+	}
 
 	handshakeCtx, cancel := context.WithCancel(ctx)
 	// Note: defer this before starting the "interrupter" goroutine
@@ -347,6 +400,12 @@ func (c *UConn) handshakeContext(ctx context.Context) (ret error) {
 	if c.quic != nil {
 		c.quic.cancelc = handshakeCtx.Done()
 		c.quic.cancel = cancel
+	} else if ctx.Done() != nil && c.config.NoBackgroundTasks {
+		done, err := c.contextDeadline(ctx)
+		if err != nil {
+			return err
+		}
+		defer func() { ret = done(ret) }()
 	} else if ctx.Done() != nil {
 		done := make(chan struct{})
 		interruptRes := make(chan error, 1)
@@ -382,6 +441,17 @@ func (c *UConn) handshakeContext(ctx context.Context) (ret error) {
 	c.in.Lock()
 	defer c.in.Unlock()
 
+	// Set for the whole handshake, including building the handshake state.
+	c.utls.handshakeCtx = handshakeCtx
+	defer func() { c.utls.handshakeCtx = nil }()
+
+	release, err := c.acquireHandshakeSlot(handshakeCtx)
+	if err != nil {
+		c.handshakeErr = err
+		return err
+	}
+	defer release()
+
 	// [uTLS section begins]
 	if c.isClient {
 		err := c.BuildHandshakeState()
@@ -390,6 +460,8 @@ func (c *UConn) handshakeContext(ctx context.Context) (ret error) {
 		}
 	}
 	// [uTLS section ends]
+	c.startForensics()
+	c.configureRecordLayer()
 	c.handshakeErr = c.handshakeFn(handshakeCtx)
 	if c.handshakeErr == nil {
 		c.handshakes++
@@ -397,7 +469,9 @@ func (c *UConn) handshakeContext(ctx context.Context) (ret error) {
 		// If an error occurred during the hadshake try to flush the
 		// alert that might be left in the buffer.
 		c.flush()
+		c.reportGREASESelected(c.handshakeErr)
 	}
+	c.handshakeErr = c.finishForensics(c.handshakeErr)
 
 	if c.handshakeErr == nil && !c.isHandshakeComplete.Load() {
 		c.handshakeErr = errors.New("tls: internal error: handshake should have had a result")
@@ -433,7 +507,7 @@ func (c *UConn) handshakeContext(ctx context.Context) (ret error) {
 	return c.handshakeErr
 }
 
-// Copy-pasted from tls.Conn in its entirety. But c.Handshake() is now utls' one, not tls.
+// Like tls.Conn.Write, but c.Handshake() is now utls' one, not tls.
 // Write writes data to the connection.
 func (c *UConn) Write(b []byte) (int, error) {
 	// interlock with Close below
@@ -452,43 +526,29 @@ func (c *UConn) Write(b []byte) (int, error) {
 		return 0, err
 	}
 
-	c.out.Lock()
-	defer c.out.Unlock()
-
-	if err := c.out.err; err != nil {
-		return 0, err
-	}
-
-	if !c.isHandshakeComplete.Load() {
-		return 0, alertInternalError
+	if c.utls.pacer.active() {
+		return c.pacedWrite(b)
 	}
+	return c.writeUnpaced(b)
+}
 
-	if c.closeNotifySent {
-		return 0, errShutdown
+// CloseWrite shuts down the writing side of the connection like
+// net.TCPConn.CloseWrite: it sends close_notify, so that the peer reads EOF,
+// and then calls CloseWrite on the underlying connection if it has one, so
+// that proxies relaying the transport see the half-close too. Reads are still
+// possible until the peer closes its side. It must only be called once the
+// handshake has completed, and may be called several times.
+func (c *UConn) CloseWrite() error {
+	if c.conn == nil || !c.isHandshakeComplete.Load() {
+		return errEarlyCloseWrite
+	}
+	if err := c.closeNotify(); err != nil {
+		return err
 	}
-
-	// SSL 3.0 and TLS 1.0 are susceptible to a chosen-plaintext
-	// attack when using block mode ciphers due to predictable IVs.
-	// This can be prevented by splitting each Application Data
-	// record into two records, effectively randomizing the IV.
-	//
-	// https://www.openssl.org/~bodo/tls-cbc.txt
-	// https://bugzilla.mozilla.org/show_bug.cgi?id=665814
-	// https://www.imperialviolet.org/2012/01/15/beastfollowup.html
-
-	var m int
-	if len(b) > 1 && c.vers <= VersionTLS10 {
-		if _, ok := c.out.cipher.(cipher.BlockMode); ok {
-			n, err := c.writeRecordLocked(recordTypeApplicationData, b[:1])
-			if err != nil {
-				return n, c.out.setErrorLocked(err)
-			}
-			m, b = 1, b[1:]
-		}
+	if cw, ok := c.NetConn().(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
 	}
-
-	n, err := c.writeRecordLocked(recordTypeApplicationData, b)
-	return n + m, c.out.setErrorLocked(err)
+	return nil
 }
 
 func (uconn *UConn) ApplyConfig() error {
@@ -509,11 +569,23 @@ func (uconn *UConn) extensionsList() []uint16 {
 		ext.Read(buffer)
 		var extension uint16
 		buffer.ReadUint16(&extension)
-		outerExts = append(outerExts, extension)
+		outerExts = append(outerExts, uconn.extensionCodepoint(extension))
 	}
 	return outerExts
 }
 
+// echCompressedExtensions returns the extensions to compress in the inner
+// ClientHello, in the order of the outer ClientHello.
+func (uconn *UConn) echCompressedExtensions() []uint16 {
+	outerExts := uconn.extensionsList()
+	if uconn.echOuterExtensions == nil {
+		return outerExts
+	}
+	return slices.DeleteFunc(outerExts, func(ext uint16) bool {
+		return !slices.Contains(uconn.echOuterExtensions, ext)
+	})
+}
+
 func (uconn *UConn) computeAndUpdateOuterECHExtension(inner *clientHelloMsg, ech *echClientContext, useKey bool) error {
 	// This function is mostly copied from
 	// https://github.com/refraction-networking/utls/blob/e430876b1d82fdf582efc57f3992d448e7ab3d8a/ech.go#L408
@@ -522,7 +594,7 @@ func (uconn *UConn) computeAndUpdateOuterECHExtension(inner *clientHelloMsg, ech
 		encapKey = ech.encapsulatedKey
 	}
 
-	encodedInner, err := encodeInnerClientHelloReorderOuterExts(inner, int(ech.config.MaxNameLength), uconn.extensionsList())
+	encodedInner, err := encodeInnerClientHelloReorderOuterExts(inner, int(ech.config.MaxNameLength), uconn.echCompressedExtensions())
 	if err != nil {
 		return err
 	}
@@ -622,6 +694,8 @@ func (uconn *UConn) MarshalClientHelloNoECH() error {
 			// If padding - process it later
 			if paddingExt == nil {
 				paddingExt = pe
+			} else if uconn.malformations&MalformDuplicateExtensions != 0 {
+				extensionsLen += ext.Len()
 			} else {
 				return errors.New("multiple padding extensions")
 			}
@@ -665,7 +739,11 @@ func (uconn *UConn) MarshalClientHelloNoECH() error {
 	if len(uconn.Extensions) > 0 {
 		binary.Write(bufferedWriter, binary.BigEndian, uint16(extensionsLen))
 		for _, ext := range uconn.Extensions {
-			if _, err := bufferedWriter.ReadFrom(ext); err != nil {
+			r, err := uconn.extensionReader(ext)
+			if err != nil {
+				return err
+			}
+			if _, err := bufferedWriter.ReadFrom(r); err != nil {
 				return err
 			}
 		}
@@ -681,6 +759,14 @@ func (uconn *UConn) MarshalClientHelloNoECH() error {
 			". Got: " + strconv.Itoa(helloBuffer.Len()))
 	}
 
+	extTypes, ok := clientHelloExtensionTypes(helloBuffer.Bytes())
+	if !ok {
+		return errors.New("tls: failed to parse the marshaled ClientHello extensions")
+	}
+	if err := uconn.malformations.checkExtensionTypes(extTypes); err != nil {
+		return err
+	}
+
 	hello.Raw = helloBuffer.Bytes()
 	return nil
 }
@@ -702,6 +788,11 @@ func (uconn *UConn) GetOutKeystream(length int) ([]byte, error) {
 //  2. specExtensions may have SupportedVersionsExtension
 //  3. [default] min = TLS 1.0, max = TLS 1.2
 //
+// These are the versions advertised in the ClientHello. The MinVersion and
+// MaxVersion of the Config passed to UClient, if set, still limit which of them
+// the server may select, so that a spec can advertise versions the client
+// won't accept. The Config itself is not modified.
+//
 // Error is only returned if things are in clearly undesirable state
 // to help user fix them.
 func (uconn *UConn) SetTLSVers(minTLSVers, maxTLSVers uint16, specExtensions []TLSExtension) error {
@@ -758,6 +849,15 @@ func (uconn *UConn) SetTLSVers(minTLSVers, maxTLSVers uint16, specExtensions []T
 
 	uconn.HandshakeState.Hello.SupportedVersions = makeSupportedVersions(minTLSVers, maxTLSVers)
 	if uconn.config.EncryptedClientHelloConfigList == nil {
+		// The advertised versions are set on a copy of the Config, whose own
+		// MinVersion and MaxVersion still bound the version the server may
+		// select, see checkAcceptedVersion.
+		if !uconn.utls.ownsConfig {
+			uconn.utls.acceptMinVersion = uconn.config.MinVersion
+			uconn.utls.acceptMaxVersion = uconn.config.MaxVersion
+			uconn.config = uconn.config.Clone()
+			uconn.utls.ownsConfig = true
+		}
 		uconn.config.MinVersion = minTLSVers
 		uconn.config.MaxVersion = maxTLSVers
 	}
@@ -852,6 +952,35 @@ func (c *Conn) utlsHandshakeMessageType(msgType byte) (handshakeMessage, error)
 // Extending (*Conn).connectionStateLocked()
 func (c *Conn) utlsConnectionStateLocked(state *ConnectionState) {
 	state.PeerApplicationSettings = c.utls.peerApplicationSettings
+	state.TranscriptHash = c.utls.transcriptHash
+	state.ctx = c.utls.handshakeCtx
+	if c.isClient {
+		if len(c.peerCertificates) > 0 {
+			state.serverCertificate = c.peerCertificates[0].Raw
+		}
+	} else {
+		state.serverCertificate = c.utls.localCertificate
+	}
+}
+
+// Context returns the context passed to HandshakeContext if the
+// ConnectionState was obtained during the handshake, e.g. by VerifyConnection,
+// so that lookups made by callbacks respect its cancellation. Otherwise it
+// returns context.Background.
+func (cs *ConnectionState) Context() context.Context {
+	if cs.ctx == nil {
+		return context.Background()
+	}
+	return cs.ctx
+}
+
+// handshakeContextOrBackground returns the context of the handshake in
+// progress, or context.Background outside of it.
+func (c *Conn) handshakeContextOrBackground() context.Context {
+	if c.utls.handshakeCtx == nil {
+		return context.Background()
+	}
+	return c.utls.handshakeCtx
 }
 
 type utlsConnExtraFields struct {
@@ -861,6 +990,105 @@ type utlsConnExtraFields struct {
 	applicationSettingsCodepoint uint16
 
 	sessionController *sessionController
+
+	// transcriptHash is recorded when the handshake completes and exposed as
+	// ConnectionState.TranscriptHash.
+	transcriptHash []byte
+
+	// localCertificate is the leaf certificate sent by a server, used for
+	// the tls-server-end-point channel binding.
+	localCertificate []byte
+
+	// ownsConfig is set once the Config passed to UClient has been replaced
+	// by a copy whose MinVersion and MaxVersion are the versions advertised
+	// by the ClientHelloSpec. acceptMinVersion and acceptMaxVersion are the
+	// original values, which limit the version accepted from the server.
+	ownsConfig       bool
+	acceptMinVersion uint16
+	acceptMaxVersion uint16
+
+	stats connStats
+
+	// forensics captures the handshake in progress if
+	// Config.HandshakeForensics is set.
+	forensics *forensicsRecorder
+
+	// sentFirstFlight is the part of the first flight delivered before
+	// UConn.Attach that the handshake hasn't written yet.
+	sentFirstFlight []byte
+
+	// certSignatureAlgorithms are the signature algorithms offered for
+	// certificates, checked if Config.VerifyCertSignatureAlgorithms is set.
+	certSignatureAlgorithms []SignatureScheme
+
+	// handshakeCtx is the context passed to HandshakeContext, set while the
+	// handshake runs. It only reaches AIAFetcher and, through
+	// ConnectionState.Context, the callbacks given a ConnectionState, like
+	// VerifyConnection. Extensions and the other callbacks don't see it.
+	handshakeCtx context.Context
+
+	// handshakeSlot is the Config.HandshakeLimiter slot of the handshake in
+	// progress, if any.
+	handshakeSlot *handshakeSlot
+
+	// serverIssuedTicket and serverAllowsEarlyData are reported by
+	// ServerCapabilities. They are atomic because TLS 1.3 tickets are
+	// handled by Read.
+	serverIssuedTicket    atomic.Bool
+	serverAllowsEarlyData atomic.Bool
+
+	// ticketAgeAdjustment is the offset applied to ticket ages by
+	// Config.TicketAge, once ticketAgeAdjusted is set.
+	ticketAgeAdjustment time.Duration
+	ticketAgeAdjusted   bool
+
+	// handshakeJitter is the delay before the first handshake, see
+	// Profile.HandshakeJitter.
+	handshakeJitter atomic.Int64
+
+	// deadlines are the deadlines set with SetDeadline and its variants.
+	deadlines connDeadlines
+
+	// pskAgeTransform is the offset Config.TransformPskIdentity added to
+	// the obfuscated ticket age, kept for the ClientHello sent after a
+	// HelloRetryRequest.
+	pskAgeTransform uint32
+
+	// pacer paces writes if Config.WritePacing or Conn.SetWritePacing is
+	// set.
+	pacer writePacer
+
+	// pacedWriteMu serializes paced Writes, which don't hold the output
+	// lock while they wait.
+	pacedWriteMu sync.Mutex
+
+	// falseStart, if not nil, completes the handshake of a client that
+	// False Started. It's called by the first Read.
+	falseStart func() error
+
+	// connMu guards Conn.conn against SwapNetConn for the methods that use
+	// it without holding the in or out lock, like Close and SetDeadline.
+	connMu sync.RWMutex
+
+	// netConnClosed is set once closeNetConn closed the underlying
+	// connection.
+	netConnClosed atomic.Bool
+}
+
+// checkAcceptedVersion returns an error if vers, selected by the server among
+// the advertised versions, is outside of the MinVersion and MaxVersion set in
+// the Config passed to UClient.
+func (c *Conn) checkAcceptedVersion(vers uint16) error {
+	if !c.utls.ownsConfig {
+		return nil
+	}
+	if min := c.utls.acceptMinVersion; min != 0 && vers < min {
+		return fmt.Errorf("tls: server selected %s, which is advertised by the ClientHelloSpec but below Config.MinVersion %s", VersionName(vers), VersionName(min))
+	}
+	if max := c.utls.acceptMaxVersion; max != 0 && vers > max {
+		return fmt.Errorf("tls: server selected %s, which is advertised by the ClientHelloSpec but above Config.MaxVersion %s", VersionName(vers), VersionName(max))
+	}
+	return nil
 }
 
 // Read reads data from the connection.
@@ -882,6 +1110,10 @@ func (c *UConn) Read(b []byte) (int, error) {
 	c.in.Lock()
 	defer c.in.Unlock()
 
+	if err := c.completeFalseStart(); err != nil {
+		return 0, err
+	}
+
 	for c.input.Len() == 0 {
 		if err := c.readRecord(); err != nil {
 			return 0, err
@@ -959,6 +1191,7 @@ func (c *UConn) handleRenegotiation() error {
 	// [uTLS section ends]
 	if c.handshakeErr = c.clientHandshake(context.Background()); c.handshakeErr == nil {
 		c.handshakes++
+		c.utls.stats.renegotiations.Add(1)
 	}
 	return c.handshakeErr
 }
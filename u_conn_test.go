@@ -6,13 +6,17 @@ package tls
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"os/exec"
 	"runtime/debug"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -755,6 +759,18 @@ func TestUTLSECH(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	chromeUncompressed, err := utlsIdToSpec(HelloChrome_Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chromeUncompressed.ECHOuterExtensions = []uint16{}
+
+	chromeKeyShareCompressed, err := utlsIdToSpec(HelloChrome_Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chromeKeyShareCompressed.ECHOuterExtensions = []uint16{extensionKeyShare}
+
 	for _, test := range []struct {
 		name          string
 		spec          *ClientHelloSpec
@@ -765,6 +781,16 @@ func TestUTLSECH(t *testing.T) {
 			spec:          &chromeLatest,
 			expectSuccess: true,
 		},
+		{
+			name:          "latest chrome without outer extensions compression",
+			spec:          &chromeUncompressed,
+			expectSuccess: true,
+		},
+		{
+			name:          "latest chrome with only key_share compressed",
+			spec:          &chromeKeyShareCompressed,
+			expectSuccess: true,
+		},
 		{
 			name:          "latest firefox",
 			spec:          &firefoxLatest,
@@ -852,6 +878,58 @@ func TestUTLSECH(t *testing.T) {
 	}
 }
 
+func TestECHOuterExtensionsCompression(t *testing.T) {
+	inner := &clientHelloMsg{
+		vers:          VersionTLS12,
+		random:        make([]byte, 32),
+		cipherSuites:  []uint16{TLS_AES_128_GCM_SHA256},
+		alpnProtocols: []string{"h2"},
+		keyShares:     []keyShare{{group: X25519, data: make([]byte, 32)}},
+		pskModes:      []uint8{pskModeDHE},
+	}
+	for _, test := range []struct {
+		outerExts  []uint16
+		compressed []uint16
+	}{
+		{nil, []uint16{extensionALPN, extensionKeyShare, extensionPSKModes}},
+		{[]uint16{extensionPSKModes, extensionKeyShare}, []uint16{extensionPSKModes, extensionKeyShare}},
+		{[]uint16{}, nil},
+	} {
+		raw, err := inner.marshalMsgReorderOuterExts(true, test.outerExts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var m clientHelloMsg
+		if !m.unmarshal(raw) {
+			t.Fatalf("%v: failed to parse inner ClientHello", test.outerExts)
+		}
+		var repeated, compressed []uint16
+		for _, ext := range m.extensions {
+			if ext != extensionECHOuterExtensions {
+				repeated = append(repeated, ext)
+			}
+		}
+		for _, ext := range []uint16{extensionALPN, extensionKeyShare, extensionPSKModes} {
+			if !slices.Contains(repeated, ext) {
+				compressed = append(compressed, ext)
+			}
+		}
+		slices.Sort(compressed)
+		want := slices.Clone(test.compressed)
+		slices.Sort(want)
+		if !slices.Equal(compressed, want) {
+			t.Errorf("%v: compressed %v, want %v", test.outerExts, compressed, want)
+		}
+		if len(test.outerExts) > 0 {
+			// ech_outer_extensions must follow the given order.
+			i := bytes.Index(raw, []byte{0xfd, 0x00})
+			if i < 0 || !bytes.Equal(raw[i+5:i+5+2*len(test.compressed)], []byte{0x00, 0x2d, 0x00, 0x33}) {
+				t.Errorf("%v: unexpected ech_outer_extensions", test.outerExts)
+			}
+		}
+	}
+}
+
 var spec *ClientHelloSpec = nil
 
 func TestDowngradeCanaryUTLS(t *testing.T) {
@@ -907,3 +985,324 @@ func TestDowngradeCanaryUTLS(t *testing.T) {
 
 	}
 }
+
+func TestUTLSOnPeerCertificates(t *testing.T) {
+	for _, version := range []uint16{VersionTLS12, VersionTLS13} {
+		sentinelErr := errors.New("TestUTLSOnPeerCertificates")
+
+		clientConfig := testConfig.Clone()
+		clientConfig.MaxVersion = version
+		clientConfig.OnPeerCertificates = func(rawCerts [][]byte) error {
+			return sentinelErr
+		}
+		_, _, err := testHandshake(t, clientConfig, testConfig.Clone())
+		if err == nil || !strings.Contains(err.Error(), sentinelErr.Error()) {
+			t.Errorf("%x: expected handshake to fail with %v, got %v", version, sentinelErr, err)
+		}
+
+		called := false
+		clientConfig.OnPeerCertificates = func(rawCerts [][]byte) error {
+			if len(rawCerts) == 0 {
+				return errors.New("got no certificates")
+			}
+			called = true
+			return nil
+		}
+		if _, _, err := testHandshake(t, clientConfig, testConfig.Clone()); err != nil {
+			t.Errorf("%x: handshake failed: %v", version, err)
+		}
+		if !called {
+			t.Errorf("%x: OnPeerCertificates was not called", version)
+		}
+	}
+}
+
+func TestUTLSPointFormatsAndRenegotiationInfo(t *testing.T) {
+	newSpec := func(exts ...TLSExtension) *ClientHelloSpec {
+		return &ClientHelloSpec{
+			TLSVersMin:         VersionTLS12,
+			TLSVersMax:         VersionTLS12,
+			CipherSuites:       []uint16{TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+			CompressionMethods: []uint8{compressionNone},
+			Extensions: append([]TLSExtension{
+				&SNIExtension{},
+				&SupportedCurvesExtension{[]CurveID{X25519, CurveP256}},
+				&SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: []SignatureScheme{PSSWithSHA256, PKCS1WithSHA256}},
+			}, exts...),
+		}
+	}
+
+	for _, test := range []struct {
+		name         string
+		spec         *ClientHelloSpec
+		points       []uint8
+		renegotiated bool
+	}{
+		{"absent", newSpec(), nil, false},
+		{"uncompressed", newSpec(&SupportedPointsExtension{SupportedPoints: []uint8{pointFormatUncompressed}}, &RenegotiationInfoExtension{}), []uint8{pointFormatUncompressed}, true},
+		{"all formats", newSpec(&SupportedPointsExtension{SupportedPoints: []uint8{0, 1, 2}}), []uint8{0, 1, 2}, false},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloCustom)
+			if err := uconn.ApplyPreset(test.spec); err != nil {
+				t.Fatal(err)
+			}
+			if err := uconn.BuildHandshakeState(); err != nil {
+				t.Fatal(err)
+			}
+			var m clientHelloMsg
+			if !m.unmarshal(uconn.HandshakeState.Hello.Raw) {
+				t.Fatal("failed to parse ClientHello")
+			}
+			if !bytes.Equal(m.supportedPoints, test.points) || !bytes.Equal(uconn.HandshakeState.Hello.SupportedPoints, test.points) {
+				t.Errorf("sent point formats %v, state has %v, want %v", m.supportedPoints, uconn.HandshakeState.Hello.SupportedPoints, test.points)
+			}
+			if m.secureRenegotiationSupported != test.renegotiated || uconn.HandshakeState.Hello.SecureRenegotiationSupported != test.renegotiated {
+				t.Errorf("sent renegotiation_info %v, state has %v, want %v", m.secureRenegotiationSupported, uconn.HandshakeState.Hello.SecureRenegotiationSupported, test.renegotiated)
+			}
+
+			serverConfig := testConfig.Clone()
+			serverConfig.MaxVersion = VersionTLS12
+			clientConfig := testConfig.Clone()
+			clientConfig.MaxVersion = VersionTLS12
+			_, cs, err := testUtlsHandshake(t, clientConfig, serverConfig, test.spec)
+			if err != nil {
+				t.Fatalf("handshake failed: %v", err)
+			}
+			if cs.CipherSuite != TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 {
+				t.Errorf("negotiated %s, want ECDHE", CipherSuiteName(cs.CipherSuite))
+			}
+		})
+	}
+}
+
+func TestUTLSAcceptedVersionIndependentOfSpec(t *testing.T) {
+	s, err := NewClientHelloEchoServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate)
+
+	dial := func(config *Config) (*UConn, error) {
+		conn, err := net.Dial("tcp", s.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		uconn := UClient(conn, config, HelloChrome_120)
+		return uconn, uconn.Handshake()
+	}
+
+	// HelloChrome_120 advertises TLS 1.0 to 1.3 regardless of the Config.
+	clientConfig := &Config{ServerName: "localhost", RootCAs: roots, MaxVersion: VersionTLS12}
+	uconn, err := dial(clientConfig)
+	uconn.Close()
+	if err == nil || !strings.Contains(err.Error(), "above Config.MaxVersion") {
+		t.Fatalf("handshake with a server selecting TLS 1.3: got error %v, want Config.MaxVersion error", err)
+	}
+	if !slices.Contains(uconn.HandshakeState.Hello.SupportedVersions, VersionTLS13) {
+		t.Errorf("supported_versions %v doesn't advertise TLS 1.3", uconn.HandshakeState.Hello.SupportedVersions)
+	}
+	if clientConfig.MaxVersion != VersionTLS12 || clientConfig.MinVersion != 0 {
+		t.Errorf("Config was modified to MinVersion %x, MaxVersion %x", clientConfig.MinVersion, clientConfig.MaxVersion)
+	}
+
+	// Accepting TLS 1.3 only doesn't change what is advertised either.
+	clientConfig = &Config{ServerName: "localhost", RootCAs: roots, MinVersion: VersionTLS13}
+	uconn, err = dial(clientConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer uconn.Close()
+	if v := uconn.ConnectionState().Version; v != VersionTLS13 {
+		t.Errorf("negotiated %s, want TLS 1.3", VersionName(v))
+	}
+	if !slices.Contains(uconn.HandshakeState.Hello.SupportedVersions, VersionTLS12) {
+		t.Errorf("supported_versions %v doesn't advertise TLS 1.2", uconn.HandshakeState.Hello.SupportedVersions)
+	}
+}
+
+func TestUTLSSessionIDPolicy(t *testing.T) {
+	newSpec := func(policy SessionIDPolicy) *ClientHelloSpec {
+		return &ClientHelloSpec{
+			TLSVersMin:         VersionTLS12,
+			TLSVersMax:         VersionTLS12,
+			CipherSuites:       []uint16{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			CompressionMethods: []uint8{compressionNone},
+			Extensions: []TLSExtension{
+				&SNIExtension{},
+				&SupportedCurvesExtension{[]CurveID{X25519, CurveP256}},
+				&SupportedPointsExtension{SupportedPoints: []uint8{pointFormatUncompressed}},
+				&SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: []SignatureScheme{ECDSAWithP256AndSHA256}},
+				&SessionTicketExtension{},
+				&ExtendedMasterSecretExtension{},
+			},
+			SessionIDPolicy: policy,
+		}
+	}
+
+	for _, test := range []struct {
+		policy SessionIDPolicy
+		full   int
+	}{
+		{SessionIDRandom, 32},
+		{SessionIDEmpty, 0},
+	} {
+		s, err := NewUTLSServer(&Config{MaxVersion: VersionTLS12})
+		if err != nil {
+			t.Fatal(err)
+		}
+		clientConfig := &Config{ClientSessionCache: NewLRUClientSessionCache(1)}
+		for i, want := range []int{test.full, 32} {
+			c := NewUnstartedUTLSClient(s, clientConfig, HelloCustom)
+			if err := c.ApplyPreset(newSpec(test.policy)); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.Start(); err != nil {
+				t.Fatal(err)
+			}
+			rec := &clientHelloRecorder{buf: c.ClientFlight()}
+			var m clientHelloMsg
+			if !m.unmarshal(rec.clientHello()) {
+				t.Fatal("failed to parse ClientHello")
+			}
+			resumed := c.ConnectionState().DidResume
+			c.Close()
+			// The resumption offers a ticket, which requires a session ID.
+			if len(m.sessionId) != want || resumed != (i == 1) {
+				t.Errorf("policy %d, connection %d: sent %d byte session ID, resumed %v; want %d bytes, resumed %v",
+					test.policy, i, len(m.sessionId), resumed, want, i == 1)
+			}
+		}
+		s.Close()
+	}
+}
+
+func TestFromRawSessionIDPolicy(t *testing.T) {
+	for _, policy := range []SessionIDPolicy{SessionIDRandom, SessionIDEmpty} {
+		uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloCustom)
+		if err := uconn.ApplyPreset(&ClientHelloSpec{
+			CipherSuites:       []uint16{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			CompressionMethods: []uint8{compressionNone},
+			Extensions:         []TLSExtension{&SNIExtension{}},
+			SessionIDPolicy:    policy,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := uconn.BuildHandshakeState(); err != nil {
+			t.Fatal(err)
+		}
+		record := append([]byte{byte(recordTypeHandshake), 3, 1, 0, 0}, uconn.HandshakeState.Hello.Raw...)
+		var spec ClientHelloSpec
+		if err := spec.FromRaw(record); err != nil {
+			t.Fatal(err)
+		}
+		if spec.SessionIDPolicy != policy {
+			t.Errorf("FromRaw read SessionIDPolicy %d, want %d", spec.SessionIDPolicy, policy)
+		}
+	}
+}
+
+func TestUTLSCloseWrite(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, version := range []uint16{VersionTLS12, VersionTLS13} {
+		serverErr := make(chan error, 1)
+		go func() {
+			tcpConn, err := ln.Accept()
+			if err != nil {
+				serverErr <- err
+				return
+			}
+			config := s.Config.Clone()
+			config.MaxVersion = version
+			conn := Server(tcpConn, config)
+			defer conn.Close()
+			if b, err := io.ReadAll(conn); err != nil || string(b) != "request" {
+				serverErr <- fmt.Errorf("server read %q, %v", b, err)
+				return
+			}
+			// The client half-closed the TCP connection too.
+			if n, err := tcpConn.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+				serverErr <- fmt.Errorf("TCP read after close_notify: %d, %v", n, err)
+				return
+			}
+			_, err = conn.Write([]byte("response"))
+			serverErr <- err
+		}()
+
+		tcpConn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots := x509.NewCertPool()
+		roots.AddCert(s.Certificate)
+		uconn := UClient(tcpConn, &Config{ServerName: "localhost", RootCAs: roots}, HelloChrome_Auto)
+		if err := uconn.CloseWrite(); err == nil {
+			t.Errorf("%x: CloseWrite before the handshake succeeded", version)
+		}
+		if _, err := uconn.Write([]byte("request")); err != nil {
+			t.Fatal(err)
+		}
+		if err := uconn.CloseWrite(); err != nil {
+			t.Fatalf("%x: CloseWrite: %v", version, err)
+		}
+		if err := uconn.CloseWrite(); err != nil {
+			t.Errorf("%x: second CloseWrite: %v", version, err)
+		}
+		if _, err := uconn.Write([]byte("more")); err == nil {
+			t.Errorf("%x: Write after CloseWrite succeeded", version)
+		}
+		if b, err := io.ReadAll(uconn); err != nil || string(b) != "response" {
+			t.Errorf("%x: client read %q, %v after CloseWrite", version, b, err)
+		}
+		if err := <-serverErr; err != nil {
+			t.Errorf("%x: %v", version, err)
+		}
+		uconn.Close()
+	}
+}
+
+func TestUTLSHandshakeContextCallbacks(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	var called bool
+	config := &Config{
+		VerifyConnection: func(cs ConnectionState) error {
+			called = true
+			if v := cs.Context().Value(key{}); v != "value" {
+				t.Errorf("VerifyConnection got context value %v", v)
+			}
+			return nil
+		},
+	}
+	c := NewUnstartedUTLSClient(s, config, HelloChrome_Auto)
+	go c.ServerConn.Handshake()
+	if err := c.HandshakeContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if !called {
+		t.Error("VerifyConnection was not called")
+	}
+	cs := c.ConnectionState()
+	if cs.Context().Value(key{}) != nil {
+		t.Error("ConnectionState after the handshake has the handshake context")
+	}
+}
@@ -0,0 +1,61 @@
+package tls
+
+import (
+	"io"
+	"net"
+	"time"
+)
+
+// DecryptErrorPolicy is how a connection reacts to a record that fails
+// authentication, which RFC 8446 and RFC 5246 require answering with a
+// bad_record_mac alert. Probe-resistant servers may prefer not to reveal that
+// they speak TLS to peers that don't hold the keys of the connection.
+//
+// Except with DecryptErrorAlert, nothing is sent on the connection once the
+// error is detected, not even a close_notify alert on Close.
+type DecryptErrorPolicy uint8
+
+const (
+	// DecryptErrorAlert sends a bad_record_mac alert, as crypto/tls does.
+	DecryptErrorAlert DecryptErrorPolicy = iota
+
+	// DecryptErrorClose closes the underlying connection without sending
+	// an alert.
+	DecryptErrorClose
+
+	// DecryptErrorDelayedClose reads and discards incoming data for
+	// Config.DecryptErrorCloseDelay, or until the peer closes the
+	// connection, before closing it without sending an alert.
+	DecryptErrorDelayedClose
+
+	// DecryptErrorReadForever reads and discards incoming data until the
+	// peer closes the connection, the read deadline expires, or the Conn is
+	// closed, and then closes it without sending an alert.
+	DecryptErrorReadForever
+)
+
+// decryptError reacts to err, returned by halfConn.decrypt, according to
+// Config.DecryptErrors, and returns the error of the read.
+func (c *Conn) decryptError(err alert) error {
+	policy := c.config.DecryptErrors
+	if err != alertBadRecordMAC || policy == DecryptErrorAlert {
+		return c.sendAlert(err)
+	}
+
+	opErr := &net.OpError{Op: "local error", Err: err}
+	c.out.Lock()
+	c.closeNotifySent = true
+	c.out.setErrorLocked(opErr)
+	c.out.Unlock()
+
+	conn := c.NetConn()
+	switch policy {
+	case DecryptErrorDelayedClose:
+		conn.SetReadDeadline(time.Now().Add(c.config.DecryptErrorCloseDelay))
+		io.Copy(io.Discard, conn)
+	case DecryptErrorReadForever:
+		io.Copy(io.Discard, conn)
+	}
+	c.closeNetConn()
+	return opErr
+}
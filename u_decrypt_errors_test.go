@@ -0,0 +1,99 @@
+package tls
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDecryptErrorPolicy(t *testing.T) {
+	for _, test := range []struct {
+		policy    DecryptErrorPolicy
+		wantAlert bool
+		minDelay  time.Duration
+	}{
+		{DecryptErrorAlert, true, 0},
+		{DecryptErrorClose, false, 0},
+		{DecryptErrorDelayedClose, false, 50 * time.Millisecond},
+		{DecryptErrorReadForever, false, 0},
+	} {
+		c, s := net.Pipe()
+		serverConfig := testConfig.Clone()
+		serverConfig.DecryptErrors = test.policy
+		serverConfig.DecryptErrorCloseDelay = test.minDelay
+		// Don't send tickets nobody reads from the pipe.
+		serverConfig.SessionTicketsDisabled = true
+		server := Server(&closeOnceConn{Conn: s}, serverConfig)
+		client := Client(c, testConfig.Clone())
+
+		go client.Handshake()
+		if err := server.Handshake(); err != nil {
+			t.Fatalf("policy %d: %v", test.policy, err)
+		}
+
+		serverErr := make(chan error, 1)
+		go func() {
+			_, err := server.Read(make([]byte, 1))
+			serverErr <- err
+		}()
+		// A record that can't be authenticated with the connection keys.
+		bogus := make([]byte, recordHeaderLen+32)
+		copy(bogus, []byte{byte(recordTypeApplicationData), 3, 3, 0, 32})
+		start := time.Now()
+		if _, err := c.Write(bogus); err != nil {
+			t.Fatalf("policy %d: %v", test.policy, err)
+		}
+		if test.policy == DecryptErrorReadForever {
+			// Data is discarded until the peer closes the connection.
+			c.Write([]byte("more"))
+			select {
+			case err := <-serverErr:
+				t.Fatalf("policy %d: Read returned %v before the peer closed", test.policy, err)
+			case <-time.After(20 * time.Millisecond):
+			}
+			c.Close()
+		}
+
+		// With DecryptErrorAlert, the connection is left open.
+		c.SetReadDeadline(time.Now().Add(time.Second))
+		received, _ := io.ReadAll(c)
+		// The TLS 1.3 alert is encrypted, so any record is taken as the alert.
+		if gotAlert := len(received) > 0; gotAlert != test.wantAlert {
+			t.Errorf("policy %d: received %x", test.policy, received)
+		}
+		err := <-serverErr
+		var opErr *net.OpError
+		if !errors.As(err, &opErr) || opErr.Err != alertBadRecordMAC {
+			t.Errorf("policy %d: Read error %v", test.policy, err)
+		}
+		if elapsed := time.Since(start); elapsed < test.minDelay {
+			t.Errorf("policy %d: closed after %v, want at least %v", test.policy, elapsed, test.minDelay)
+		}
+		if test.policy != DecryptErrorAlert {
+			if _, err := server.Write([]byte{0}); err == nil {
+				t.Errorf("policy %d: Write succeeded after the decrypt error", test.policy)
+			}
+		}
+		c.Close()
+		// The connection closed after the decrypt error is not closed again.
+		if err := server.Close(); err != nil && test.policy != DecryptErrorAlert {
+			t.Errorf("policy %d: Close = %v", test.policy, err)
+		}
+	}
+}
+
+// closeOnceConn fails a second Close like a net.TCPConn, unlike net.Pipe.
+type closeOnceConn struct {
+	net.Conn
+	closed atomic.Bool
+}
+
+func (c *closeOnceConn) Close() error {
+	if c.closed.Swap(true) {
+		return net.ErrClosed
+	}
+	return c.Conn.Close()
+}
@@ -0,0 +1,285 @@
+package tls
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ClientHelloReport is a structured parse of a received ClientHello, together
+// with its JA3 and JA4 fingerprints. GREASE values are reported as sent.
+type ClientHelloReport struct {
+	JA3     string `json:"ja3"`
+	JA3Hash string `json:"ja3_hash"`
+	JA4     string `json:"ja4"`
+
+	Version             uint16            `json:"version"`
+	CipherSuites        []uint16          `json:"cipher_suites"`
+	CompressionMethods  []int             `json:"compression_methods"`
+	Extensions          []uint16          `json:"extensions"`
+	ServerName          string            `json:"server_name,omitempty"`
+	SupportedGroups     []CurveID         `json:"supported_groups,omitempty"`
+	PointFormats        []int             `json:"point_formats,omitempty"`
+	SignatureAlgorithms []SignatureScheme `json:"signature_algorithms,omitempty"`
+	ALPN                []string          `json:"alpn,omitempty"`
+	SupportedVersions   []uint16          `json:"supported_versions,omitempty"`
+	KeyShareGroups      []CurveID         `json:"key_share_groups,omitempty"`
+	PSKModes            []int             `json:"psk_modes,omitempty"`
+	SessionID           []byte            `json:"session_id,omitempty"`
+
+	// Raw is the hex encoded ClientHello handshake message.
+	Raw string `json:"raw"`
+}
+
+// NewClientHelloReport parses raw, a ClientHello handshake message without
+// record header, into a ClientHelloReport.
+func NewClientHelloReport(raw []byte) (*ClientHelloReport, error) {
+	if len(raw) == 0 || raw[0] != typeClientHello {
+		return nil, errors.New("tls: not a ClientHello message")
+	}
+	m := new(clientHelloMsg)
+	if !m.unmarshal(raw) {
+		return nil, errors.New("tls: unable to parse ClientHello")
+	}
+
+	ja3 := ja3String(m)
+	r := &ClientHelloReport{
+		JA3:                 ja3,
+		JA3Hash:             ja3Hash(ja3),
+		JA4:                 ja4String(m, false),
+		Version:             m.vers,
+		CipherSuites:        m.cipherSuites,
+		CompressionMethods:  bytesToInts(m.compressionMethods),
+		Extensions:          m.extensions,
+		ServerName:          m.serverName,
+		SupportedGroups:     m.supportedCurves,
+		PointFormats:        bytesToInts(m.supportedPoints),
+		SignatureAlgorithms: m.supportedSignatureAlgorithms,
+		ALPN:                m.alpnProtocols,
+		SupportedVersions:   m.supportedVersions,
+		PSKModes:            bytesToInts(m.pskModes),
+		SessionID:           m.sessionId,
+		Raw:                 hex.EncodeToString(raw),
+	}
+	for _, ks := range m.keyShares {
+		r.KeyShareGroups = append(r.KeyShareGroups, ks.group)
+	}
+	return r, nil
+}
+
+func bytesToInts(b []byte) []int {
+	if b == nil {
+		return nil
+	}
+	ints := make([]int, len(b))
+	for i, v := range b {
+		ints[i] = int(v)
+	}
+	return ints
+}
+
+// ClientHelloEchoServer is a local HTTPS server for verifying fingerprints: it
+// answers every HTTP/1.1 request with the JSON encoded ClientHelloReport of the
+// connection's ClientHello. Point a uTLS client at URL to check what it sends.
+//
+// It is intended for tests and debugging, not for production use.
+type ClientHelloEchoServer struct {
+	// URL is the base URL of the server, of the form https://127.0.0.1:port.
+	URL string
+
+	// Certificate is the server certificate, to be trusted by clients.
+	Certificate *x509.Certificate
+
+	// OnReport, if set before the first connection, is called with the
+	// report of every received ClientHello.
+	OnReport func(*ClientHelloReport)
+
+	listener net.Listener
+	config   *Config
+	wg       sync.WaitGroup
+}
+
+// NewClientHelloEchoServer starts a ClientHelloEchoServer listening on a local
+// port. If config is nil or has no certificate, a self-signed certificate for
+// "localhost" and 127.0.0.1 is generated.
+func NewClientHelloEchoServer(config *Config) (*ClientHelloEchoServer, error) {
+	if config == nil {
+		config = &Config{}
+	} else {
+		config = config.Clone()
+	}
+	if len(config.Certificates) == 0 && config.GetCertificate == nil {
+		cert, err := selfSignedTestCertificate()
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []Certificate{cert}
+	}
+	if len(config.NextProtos) == 0 {
+		config.NextProtos = []string{"http/1.1"}
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &ClientHelloEchoServer{
+		URL:      "https://" + l.Addr().String(),
+		listener: l,
+		config:   config,
+	}
+	if len(config.Certificates) > 0 {
+		s.Certificate = config.Certificates[0].Leaf
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address the server is listening on.
+func (s *ClientHelloEchoServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops the server and waits for open connections to finish.
+func (s *ClientHelloEchoServer) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *ClientHelloEchoServer) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(c)
+		}()
+	}
+}
+
+func (s *ClientHelloEchoServer) handle(c net.Conn) {
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(30 * time.Second))
+
+	rc := &clientHelloRecorder{Conn: c}
+	conn := Server(rc, s.config)
+	if err := conn.Handshake(); err != nil {
+		return
+	}
+	report, err := NewClientHelloReport(rc.clientHello())
+	if err != nil {
+		return
+	}
+	if s.OnReport != nil {
+		s.OnReport(report)
+	}
+
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return
+	}
+	br := bufio.NewReader(conn)
+	for {
+		req, err := http.ReadRequest(br)
+		if err != nil {
+			return
+		}
+		req.Body.Close()
+		fmt.Fprintf(conn, "HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: %d\r\n\r\n", len(body))
+		if _, err := conn.Write(body); err != nil || req.Close {
+			return
+		}
+	}
+}
+
+// clientHelloRecorder records the handshake records read from the client until
+// it has seen a complete ClientHello message.
+type clientHelloRecorder struct {
+	net.Conn
+	buf  []byte
+	done bool
+}
+
+func (r *clientHelloRecorder) Read(b []byte) (int, error) {
+	n, err := r.Conn.Read(b)
+	if !r.done {
+		r.buf = append(r.buf, b[:n]...)
+		r.done = r.clientHello() != nil || len(r.buf) > maxHandshake+recordHeaderLen
+	}
+	return n, err
+}
+
+// clientHello reassembles the ClientHello message from the recorded records,
+// or returns nil if it's incomplete.
+func (r *clientHelloRecorder) clientHello() []byte {
+	var msg []byte
+	records := r.buf
+	for len(records) >= recordHeaderLen {
+		if recordType(records[0]) != recordTypeHandshake {
+			return nil
+		}
+		n := int(records[3])<<8 | int(records[4])
+		if len(records) < recordHeaderLen+n {
+			return nil
+		}
+		msg = append(msg, records[recordHeaderLen:recordHeaderLen+n]...)
+		records = records[recordHeaderLen+n:]
+		if len(msg) >= 4 {
+			msgLen := int(msg[1])<<16 | int(msg[2])<<8 | int(msg[3])
+			if len(msg) >= 4+msgLen {
+				return msg[:4+msgLen]
+			}
+		}
+	}
+	return nil
+}
+
+func selfSignedTestCertificate() (Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"uTLS test"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return Certificate{}, err
+	}
+	return Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
@@ -0,0 +1,135 @@
+package tls
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestJA3JA4(t *testing.T) {
+	spec := &ClientHelloSpec{
+		CipherSuites:       []uint16{GREASE_PLACEHOLDER, TLS_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		CompressionMethods: []uint8{compressionNone},
+		Extensions: []TLSExtension{
+			&UtlsGREASEExtension{},
+			&SNIExtension{},
+			&SupportedCurvesExtension{[]CurveID{GREASE_PLACEHOLDER, X25519, CurveP256}},
+			&SupportedPointsExtension{SupportedPoints: []byte{pointFormatUncompressed}},
+			&SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: []SignatureScheme{ECDSAWithP256AndSHA256}},
+			&ALPNExtension{AlpnProtocols: []string{"h2", "http/1.1"}},
+			&SupportedVersionsExtension{[]uint16{GREASE_PLACEHOLDER, VersionTLS13, VersionTLS12}},
+			&KeyShareExtension{[]KeyShare{{Group: X25519}}},
+		},
+	}
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloCustom)
+	if err := uconn.ApplyPreset(spec); err != nil {
+		t.Fatal(err)
+	}
+	if err := uconn.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	report, err := NewClientHelloReport(uconn.HandshakeState.Hello.Raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "771,4865-49195,0-10-11-13-16-43-51,29-23,0"; report.JA3 != want {
+		t.Errorf("JA3 = %q, want %q", report.JA3, want)
+	}
+	hash := func(s string) string {
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])[:12]
+	}
+	want := "t13d0207h2_" + hash("1301,c02b") + "_" + hash("000a,000b,000d,002b,0033_0403")
+	if report.JA4 != want {
+		t.Errorf("JA4 = %q, want %q", report.JA4, want)
+	}
+	if report.ServerName != "example.com" || len(report.KeyShareGroups) != 1 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestJA4ALPN(t *testing.T) {
+	for _, test := range []struct {
+		protos []string
+		want   string
+	}{
+		{nil, "00"},
+		{[]string{"h2"}, "h2"},
+		{[]string{"http/1.1", "h2"}, "h1"},
+		{[]string{"\xabx\xcd"}, "ad"},
+	} {
+		if got := ja4ALPN(test.protos); got != test.want {
+			t.Errorf("ja4ALPN(%q) = %q, want %q", test.protos, got, test.want)
+		}
+	}
+}
+
+func TestClientHelloEchoServer(t *testing.T) {
+	s, err := NewClientHelloEchoServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	reports := make(chan *ClientHelloReport, 1)
+	s.OnReport = func(r *ClientHelloReport) { reports <- r }
+
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate)
+	for _, id := range []ClientHelloID{HelloChrome_120, HelloFirefox_120} {
+		c, err := net.Dial("tcp", s.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		uconn := UClient(c, &Config{ServerName: "localhost", RootCAs: roots, NextProtos: []string{"http/1.1"}}, id)
+		if err := uconn.BuildHandshakeState(); err != nil {
+			t.Fatal(err)
+		}
+		// Force HTTP/1.1, the server doesn't speak HTTP/2.
+		for _, ext := range uconn.Extensions {
+			if alpn, ok := ext.(*ALPNExtension); ok {
+				alpn.AlpnProtocols = []string{"http/1.1"}
+			}
+		}
+		if err := uconn.BuildHandshakeState(); err != nil {
+			t.Fatal(err)
+		}
+		sent, err := NewClientHelloReport(uconn.HandshakeState.Hello.Raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := uconn.Handshake(); err != nil {
+			t.Fatalf("%s: %v", id.Str(), err)
+		}
+
+		fmt.Fprintf(uconn, "GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+		resp, err := http.ReadResponse(bufio.NewReader(uconn), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got ClientHelloReport
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		uconn.Close()
+
+		if got.JA3 != sent.JA3 || got.JA4 != sent.JA4 || got.Raw != sent.Raw {
+			t.Errorf("%s: server saw %s %s, client sent %s %s", id.Str(), got.JA3, got.JA4, sent.JA3, sent.JA4)
+		}
+		if !strings.HasPrefix(got.JA4, "t13d") {
+			t.Errorf("%s: unexpected JA4 %s", id.Str(), got.JA4)
+		}
+		if r := <-reports; r.Raw != sent.Raw {
+			t.Errorf("%s: OnReport got a different ClientHello", id.Str())
+		}
+	}
+}
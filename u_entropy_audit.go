@@ -0,0 +1,312 @@
+package tls
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// ClientHelloFieldEntropy describes how a field of the ClientHello changed
+// across the connections sampled by an entropy audit.
+type ClientHelloFieldEntropy struct {
+	// Name is "version", "random", "session_id", "cipher_suites",
+	// "compression_methods" or "extension N", where N is the extension
+	// type. GREASE extensions are named "extension GREASE".
+	Name string
+
+	// Extension is the type of the extension, if Name is an extension.
+	// GREASE extensions have type GREASE_PLACEHOLDER.
+	Extension uint16
+
+	// Length is the length of the field in the first sample, excluding the
+	// extension header.
+	Length int
+
+	// VariableBytes is the number of bytes of the field that differed
+	// between samples. It is only meaningful if LengthVaries is false.
+	VariableBytes int
+
+	// LengthVaries reports whether the length of the field differed between
+	// samples, or whether it was missing from some of them.
+	LengthVaries bool
+}
+
+// Static reports whether the field was identical in all samples.
+func (f *ClientHelloFieldEntropy) Static() bool {
+	return !f.LengthVaries && f.VariableBytes == 0
+}
+
+// ClientHelloEntropyReport is the result of an entropy audit of a
+// ClientHelloID or ClientHelloSpec. It tells which parts of its ClientHello
+// are the same on every connection, and are therefore part of the
+// fingerprint, and which are random per connection.
+type ClientHelloEntropyReport struct {
+	// Samples is the number of ClientHellos compared.
+	Samples int
+
+	// Fields are the fields of the first sample, in order.
+	Fields []ClientHelloFieldEntropy
+
+	// StaticBytes and VariableBytes are the number of bytes of the first
+	// sample that were identical in all samples, and that differed between
+	// samples, respectively. Bytes of fields whose length varies count as
+	// variable.
+	StaticBytes, VariableBytes int
+
+	// ExtensionOrderVaries reports whether the extensions were sent in a
+	// different order, e.g. because the spec shuffles them.
+	ExtensionOrderVaries bool
+
+	// Linkable lists the fields that are expected to be random on every
+	// connection, but were identical in all samples, so that an observer can
+	// link connections to each other: a static random or non-empty
+	// session_id, reused key_share public keys, the same session ticket or
+	// pre_shared_key identity (resumed from a shared ClientSessionCache), or
+	// a static encrypted_client_hello payload.
+	Linkable []string
+}
+
+// AuditClientHelloEntropy builds samples ClientHellos of id with config, as
+// BuildHandshakeState does before a handshake, and reports which of their
+// bytes are static and which are random per connection. samples must be at
+// least two; a larger number makes it less likely that a random byte is
+// reported as static by chance.
+//
+// Nothing is sent on the network. If config is nil, a Config with
+// InsecureSkipVerify is used. Sessions from config.ClientSessionCache are
+// offered like on a real connection, which the report flags as linkable.
+func AuditClientHelloEntropy(id ClientHelloID, config *Config, samples int) (*ClientHelloEntropyReport, error) {
+	return auditClientHelloEntropy(id, config, samples, nil)
+}
+
+// AuditClientHelloSpecEntropy is like AuditClientHelloEntropy, but audits the
+// spec returned by newSpec, which is called once per sample, as extensions
+// can't be shared between connections. UTLSIdToSpec and SpecWatcher.Spec are
+// suitable after binding their argument.
+func AuditClientHelloSpecEntropy(newSpec func() (ClientHelloSpec, error), config *Config, samples int) (*ClientHelloEntropyReport, error) {
+	return auditClientHelloEntropy(HelloCustom, config, samples, func(uconn *UConn) error {
+		spec, err := newSpec()
+		if err != nil {
+			return err
+		}
+		return uconn.ApplyPreset(&spec)
+	})
+}
+
+// auditClientHelloEntropy builds samples ClientHellos of id, calling apply, if
+// not nil, on each UConn first.
+func auditClientHelloEntropy(id ClientHelloID, config *Config, samples int, apply func(*UConn) error) (*ClientHelloEntropyReport, error) {
+	if samples < 2 {
+		return nil, errors.New("tls: entropy audit needs at least two samples")
+	}
+	if config == nil {
+		config = &Config{InsecureSkipVerify: true}
+	}
+
+	hellos := make([][]clientHelloField, 0, samples)
+	for i := 0; i < samples; i++ {
+		// The session cache key is derived from the remote address if
+		// ServerName is empty, so the connection must have one.
+		conn, peer := net.Pipe()
+		conn.Close()
+		peer.Close()
+		uconn := UClient(conn, config.Clone(), id)
+		if apply != nil {
+			if err := apply(uconn); err != nil {
+				return nil, err
+			}
+		}
+		if err := uconn.BuildHandshakeState(); err != nil {
+			return nil, err
+		}
+		fields, err := splitClientHello(uconn.HandshakeState.Hello.Raw)
+		if err != nil {
+			return nil, err
+		}
+		hellos = append(hellos, fields)
+	}
+	return compareClientHellos(hellos), nil
+}
+
+// clientHelloField is a field of a marshaled ClientHello.
+type clientHelloField struct {
+	name      string
+	extension uint16
+	data      []byte
+}
+
+// splitClientHello splits a marshaled ClientHello into its fields.
+func splitClientHello(raw []byte) ([]clientHelloField, error) {
+	s := cryptobyte.String(raw)
+	var version, random, sessionID, cipherSuites, compressionMethods, extensions cryptobyte.String
+	if !s.Skip(4) || // type and length
+		!s.ReadBytes((*[]byte)(&version), 2) ||
+		!s.ReadBytes((*[]byte)(&random), 32) ||
+		!s.ReadUint8LengthPrefixed(&sessionID) ||
+		!s.ReadUint16LengthPrefixed(&cipherSuites) ||
+		!s.ReadUint8LengthPrefixed(&compressionMethods) {
+		return nil, errors.New("tls: unable to parse ClientHello")
+	}
+	fields := []clientHelloField{
+		{name: "version", data: version},
+		{name: "random", data: random},
+		{name: "session_id", data: sessionID},
+		{name: "cipher_suites", data: cipherSuites},
+		{name: "compression_methods", data: compressionMethods},
+	}
+	if s.Empty() {
+		return fields, nil
+	}
+	if !s.ReadUint16LengthPrefixed(&extensions) {
+		return nil, errors.New("tls: unable to parse ClientHello extensions")
+	}
+	for !extensions.Empty() {
+		var extType uint16
+		var extData cryptobyte.String
+		if !extensions.ReadUint16(&extType) || !extensions.ReadUint16LengthPrefixed(&extData) {
+			return nil, errors.New("tls: unable to parse ClientHello extensions")
+		}
+		name := fmt.Sprintf("extension %d", extType)
+		if isGREASEUint16(extType) {
+			extType = GREASE_PLACEHOLDER
+			name = "extension GREASE"
+		}
+		fields = append(fields, clientHelloField{name: name, extension: extType, data: extData})
+	}
+	return fields, nil
+}
+
+// compareClientHellos compares the fields of hellos to the first one.
+func compareClientHellos(hellos [][]clientHelloField) *ClientHelloEntropyReport {
+	// index maps the fields of a sample by name and occurrence, as GREASE
+	// extensions share a name and may be repeated.
+	type occurrence struct {
+		name string
+		n    int
+	}
+	index := func(fields []clientHelloField) (map[occurrence][]byte, []occurrence) {
+		m := make(map[occurrence][]byte)
+		seen := make(map[string]int)
+		order := make([]occurrence, 0, len(fields))
+		for _, f := range fields {
+			o := occurrence{f.name, seen[f.name]}
+			seen[f.name]++
+			m[o] = f.data
+			order = append(order, o)
+		}
+		return m, order
+	}
+
+	report := &ClientHelloEntropyReport{Samples: len(hellos)}
+	first := hellos[0]
+	_, firstOrder := index(first)
+	others := make([]map[occurrence][]byte, 0, len(hellos)-1)
+	for _, fields := range hellos[1:] {
+		m, order := index(fields)
+		others = append(others, m)
+		if !slices.Equal(order, firstOrder) {
+			report.ExtensionOrderVaries = true
+		}
+	}
+
+	for i, f := range first {
+		fe := ClientHelloFieldEntropy{
+			Name:      f.name,
+			Extension: f.extension,
+			Length:    len(f.data),
+		}
+		variable := make([]bool, len(f.data))
+		// A part of the field is linkable if it is found in every sample.
+		linkIDs := linkableData(&f)
+		for _, m := range others {
+			data, ok := m[firstOrder[i]]
+			var parts [][]byte
+			if ok {
+				parts = linkableData(&clientHelloField{f.name, f.extension, data})
+			}
+			linkIDs = slices.DeleteFunc(linkIDs, func(id []byte) bool {
+				return !slices.ContainsFunc(parts, func(part []byte) bool { return bytes.Equal(part, id) })
+			})
+			if !ok || len(data) != len(f.data) {
+				fe.LengthVaries = true
+				continue
+			}
+			for j := range data {
+				if data[j] != f.data[j] {
+					variable[j] = true
+				}
+			}
+		}
+		for _, v := range variable {
+			if v {
+				fe.VariableBytes++
+			}
+		}
+		if fe.LengthVaries {
+			report.VariableBytes += fe.Length
+		} else {
+			report.VariableBytes += fe.VariableBytes
+			report.StaticBytes += fe.Length - fe.VariableBytes
+		}
+		if len(linkIDs) > 0 {
+			report.Linkable = append(report.Linkable, fe.Name)
+		}
+		report.Fields = append(report.Fields, fe)
+	}
+	return report
+}
+
+// linkableData returns the parts of f that should differ on every
+// connection: the key of each key share and the identity of each pre-shared
+// key, or the whole field. Empty parts are omitted.
+func linkableData(f *clientHelloField) [][]byte {
+	var parts [][]byte
+	add := func(part []byte) {
+		if len(part) > 0 {
+			parts = append(parts, part)
+		}
+	}
+	switch {
+	case f.name == "random", f.name == "session_id":
+		add(f.data)
+	case f.extension == utlsExtensionECH, f.extension == extensionSessionTicket:
+		add(f.data)
+	case f.extension == extensionKeyShare:
+		// GREASE key shares are static by design.
+		s := cryptobyte.String(f.data)
+		var shares cryptobyte.String
+		if !s.ReadUint16LengthPrefixed(&shares) {
+			return nil
+		}
+		for !shares.Empty() {
+			var group uint16
+			var key cryptobyte.String
+			if !shares.ReadUint16(&group) || !shares.ReadUint16LengthPrefixed(&key) {
+				return nil
+			}
+			if !isGREASEUint16(group) {
+				add(key)
+			}
+		}
+	case f.extension == extensionPreSharedKey:
+		// The binders and obfuscated ticket ages change with every
+		// ClientHello, but the identities are the resumed tickets.
+		s := cryptobyte.String(f.data)
+		var identities cryptobyte.String
+		if !s.ReadUint16LengthPrefixed(&identities) {
+			return nil
+		}
+		for !identities.Empty() {
+			var label cryptobyte.String
+			if !identities.ReadUint16LengthPrefixed(&label) || !identities.Skip(4) {
+				return nil
+			}
+			add(label)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,123 @@
+package tls
+
+import (
+	"io"
+	"slices"
+	"testing"
+)
+
+// constantReader is a deterministic io.Reader, so that every connection
+// using it generates the same random values.
+type constantReader byte
+
+func (r constantReader) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = byte(r)
+	}
+	return len(b), nil
+}
+
+func TestAuditClientHelloEntropy(t *testing.T) {
+	report, err := AuditClientHelloEntropy(HelloChrome_120, nil, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Linkable) != 0 {
+		t.Errorf("Linkable = %v, want none", report.Linkable)
+	}
+	if !report.ExtensionOrderVaries {
+		t.Error("shuffled extensions reported in a static order")
+	}
+	for _, f := range report.Fields {
+		switch f.Name {
+		case "random", "session_id", "extension 51":
+			if f.Static() {
+				t.Errorf("%s reported as static", f.Name)
+			}
+		case "version", "compression_methods", "extension 13":
+			if !f.Static() {
+				t.Errorf("%s reported as variable: %+v", f.Name, f)
+			}
+		}
+	}
+	if report.StaticBytes == 0 || report.VariableBytes < 32+32+32 {
+		t.Errorf("StaticBytes = %d, VariableBytes = %d", report.StaticBytes, report.VariableBytes)
+	}
+
+	report, err = AuditClientHelloSpecEntropy(func() (ClientHelloSpec, error) {
+		return UTLSIdToSpec(HelloFirefox_120)
+	}, &Config{InsecureSkipVerify: true, Rand: constantReader(0x42)}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"random", "session_id"} {
+		if !slices.Contains(report.Linkable, name) {
+			t.Errorf("%s not reported as linkable with a constant Rand: %v", name, report.Linkable)
+		}
+	}
+
+	if _, err := AuditClientHelloEntropy(HelloChrome_120, nil, 1); err == nil {
+		t.Error("audit with a single sample succeeded")
+	}
+}
+
+func TestAuditClientHelloEntropyResumption(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	cache := NewLRUClientSessionCache(0)
+	c, err := NewUTLSClient(s, &Config{ClientSessionCache: cache, OmitEmptyPsk: true}, HelloChrome_100_PSK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Read the echo, and the session ticket sent before it.
+	if _, err := c.Write([]byte{0}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(c, make([]byte, 1)); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	config := &Config{ServerName: "localhost", InsecureSkipVerify: true, ClientSessionCache: cache}
+	report, err := AuditClientHelloEntropy(HelloChrome_100_PSK, config, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(report.Linkable, []string{"extension 41"}) {
+		t.Errorf("Linkable = %v, want the pre_shared_key extension", report.Linkable)
+	}
+}
+
+func TestAuditClientHelloEntropySessionCacheWithoutServerName(t *testing.T) {
+	config := &Config{InsecureSkipVerify: true, ClientSessionCache: NewLRUClientSessionCache(0)}
+	if _, err := AuditClientHelloEntropy(HelloChrome_120, config, 2); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuditClientHelloEntropyReusedKeyShare(t *testing.T) {
+	static := make([]byte, 65)
+	static[0] = 4
+	report, err := AuditClientHelloSpecEntropy(func() (ClientHelloSpec, error) {
+		spec, err := UTLSIdToSpec(HelloChrome_120)
+		if err != nil {
+			return spec, err
+		}
+		for _, ext := range spec.Extensions {
+			if ks, ok := ext.(*KeyShareExtension); ok {
+				ks.KeyShares = append(ks.KeyShares, KeyShare{Group: CurveP256, Data: static})
+			}
+		}
+		return spec, nil
+	}, nil, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(report.Linkable, []string{"extension 51"}) {
+		t.Errorf("Linkable = %v, want the key_share extension", report.Linkable)
+	}
+}
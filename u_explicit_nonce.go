@@ -0,0 +1,19 @@
+package tls
+
+// ExplicitNoncePolicy is how the explicit nonce of TLS 1.2 AES-GCM records,
+// sent in the clear before each record, is generated (RFC 5288, Section 3).
+// Peers can't tell the policies apart other than by observing the nonces, but
+// observers can, so it's part of the fingerprint of some stacks.
+type ExplicitNoncePolicy uint8
+
+const (
+	// ExplicitNonceSequence uses the record sequence number, as crypto/tls,
+	// BoringSSL and OpenSSL do. It's the secure default.
+	ExplicitNonceSequence ExplicitNoncePolicy = iota
+
+	// ExplicitNonceRandom draws each nonce from Config.Rand. Random 64-bit
+	// nonces are expected to repeat, which breaks AES-GCM, after about 2^32
+	// records with the same key, so it's only meant for emulating stacks
+	// that do so.
+	ExplicitNonceRandom
+)
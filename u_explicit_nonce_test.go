@@ -0,0 +1,48 @@
+package tls
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExplicitNonce(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// finishedNonce returns the explicit nonce of the client's Finished
+	// message, the first record it encrypts.
+	finishedNonce := func(policy ExplicitNoncePolicy) []byte {
+		c, err := NewUTLSClient(s, &Config{
+			MaxVersion:    VersionTLS12,
+			CipherSuites:  []uint16{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+			ExplicitNonce: policy,
+		}, HelloGolang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		flight := c.ClientFlight()
+		afterCCS := false
+		for len(flight) >= recordHeaderLen {
+			n := int(flight[3])<<8 | int(flight[4])
+			record := flight[recordHeaderLen : recordHeaderLen+n]
+			if afterCCS {
+				return record[:8]
+			}
+			afterCCS = recordType(flight[0]) == recordTypeChangeCipherSpec
+			flight = flight[recordHeaderLen+n:]
+		}
+		t.Fatal("client sent no encrypted handshake record")
+		return nil
+	}
+
+	if nonce := finishedNonce(ExplicitNonceSequence); !bytes.Equal(nonce, make([]byte, 8)) {
+		t.Errorf("ExplicitNonceSequence: nonce of the first record = %x, want the sequence number 0", nonce)
+	}
+	if nonce := finishedNonce(ExplicitNonceRandom); bytes.Equal(nonce, make([]byte, 8)) {
+		t.Errorf("ExplicitNonceRandom: nonce of the first record = %x, want a random one", nonce)
+	}
+}
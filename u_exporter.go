@@ -0,0 +1,62 @@
+package tls
+
+import (
+	"errors"
+	"strings"
+)
+
+// exporterLabelPrefix namespaces the labels used by ExportNamespacedKey, so
+// that keys derived through it can't collide with registered RFC 5705 labels.
+const exporterLabelPrefix = "EXPORTER-uTLS/"
+
+// ExportNamespacedKey derives length bytes of keying material for purpose
+// within namespace, for use by protocols layered over the connection, such as
+// obfuscation or tunneling layers.
+//
+// The RFC 5705 exporter is used with the label
+// "EXPORTER-uTLS/<namespace>/<purpose>", and the context is automatically bound
+// to the connection's handshake transcript hash, so that keys are unique to the
+// connection even if callers reuse labels. Both peers derive the same key.
+//
+// The same restrictions as for ExportKeyingMaterial apply.
+func (cs *ConnectionState) ExportNamespacedKey(namespace, purpose string, length int) ([]byte, error) {
+	label, err := exporterLabel(namespace, purpose)
+	if err != nil {
+		return nil, err
+	}
+	if len(cs.TranscriptHash) == 0 {
+		if cs.HandshakeComplete {
+			return nil, errFalseStartPending
+		}
+		return nil, errors.New("tls: ExportNamespacedKey called before the handshake completed")
+	}
+	return cs.ExportKeyingMaterial(label, cs.TranscriptHash, length)
+}
+
+// ExportNamespacedKeyPair derives a pair of directional keys of length bytes
+// each, for purpose within namespace. clientToServer should protect data
+// sent by the client and serverToClient data sent by the server, regardless of
+// which side of the connection calls it.
+//
+// See ExportNamespacedKey for how the keys are derived.
+func (cs *ConnectionState) ExportNamespacedKeyPair(namespace, purpose string, length int) (clientToServer, serverToClient []byte, err error) {
+	clientToServer, err = cs.ExportNamespacedKey(namespace, purpose+" client", length)
+	if err != nil {
+		return nil, nil, err
+	}
+	serverToClient, err = cs.ExportNamespacedKey(namespace, purpose+" server", length)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clientToServer, serverToClient, nil
+}
+
+func exporterLabel(namespace, purpose string) (string, error) {
+	if namespace == "" || purpose == "" {
+		return "", errors.New("tls: exporter namespace and purpose must not be empty")
+	}
+	if strings.Contains(namespace, "/") {
+		return "", errors.New("tls: exporter namespace must not contain '/'")
+	}
+	return exporterLabelPrefix + namespace + "/" + purpose, nil
+}
@@ -0,0 +1,96 @@
+package tls
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestExportNamespacedKey(t *testing.T) {
+	for _, version := range []uint16{VersionTLS12, VersionTLS13} {
+		clientConfig := testConfig.Clone()
+		clientConfig.MaxVersion = version
+		serverState, clientState, err := testHandshake(t, clientConfig, testConfig.Clone())
+		if err != nil {
+			t.Fatalf("%x: handshake failed: %v", version, err)
+		}
+
+		clientKey, err := clientState.ExportNamespacedKey("obfs", "stream key", 32)
+		if err != nil {
+			t.Fatalf("%x: %v", version, err)
+		}
+		serverKey, err := serverState.ExportNamespacedKey("obfs", "stream key", 32)
+		if err != nil {
+			t.Fatalf("%x: %v", version, err)
+		}
+		if len(clientKey) != 32 || !bytes.Equal(clientKey, serverKey) {
+			t.Errorf("%x: client and server keys differ: %x != %x", version, clientKey, serverKey)
+		}
+
+		otherKey, err := clientState.ExportNamespacedKey("other", "stream key", 32)
+		if err != nil {
+			t.Fatalf("%x: %v", version, err)
+		}
+		if bytes.Equal(clientKey, otherKey) {
+			t.Errorf("%x: keys from different namespaces are equal", version)
+		}
+		plainKey, err := clientState.ExportKeyingMaterial(exporterLabelPrefix+"obfs/stream key", nil, 32)
+		if err != nil {
+			t.Fatalf("%x: %v", version, err)
+		}
+		if bytes.Equal(clientKey, plainKey) {
+			t.Errorf("%x: key is not bound to the transcript", version)
+		}
+
+		c2s, s2c, err := clientState.ExportNamespacedKeyPair("obfs", "record", 16)
+		if err != nil {
+			t.Fatalf("%x: %v", version, err)
+		}
+		serverC2S, serverS2C, err := serverState.ExportNamespacedKeyPair("obfs", "record", 16)
+		if err != nil {
+			t.Fatalf("%x: %v", version, err)
+		}
+		if bytes.Equal(c2s, s2c) || !bytes.Equal(c2s, serverC2S) || !bytes.Equal(s2c, serverS2C) {
+			t.Errorf("%x: unexpected directional keys", version)
+		}
+	}
+}
+
+func TestExportNamespacedKeyInvalid(t *testing.T) {
+	var cs ConnectionState
+	if _, err := cs.ExportNamespacedKey("", "key", 16); err == nil {
+		t.Error("expected error for empty namespace")
+	}
+	if _, err := cs.ExportNamespacedKey("a/b", "key", 16); err == nil {
+		t.Error("expected error for namespace containing a separator")
+	}
+	if _, err := cs.ExportNamespacedKey("obfs", "key", 16); err == nil {
+		t.Error("expected error before the handshake completed")
+	}
+}
+
+func TestTranscriptHash(t *testing.T) {
+	for _, test := range []struct {
+		version uint16
+		suite   uint16
+		size    int
+	}{
+		{VersionTLS11, TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA, 36}, // MD5 and SHA-1
+		{VersionTLS12, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, 32},
+		{VersionTLS12, TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, 48},
+		{VersionTLS13, TLS_AES_128_GCM_SHA256, 32},
+	} {
+		clientConfig := testConfig.Clone()
+		clientConfig.MaxVersion = test.version
+		clientConfig.CipherSuites = []uint16{test.suite}
+		serverState, clientState, err := testHandshake(t, clientConfig, testConfig.Clone())
+		if err != nil {
+			t.Fatalf("%x: handshake failed: %v", test.version, err)
+		}
+		if len(clientState.TranscriptHash) != test.size {
+			t.Errorf("%x: got transcript hash of %d bytes, want %d", test.version, len(clientState.TranscriptHash), test.size)
+		}
+		if !bytes.Equal(clientState.TranscriptHash, serverState.TranscriptHash) {
+			t.Errorf("%x: client and server transcript hashes differ: %x != %x", test.version, clientState.TranscriptHash, serverState.TranscriptHash)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package tls
+
+import (
+	"bytes"
+	"io"
+)
+
+// extensionReader returns a reader of ext as it is marshaled in the
+// ClientHello, with its type renumbered according to
+// ClientHelloSpec.ExtensionCodepoints.
+func (uconn *UConn) extensionReader(ext TLSExtension) (io.Reader, error) {
+	if len(uconn.extensionCodepoints) == 0 {
+		return ext, nil
+	}
+	b := make([]byte, ext.Len())
+	if _, err := io.ReadFull(ext, b); err != nil {
+		return nil, err
+	}
+	if len(b) >= 2 {
+		if codepoint, ok := uconn.extensionCodepoints[uint16(b[0])<<8|uint16(b[1])]; ok {
+			b[0] = byte(codepoint >> 8)
+			b[1] = byte(codepoint)
+		}
+	}
+	return bytes.NewReader(b), nil
+}
+
+// extensionCodepoint returns the type ext is sent with.
+func (uconn *UConn) extensionCodepoint(extType uint16) uint16 {
+	if codepoint, ok := uconn.extensionCodepoints[extType]; ok {
+		return codepoint
+	}
+	return extType
+}
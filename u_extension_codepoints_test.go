@@ -0,0 +1,47 @@
+package tls
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtensionCodepoints(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	spec, err := UTLSIdToSpec(HelloChrome_120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.ExtensionCodepoints = map[uint16]uint16{
+		utlsExtensionApplicationSettings: utlsExtensionApplicationSettingsNew,
+	}
+	c := NewUnstartedUTLSClient(s, nil, HelloCustom)
+	if err := c.ApplyPreset(&spec); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var sent ClientHelloSpec
+	if err := sent.FromRaw(append([]byte{byte(recordTypeHandshake), 3, 1, 0, 0}, c.HandshakeState.Hello.Raw...), true); err != nil {
+		t.Fatal(err)
+	}
+	var types []uint16
+	for _, ext := range sent.Extensions {
+		switch ext.(type) {
+		case *ApplicationSettingsExtension:
+			types = append(types, utlsExtensionApplicationSettings)
+		case *ApplicationSettingsExtensionNew:
+			types = append(types, utlsExtensionApplicationSettingsNew)
+		}
+	}
+	if !slices.Equal(types, []uint16{utlsExtensionApplicationSettingsNew}) {
+		t.Errorf("sent application_settings codepoints %v, want only %d", types, utlsExtensionApplicationSettingsNew)
+	}
+}
@@ -0,0 +1,60 @@
+package tls
+
+import (
+	"errors"
+
+	"github.com/refraction-networking/utls/internal/tls13"
+)
+
+// ExternalPSKSchedule is the early part of the TLS 1.3 key schedule for an
+// externally provisioned PSK, as defined in RFC 8446, Section 7.1. It derives
+// the same secrets the handshake does, so that the binders of a
+// pre_shared_key extension and the keys of 0-RTT data can be computed
+// consistently with it.
+type ExternalPSKSchedule struct {
+	suite *cipherSuiteTLS13
+	early *tls13.EarlySecret
+}
+
+// NewExternalPSKSchedule starts the key schedule of psk for the TLS 1.3
+// cipher suite cipherSuite, whose hash the PSK is associated with.
+func NewExternalPSKSchedule(cipherSuite uint16, psk []byte) (*ExternalPSKSchedule, error) {
+	suite := cipherSuiteTLS13ByID(cipherSuite)
+	if suite == nil {
+		return nil, errors.New("tls: " + CipherSuiteName(cipherSuite) + " is not a TLS 1.3 cipher suite")
+	}
+	if len(psk) == 0 {
+		return nil, errors.New("tls: empty external PSK")
+	}
+	return &ExternalPSKSchedule{
+		suite: suite,
+		early: tls13.NewEarlySecret(suite.hash.New, psk),
+	}, nil
+}
+
+// EarlySecret returns the Early Secret.
+func (s *ExternalPSKSchedule) EarlySecret() []byte {
+	return s.early.Secret()
+}
+
+// BinderKey returns the binder_key, derived with the "ext binder" label.
+func (s *ExternalPSKSchedule) BinderKey() []byte {
+	return s.early.ExternalBinderKey()
+}
+
+// Binder returns the PSK binder for transcript, the handshake messages up to
+// and including the ClientHello truncated before its binders list.
+func (s *ExternalPSKSchedule) Binder(transcript []byte) []byte {
+	h := s.suite.hash.New()
+	h.Write(transcript)
+	return s.suite.finishedHash(s.BinderKey(), h)
+}
+
+// ClientEarlyTrafficSecret returns the client_early_traffic_secret for
+// transcript, the handshake messages up to and including the ClientHello. The
+// 0-RTT data is protected with keys derived from it.
+func (s *ExternalPSKSchedule) ClientEarlyTrafficSecret(transcript []byte) []byte {
+	h := s.suite.hash.New()
+	h.Write(transcript)
+	return s.early.ClientEarlyTrafficSecret(h)
+}
@@ -0,0 +1,52 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/refraction-networking/utls/internal/tls13"
+)
+
+func TestExternalPSKSchedule(t *testing.T) {
+	psk := bytes.Repeat([]byte{0x42}, 32)
+	s, err := NewExternalPSKSchedule(TLS_AES_128_GCM_SHA256, psk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	early := tls13.NewEarlySecret(sha256.New, psk)
+	if !bytes.Equal(s.EarlySecret(), early.Secret()) {
+		t.Error("early secret differs from the key schedule")
+	}
+	empty := sha256.Sum256(nil)
+	wantKey := tls13.ExpandLabel(sha256.New, early.Secret(), "ext binder", empty[:], sha256.Size)
+	if !bytes.Equal(s.BinderKey(), wantKey) {
+		t.Error("binder key not derived with the ext binder label")
+	}
+	if bytes.Equal(s.BinderKey(), early.ResumptionBinderKey()) {
+		t.Error("binder key equals the resumption binder key")
+	}
+
+	transcript := []byte("truncated ClientHello")
+	th := sha256.Sum256(transcript)
+	finishedKey := tls13.ExpandLabel(sha256.New, wantKey, "finished", nil, sha256.Size)
+	mac := hmac.New(sha256.New, finishedKey)
+	mac.Write(th[:])
+	if !bytes.Equal(s.Binder(transcript), mac.Sum(nil)) {
+		t.Error("wrong binder")
+	}
+
+	wantTraffic := tls13.ExpandLabel(sha256.New, early.Secret(), "c e traffic", th[:], sha256.Size)
+	if !bytes.Equal(s.ClientEarlyTrafficSecret(transcript), wantTraffic) {
+		t.Error("wrong client early traffic secret")
+	}
+
+	if _, err := NewExternalPSKSchedule(TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, psk); err == nil {
+		t.Error("TLS 1.2 cipher suite accepted")
+	}
+	if _, err := NewExternalPSKSchedule(TLS_AES_128_GCM_SHA256, nil); err == nil {
+		t.Error("empty PSK accepted")
+	}
+}
@@ -0,0 +1,86 @@
+package tls
+
+import "errors"
+
+// errFalseStartPending is returned by the exporters of a client that False
+// Started, until the server's Finished is verified.
+var errFalseStartPending = errors.New("tls: keying material is not available until the server's Finished is verified after False Start")
+
+func noEKMBeforeFalseStartCompletes(label string, context []byte, length int) ([]byte, error) {
+	return nil, errFalseStartPending
+}
+
+// canFalseStart reports whether the client may send application data before
+// the server's Finished, as described in RFC 7918: in a full TLS 1.2 handshake
+// with a forward secret AEAD cipher suite and a negotiated ALPN protocol, the
+// conditions under which Chrome and Firefox False Start. It's only enabled by
+// ClientHelloSpec.FalseStart or Config.FalseStart, and never on renegotiation.
+func (hs *clientHandshakeState) canFalseStart() bool {
+	c := hs.c
+	if hs.uconn == nil || c.handshakes != 0 {
+		return false
+	}
+	enabled := hs.uconn.falseStart || c.config.FalseStart && presetFalseStart(hs.uconn.ClientHelloID)
+	return enabled && c.vers == VersionTLS12 && !c.didResume &&
+		hs.suite.flags&suiteECDHE != 0 && hs.suite.aead != nil &&
+		c.clientProtocol != ""
+}
+
+// finishFalseStart reads the rest of the server's flight after the client
+// False Started, and completes the handshake.
+func (hs *clientHandshakeState) finishFalseStart() error {
+	c := hs.c
+	if err := hs.readSessionTicket(); err != nil {
+		return err
+	}
+	if err := hs.readFinished(c.serverFinished[:]); err != nil {
+		return err
+	}
+	if err := c.checkResumption(hs.session); err != nil {
+		return err
+	}
+	if err := hs.saveSessionTicket(); err != nil {
+		return err
+	}
+	c.ekm = ekmFromMasterSecret(c.vers, hs.suite, hs.masterSecret, hs.hello.random, hs.serverHello.random)
+	c.utls.transcriptHash = hs.finishedHash.Sum()
+	return nil
+}
+
+// completeFalseStart completes the handshake of a client that False Started,
+// before the first application data is read. c.in must be locked.
+//
+// The handshake state is completed under handshakeMutex, like on
+// renegotiation, so that it's not read concurrently by ConnectionState.
+func (c *Conn) completeFalseStart() error {
+	if c.utls.falseStart == nil {
+		return nil
+	}
+	c.handshakeMutex.Lock()
+	defer c.handshakeMutex.Unlock()
+	finish := c.utls.falseStart
+	c.utls.falseStart = nil
+	if err := finish(); err != nil {
+		return c.in.setErrorLocked(err)
+	}
+	return nil
+}
+
+// waitFalseStart completes the handshake of a client that False Started by
+// reading the rest of the server's flight, without waiting for a Read.
+func (c *Conn) waitFalseStart() error {
+	c.in.Lock()
+	defer c.in.Unlock()
+	return c.completeFalseStart()
+}
+
+// presetFalseStart reports whether the client of the preset id False Starts,
+// which Chromium and NSS do by default.
+func presetFalseStart(id ClientHelloID) bool {
+	switch id.Client {
+	case helloChrome, helloEdge, helloElectron, hello360, helloQQ, helloFirefox:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,148 @@
+package tls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestFalseStart(t *testing.T) {
+	s, err := NewUTLSServer(&Config{MaxVersion: VersionTLS12, NextProtos: []string{"h2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	cache := NewLRUClientSessionCache(1)
+	c, err := NewUTLSClient(s, &Config{ClientSessionCache: cache, FalseStart: true}, HelloChrome_131)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// The handshake returned after the client's Finished.
+	if c.utls.falseStart == nil || c.serverFinished != [12]byte{} {
+		t.Fatal("client didn't False Start")
+	}
+	if _, ok := cache.Get(c.clientSessionCacheKey()); ok {
+		t.Error("session stored before the server's Finished was verified")
+	}
+
+	// ConnectionState may be called while Read completes the handshake.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			c.ConnectionState()
+		}
+	}()
+	msg := []byte("hello")
+	if _, err := c.Write(msg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(c, make([]byte, len(msg))); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if c.utls.falseStart != nil || c.serverFinished == [12]byte{} {
+		t.Error("Read didn't complete the handshake")
+	}
+	if _, ok := cache.Get(c.clientSessionCacheKey()); !ok {
+		t.Error("session not stored after the handshake completed")
+	}
+}
+
+func TestFalseStartConditions(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		server *Config
+		id     ClientHelloID
+	}{
+		{"TLS 1.3", &Config{NextProtos: []string{"h2"}}, HelloChrome_131},
+		{"no ALPN", &Config{MaxVersion: VersionTLS12}, HelloChrome_131},
+		{"CBC", &Config{MaxVersion: VersionTLS12, NextProtos: []string{"h2"}, CipherSuites: []uint16{TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA}}, HelloFirefox_133},
+		{"Golang", &Config{MaxVersion: VersionTLS12, NextProtos: []string{"h2"}}, HelloGolang},
+		{"not enabled", &Config{MaxVersion: VersionTLS12, NextProtos: []string{"h2"}}, HelloChrome_131},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := NewUTLSServer(test.server)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			config := &Config{NextProtos: []string{"h2"}, FalseStart: test.name != "not enabled"}
+			c, err := NewUTLSClient(s, config, test.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			if c.utls.falseStart != nil || c.serverFinished == [12]byte{} && c.vers == VersionTLS12 {
+				t.Error("client False Started")
+			}
+		})
+	}
+}
+
+func TestFalseStartExporters(t *testing.T) {
+	s, err := NewUTLSServer(&Config{MaxVersion: VersionTLS12, NextProtos: []string{"h2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// Chrome without renegotiation, which disables the TLS 1.2 exporter.
+	spec, err := UTLSIdToSpec(HelloChrome_131)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ext := range spec.Extensions {
+		if ri, ok := ext.(*RenegotiationInfoExtension); ok {
+			ri.Renegotiation = RenegotiateNever
+		}
+	}
+	spec.FalseStart = true
+	c := NewUnstartedUTLSClient(s, nil, HelloCustom)
+	if err := c.ApplyPreset(&spec); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.utls.falseStart == nil {
+		t.Fatal("client didn't False Start")
+	}
+
+	// The keys aren't authenticated by the server's Finished yet.
+	cs := c.ConnectionState()
+	if !cs.HandshakeComplete || cs.TranscriptHash != nil {
+		t.Errorf("HandshakeComplete = %v, TranscriptHash = %x while False Start is pending", cs.HandshakeComplete, cs.TranscriptHash)
+	}
+	if _, err := cs.ExportKeyingMaterial("EXPORTER-test", nil, 32); !errors.Is(err, errFalseStartPending) {
+		t.Errorf("ExportKeyingMaterial = %v, want %v", err, errFalseStartPending)
+	}
+	if _, err := cs.ConnectionID(); !errors.Is(err, errFalseStartPending) {
+		t.Errorf("ConnectionID = %v, want %v", err, errFalseStartPending)
+	}
+
+	// NewMuxConn reads the server's Finished to derive the ConnectionID.
+	mc, err := NewMuxConn(context.Background(), c.UConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cs = c.ConnectionState()
+	if cs.TranscriptHash == nil {
+		t.Error("no TranscriptHash after the server's Finished")
+	}
+	if _, err := cs.ExportKeyingMaterial("EXPORTER-test", nil, 32); err != nil {
+		t.Errorf("ExportKeyingMaterial = %v after the server's Finished", err)
+	}
+	serverState := c.ServerConn.ConnectionState()
+	serverID, err := serverState.ConnectionID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(mc.ID(), serverID) {
+		t.Errorf("client ConnectionID %x, server %x", mc.ID(), serverID)
+	}
+}
@@ -0,0 +1,134 @@
+package tls
+
+import (
+	"context"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// FastOpenDialer dials uTLS connections with TCP Fast Open where the OS allows
+// it, so that the ClientHello is sent in the payload of the SYN and the
+// handshake completes one round trip earlier. See UConn.FirstFlight.
+//
+// Fast Open is only used on Linux, with TCP_FASTOPEN_CONNECT. If it is
+// unsupported, disabled, or the server has no Fast Open cookie for the client
+// yet, the ClientHello is sent right after the TCP handshake as usual.
+type FastOpenDialer struct {
+	// NetDialer is the optional dialer to use for the underlying TCP
+	// connections. A nil NetDialer is equivalent to the net.Dialer zero
+	// value. Its Control or ControlContext function, if any, is still
+	// called.
+	NetDialer *net.Dialer
+
+	// Config is the TLS configuration to use for new connections. If nil,
+	// or if it has no ServerName, the host of the dialed address is used
+	// as ServerName.
+	Config *Config
+
+	// ClientHelloID is the ClientHelloID of new connections.
+	ClientHelloID ClientHelloID
+
+	// TransportShaping, if not nil, is applied to the TCP connections.
+	// Its MaxSegmentSize is set before connecting, so that the clamped MSS
+	// is advertised in the SYN.
+	TransportShaping *TransportShaping
+}
+
+// Dial connects to the given address and completes the handshake.
+func (d *FastOpenDialer) Dial(network, addr string) (*UConn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// DialContext connects to the given address and completes the handshake. The
+// context bounds the TCP connection and the handshake as a whole.
+func (d *FastOpenDialer) DialContext(ctx context.Context, network, addr string) (*UConn, error) {
+	var netDialer net.Dialer
+	if d.NetDialer != nil {
+		netDialer = *d.NetDialer
+	}
+	if netDialer.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, netDialer.Timeout)
+		defer cancel()
+	}
+	if !netDialer.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, netDialer.Deadline)
+		defer cancel()
+	}
+
+	config := d.Config
+	if config == nil {
+		config = &Config{}
+	}
+	if config.ServerName == "" {
+		hostname := addr
+		if colonPos := strings.LastIndex(addr, ":"); colonPos != -1 {
+			hostname = addr[:colonPos]
+		}
+		config = config.Clone()
+		config.ServerName = strings.Trim(hostname, "[]")
+	}
+
+	uconn := UClient(nil, config, d.ClientHelloID)
+	flight, err := uconn.FirstFlight()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(network, "tcp") {
+		control, controlContext := netDialer.Control, netDialer.ControlContext
+		netDialer.Control = nil
+		netDialer.ControlContext = func(ctx context.Context, network, address string, c syscall.RawConn) error {
+			if controlContext != nil {
+				if err := controlContext(ctx, network, address, c); err != nil {
+					return err
+				}
+			} else if control != nil {
+				if err := control(network, address, c); err != nil {
+					return err
+				}
+			}
+			if d.TransportShaping != nil {
+				if err := d.TransportShaping.control(c); err != nil {
+					return err
+				}
+			}
+			// Errors are ignored: without Fast Open, the first flight
+			// is sent once connected.
+			c.Control(func(fd uintptr) {
+				setFastOpenConnect(fd)
+			})
+			return nil
+		}
+	}
+
+	conn, err := netDialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if d.TransportShaping != nil {
+		shaped, err := d.TransportShaping.shape(conn, false)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = shaped
+	}
+	// With TCP_FASTOPEN_CONNECT, connecting is deferred to this first write,
+	// whose data is sent in the SYN.
+	if _, err := conn.Write(flight); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := uconn.Attach(conn, true); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := uconn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return uconn, nil
+}
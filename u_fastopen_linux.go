@@ -0,0 +1,8 @@
+package tls
+
+import "golang.org/x/sys/unix"
+
+// setFastOpenConnect enables TCP Fast Open on a socket before it connects.
+func setFastOpenConnect(fd uintptr) error {
+	return unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_FASTOPEN_CONNECT, 1)
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package tls
+
+import "errors"
+
+// setFastOpenConnect enables TCP Fast Open on a socket before it connects.
+func setFastOpenConnect(fd uintptr) error {
+	return errors.New("tls: TCP Fast Open is not supported on this platform")
+}
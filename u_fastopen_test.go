@@ -0,0 +1,52 @@
+package tls
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFastOpenDialer(t *testing.T) {
+	s, err := NewClientHelloEchoServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	reports := make(chan *ClientHelloReport, 1)
+	s.OnReport = func(r *ClientHelloReport) { reports <- r }
+
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate)
+	controlCalled := false
+	d := &FastOpenDialer{
+		NetDialer: &net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(network, address string, c syscall.RawConn) error {
+				controlCalled = true
+				return nil
+			},
+		},
+		Config:        &Config{RootCAs: roots},
+		ClientHelloID: HelloChrome_Auto,
+	}
+	uconn, err := d.DialContext(context.Background(), "tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer uconn.Close()
+	if !controlCalled {
+		t.Error("NetDialer.Control was not called")
+	}
+	if name := uconn.config.ServerName; name != "127.0.0.1" {
+		t.Errorf("ServerName = %q, want the host of the address", name)
+	}
+
+	r := <-reports
+	if want := uconn.HandshakeState.Hello.Raw; r.Raw != hex.EncodeToString(want) {
+		t.Error("server received a different ClientHello than the first flight")
+	}
+}
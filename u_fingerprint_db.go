@@ -0,0 +1,127 @@
+package tls
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+//go:generate go run gen_fingerprints.go
+
+// fingerprintDB is the embedded fingerprint database, generated from the
+// ClientHellos in fingerprints/captures. To add a client, drop a capture of
+// its ClientHello there and run go generate.
+//
+//go:embed fingerprints/fingerprints.json
+var fingerprintDB []byte
+
+// CapturedFingerprint is a ClientHello from the embedded fingerprint database,
+// together with its provenance. Source tells whether it was captured from the
+// real client: the firefox_133 seed entry was generated from the
+// HelloFirefox_133 preset instead.
+type CapturedFingerprint struct {
+	PresetMetadata
+
+	// ClientHello is the TLS record carrying the captured ClientHello.
+	ClientHello []byte
+}
+
+// capturedFingerprintJSON is an entry of fingerprints/fingerprints.json, as
+// written by gen_fingerprints.go.
+type capturedFingerprintJSON struct {
+	Name        string `json:"name"`
+	Browser     string `json:"browser"`
+	Captured    string `json:"captured"`
+	Source      string `json:"source"`
+	Notes       string `json:"notes,omitempty"`
+	ClientHello string `json:"client_hello"`
+}
+
+var (
+	capturedFingerprintsOnce sync.Once
+	capturedFingerprints     []CapturedFingerprint
+	capturedFingerprintsErr  error
+)
+
+// loadCapturedFingerprints parses the embedded fingerprint database once.
+func loadCapturedFingerprints() ([]CapturedFingerprint, error) {
+	capturedFingerprintsOnce.Do(func() {
+		var entries []capturedFingerprintJSON
+		if err := json.Unmarshal(fingerprintDB, &entries); err != nil {
+			capturedFingerprintsErr = fmt.Errorf("tls: invalid fingerprint database: %w", err)
+			return
+		}
+		for _, e := range entries {
+			raw, err := hex.DecodeString(e.ClientHello)
+			if err != nil {
+				capturedFingerprintsErr = fmt.Errorf("tls: invalid fingerprint %q: %w", e.Name, err)
+				return
+			}
+			captured, err := time.Parse("2006-01", e.Captured)
+			if err != nil {
+				capturedFingerprintsErr = fmt.Errorf("tls: invalid fingerprint %q: %w", e.Name, err)
+				return
+			}
+			capturedFingerprints = append(capturedFingerprints, CapturedFingerprint{
+				PresetMetadata: PresetMetadata{
+					ID:             CapturedHelloID(e.Name),
+					BrowserVersion: e.Browser,
+					CaptureDate:    captured,
+					Source:         e.Source,
+					Notes:          e.Notes,
+				},
+				ClientHello: raw,
+			})
+		}
+		sort.Slice(capturedFingerprints, func(i, j int) bool {
+			return capturedFingerprints[i].ID.Version < capturedFingerprints[j].ID.Version
+		})
+	})
+	return capturedFingerprints, capturedFingerprintsErr
+}
+
+// CapturedFingerprints returns the fingerprints of the embedded database,
+// sorted by name. The returned slice must not be modified.
+func CapturedFingerprints() ([]CapturedFingerprint, error) {
+	return loadCapturedFingerprints()
+}
+
+// CapturedHelloID returns the ClientHelloID of the fingerprint named name in
+// the embedded database, the base name of its capture file, e.g.
+// "firefox_133". UTLSIdToSpec parses the captured ClientHello into a spec.
+func CapturedHelloID(name string) ClientHelloID {
+	return ClientHelloID{Client: helloCaptured, Version: name}
+}
+
+// capturedFingerprint returns the fingerprint named name.
+func capturedFingerprint(name string) (*CapturedFingerprint, error) {
+	fingerprints, err := loadCapturedFingerprints()
+	if err != nil {
+		return nil, err
+	}
+	for i := range fingerprints {
+		if fingerprints[i].ID.Version == name {
+			return &fingerprints[i], nil
+		}
+	}
+	id := CapturedHelloID(name)
+	return nil, fmt.Errorf("%w: %s", ErrUnknownClientHelloID, id.Str())
+}
+
+// capturedFingerprintSpec returns a new ClientHelloSpec of the fingerprint
+// named name.
+func capturedFingerprintSpec(name string) (ClientHelloSpec, error) {
+	fp, err := capturedFingerprint(name)
+	if err != nil {
+		return ClientHelloSpec{}, err
+	}
+	var spec ClientHelloSpec
+	if err := spec.FromRaw(fp.ClientHello, true); err != nil {
+		return ClientHelloSpec{}, err
+	}
+	return spec, nil
+}
@@ -0,0 +1,85 @@
+package tls
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCapturedFingerprints(t *testing.T) {
+	fingerprints, err := CapturedFingerprints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob("fingerprints/captures/*.hex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fingerprints) != len(files) {
+		t.Fatalf("database has %d fingerprints, but there are %d captures; run go generate", len(fingerprints), len(files))
+	}
+
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, fp := range fingerprints {
+		// The database must be regenerated when a capture changes.
+		capture, err := os.ReadFile(filepath.Join("fingerprints/captures", fp.ID.Version+".hex"))
+		if err != nil {
+			t.Fatalf("%s: %v; run go generate", fp.ID.Version, err)
+		}
+		var data strings.Builder
+		for _, line := range strings.Split(string(capture), "\n") {
+			if !strings.HasPrefix(line, "#") {
+				data.WriteString(strings.Join(strings.Fields(line), ""))
+			}
+		}
+		raw, err := hex.DecodeString(data.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(raw, fp.ClientHello) {
+			t.Errorf("%s: database doesn't match the capture; run go generate", fp.ID.Version)
+		}
+
+		if m, ok := fp.ID.Metadata(); !ok || m.BrowserVersion != fp.BrowserVersion {
+			t.Errorf("%s: Metadata() = %v, %v", fp.ID.Version, m, ok)
+		}
+		c, err := NewUTLSClient(s, nil, fp.ID)
+		if err != nil {
+			t.Fatalf("%s: %v", fp.ID.Version, err)
+		}
+		c.Close()
+	}
+}
+
+func TestCapturedFingerprintJA4(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := NewUTLSClient(s, nil, CapturedHelloID("firefox_133"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	hello := new(clientHelloMsg)
+	if !hello.unmarshal(c.HandshakeState.Hello.Raw) {
+		t.Fatal("unable to parse the ClientHello")
+	}
+	if ja4 := ja4String(hello, false); ja4 != "t13d1717h2_5b57614c22b0_3cbfd9057e0d" {
+		t.Errorf("JA4 = %s, want t13d1717h2_5b57614c22b0_3cbfd9057e0d", ja4)
+	}
+
+	if _, err := UTLSIdToSpec(CapturedHelloID("netscape_1")); !errors.Is(err, ErrUnknownClientHelloID) {
+		t.Errorf("UTLSIdToSpec of an unknown capture: %v, want ErrUnknownClientHelloID", err)
+	}
+}
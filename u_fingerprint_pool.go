@@ -0,0 +1,80 @@
+package tls
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+)
+
+// WeightedClientHelloID is a ClientHelloID of a FingerprintPool, with its
+// weight relative to the others.
+type WeightedClientHelloID struct {
+	ID     ClientHelloID
+	Weight int
+}
+
+// FingerprintPool picks the ClientHelloID of each connection among a fixed set,
+// with probabilities proportional to their weights, so that a fleet of clients
+// follows a realistic browser market share, e.g. 70% Chrome, 20% Firefox and
+// 10% Safari.
+//
+// Each pick is independent, so a destination sees several fingerprints over
+// time; see IdentityManager to keep them consistent per destination.
+//
+// It is safe for concurrent use.
+type FingerprintPool struct {
+	ids        []ClientHelloID
+	cumulative []int64 // cumulative[i] is the sum of the weights of ids[:i+1]
+
+	mu sync.Mutex
+	r  *prng
+}
+
+// NewFingerprintPool returns a FingerprintPool of the given ClientHelloIDs.
+// Weights must not be negative, and at least one must be positive. Entries
+// with a zero weight are never picked.
+func NewFingerprintPool(ids ...WeightedClientHelloID) (*FingerprintPool, error) {
+	p := &FingerprintPool{}
+	var total int64
+	for _, w := range ids {
+		if w.Weight < 0 {
+			return nil, fmt.Errorf("tls: negative weight for %s in fingerprint pool", w.ID.Str())
+		}
+		if w.Weight == 0 {
+			continue
+		}
+		total += int64(w.Weight)
+		p.ids = append(p.ids, w.ID)
+		p.cumulative = append(p.cumulative, total)
+	}
+	if total == 0 {
+		return nil, errors.New("tls: fingerprint pool has no ClientHelloID with a positive weight")
+	}
+	r, err := newPRNG()
+	if err != nil {
+		return nil, err
+	}
+	p.r = r
+	return p, nil
+}
+
+// Pick returns a ClientHelloID of the pool, picked at random according to the
+// weights.
+func (p *FingerprintPool) Pick() ClientHelloID {
+	p.mu.Lock()
+	n := p.r.Int63n(p.cumulative[len(p.cumulative)-1])
+	p.mu.Unlock()
+	for i, c := range p.cumulative {
+		if n < c {
+			return p.ids[i]
+		}
+	}
+	panic("unreachable")
+}
+
+// UClient is like the package-level UClient, with a ClientHelloID returned by
+// Pick.
+func (p *FingerprintPool) UClient(conn net.Conn, config *Config) *UConn {
+	return UClient(conn, config, p.Pick())
+}
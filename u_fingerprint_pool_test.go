@@ -0,0 +1,54 @@
+package tls
+
+import (
+	"net"
+	"testing"
+)
+
+func TestFingerprintPool(t *testing.T) {
+	p, err := NewFingerprintPool(
+		WeightedClientHelloID{HelloChrome_Auto, 70},
+		WeightedClientHelloID{HelloFirefox_Auto, 20},
+		WeightedClientHelloID{HelloSafari_Auto, 10},
+		WeightedClientHelloID{HelloGolang, 0},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const picks = 10000
+	counts := make(map[ClientHelloID]int)
+	for i := 0; i < picks; i++ {
+		counts[p.Pick()]++
+	}
+	for id, want := range map[ClientHelloID]int{
+		HelloChrome_Auto:  7000,
+		HelloFirefox_Auto: 2000,
+		HelloSafari_Auto:  1000,
+	} {
+		// Over 6 standard deviations away for the least likely ID.
+		if got := counts[id]; got < want-500 || got > want+500 {
+			t.Errorf("%s picked %d times out of %d, want about %d", id.Str(), got, picks, want)
+		}
+	}
+	if counts[HelloGolang] != 0 {
+		t.Errorf("ClientHelloID with zero weight picked %d times", counts[HelloGolang])
+	}
+
+	uconn := p.UClient(&net.TCPConn{}, &Config{ServerName: "example.com"})
+	if _, ok := counts[uconn.ClientHelloID]; !ok {
+		t.Errorf("UClient used %s, which is not in the pool", uconn.ClientHelloID.Str())
+	}
+}
+
+func TestFingerprintPoolInvalid(t *testing.T) {
+	if _, err := NewFingerprintPool(); err == nil {
+		t.Error("empty pool accepted")
+	}
+	if _, err := NewFingerprintPool(WeightedClientHelloID{HelloChrome_Auto, 0}); err == nil {
+		t.Error("pool without positive weights accepted")
+	}
+	if _, err := NewFingerprintPool(WeightedClientHelloID{HelloChrome_Auto, 1}, WeightedClientHelloID{HelloFirefox_Auto, -1}); err == nil {
+		t.Error("negative weight accepted")
+	}
+}
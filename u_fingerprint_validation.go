@@ -0,0 +1,230 @@
+package tls
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sync"
+)
+
+// knownClientHello is the ClientHello of a preset or of a fingerprint of the
+// embedded database.
+type knownClientHello struct {
+	id  ClientHelloID
+	msg *clientHelloMsg
+
+	// shuffled is set if the client shuffles its extensions, in which case
+	// their order is not part of its fingerprint.
+	shuffled bool
+}
+
+var (
+	knownClientHellosOnce sync.Once
+	knownClientHellos     []knownClientHello
+)
+
+// loadKnownClientHellos builds the ClientHellos of the presets and of the
+// embedded fingerprint database once, for the server name example.com.
+// Clients whose ClientHello can't be built are skipped.
+func loadKnownClientHellos() []knownClientHello {
+	knownClientHellosOnce.Do(func() {
+		var ids []ClientHelloID
+		for _, m := range presetMetadata {
+			ids = append(ids, m.ID)
+		}
+		if fingerprints, err := loadCapturedFingerprints(); err == nil {
+			for _, fp := range fingerprints {
+				ids = append(ids, fp.ID)
+			}
+		}
+		for _, id := range ids {
+			m, err := buildExampleClientHello(id, nil)
+			if err != nil {
+				continue
+			}
+			k := knownClientHello{id: id, msg: m}
+			if again, err := buildExampleClientHello(id, nil); err == nil {
+				k.shuffled = !slices.Equal(withoutGREASE(m.extensions), withoutGREASE(again.extensions))
+			}
+			knownClientHellos = append(knownClientHellos, k)
+		}
+	})
+	return knownClientHellos
+}
+
+// buildExampleClientHello builds and parses the ClientHello of id, or of spec
+// if not nil, for the server name example.com.
+func buildExampleClientHello(id ClientHelloID, spec *ClientHelloSpec) (*clientHelloMsg, error) {
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, id)
+	if spec != nil {
+		if err := uconn.ApplyPreset(spec); err != nil {
+			return nil, err
+		}
+	}
+	if err := uconn.BuildHandshakeState(); err != nil {
+		return nil, err
+	}
+	m := new(clientHelloMsg)
+	if !m.unmarshal(uconn.HandshakeState.Hello.Raw) {
+		return nil, errors.New("tls: unable to parse the built ClientHello")
+	}
+	return m, nil
+}
+
+// FingerprintValidation is the result of ValidateAgainstKnownFingerprints.
+type FingerprintValidation struct {
+	// Matches is set if the spec has the fingerprint of Client.
+	Matches bool
+
+	// Client is the preset, or the fingerprint of the embedded database,
+	// whose fingerprint matches the spec or, if none does, is the closest
+	// to it.
+	Client ClientHelloID
+
+	// Drift describes how the fingerprint of the spec differs from the one
+	// of Client, e.g. "missing padding extension" or "no GREASE at
+	// supported_groups[0]". It is empty if Matches is set.
+	Drift []string
+}
+
+// ValidateAgainstKnownFingerprints builds the ClientHello of spec and checks
+// it against the presets of this package and the fingerprints of the embedded
+// database, to catch a spec that silently drifted from the client it imitates
+// after being edited. The cipher suites, extensions, supported groups and
+// versions, signature algorithms, ALPN protocols, placement of GREASE values
+// and presence of padding are compared. The order of the extensions is
+// ignored for clients that shuffle them.
+//
+// The spec is applied to a HelloCustom UConn in the process, so like with
+// ApplyPreset its extensions must not be shared with another connection.
+func ValidateAgainstKnownFingerprints(spec *ClientHelloSpec) (*FingerprintValidation, error) {
+	m, err := buildExampleClientHello(HelloCustom, spec)
+	if err != nil {
+		return nil, err
+	}
+	var best *FingerprintValidation
+	for _, k := range loadKnownClientHellos() {
+		drift := fingerprintDrift(m, &k)
+		if best == nil || len(drift) < len(best.Drift) {
+			best = &FingerprintValidation{Matches: len(drift) == 0, Client: k.id, Drift: drift}
+			if best.Matches {
+				break
+			}
+		}
+	}
+	if best == nil {
+		return nil, errors.New("tls: no known fingerprint to validate against")
+	}
+	return best, nil
+}
+
+// fingerprintDrift describes the differences of m from the known ClientHello.
+func fingerprintDrift(m *clientHelloMsg, known *knownClientHello) []string {
+	k := known.msg
+	var drift []string
+	if v, kv := ja4Version(maxVersion(m)), ja4Version(maxVersion(k)); v != kv {
+		drift = append(drift, fmt.Sprintf("maximum version %s, want %s", v, kv))
+	}
+	drift = appendListDrift(drift, "cipher suites", withoutGREASE(m.cipherSuites), withoutGREASE(k.cipherSuites), true)
+
+	isPadding := func(e uint16) bool { return e == utlsExtensionPadding }
+	exts := slices.DeleteFunc(withoutGREASE(m.extensions), isPadding)
+	knownExts := slices.DeleteFunc(withoutGREASE(k.extensions), isPadding)
+	drift = appendListDrift(drift, "extensions", exts, knownExts, !known.shuffled)
+	if has, want := slices.Contains(m.extensions, utlsExtensionPadding), slices.Contains(k.extensions, utlsExtensionPadding); has != want {
+		if want {
+			drift = append(drift, "missing padding extension")
+		} else {
+			drift = append(drift, "unexpected padding extension")
+		}
+	}
+
+	drift = appendListDrift(drift, "supported groups", withoutGREASE(m.supportedCurves), withoutGREASE(k.supportedCurves), true)
+	drift = appendListDrift(drift, "supported versions", withoutGREASE(m.supportedVersions), withoutGREASE(k.supportedVersions), true)
+	drift = appendListDrift(drift, "signature algorithms", m.supportedSignatureAlgorithms, k.supportedSignatureAlgorithms, true)
+	drift = appendListDrift(drift, "ALPN protocols", m.alpnProtocols, k.alpnProtocols, true)
+
+	layout, knownLayout := greaseLayout(m), greaseLayout(k)
+	for _, pos := range knownLayout {
+		if !slices.Contains(layout, pos) {
+			drift = append(drift, "no GREASE at "+pos)
+		}
+	}
+	for _, pos := range layout {
+		if !slices.Contains(knownLayout, pos) {
+			drift = append(drift, "unexpected GREASE at "+pos)
+		}
+	}
+	return drift
+}
+
+// appendListDrift appends to drift the values missing from and extra in list
+// compared to want, and, if ordered is set and there are none, whether they
+// are in a different order.
+func appendListDrift[E comparable](drift []string, name string, list, want []E, ordered bool) []string {
+	var missing, extra []E
+	for _, v := range want {
+		if !slices.Contains(list, v) {
+			missing = append(missing, v)
+		}
+	}
+	for _, v := range list {
+		if !slices.Contains(want, v) {
+			extra = append(extra, v)
+		}
+	}
+	if len(missing) > 0 {
+		drift = append(drift, fmt.Sprintf("missing %s %v", name, missing))
+	}
+	if len(extra) > 0 {
+		drift = append(drift, fmt.Sprintf("extra %s %v", name, extra))
+	}
+	if len(missing) == 0 && len(extra) == 0 && ordered && !slices.Equal(list, want) {
+		drift = append(drift, name+" in a different order")
+	}
+	return drift
+}
+
+// greaseLayout returns where the GREASE values of m are, e.g.
+// "cipher_suites[0]" or "extensions[last]".
+func greaseLayout(m *clientHelloMsg) []string {
+	var layout []string
+	add := func(field string, values []uint16) {
+		for i, v := range values {
+			if !isGREASEUint16(v) {
+				continue
+			}
+			pos := fmt.Sprint(i)
+			if i == len(values)-1 && i > 0 {
+				pos = "last"
+			}
+			layout = append(layout, field+"["+pos+"]")
+		}
+	}
+	add("cipher_suites", m.cipherSuites)
+	add("extensions", m.extensions)
+	add("supported_groups", convertSlice[uint16](m.supportedCurves))
+	add("supported_versions", m.supportedVersions)
+	groups := make([]uint16, len(m.keyShares))
+	for i, ks := range m.keyShares {
+		groups[i] = uint16(ks.group)
+	}
+	add("key_share", groups)
+	return layout
+}
+
+// maxVersion returns the highest version offered by m.
+func maxVersion(m *clientHelloMsg) uint16 {
+	vers := m.vers
+	for _, v := range m.supportedVersions {
+		if !isGREASEUint16(v) && v > vers {
+			vers = v
+		}
+	}
+	return vers
+}
+
+func withoutGREASE[S ~[]E, E ~uint16](s S) S {
+	return slices.DeleteFunc(slices.Clone(s), func(v E) bool { return isGREASEUint16(uint16(v)) })
+}
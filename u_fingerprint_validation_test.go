@@ -0,0 +1,52 @@
+package tls
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateAgainstKnownFingerprints(t *testing.T) {
+	for _, id := range []ClientHelloID{HelloFirefox_120, HelloChrome_120, HelloOkHttp_4} {
+		spec, err := UTLSIdToSpec(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := ValidateAgainstKnownFingerprints(&spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !v.Matches || len(v.Drift) != 0 {
+			t.Errorf("%s: spec doesn't match a known fingerprint: closest %s, drift %q", id.Str(), v.Client.Str(), v.Drift)
+		}
+	}
+
+	for _, test := range []struct {
+		id    ClientHelloID
+		edit  func(TLSExtension) bool
+		drift string
+	}{
+		{HelloChrome_70, func(e TLSExtension) bool {
+			_, ok := e.(*UtlsPaddingExtension)
+			return ok
+		}, "missing padding extension"},
+		{HelloChrome_120, func(e TLSExtension) bool {
+			_, ok := e.(*UtlsGREASEExtension)
+			return ok
+		}, "no GREASE at extensions[0]"},
+	} {
+		spec, err := UTLSIdToSpec(test.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		spec.Extensions = slices.DeleteFunc(spec.Extensions, test.edit)
+		v, err := ValidateAgainstKnownFingerprints(&spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v.Matches {
+			t.Errorf("%s: drifted spec matches %s", test.id.Str(), v.Client.Str())
+		} else if !slices.Contains(v.Drift, test.drift) || v.Client.Client != helloChrome {
+			t.Errorf("%s: closest %s with drift %q, want a Chrome one with %q", test.id.Str(), v.Client.Str(), v.Drift, test.drift)
+		}
+	}
+}
@@ -7,7 +7,10 @@ package tls
 // Fingerprinter is a struct largely for holding options for the FingerprintClientHello func
 type Fingerprinter struct {
 	// AllowBluntMimicry will ensure that unknown extensions are
-	// passed along into the resulting ClientHelloSpec as-is
+	// passed along into the resulting ClientHelloSpec as-is, as
+	// GenericExtensions holding a copy of their exact bytes, so that
+	// arbitrary captured ClientHellos can be replayed faithfully. Known
+	// extensions whose contents can't be parsed are kept the same way.
 	// WARNING: there could be numerous subtle issues with ClientHelloSpecs
 	// that are generated with this flag which could compromise security and/or mimicry
 	AllowBluntMimicry bool
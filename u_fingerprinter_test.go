@@ -729,3 +729,50 @@ func TestFingerprintDumpLargerThanExtensions(t *testing.T) {
 		t.Error("clientHelloSpec cannot be nil")
 	}
 }
+
+func TestUTLSFingerprintClientHelloBluntMimicryUnparsable(t *testing.T) {
+	// An ALPN extension whose protocol list overflows, and an unknown one.
+	badALPN := []byte{0x00, 0x05, 0x02, 'h', '2'}
+	unknown := []byte("opaque")
+	spec := &ClientHelloSpec{
+		CipherSuites:       []uint16{TLS_AES_128_GCM_SHA256},
+		CompressionMethods: []uint8{compressionNone},
+		Extensions: []TLSExtension{
+			&SNIExtension{},
+			&GenericExtension{Id: extensionALPN, Data: badALPN},
+			&GenericExtension{Id: 0xfeed, Data: unknown},
+			&SupportedVersionsExtension{Versions: []uint16{VersionTLS13}},
+			&KeyShareExtension{KeyShares: []KeyShare{{Group: X25519}}},
+		},
+	}
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloCustom)
+	if err := uconn.ApplyPreset(spec); err != nil {
+		t.Fatal(err)
+	}
+	if err := uconn.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	raw := prependRecordHeader(uconn.HandshakeState.Hello.Raw, VersionTLS10)
+
+	if _, err := (&Fingerprinter{}).FingerprintClientHello(raw); err == nil {
+		t.Error("unparsable extension accepted without blunt mimicry")
+	}
+	generated, err := (&Fingerprinter{AllowBluntMimicry: true}).FingerprintClientHello(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range raw {
+		raw[i] = 0
+	}
+	alpn, ok := generated.Extensions[1].(*GenericExtension)
+	if !ok || alpn.Id != extensionALPN || !bytes.Equal(alpn.Data, badALPN) {
+		t.Errorf("unparsable ALPN extension not preserved: %#v", generated.Extensions[1])
+	}
+	opaque, ok := generated.Extensions[2].(*GenericExtension)
+	if !ok || opaque.Id != 0xfeed || !bytes.Equal(opaque.Data, unknown) {
+		t.Errorf("unknown extension not preserved: %#v", generated.Extensions[2])
+	}
+	if generated.TLSVersMax != 0 {
+		t.Errorf("TLSVersMax = %x, want it taken from supported_versions", generated.TLSVersMax)
+	}
+}
@@ -0,0 +1,93 @@
+package tls
+
+import (
+	"bytes"
+	"errors"
+	"net"
+)
+
+// FirstFlight builds the ClientHello if needed and returns the records the
+// handshake starts by writing, so that they can be delivered while the
+// connection is being established, e.g. in the SYN of a TCP Fast Open
+// connection or as part of a custom transport handshake.
+//
+// The UConn may be created by UClient with a nil net.Conn, in which case no
+// other method than BuildHandshakeState, FirstFlight and Attach may be called
+// until Attach sets the connection.
+func (uconn *UConn) FirstFlight() ([]byte, error) {
+	flight, err := uconn.buildFirstFlight()
+	if err != nil {
+		return nil, err
+	}
+	uconn.firstFlight = flight
+	return bytes.Clone(flight), nil
+}
+
+// buildFirstFlight builds the ClientHello if needed and returns the records it
+// is written in.
+func (uconn *UConn) buildFirstFlight() ([]byte, error) {
+	if uconn.quic != nil {
+		return nil, errors.New("tls: the first flight is not available over QUIC")
+	}
+	if err := uconn.BuildHandshakeState(); err != nil {
+		return nil, err
+	}
+	hello := uconn.HandshakeState.Hello.Raw
+	if len(hello) == 0 {
+		hello, _ = uconn.HandshakeState.Hello.getPrivatePtr().marshal()
+	}
+	if len(hello) == 0 {
+		return nil, errors.New("tls: ClientHello is not marshaled")
+	}
+
+	// Frame the ClientHello like writeRecordLocked does before any version is
+	// negotiated.
+	var flight []byte
+	for len(hello) > 0 {
+		m := min(len(hello), maxPlaintext)
+		flight = append(flight, byte(recordTypeHandshake), byte(VersionTLS10>>8), byte(VersionTLS10&0xff), byte(m>>8), byte(m))
+		flight = append(flight, hello[:m]...)
+		hello = hello[m:]
+	}
+	return flight, nil
+}
+
+// Attach sets the connection of a UConn created with a nil net.Conn. If
+// firstFlightSent is true, the bytes returned by FirstFlight were already
+// delivered through conn and the handshake doesn't write them again. It fails
+// if the handshake then starts differently, e.g. because the UConn was
+// modified after FirstFlight.
+func (uconn *UConn) Attach(conn net.Conn, firstFlightSent bool) error {
+	if conn == nil {
+		return errors.New("tls: cannot attach a nil connection")
+	}
+	if uconn.conn != nil {
+		return errors.New("tls: UConn already has a connection")
+	}
+	if firstFlightSent {
+		if uconn.firstFlight == nil {
+			return errors.New("tls: first flight sent before calling FirstFlight")
+		}
+		uconn.utls.sentFirstFlight = uconn.firstFlight
+	}
+	uconn.conn = conn
+	return nil
+}
+
+// writeAfterFirstFlight writes data to the connection, skipping its prefix
+// that was already sent as the first flight.
+func (c *Conn) writeAfterFirstFlight(data []byte) (int, error) {
+	sent := c.utls.sentFirstFlight
+	n := min(len(data), len(sent))
+	if !bytes.Equal(data[:n], sent[:n]) {
+		return 0, errors.New("tls: handshake records differ from the first flight already sent")
+	}
+	c.utls.sentFirstFlight = sent[n:]
+	c.bytesSent += int64(n)
+	if n == len(data) {
+		return n, nil
+	}
+	m, err := c.conn.Write(data[n:])
+	c.bytesSent += int64(m)
+	return n + m, err
+}
@@ -0,0 +1,97 @@
+package tls
+
+import (
+	"time"
+)
+
+// FirstFlightPlan describes how the first flight of a connection, see
+// UConn.FirstFlight, will be emitted.
+type FirstFlightPlan struct {
+	// Length is the total length of the first flight, record headers
+	// included.
+	Length int
+
+	// Records are the records of the first flight, in order.
+	Records []PlannedRecord
+
+	// Writes are the writes of the first flight to the underlying
+	// connection of the UConn, once split by its transport shaping, see
+	// ShapeConn. The kernel may further split each write in segments of the
+	// maximum segment size of the connection.
+	Writes []PlannedWrite
+}
+
+// PlannedRecord is a record of a FirstFlightPlan.
+type PlannedRecord struct {
+	// Type is the content type of the record, 22 (handshake) for the
+	// records carrying the ClientHello.
+	Type uint8
+
+	// Version is the legacy version in the record header.
+	Version uint16
+
+	// Length is the length of the record fragment, not including the 5
+	// bytes header.
+	Length int
+}
+
+// PlannedWrite is a write to the underlying connection of a FirstFlightPlan.
+type PlannedWrite struct {
+	// Offset and Length locate the written bytes in the first flight.
+	Offset int
+	Length int
+
+	// Delay is how long the write is delayed, after the previous one or,
+	// for the first one, after the handshake starts, e.g. by the handshake
+	// jitter of a Profile or the split delay of a TransportShaping.
+	Delay time.Duration
+}
+
+// PlanFirstFlight builds the ClientHello if needed and returns how the first
+// flight will be emitted with the current configuration of uconn, without
+// writing anything, so that record and transport policies such as ClientHello
+// padding, Profile transport shaping and handshake jitter can be validated
+// offline. It may be called before FirstFlight and Attach, and must be called
+// after the underlying connection is wrapped, e.g. by Profile.Apply, for the
+// writes to reflect the shaping.
+//
+// Like with FirstFlight, the UConn must not be modified after the plan is
+// made, or the handshake may start differently.
+func (uconn *UConn) PlanFirstFlight() (*FirstFlightPlan, error) {
+	flight, err := uconn.buildFirstFlight()
+	if err != nil {
+		return nil, err
+	}
+	plan := &FirstFlightPlan{Length: len(flight)}
+	for b := flight; len(b) >= recordHeaderLen; {
+		n := int(b[3])<<8 | int(b[4])
+		plan.Records = append(plan.Records, PlannedRecord{
+			Type:    b[0],
+			Version: uint16(b[1])<<8 | uint16(b[2]),
+			Length:  n,
+		})
+		b = b[recordHeaderLen+n:]
+	}
+
+	// Follow the wrappers of the underlying connection that change how the
+	// first write is emitted.
+	first := PlannedWrite{Length: len(flight), Delay: time.Duration(uconn.utls.handshakeJitter.Load())}
+	var second *PlannedWrite
+	for conn := uconn.GetUnderlyingConn(); conn != nil; {
+		switch c := conn.(type) {
+		case *shapedConn:
+			if at := serverNameSplitOffset(flight); at != 0 && !c.written.Load() && second == nil {
+				first.Length = at
+				second = &PlannedWrite{Offset: at, Length: len(flight) - at, Delay: c.delay}
+			}
+			conn = c.Conn
+			continue
+		}
+		break
+	}
+	plan.Writes = append(plan.Writes, first)
+	if second != nil {
+		plan.Writes = append(plan.Writes, *second)
+	}
+	return plan, nil
+}
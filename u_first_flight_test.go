@@ -0,0 +1,148 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/x509"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUTLSFirstFlight(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate)
+
+	for _, test := range []struct {
+		name    string
+		id      ClientHelloID
+		sent    bool
+		corrupt bool
+	}{
+		{"sent", HelloChrome_Auto, true, false},
+		{"sent golang", HelloGolang, true, false},
+		{"not sent", HelloFirefox_Auto, false, false},
+		{"modified", HelloChrome_Auto, true, true},
+	} {
+		// The client is created before there is a connection.
+		uconn := UClient(nil, &Config{ServerName: "localhost", RootCAs: roots}, test.id)
+		flight, err := uconn.FirstFlight()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if !bytes.HasPrefix(flight, []byte{byte(recordTypeHandshake), 3, 1}) || !bytes.Contains(flight, uconn.HandshakeState.Hello.Raw) {
+			t.Errorf("%s: first flight is not a ClientHello record", test.name)
+		}
+
+		clientEnd, serverEnd := memPipe()
+		serverConn := Server(serverEnd, s.Config)
+		serverErr := make(chan error, 1)
+		go func() {
+			err := serverConn.Handshake()
+			serverConn.Close()
+			serverErr <- err
+		}()
+
+		if test.sent {
+			// Like the payload of a TCP Fast Open SYN.
+			if _, err := clientEnd.Write(flight); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := uconn.Attach(clientEnd, test.sent); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if err := uconn.Attach(clientEnd, test.sent); err == nil {
+			t.Errorf("%s: attached a connection twice", test.name)
+		}
+		if test.corrupt {
+			uconn.utls.sentFirstFlight = bytes.Clone(uconn.utls.sentFirstFlight)
+			uconn.utls.sentFirstFlight[len(flight)-1] ^= 0xff
+		}
+
+		err = uconn.Handshake()
+		uconn.Close()
+		<-serverErr
+		if test.corrupt {
+			if err == nil || !strings.Contains(err.Error(), "first flight") {
+				t.Errorf("%s: expected an error about the first flight, got %v", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: handshake failed: %v", test.name, err)
+		}
+	}
+}
+
+func TestUTLSFirstFlightWithoutConnection(t *testing.T) {
+	uconn := UClient(nil, &Config{ServerName: "example.com"}, HelloChrome_Auto)
+	if err := uconn.Handshake(); err == nil {
+		t.Error("handshake without a connection succeeded")
+	}
+	if err := uconn.Attach(&memConn{}, true); err == nil {
+		t.Error("attached with a first flight sent before calling FirstFlight")
+	}
+	if err := uconn.Close(); err != nil {
+		t.Errorf("Close without a connection: %v", err)
+	}
+}
+
+func TestUTLSPlanFirstFlight(t *testing.T) {
+	p, err := NewProfile("chrome", HelloChrome_120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Transport = &TransportShaping{SplitServerName: true, SplitDelay: time.Millisecond}
+	p.HandshakeJitter = time.Millisecond
+
+	rec := &writeRecorder{}
+	uconn := UClient(rec, &Config{ServerName: "example.com"}, HelloCustom)
+	if err := p.Apply(uconn); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := uconn.PlanFirstFlight()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Records) != 1 || plan.Records[0].Type != byte(recordTypeHandshake) ||
+		plan.Records[0].Version != VersionTLS10 || plan.Records[0].Length != len(uconn.HandshakeState.Hello.Raw) {
+		t.Errorf("records = %+v", plan.Records)
+	}
+	if len(plan.Writes) != 2 || plan.Writes[0].Delay > time.Millisecond || plan.Writes[1].Delay != time.Millisecond {
+		t.Fatalf("writes = %+v", plan.Writes)
+	}
+
+	// The plan matches the writes of the first flight.
+	flight, err := uconn.FirstFlight()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Length != len(flight) {
+		t.Errorf("planned length %d, want %d", plan.Length, len(flight))
+	}
+	if _, err := uconn.GetUnderlyingConn().Write(flight); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.writes) != len(plan.Writes) {
+		t.Fatalf("got %d writes, planned %d", len(rec.writes), len(plan.Writes))
+	}
+	for i, w := range plan.Writes {
+		if !bytes.Equal(rec.writes[i], flight[w.Offset:w.Offset+w.Length]) {
+			t.Errorf("write %d doesn't match the plan %+v", i, w)
+		}
+	}
+
+	// Without shaping, the flight is written at once.
+	uconn = UClient(nil, &Config{ServerName: "example.com"}, HelloChrome_120)
+	if plan, err = uconn.PlanFirstFlight(); err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Writes) != 1 || plan.Writes[0].Length != plan.Length || plan.Writes[0].Delay != 0 {
+		t.Errorf("writes = %+v", plan.Writes)
+	}
+}
@@ -0,0 +1,200 @@
+package tls
+
+import (
+	"bytes"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxForensicsRecordBytes bounds the records captured by a HandshakeForensics,
+// so that a peer can't make a failing handshake buffer unbounded data.
+const maxForensicsRecordBytes = 256 << 10
+
+// HandshakeForensics is a record of a failed handshake, captured if
+// Config.HandshakeForensics is set, to debug handshakes failing remotely, e.g.
+// because a middlebox blocks a fingerprint. It's retrieved from the error
+// returned by the handshake with errors.As and a *HandshakeForensicsError.
+type HandshakeForensics struct {
+	// Start is when the handshake started, and Duration how long it ran
+	// until it failed.
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+
+	// Stage is the last handshake message sent or received, e.g.
+	// "received ServerHello", or "started" if there was none.
+	Stage string `json:"stage"`
+
+	// Version is the negotiated version, or zero if the handshake failed
+	// before it was.
+	Version uint16 `json:"version,omitempty"`
+
+	// SentAlert and ReceivedAlert are the last alerts sent to and received
+	// from the peer, if any.
+	SentAlert     *AlertError `json:"sent_alert,omitempty"`
+	ReceivedAlert *AlertError `json:"received_alert,omitempty"`
+
+	// Messages are the handshake messages sent and received, in order.
+	Messages []HandshakeForensicsMessage `json:"messages"`
+
+	// Records are the raw records sent and received, in order, as they were
+	// on the wire. Records protected by the handshake keys are encrypted.
+	// Truncated is set if some records were dropped because the records
+	// captured exceeded 256 KiB.
+	Records   []HandshakeForensicsRecord `json:"records"`
+	Truncated bool                       `json:"truncated,omitempty"`
+
+	// Error is the handshake error.
+	Error string `json:"error"`
+}
+
+// HandshakeForensicsMessage is a handshake message of a HandshakeForensics.
+type HandshakeForensicsMessage struct {
+	Sent bool          `json:"sent"`
+	Time time.Duration `json:"time"` // since HandshakeForensics.Start
+	Type uint8         `json:"type"`
+}
+
+// HandshakeForensicsRecord is a record of a HandshakeForensics, including its
+// header.
+type HandshakeForensicsRecord struct {
+	Sent bool          `json:"sent"`
+	Time time.Duration `json:"time"` // since HandshakeForensics.Start
+	Data []byte        `json:"data"`
+}
+
+// HandshakeForensicsError wraps the error of a failed handshake if
+// Config.HandshakeForensics is set.
+type HandshakeForensicsError struct {
+	Err       error
+	Forensics *HandshakeForensics
+}
+
+func (e *HandshakeForensicsError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *HandshakeForensicsError) Unwrap() error {
+	return e.Err
+}
+
+// forensicsRecorder captures the HandshakeForensics of a handshake in
+// progress.
+type forensicsRecorder struct {
+	mu          sync.Mutex
+	f           HandshakeForensics
+	recordBytes int
+}
+
+// startForensics starts capturing the handshake if Config.HandshakeForensics
+// is set.
+func (c *Conn) startForensics() {
+	if !c.config.HandshakeForensics {
+		return
+	}
+	c.utls.forensics = &forensicsRecorder{f: HandshakeForensics{Start: time.Now()}}
+}
+
+// finishForensics stops capturing the handshake and, if it failed with err,
+// returns err wrapped in a *HandshakeForensicsError.
+func (c *Conn) finishForensics(err error) error {
+	r := c.utls.forensics
+	if r == nil {
+		return err
+	}
+	c.utls.forensics = nil
+	if err == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := &r.f
+	f.Duration = time.Since(f.Start)
+	f.Version = c.vers
+	f.Error = err.Error()
+	f.Stage = "started"
+	if len(f.Messages) > 0 {
+		m := f.Messages[len(f.Messages)-1]
+		if m.Sent {
+			f.Stage = "sent " + handshakeMessageName(m.Type)
+		} else {
+			f.Stage = "received " + handshakeMessageName(m.Type)
+		}
+	}
+	return &HandshakeForensicsError{Err: err, Forensics: f}
+}
+
+func (c *Conn) forensicsRecord(sent bool, record []byte) {
+	r := c.utls.forensics
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.recordBytes+len(record) > maxForensicsRecordBytes {
+		r.f.Truncated = true
+		return
+	}
+	r.recordBytes += len(record)
+	r.f.Records = append(r.f.Records, HandshakeForensicsRecord{
+		Sent: sent,
+		Time: time.Since(r.f.Start),
+		Data: bytes.Clone(record),
+	})
+}
+
+func (c *Conn) forensicsMessage(sent bool, typ uint8) {
+	r := c.utls.forensics
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.f.Messages = append(r.f.Messages, HandshakeForensicsMessage{
+		Sent: sent,
+		Time: time.Since(r.f.Start),
+		Type: typ,
+	})
+}
+
+func (c *Conn) forensicsAlert(sent bool, a alert) {
+	r := c.utls.forensics
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e := AlertError(a)
+	if sent {
+		r.f.SentAlert = &e
+	} else {
+		r.f.ReceivedAlert = &e
+	}
+}
+
+var handshakeMessageNames = map[uint8]string{
+	typeHelloRequest:              "HelloRequest",
+	typeClientHello:               "ClientHello",
+	typeServerHello:               "ServerHello",
+	typeNewSessionTicket:          "NewSessionTicket",
+	typeEndOfEarlyData:            "EndOfEarlyData",
+	typeEncryptedExtensions:       "EncryptedExtensions",
+	typeCertificate:               "Certificate",
+	typeServerKeyExchange:         "ServerKeyExchange",
+	typeCertificateRequest:        "CertificateRequest",
+	typeServerHelloDone:           "ServerHelloDone",
+	typeCertificateVerify:         "CertificateVerify",
+	typeClientKeyExchange:         "ClientKeyExchange",
+	typeFinished:                  "Finished",
+	typeCertificateStatus:         "CertificateStatus",
+	typeKeyUpdate:                 "KeyUpdate",
+	utlsTypeCompressedCertificate: "CompressedCertificate",
+}
+
+func handshakeMessageName(typ uint8) string {
+	if name, ok := handshakeMessageNames[typ]; ok {
+		return name
+	}
+	return "handshake message " + strconv.Itoa(int(typ))
+}
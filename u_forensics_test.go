@@ -0,0 +1,62 @@
+package tls
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHandshakeForensics(t *testing.T) {
+	s, err := NewUTLSServer(&Config{MinVersion: VersionTLS13})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// HelloDotNet_6 doesn't offer TLS 1.3, so the server aborts after the
+	// ClientHello.
+	c := NewUnstartedUTLSClient(s, &Config{HandshakeForensics: true}, HelloDotNet_6)
+	err = c.Start()
+	var ferr *HandshakeForensicsError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("handshake error %v doesn't carry forensics", err)
+	}
+	f := ferr.Forensics
+	if f.Stage != "sent ClientHello" {
+		t.Errorf("Stage = %q, want %q", f.Stage, "sent ClientHello")
+	}
+	if f.ReceivedAlert == nil || alert(*f.ReceivedAlert) != alertProtocolVersion {
+		t.Errorf("ReceivedAlert = %v, want %v", f.ReceivedAlert, alertProtocolVersion)
+	}
+	if f.SentAlert != nil {
+		t.Errorf("unexpected SentAlert %v", f.SentAlert)
+	}
+	if len(f.Messages) != 1 || !f.Messages[0].Sent || f.Messages[0].Type != typeClientHello {
+		t.Errorf("unexpected messages %+v", f.Messages)
+	}
+	if len(f.Records) != 2 {
+		t.Fatalf("captured %d records, want 2", len(f.Records))
+	}
+	if r := f.Records[0]; !r.Sent || recordType(r.Data[0]) != recordTypeHandshake || r.Data[recordHeaderLen] != typeClientHello {
+		t.Errorf("first record is not the ClientHello: %x", r.Data)
+	}
+	if r := f.Records[1]; r.Sent || recordType(r.Data[0]) != recordTypeAlert || r.Time < f.Records[0].Time {
+		t.Errorf("second record is not the received alert: %+v", r)
+	}
+	if f.Duration <= 0 || f.Error == "" {
+		t.Errorf("incomplete forensics %+v", f)
+	}
+
+	s2, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s2.Close()
+	c, err = NewUTLSClient(s2, &Config{HandshakeForensics: true}, HelloChrome_Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.utls.forensics != nil {
+		t.Error("forensics still captured after a successful handshake")
+	}
+}
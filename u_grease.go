@@ -0,0 +1,98 @@
+package tls
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// GREASEField is the part of the handshake in which a server negotiated a
+// GREASE value.
+type GREASEField uint8
+
+const (
+	GREASECipherSuite GREASEField = iota + 1
+	GREASEVersion
+	GREASEGroup
+	GREASEExtension
+	GREASEALPNProtocol
+)
+
+func (f GREASEField) String() string {
+	switch f {
+	case GREASECipherSuite:
+		return "cipher suite"
+	case GREASEVersion:
+		return "version"
+	case GREASEGroup:
+		return "group"
+	case GREASEExtension:
+		return "extension"
+	case GREASEALPNProtocol:
+		return "ALPN protocol"
+	default:
+		return fmt.Sprintf("GREASEField(%d)", uint8(f))
+	}
+}
+
+// GREASESelectedError is returned by a client handshake aborted because the
+// server selected one of the GREASE values offered in the ClientHello, or sent
+// a GREASE extension. GREASE values are reserved so that servers ignore them
+// (RFC 8701), so this indicates a broken server or, more likely, active
+// interference by a middlebox replaying or forging messages.
+type GREASESelectedError struct {
+	Field GREASEField
+	Value uint16
+}
+
+func (e *GREASESelectedError) Error() string {
+	return fmt.Sprintf("tls: server negotiated GREASE %s 0x%04x", e.Field, e.Value)
+}
+
+// rejectGREASE aborts the handshake because the server negotiated a GREASE
+// value.
+func (c *Conn) rejectGREASE(field GREASEField, value uint16) error {
+	c.sendAlert(alertIllegalParameter)
+	return &GREASESelectedError{Field: field, Value: value}
+}
+
+// rejectGREASEExtensions aborts the handshake if extTypes, the extensions sent
+// by the server, include a GREASE extension.
+func (c *Conn) rejectGREASEExtensions(extTypes []uint16) error {
+	for _, ext := range extTypes {
+		if isGREASEUint16(ext) {
+			c.sendAlert(alertUnsupportedExtension)
+			return &GREASESelectedError{Field: GREASEExtension, Value: ext}
+		}
+	}
+	return nil
+}
+
+// reportGREASESelected calls Config.OnGREASESelected if err, the result of a
+// handshake, is a GREASESelectedError.
+func (c *Conn) reportGREASESelected(err error) {
+	if c.config.OnGREASESelected == nil {
+		return
+	}
+	var gerr *GREASESelectedError
+	if errors.As(err, &gerr) {
+		c.config.OnGREASESelected(gerr)
+	}
+}
+
+// greaseRand returns the source of the GREASE values of a ClientHello.
+func (c *Config) greaseRand() (io.Reader, error) {
+	if c.GREASESeed == nil {
+		return c.rand(), nil
+	}
+	return newPRNGWithSaltedSeed(c.GREASESeed, "GREASE")
+}
+
+// greaseRand returns the source of the GREASE values of the ClientHello: the
+// seed of a seeded randomized ClientHelloID, unless Config.GREASESeed is set.
+func (uconn *UConn) greaseRand() (io.Reader, error) {
+	if uconn.config.GREASESeed == nil && uconn.ClientHelloID.Seed != nil {
+		return newPRNGWithSaltedSeed(uconn.ClientHelloID.Seed, "GREASE")
+	}
+	return uconn.config.greaseRand()
+}
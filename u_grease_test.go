@@ -0,0 +1,151 @@
+package tls
+
+import (
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+// testGREASEServer answers the ClientHello read from serverEnd with the
+// ServerHello returned by makeServerHello.
+func testGREASEServer(t *testing.T, field GREASEField, makeServerHello func(*clientHelloMsg) []byte) {
+	clientEnd, serverEnd := memPipe()
+	defer serverEnd.Close()
+	var reported []*GREASESelectedError
+	config := &Config{
+		ServerName: "example.com",
+		RootCAs:    x509.NewCertPool(),
+		OnGREASESelected: func(err *GREASESelectedError) {
+			reported = append(reported, err)
+		},
+	}
+	uconn := UClient(clientEnd, config, HelloChrome_Auto)
+
+	go func() {
+		hdr := make([]byte, recordHeaderLen)
+		if _, err := io.ReadFull(serverEnd, hdr); err != nil {
+			return
+		}
+		body := make([]byte, int(hdr[3])<<8|int(hdr[4]))
+		if _, err := io.ReadFull(serverEnd, body); err != nil {
+			return
+		}
+		hello := new(clientHelloMsg)
+		if !hello.unmarshal(body) {
+			return
+		}
+		serverHello := makeServerHello(hello)
+		record := append([]byte{byte(recordTypeHandshake), 3, 3, byte(len(serverHello) >> 8), byte(len(serverHello))}, serverHello...)
+		serverEnd.Write(record)
+		io.Copy(io.Discard, serverEnd)
+	}()
+
+	err := uconn.Handshake()
+	var gerr *GREASESelectedError
+	if !errors.As(err, &gerr) || gerr.Field != field || !isGREASEUint16(gerr.Value) {
+		t.Errorf("%v: expected a GREASESelectedError, got %v", field, err)
+	}
+	if len(reported) != 1 || reported[0] != gerr {
+		t.Errorf("%v: OnGREASESelected called with %v", field, reported)
+	}
+	uconn.Close()
+}
+
+func marshalTestServerHello(t *testing.T, m *serverHelloMsg) []byte {
+	m.random = make([]byte, 32)
+	b, err := m.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestGREASESelected(t *testing.T) {
+	testGREASEServer(t, GREASECipherSuite, func(hello *clientHelloMsg) []byte {
+		return marshalTestServerHello(t, &serverHelloMsg{
+			vers:        VersionTLS12,
+			sessionId:   hello.sessionId,
+			cipherSuite: hello.cipherSuites[0],
+		})
+	})
+	testGREASEServer(t, GREASEVersion, func(hello *clientHelloMsg) []byte {
+		return marshalTestServerHello(t, &serverHelloMsg{
+			vers:             VersionTLS12,
+			sessionId:        hello.sessionId,
+			cipherSuite:      TLS_AES_128_GCM_SHA256,
+			supportedVersion: hello.supportedVersions[0],
+		})
+	})
+	testGREASEServer(t, GREASEGroup, func(hello *clientHelloMsg) []byte {
+		return marshalTestServerHello(t, &serverHelloMsg{
+			vers:             VersionTLS12,
+			sessionId:        hello.sessionId,
+			cipherSuite:      TLS_AES_128_GCM_SHA256,
+			supportedVersion: VersionTLS13,
+			serverShare:      keyShare{group: hello.keyShares[0].group, data: []byte{0}},
+		})
+	})
+	testGREASEServer(t, GREASEExtension, func(hello *clientHelloMsg) []byte {
+		b := marshalTestServerHello(t, &serverHelloMsg{
+			vers:        VersionTLS12,
+			sessionId:   hello.sessionId,
+			cipherSuite: TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		})
+		// Echo the first GREASE extension of the ClientHello.
+		ext := hello.extensions[0]
+		b = append(b, 0, 4, byte(ext>>8), byte(ext), 0, 0)
+		n := len(b) - 4
+		b[1], b[2], b[3] = byte(n>>16), byte(n>>8), byte(n)
+		return b
+	})
+}
+
+func TestGREASESeed(t *testing.T) {
+	greaseSeed := func(seed *PRNGSeed) [ssl_grease_last_index]uint16 {
+		uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com", GREASESeed: seed}, HelloChrome_Auto)
+		if err := uconn.BuildHandshakeState(); err != nil {
+			t.Fatal(err)
+		}
+		return uconn.greaseSeed
+	}
+	seed, err := NewPRNGSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if greaseSeed(seed) != greaseSeed(seed) {
+		t.Error("connections sharing a GREASESeed sent different GREASE values")
+	}
+	// Without a seed, collisions of all the values are vanishingly unlikely.
+	if greaseSeed(nil) == greaseSeed(nil) {
+		t.Error("connections without a GREASESeed sent the same GREASE values")
+	}
+}
+
+func TestGREASESeedALPN(t *testing.T) {
+	seed, err := NewPRNGSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	alpn := func() string {
+		uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com", GREASESeed: seed}, HelloCustom)
+		if err := uconn.ApplyPreset(greaseALPNTestSpec(t, GREASE_ALPN_PLACEHOLDER, "h2")); err != nil {
+			t.Fatal(err)
+		}
+		if err := uconn.BuildHandshakeState(); err != nil {
+			t.Fatal(err)
+		}
+		m := new(clientHelloMsg)
+		if !m.unmarshal(uconn.HandshakeState.Hello.Raw) || len(m.alpnProtocols) != 2 || !isGREASEALPN(m.alpnProtocols[0]) {
+			t.Fatalf("ClientHello offers ALPN protocols %q, want a GREASE value first", m.alpnProtocols)
+		}
+		return m.alpnProtocols[0]
+	}
+	first := alpn()
+	for i := 0; i < 8; i++ {
+		if got := alpn(); got != first {
+			t.Fatalf("connections sharing a GREASESeed sent the GREASE ALPN protocols %x and %x", first, got)
+		}
+	}
+}
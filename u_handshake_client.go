@@ -94,6 +94,11 @@ func (hs *clientHandshakeStateTLS13) decompressCert(m utlsCompressedCertificateM
 		return nil, fmt.Errorf("unsupported algorithm (%d)", m.algorithm)
 	}
 
+	if limit := c.config.HandshakeSizeLimits.maxCertificateMessageSize(); int(m.uncompressedLength) > limit {
+		c.sendAlert(alertBadCertificate)
+		return nil, &HandshakeSizeError{Kind: LimitMessageSize, MsgType: typeCertificate, Size: int(m.uncompressedLength), Limit: limit}
+	}
+
 	rawMsg := make([]byte, m.uncompressedLength+4) // +4 for message type and uint24 length field
 	rawMsg[0] = typeCertificate
 	rawMsg[1] = uint8(m.uncompressedLength >> 16)
@@ -116,6 +121,10 @@ func (hs *clientHandshakeStateTLS13) decompressCert(m utlsCompressedCertificateM
 	if !certMsg.unmarshal(rawMsg) {
 		return nil, c.sendAlert(alertUnexpectedMessage)
 	}
+	if err := c.checkHandshakeSizeLimits(certMsg, rawMsg); err != nil {
+		c.sendAlert(alertBadCertificate)
+		return nil, err
+	}
 	return certMsg, nil
 }
 
@@ -490,6 +499,7 @@ func (c *UConn) clientHandshake(ctx context.Context) (err error) {
 
 	c.serverName = hello.serverName
 
+	c.utls.certSignatureAlgorithms = hello.certSignatureAlgorithms() // [uTLS]
 	if _, err := c.writeHandshakeRecord(hello, nil); err != nil {
 		return err
 	}
@@ -520,6 +530,10 @@ func (c *UConn) clientHandshake(ctx context.Context) (err error) {
 		return err
 	}
 
+	if err := c.checkUnsolicitedServerHello(serverHello, hello, ech); err != nil {
+		return err
+	}
+
 	// If we are negotiating a protocol version that's lower than what we
 	// support, check for the server downgrade canaries.
 	// See RFC 8446, Section 4.1.3.
@@ -577,7 +591,7 @@ func (c *UConn) clientHandshake(ctx context.Context) (err error) {
 func (c *UConn) echTranscriptMsg(outer *clientHelloMsg, echCtx *echClientContext) (err error) {
 	// Recreate the inner ClientHello from its compressed form using server's decodeInnerClientHello function.
 	// See https://github.com/refraction-networking/utls/blob/e430876b1d82fdf582efc57f3992d448e7ab3d8a/ech.go#L276-L283
-	encodedInner, err := encodeInnerClientHelloReorderOuterExts(echCtx.innerHello, int(echCtx.config.MaxNameLength), c.extensionsList())
+	encodedInner, err := encodeInnerClientHelloReorderOuterExts(echCtx.innerHello, int(echCtx.config.MaxNameLength), c.echCompressedExtensions())
 	if err != nil {
 		return err
 	}
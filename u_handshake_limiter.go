@@ -0,0 +1,179 @@
+package tls
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrHandshakeQueueFull is returned by handshakes rejected by a
+// HandshakeLimiter whose queue is full.
+var ErrHandshakeQueueFull = errors.New("tls: too many handshakes queued")
+
+// HandshakeLimiter limits the number of concurrent handshakes, protecting
+// relays and servers from handshake storms: key generation, including of post-
+// quantum key shares, and certificate signatures and verifications are
+// expensive enough to starve other work. Set Config.HandshakeLimiter to enable
+// it.
+//
+// A handshake holds its slot until it completes or fails, except while it
+// waits for the peer: the slot is given back before reading from the
+// network and taken again, without queueing limits, once the peer's data
+// arrived. A slow peer thus can't keep a slot busy. Handshakes waiting for a
+// slot give up when their context is done.
+//
+// A HandshakeLimiter is safe for concurrent use. Its limits must not be
+// changed once it is in use.
+type HandshakeLimiter struct {
+	// MaxConcurrent is the number of handshakes allowed to run at once. If
+	// zero, runtime.GOMAXPROCS(0) at first use is used.
+	MaxConcurrent int
+
+	// MaxQueued is the number of handshakes allowed to wait for a slot.
+	// Handshakes beyond it fail immediately with ErrHandshakeQueueFull. If
+	// zero, the queue is unbounded. If negative, handshakes never wait.
+	MaxQueued int
+
+	initOnce sync.Once
+	slots    chan struct{}
+
+	queued                       atomic.Int64
+	admitted, rejected, canceled atomic.Uint64
+	queueTime                    atomic.Int64
+}
+
+// HandshakeLimiterStats are the metrics of a HandshakeLimiter, as returned by
+// HandshakeLimiter.Stats.
+type HandshakeLimiterStats struct {
+	// Running and Queued are the handshakes currently holding and waiting
+	// for a slot. Handshakes waiting for the peer are neither.
+	Running int
+	Queued  int
+
+	// Admitted counts the handshakes that got a slot, Rejected those that
+	// found the queue full, and Canceled those whose context was done while
+	// queued.
+	Admitted uint64
+	Rejected uint64
+	Canceled uint64
+
+	// QueueTime is the total time admitted handshakes waited for a slot.
+	QueueTime time.Duration
+}
+
+// NewHandshakeLimiter returns a HandshakeLimiter with the given limits.
+func NewHandshakeLimiter(maxConcurrent, maxQueued int) *HandshakeLimiter {
+	return &HandshakeLimiter{MaxConcurrent: maxConcurrent, MaxQueued: maxQueued}
+}
+
+func (l *HandshakeLimiter) init() {
+	l.initOnce.Do(func() {
+		n := l.MaxConcurrent
+		if n <= 0 {
+			n = runtime.GOMAXPROCS(0)
+		}
+		l.slots = make(chan struct{}, n)
+	})
+}
+
+// Stats returns the metrics of the limiter.
+func (l *HandshakeLimiter) Stats() HandshakeLimiterStats {
+	l.init()
+	return HandshakeLimiterStats{
+		Running:   len(l.slots),
+		Queued:    int(l.queued.Load()),
+		Admitted:  l.admitted.Load(),
+		Rejected:  l.rejected.Load(),
+		Canceled:  l.canceled.Load(),
+		QueueTime: time.Duration(l.queueTime.Load()),
+	}
+}
+
+// acquire waits for a slot, and returns the function releasing it.
+func (l *HandshakeLimiter) acquire(ctx context.Context) (func(), error) {
+	l.init()
+	select {
+	case l.slots <- struct{}{}:
+		l.admitted.Add(1)
+		return l.release, nil
+	default:
+	}
+
+	n := l.queued.Add(1)
+	defer l.queued.Add(-1)
+	if l.MaxQueued < 0 || (l.MaxQueued > 0 && n > int64(l.MaxQueued)) {
+		l.rejected.Add(1)
+		return nil, ErrHandshakeQueueFull
+	}
+	start := time.Now()
+	select {
+	case l.slots <- struct{}{}:
+		l.queueTime.Add(int64(time.Since(start)))
+		l.admitted.Add(1)
+		return l.release, nil
+	case <-ctx.Done():
+		l.canceled.Add(1)
+		return nil, ctx.Err()
+	}
+}
+
+func (l *HandshakeLimiter) release() {
+	<-l.slots
+}
+
+// handshakeSlot is a slot acquired by a handshake, given back while the
+// handshake waits for the peer.
+type handshakeSlot struct {
+	limiter *HandshakeLimiter
+	ctx     context.Context
+	held    bool
+}
+
+// acquireHandshakeSlot waits for a slot of Config.HandshakeLimiter, if any,
+// and returns the function releasing it.
+func (c *Conn) acquireHandshakeSlot(ctx context.Context) (func(), error) {
+	l := c.config.HandshakeLimiter
+	if l == nil {
+		return func() {}, nil
+	}
+	if _, err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	slot := &handshakeSlot{limiter: l, ctx: ctx, held: true}
+	c.utls.handshakeSlot = slot
+	return func() {
+		c.utls.handshakeSlot = nil
+		if slot.held {
+			l.release()
+		}
+	}, nil
+}
+
+// pauseHandshakeSlot gives back the slot of the handshake in progress, if
+// any, before it waits for the peer.
+func (c *Conn) pauseHandshakeSlot() {
+	if slot := c.utls.handshakeSlot; slot != nil && slot.held {
+		slot.limiter.release()
+		slot.held = false
+	}
+}
+
+// resumeHandshakeSlot takes the slot given back by pauseHandshakeSlot again.
+// The handshake already waited in the queue, so it isn't subject to
+// MaxQueued.
+func (c *Conn) resumeHandshakeSlot() error {
+	slot := c.utls.handshakeSlot
+	if slot == nil || slot.held {
+		return nil
+	}
+	select {
+	case slot.limiter.slots <- struct{}{}:
+		slot.held = true
+		return nil
+	case <-slot.ctx.Done():
+		return slot.ctx.Err()
+	}
+}
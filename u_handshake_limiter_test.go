@@ -0,0 +1,121 @@
+package tls
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHandshakeLimiter(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	limiter := NewHandshakeLimiter(1, 1)
+	config := &Config{HandshakeLimiter: limiter}
+
+	waitFor := func(cond func(HandshakeLimiterStats) bool) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); !cond(limiter.Stats()); {
+			if time.Now().After(deadline) {
+				t.Fatalf("unexpected stats %+v", limiter.Stats())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// The handshake holds the slot while it verifies the server
+	// certificate, which blocks until unblock is closed.
+	unblock := make(chan struct{})
+	stalledConfig := config.Clone()
+	stalledConfig.VerifyPeerCertificate = func([][]byte, [][]*x509.Certificate) error {
+		<-unblock
+		return errors.New("stalled")
+	}
+	stalled := NewUnstartedUTLSClient(s, stalledConfig, HelloChrome_Auto)
+	stalledErr := make(chan error, 1)
+	go func() { stalledErr <- stalled.Start() }()
+	waitFor(func(s HandshakeLimiterStats) bool { return s.Running == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	expired := NewUnstartedUTLSClient(s, config, HelloChrome_Auto)
+	if err := expired.HandshakeContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("queued handshake returned %v after its context expired", err)
+	}
+
+	queued := NewUnstartedUTLSClient(s, config, HelloChrome_Auto)
+	queuedErr := make(chan error, 1)
+	go func() { queuedErr <- queued.Start() }()
+	waitFor(func(s HandshakeLimiterStats) bool { return s.Queued == 1 })
+
+	rejected := NewUnstartedUTLSClient(s, config, HelloChrome_Auto)
+	if err := rejected.Handshake(); err != ErrHandshakeQueueFull {
+		t.Errorf("handshake with a full queue returned %v", err)
+	}
+
+	close(unblock)
+	if err := <-stalledErr; err == nil {
+		t.Error("stalled handshake succeeded")
+	}
+	if err := <-queuedErr; err != nil {
+		t.Errorf("queued handshake failed: %v", err)
+	}
+	queued.Close()
+
+	stats := limiter.Stats()
+	if stats.Running != 0 || stats.Queued != 0 || stats.Admitted != 2 || stats.Rejected != 1 || stats.Canceled != 1 || stats.QueueTime <= 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestHandshakeLimiterPeerWait(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	limiter := NewHandshakeLimiter(1, -1)
+	config := &Config{HandshakeLimiter: limiter}
+
+	// The server never answers. The handshake gives its slot back while it
+	// waits, so it doesn't block the others.
+	stalled := NewUnstartedUTLSClient(s, config, HelloChrome_Auto)
+	stalledErr := make(chan error, 1)
+	go func() { stalledErr <- stalled.Handshake() }()
+	for deadline := time.Now().Add(5 * time.Second); limiter.Stats() != (HandshakeLimiterStats{Admitted: 1}); {
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected stats %+v", limiter.Stats())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	c, err := NewUTLSClient(s, config, HelloChrome_Auto)
+	if err != nil {
+		t.Fatalf("handshake blocked by a stalled peer: %v", err)
+	}
+	c.Close()
+
+	stalled.UConn.Close()
+	if err := <-stalledErr; err == nil {
+		t.Error("stalled handshake succeeded")
+	}
+	if stats := limiter.Stats(); stats.Running != 0 || stats.Admitted != 2 || stats.Rejected != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestHandshakeLimiterServer(t *testing.T) {
+	limiter := NewHandshakeLimiter(0, -1)
+	serverConfig := testConfig.Clone()
+	serverConfig.HandshakeLimiter = limiter
+	if _, _, err := testHandshake(t, testConfig, serverConfig); err != nil {
+		t.Fatal(err)
+	}
+	if stats := limiter.Stats(); stats.Admitted != 1 || stats.Running != 0 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,153 @@
+package tls
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// HandshakeSizeLimits configures the maximum sizes accepted for handshake
+// messages received from the peer. They allow embedded and proxy deployments
+// to defend against memory exhaustion from hostile peers, or to accept
+// unusually large messages.
+//
+// The zero value of each field selects the default limit.
+type HandshakeSizeLimits struct {
+	// MaxMessageSize is the maximum size of any handshake message other than
+	// Certificate. The default is 65536 bytes.
+	MaxMessageSize int
+
+	// MaxCertificateMessageSize is the maximum size of a Certificate message,
+	// including its decompressed size when certificate compression is used.
+	// The default is 262144 bytes.
+	MaxCertificateMessageSize int
+
+	// MaxCertificateChainLength is the maximum number of certificates accepted
+	// in a Certificate message. The default is no limit.
+	MaxCertificateChainLength int
+
+	// MaxExtensionsSize is the maximum size of the extensions block of a
+	// ClientHello, ServerHello or EncryptedExtensions message. The default is
+	// no limit other than MaxMessageSize.
+	MaxExtensionsSize int
+}
+
+func (l *HandshakeSizeLimits) maxMessageSize() int {
+	if l == nil || l.MaxMessageSize <= 0 {
+		return maxHandshake
+	}
+	return l.MaxMessageSize
+}
+
+func (l *HandshakeSizeLimits) maxCertificateMessageSize() int {
+	if l == nil || l.MaxCertificateMessageSize <= 0 {
+		return maxHandshakeCertificateMsg
+	}
+	return l.MaxCertificateMessageSize
+}
+
+// HandshakeLimitKind identifies which of the HandshakeSizeLimits was exceeded.
+type HandshakeLimitKind int
+
+const (
+	LimitMessageSize HandshakeLimitKind = iota
+	LimitCertificateChainLength
+	LimitExtensionsSize
+)
+
+// HandshakeSizeError is returned when the peer sends a handshake message that
+// exceeds one of the configured HandshakeSizeLimits.
+type HandshakeSizeError struct {
+	Kind    HandshakeLimitKind
+	MsgType uint8 // type of the offending handshake message
+	Size    int   // size in bytes, or number of certificates for LimitCertificateChainLength
+	Limit   int
+}
+
+func (e *HandshakeSizeError) Error() string {
+	switch e.Kind {
+	case LimitCertificateChainLength:
+		return fmt.Sprintf("tls: certificate chain of %d certificates exceeds maximum of %d", e.Size, e.Limit)
+	case LimitExtensionsSize:
+		return fmt.Sprintf("tls: extensions of length %d bytes exceed maximum of %d bytes", e.Size, e.Limit)
+	default:
+		return fmt.Sprintf("tls: handshake message of length %d bytes exceeds maximum of %d bytes", e.Size, e.Limit)
+	}
+}
+
+// maxHandshakeSize returns the maximum accepted size of a handshake message
+// of type msgType.
+func (c *Conn) maxHandshakeSize(msgType uint8) int {
+	// haveVers indicates we're past the first message, forcing someone trying to
+	// make us just allocate a large buffer to at least do the initial part of
+	// the handshake first.
+	if c.haveVers && msgType == typeCertificate {
+		// Since certificate messages are likely to be the only messages that
+		// can be larger than maxHandshake, we use a special limit for just
+		// those messages.
+		return c.config.HandshakeSizeLimits.maxCertificateMessageSize()
+	}
+	return c.config.HandshakeSizeLimits.maxMessageSize()
+}
+
+// checkHandshakeSizeLimits enforces the limits on the contents of an unmarshaled
+// handshake message. data is the raw message, including its header.
+func (c *Conn) checkHandshakeSizeLimits(m handshakeMessage, data []byte) error {
+	limits := c.config.HandshakeSizeLimits
+	if limits == nil {
+		return nil
+	}
+
+	if limits.MaxCertificateChainLength > 0 {
+		var chainLen int
+		switch m := m.(type) {
+		case *certificateMsg:
+			chainLen = len(m.certificates)
+		case *certificateMsgTLS13:
+			chainLen = len(m.certificate.Certificate)
+		}
+		if chainLen > limits.MaxCertificateChainLength {
+			return &HandshakeSizeError{Kind: LimitCertificateChainLength, MsgType: data[0], Size: chainLen, Limit: limits.MaxCertificateChainLength}
+		}
+	}
+
+	if limits.MaxExtensionsSize > 0 {
+		if n := helloExtensionsLen(data); n > limits.MaxExtensionsSize {
+			return &HandshakeSizeError{Kind: LimitExtensionsSize, MsgType: data[0], Size: n, Limit: limits.MaxExtensionsSize}
+		}
+	}
+	return nil
+}
+
+// helloExtensionsLen returns the length of the extensions block of a raw
+// ClientHello, ServerHello or EncryptedExtensions message, or zero if the
+// message has none.
+func helloExtensionsLen(data []byte) int {
+	s := cryptobyte.String(data)
+	var msgType uint8
+	if !s.ReadUint8(&msgType) || !s.Skip(3) {
+		return 0
+	}
+
+	var skip cryptobyte.String
+	switch msgType {
+	case typeClientHello:
+		if !s.Skip(2+32) || !s.ReadUint8LengthPrefixed(&skip) ||
+			!s.ReadUint16LengthPrefixed(&skip) || !s.ReadUint8LengthPrefixed(&skip) {
+			return 0
+		}
+	case typeServerHello:
+		if !s.Skip(2+32) || !s.ReadUint8LengthPrefixed(&skip) || !s.Skip(2+1) {
+			return 0
+		}
+	case typeEncryptedExtensions:
+	default:
+		return 0
+	}
+
+	var extensions cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&extensions) {
+		return 0
+	}
+	return len(extensions)
+}
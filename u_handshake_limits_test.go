@@ -0,0 +1,66 @@
+package tls
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHandshakeSizeLimitsMessageSize(t *testing.T) {
+	client, server := localPipe(t)
+
+	go func() {
+		if _, err := client.Write([]byte{byte(recordTypeHandshake), 3, 1, 0, 4, typeClientHello, 0, 1, 0}); err != nil {
+			t.Log(err)
+		}
+	}()
+
+	serverConfig := testConfig.Clone()
+	serverConfig.HandshakeSizeLimits = &HandshakeSizeLimits{MaxMessageSize: 128}
+	err := Server(server, serverConfig).Handshake()
+
+	var sizeErr *HandshakeSizeError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("expected a *HandshakeSizeError, got %v", err)
+	}
+	if sizeErr.Kind != LimitMessageSize || sizeErr.MsgType != typeClientHello || sizeErr.Size != 256 || sizeErr.Limit != 128 {
+		t.Errorf("unexpected error: %+v", sizeErr)
+	}
+}
+
+func TestHandshakeSizeLimitsExtensions(t *testing.T) {
+	serverConfig := testConfig.Clone()
+	serverConfig.HandshakeSizeLimits = &HandshakeSizeLimits{MaxExtensionsSize: 8}
+	_, _, err := testHandshake(t, testConfig.Clone(), serverConfig)
+	if err == nil || !strings.Contains(err.Error(), "extensions of length") {
+		t.Errorf("expected extensions limit to be enforced, got %v", err)
+	}
+
+	serverConfig.HandshakeSizeLimits.MaxExtensionsSize = 4096
+	if _, _, err := testHandshake(t, testConfig.Clone(), serverConfig); err != nil {
+		t.Errorf("handshake failed: %v", err)
+	}
+}
+
+func TestHandshakeSizeLimitsCertificateChain(t *testing.T) {
+	for _, version := range []uint16{VersionTLS12, VersionTLS13} {
+		serverConfig := testConfig.Clone()
+		serverConfig.Certificates = []Certificate{{
+			Certificate: [][]byte{testRSACertificate, testRSACertificateIssuer},
+			PrivateKey:  testRSAPrivateKey,
+		}}
+
+		clientConfig := testConfig.Clone()
+		clientConfig.MaxVersion = version
+		clientConfig.HandshakeSizeLimits = &HandshakeSizeLimits{MaxCertificateChainLength: 1}
+		_, _, err := testHandshake(t, clientConfig, serverConfig)
+		if err == nil || !strings.Contains(err.Error(), "certificate chain of 2 certificates") {
+			t.Errorf("%x: expected chain length limit to be enforced, got %v", version, err)
+		}
+
+		clientConfig.HandshakeSizeLimits.MaxCertificateChainLength = 2
+		if _, _, err := testHandshake(t, clientConfig, serverConfig); err != nil {
+			t.Errorf("%x: handshake failed: %v", version, err)
+		}
+	}
+}
@@ -57,6 +57,9 @@ type utlsEncryptedExtensionsMsgExtraFields struct {
 	applicationSettings          []byte
 	applicationSettingsCodepoint uint16
 	customExtension              []byte
+
+	// extensions are the types of the received extensions, in order.
+	extensions []uint16
 }
 
 func (m *encryptedExtensionsMsg) utlsUnmarshal(extension uint16, extData cryptobyte.String) bool {
@@ -0,0 +1,225 @@
+package tls
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// HTTP2Setting is a single setting of an HTTP/2 SETTINGS frame.
+type HTTP2Setting struct {
+	ID  uint16
+	Val uint32
+}
+
+// HTTP2Profile describes how a persona's HTTP/2 client behaves on the wire.
+// uTLS does not speak HTTP/2 itself, the profile is carried for the HTTP stack
+// layered over the connection so that it matches the TLS fingerprint.
+type HTTP2Profile struct {
+	// Settings are sent in the initial SETTINGS frame, in order.
+	Settings []HTTP2Setting
+
+	// ConnectionFlow is the connection-level WINDOW_UPDATE increment sent
+	// after the SETTINGS frame, or 0 to send none.
+	ConnectionFlow uint32
+
+	// PseudoHeaderOrder is the order of the :method, :authority, :scheme
+	// and :path pseudo-headers.
+	PseudoHeaderOrder []string
+}
+
+// HeaderField is a single HTTP header.
+type HeaderField struct {
+	Name  string
+	Value string
+}
+
+// HeaderProfile describes the HTTP headers a persona sends by default, in
+// order. Like HTTP2Profile it is only carried by uTLS, not used by it.
+type HeaderProfile struct {
+	Headers []HeaderField
+}
+
+// Persona is a consistent client identity: a TLS fingerprint together with its
+// own session cache and the HTTP behavior expected of the client it imitates.
+// Mixing the parts of different personas on the same destination makes them
+// linkable, so IdentityManager always hands out all of them together.
+type Persona struct {
+	// Name uniquely identifies the persona within an IdentityManager.
+	Name string
+
+	// ClientHelloID is used for all connections of the persona. A
+	// randomized ClientHelloID without a Seed is given one derived from
+	// Name when the persona is added to an IdentityManager, so that the
+	// persona keeps the same randomized ClientHello, see
+	// PersistentRandomizedID.
+	ClientHelloID ClientHelloID
+
+	// SessionCache is used for all connections of the persona. If nil, an
+	// LRU cache with default capacity is created when the persona is added
+	// to an IdentityManager.
+	SessionCache ClientSessionCache
+
+	// GREASESeed is used for all connections of the persona, see
+	// Config.GREASESeed. If nil, one derived from Name and
+	// PersistentRandomizedSeed is set when the persona is added to an
+	// IdentityManager, so that the persona keeps the same GREASE values.
+	GREASESeed *PRNGSeed
+
+	HTTP2   *HTTP2Profile
+	Headers *HeaderProfile
+}
+
+// IdentityManager maintains a set of personas and assigns each destination to
+// one of them. Assignment is consistent: a destination keeps its persona as
+// long as that persona is managed, and adding or removing personas only moves
+// the destinations of the removed persona or those claimed by the added one.
+//
+// Assignments are keyed with a random secret, so that they can't be predicted
+// by an observer comparing personas across managers.
+//
+// It is safe for concurrent use.
+type IdentityManager struct {
+	key [32]byte
+
+	mu       sync.RWMutex
+	personas []*Persona
+}
+
+// NewIdentityManager returns an IdentityManager with the given personas.
+func NewIdentityManager(personas ...*Persona) (*IdentityManager, error) {
+	m := &IdentityManager{}
+	if _, err := rand.Read(m.key[:]); err != nil {
+		return nil, err
+	}
+	for _, p := range personas {
+		if err := m.Add(p); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Add adds a persona to the manager. Names must be unique.
+func (m *IdentityManager) Add(p *Persona) error {
+	if p == nil || p.Name == "" {
+		return errors.New("tls: persona must have a name")
+	}
+	if p.ClientHelloID.Client == "" {
+		return fmt.Errorf("tls: persona %q has no ClientHelloID", p.Name)
+	}
+	if p.SessionCache == nil {
+		p.SessionCache = NewLRUClientSessionCache(0)
+	}
+	if isRandomizedClient(p.ClientHelloID.Client) && p.ClientHelloID.Seed == nil {
+		id, err := PersistentRandomizedID(p.ClientHelloID, p.Name)
+		if err != nil {
+			return err
+		}
+		p.ClientHelloID = id
+	}
+	if p.GREASESeed == nil {
+		seed, err := PersistentRandomizedSeed()
+		if err != nil {
+			return err
+		}
+		if p.GREASESeed, err = newSaltedPRNGSeed(seed, "persona GREASE\x00"+p.Name); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, existing := range m.personas {
+		if existing.Name == p.Name {
+			return fmt.Errorf("tls: duplicate persona %q", p.Name)
+		}
+	}
+	m.personas = append(m.personas, p)
+	return nil
+}
+
+// Remove removes the named persona and reports whether it was present.
+func (m *IdentityManager) Remove(name string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, p := range m.personas {
+		if p.Name == name {
+			m.personas = append(m.personas[:i:i], m.personas[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Personas returns the personas currently managed.
+func (m *IdentityManager) Personas() []*Persona {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]*Persona(nil), m.personas...)
+}
+
+// PersonaFor returns the persona assigned to destination, which is a host name
+// or IP address, optionally with a port. All ports of a host share a persona.
+// It returns nil if the manager has no personas.
+func (m *IdentityManager) PersonaFor(destination string) *Persona {
+	dest := normalizeDestination(destination)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	// Rendezvous hashing: the persona with the highest weight for the
+	// destination wins, which keeps assignments stable as personas change.
+	var best *Persona
+	var bestWeight uint64
+	for _, p := range m.personas {
+		w := m.weight(dest, p.Name)
+		if best == nil || w > bestWeight {
+			best, bestWeight = p, w
+		}
+	}
+	return best
+}
+
+// UClient returns a new uTLS client for destination using its assigned
+// persona. config is cloned, and its ClientSessionCache replaced by the
+// persona's. If config.ServerName is empty, it is set from destination.
+func (m *IdentityManager) UClient(conn net.Conn, config *Config, destination string) (*UConn, *Persona, error) {
+	p := m.PersonaFor(destination)
+	if p == nil {
+		return nil, nil, errors.New("tls: IdentityManager has no personas")
+	}
+
+	if config == nil {
+		config = &Config{}
+	} else {
+		config = config.Clone()
+	}
+	config.ClientSessionCache = p.SessionCache
+	config.GREASESeed = p.GREASESeed
+	if config.ServerName == "" {
+		config.ServerName = normalizeDestination(destination)
+	}
+	return UClient(conn, config, p.ClientHelloID), p, nil
+}
+
+func (m *IdentityManager) weight(destination, persona string) uint64 {
+	mac := hmac.New(sha256.New, m.key[:])
+	mac.Write([]byte(destination))
+	mac.Write([]byte{0})
+	mac.Write([]byte(persona))
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+func normalizeDestination(destination string) string {
+	if host, _, err := net.SplitHostPort(destination); err == nil {
+		destination = host
+	}
+	return strings.ToLower(strings.TrimSuffix(destination, "."))
+}
@@ -0,0 +1,81 @@
+package tls
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestIdentityManager(t *testing.T) {
+	chrome := &Persona{Name: "chrome", ClientHelloID: HelloChrome_Auto}
+	firefox := &Persona{Name: "firefox", ClientHelloID: HelloFirefox_Auto}
+	safari := &Persona{Name: "safari", ClientHelloID: HelloSafari_Auto}
+	m, err := NewIdentityManager(chrome, firefox, safari)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chrome.SessionCache == nil || chrome.SessionCache == firefox.SessionCache {
+		t.Fatal("personas should get their own session caches")
+	}
+	if *chrome.GREASESeed == *firefox.GREASESeed {
+		t.Fatal("personas should get their own GREASE seeds")
+	}
+
+	assigned := make(map[string]*Persona)
+	used := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		dest := fmt.Sprintf("host%d.example.com", i)
+		p := m.PersonaFor(dest)
+		assigned[dest] = p
+		used[p.Name] = true
+		if again := m.PersonaFor(dest + ":443"); again != p {
+			t.Errorf("%s: port changed persona from %s to %s", dest, p.Name, again.Name)
+		}
+	}
+	if len(used) != 3 {
+		t.Errorf("only %d personas were used", len(used))
+	}
+
+	if !m.Remove("safari") || m.Remove("safari") {
+		t.Fatal("unexpected Remove result")
+	}
+	for dest, p := range assigned {
+		if p == safari {
+			continue
+		}
+		if got := m.PersonaFor(dest); got != p {
+			t.Errorf("%s moved from %s to %s after removing an unrelated persona", dest, p.Name, got.Name)
+		}
+	}
+
+	uconn, p, err := m.UClient(&net.TCPConn{}, &Config{}, "Host1.Example.com:443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != m.PersonaFor("host1.example.com") || uconn.ClientHelloID != p.ClientHelloID {
+		t.Errorf("UClient used persona %s with %s", p.Name, uconn.ClientHelloID.Str())
+	}
+	if uconn.config.ServerName != "host1.example.com" || uconn.config.ClientSessionCache != p.SessionCache ||
+		uconn.config.GREASESeed == nil || uconn.config.GREASESeed != p.GREASESeed {
+		t.Error("UClient did not configure the connection for the persona")
+	}
+}
+
+func TestIdentityManagerInvalid(t *testing.T) {
+	if _, err := NewIdentityManager(&Persona{ClientHelloID: HelloChrome_Auto}); err == nil {
+		t.Error("expected error for persona without name")
+	}
+	if _, err := NewIdentityManager(&Persona{Name: "a", ClientHelloID: HelloChrome_Auto}, &Persona{Name: "a", ClientHelloID: HelloIOS_Auto}); err == nil {
+		t.Error("expected error for duplicate persona")
+	}
+	m, err := NewIdentityManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := m.PersonaFor("example.com"); p != nil {
+		t.Errorf("got persona %s from empty manager", p.Name)
+	}
+	if _, _, err := m.UClient(&net.TCPConn{}, nil, "example.com"); err == nil {
+		t.Error("expected error from empty manager")
+	}
+}
@@ -0,0 +1,233 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InterceptionConfig returns a copy of base, which may be nil, tuned for
+// developing against an intercepting proxy such as Burp Suite or mitmproxy.
+// The secrets of every connection are written to keyLog if it's not nil, and
+// the server certificate may change on renegotiation, as happens when the
+// proxy rotates the certificate it forges for a host.
+//
+// To trust the proxy CA without installing it system-wide, see
+// TrustCAForProcess.
+func InterceptionConfig(base *Config, keyLog *ConnKeyLog) *Config {
+	var config *Config
+	if base == nil {
+		config = &Config{}
+	} else {
+		config = base.Clone()
+	}
+	if keyLog != nil {
+		config.KeyLogWriter = keyLog
+	}
+	config.AllowServerCertificateChange = true
+	return config
+}
+
+// ConnKeyLog is a Config.KeyLogWriter that keeps the secrets of each
+// connection apart, so that those of a single connection can be exported,
+// e.g. to decrypt it in Wireshark without disclosing other connections.
+//
+// Secrets are kept until Forget is called for the connection.
+type ConnKeyLog struct {
+	// Writer, if not nil, also receives every line in the NSS key log
+	// format, e.g. an SSLKEYLOGFILE.
+	Writer io.Writer
+
+	mu    sync.Mutex
+	lines map[string][]byte
+}
+
+// Write records a line in the NSS key log format, as written by crypto/tls.
+func (l *ConnKeyLog) Write(line []byte) (int, error) {
+	fields := bytes.Fields(line)
+	if len(fields) != 3 {
+		return 0, errors.New("tls: malformed key log line")
+	}
+	clientRandom := strings.ToLower(string(fields[1]))
+	l.mu.Lock()
+	if l.lines == nil {
+		l.lines = make(map[string][]byte)
+	}
+	l.lines[clientRandom] = append(l.lines[clientRandom], line...)
+	l.mu.Unlock()
+
+	if l.Writer != nil {
+		return l.Writer.Write(line)
+	}
+	return len(line), nil
+}
+
+// Secrets returns the key log lines of the connection with clientRandom, or
+// nil if none were written.
+func (l *ConnKeyLog) Secrets(clientRandom []byte) []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return bytes.Clone(l.lines[hex.EncodeToString(clientRandom)])
+}
+
+// ConnSecrets returns the key log lines of uconn.
+func (l *ConnKeyLog) ConnSecrets(uconn *UConn) []byte {
+	if uconn.HandshakeState.Hello == nil {
+		return nil
+	}
+	return l.Secrets(uconn.HandshakeState.Hello.Random)
+}
+
+// Forget drops the secrets of the connection with clientRandom.
+func (l *ConnKeyLog) Forget(clientRandom []byte) {
+	l.mu.Lock()
+	delete(l.lines, hex.EncodeToString(clientRandom))
+	l.mu.Unlock()
+}
+
+// VerificationFailureReason is a stable, machine-readable reason for a failed
+// server certificate verification.
+type VerificationFailureReason string
+
+const (
+	VerificationFailureUnknownAuthority  VerificationFailureReason = "unknown_authority"
+	VerificationFailureExpired           VerificationFailureReason = "expired"
+	VerificationFailureNotYetValid       VerificationFailureReason = "not_yet_valid"
+	VerificationFailureHostnameMismatch  VerificationFailureReason = "hostname_mismatch"
+	VerificationFailureIncompatibleUsage VerificationFailureReason = "incompatible_usage"
+	VerificationFailureInvalidChain      VerificationFailureReason = "invalid_chain"
+	VerificationFailureOther             VerificationFailureReason = "other"
+)
+
+// VerificationFailure describes why a server certificate failed to verify, in
+// a form suitable for logging as JSON or for programmatic checks. See
+// AsVerificationFailure.
+type VerificationFailure struct {
+	Reason VerificationFailureReason `json:"reason"`
+
+	// Host is the name that didn't match the certificate, for
+	// VerificationFailureHostnameMismatch.
+	Host string `json:"host,omitempty"`
+
+	// Subject, Issuer, NotBefore, NotAfter and SHA256 describe the leaf
+	// certificate sent by the server. SHA256 is its hex encoded fingerprint.
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	SHA256    string    `json:"sha256"`
+
+	// Detail is the human readable error returned by the verifier.
+	Detail string `json:"detail"`
+}
+
+// AsVerificationFailure returns the VerificationFailure described by err, if
+// it wraps a *CertificateVerificationError.
+func AsVerificationFailure(err error) (*VerificationFailure, bool) {
+	var verr *CertificateVerificationError
+	if !errors.As(err, &verr) {
+		return nil, false
+	}
+	f := &VerificationFailure{Reason: VerificationFailureOther}
+	if verr.Err != nil {
+		f.Detail = verr.Err.Error()
+	}
+	if len(verr.UnverifiedCertificates) > 0 {
+		leaf := verr.UnverifiedCertificates[0]
+		f.Subject = leaf.Subject.String()
+		f.Issuer = leaf.Issuer.String()
+		f.NotBefore = leaf.NotBefore
+		f.NotAfter = leaf.NotAfter
+		sum := sha256.Sum256(leaf.Raw)
+		f.SHA256 = hex.EncodeToString(sum[:])
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostname x509.HostnameError
+	var invalid x509.CertificateInvalidError
+	switch {
+	case errors.As(verr.Err, &unknownAuthority):
+		f.Reason = VerificationFailureUnknownAuthority
+	case errors.As(verr.Err, &hostname):
+		f.Reason = VerificationFailureHostnameMismatch
+		f.Host = hostname.Host
+	case errors.As(verr.Err, &invalid):
+		switch invalid.Reason {
+		case x509.Expired:
+			f.Reason = VerificationFailureExpired
+			if invalid.Cert != nil && strings.Contains(invalid.Detail, "is before") {
+				f.Reason = VerificationFailureNotYetValid
+			}
+		case x509.IncompatibleUsage:
+			f.Reason = VerificationFailureIncompatibleUsage
+		default:
+			f.Reason = VerificationFailureInvalidChain
+		}
+	}
+	return f, true
+}
+
+var processRoots struct {
+	sync.Mutex
+	cas  []*x509.Certificate
+	pool *x509.CertPool
+}
+
+// TrustCAForProcess makes clients of this process whose Config.RootCAs is nil
+// trust ca in addition to the system roots, without installing it in the
+// system trust store. It's intended for trusting the CA of an intercepting
+// proxy during development. The returned function stops trusting ca.
+func TrustCAForProcess(ca *x509.Certificate) (untrust func(), err error) {
+	if !ca.IsCA {
+		return nil, errors.New("tls: certificate is not a CA")
+	}
+	processRoots.Lock()
+	defer processRoots.Unlock()
+	processRoots.cas = append(processRoots.cas, ca)
+	processRoots.pool = nil
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			processRoots.Lock()
+			defer processRoots.Unlock()
+			for i, c := range processRoots.cas {
+				if c == ca {
+					processRoots.cas = append(processRoots.cas[:i], processRoots.cas[i+1:]...)
+					break
+				}
+			}
+			processRoots.pool = nil
+		})
+	}, nil
+}
+
+// rootCAs returns the roots used to verify the server certificate: RootCAs, or
+// the system roots and the CAs trusted with TrustCAForProcess if it's nil.
+func (c *Config) rootCAs() *x509.CertPool {
+	if c.RootCAs != nil {
+		return c.RootCAs
+	}
+	processRoots.Lock()
+	defer processRoots.Unlock()
+	if len(processRoots.cas) == 0 {
+		return nil
+	}
+	if processRoots.pool == nil {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, ca := range processRoots.cas {
+			pool.AddCert(ca)
+		}
+		processRoots.pool = pool
+	}
+	return processRoots.pool
+}
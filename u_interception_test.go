@@ -0,0 +1,119 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestInterceptionConfigKeyLog(t *testing.T) {
+	s, err := NewUTLSServer(&Config{MaxVersion: VersionTLS12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var all bytes.Buffer
+	keyLog := &ConnKeyLog{Writer: &all}
+	config := InterceptionConfig(nil, keyLog)
+	if !config.AllowServerCertificateChange {
+		t.Error("InterceptionConfig doesn't allow certificate changes")
+	}
+	var conns []*UTLSTestClient
+	for i := 0; i < 2; i++ {
+		c, err := NewUTLSClient(s, config, HelloChrome_Auto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		conns = append(conns, c)
+	}
+
+	first := keyLog.ConnSecrets(conns[0].UConn)
+	second := keyLog.ConnSecrets(conns[1].UConn)
+	if !bytes.HasPrefix(first, []byte(keyLogLabelTLS12+" ")) || bytes.Count(first, []byte("\n")) != 1 {
+		t.Errorf("unexpected secrets for the first connection: %q", first)
+	}
+	if len(second) == 0 || bytes.Equal(first, second) {
+		t.Errorf("secrets of the connections are not kept apart: %q, %q", first, second)
+	}
+	if all.Len() != len(first)+len(second) {
+		t.Errorf("Writer received %d bytes, want %d", all.Len(), len(first)+len(second))
+	}
+
+	keyLog.Forget(conns[0].HandshakeState.Hello.Random)
+	if secrets := keyLog.ConnSecrets(conns[0].UConn); secrets != nil {
+		t.Errorf("secrets kept after Forget: %q", secrets)
+	}
+}
+
+// clientHandshake runs a handshake with s, without trusting its certificate
+// unless config does, and returns the client error.
+func clientHandshake(s *UTLSTestServer, config *Config) error {
+	clientEnd, serverEnd := memPipe()
+	go func() {
+		Server(serverEnd, s.Config).Handshake()
+		serverEnd.Close()
+	}()
+	c := UClient(clientEnd, config, HelloChrome_Auto)
+	defer c.Close()
+	return c.Handshake()
+}
+
+func TestAsVerificationFailure(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate)
+	err = clientHandshake(s, &Config{ServerName: "example.com", RootCAs: roots})
+	f, ok := AsVerificationFailure(err)
+	if !ok {
+		t.Fatalf("AsVerificationFailure(%v) failed", err)
+	}
+	if f.Reason != VerificationFailureHostnameMismatch || f.Host != "example.com" || f.SHA256 == "" {
+		t.Errorf("unexpected failure %+v", f)
+	}
+	b, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"reason":"hostname_mismatch"`) {
+		t.Errorf("unexpected JSON %s", b)
+	}
+
+	err = clientHandshake(s, &Config{ServerName: "localhost"})
+	if f, ok := AsVerificationFailure(err); !ok || f.Reason != VerificationFailureUnknownAuthority {
+		t.Errorf("unexpected failure %+v for an untrusted certificate", f)
+	}
+
+	if _, ok := AsVerificationFailure(errNoCertificates); ok {
+		t.Error("AsVerificationFailure succeeded for an unrelated error")
+	}
+}
+
+func TestTrustCAForProcess(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	untrust, err := TrustCAForProcess(s.Certificate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := clientHandshake(s, &Config{ServerName: "localhost"}); err != nil {
+		t.Fatalf("handshake with a CA trusted for the process failed: %v", err)
+	}
+
+	untrust()
+	if err := clientHandshake(s, &Config{ServerName: "localhost"}); err == nil {
+		t.Error("handshake succeeded after the CA stopped being trusted")
+	}
+}
@@ -0,0 +1,267 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ja3String returns the JA3 fingerprint string of a ClientHello:
+// SSLVersion,Ciphers,Extensions,EllipticCurves,EllipticCurvePointFormats,
+// with GREASE values removed.
+//
+// See https://github.com/salesforce/ja3.
+func ja3String(m *clientHelloMsg) string {
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(int(m.vers)))
+	b.WriteByte(',')
+	writeJA3List(&b, m.cipherSuites)
+	b.WriteByte(',')
+	writeJA3List(&b, m.extensions)
+	b.WriteByte(',')
+	curves := make([]uint16, len(m.supportedCurves))
+	for i, c := range m.supportedCurves {
+		curves[i] = uint16(c)
+	}
+	writeJA3List(&b, curves)
+	b.WriteByte(',')
+	points := make([]uint16, len(m.supportedPoints))
+	for i, p := range m.supportedPoints {
+		points[i] = uint16(p)
+	}
+	writeJA3List(&b, points)
+	return b.String()
+}
+
+func writeJA3List(b *strings.Builder, values []uint16) {
+	first := true
+	for _, v := range values {
+		if isGREASEUint16(v) {
+			continue
+		}
+		if !first {
+			b.WriteByte('-')
+		}
+		first = false
+		b.WriteString(strconv.Itoa(int(v)))
+	}
+}
+
+// ja3Hash returns the MD5 hash of a JA3 string, as commonly used to look up
+// fingerprints.
+func ja3Hash(ja3 string) string {
+	sum := md5.Sum([]byte(ja3))
+	return hex.EncodeToString(sum[:])
+}
+
+// ja4String returns the JA4 fingerprint of a ClientHello sent over TCP, or
+// over QUIC if quic is set.
+//
+// See https://github.com/FoxIO-LLC/ja4/blob/main/technical_details/JA4.md.
+func ja4String(m *clientHelloMsg, quic bool) string {
+	a, b, c := ja4Parts(m, quic, false)
+	return a + "_" + ja4Hash(b) + "_" + ja4Hash(c)
+}
+
+// ja4RawString returns the unhashed JA4 fingerprint of a ClientHello, known as
+// JA4_r, or JA4_ro if originalOrder is set, in which case cipher suites and
+// extensions are listed as sent and server_name and ALPN are not omitted.
+func ja4RawString(m *clientHelloMsg, quic, originalOrder bool) string {
+	a, b, c := ja4Parts(m, quic, originalOrder)
+	return a + "_" + b + "_" + c
+}
+
+// ja4Parts returns JA4_a, and the unhashed JA4_b and JA4_c.
+func ja4Parts(m *clientHelloMsg, quic, originalOrder bool) (a, b, c string) {
+	var sb strings.Builder
+	if quic {
+		sb.WriteByte('q')
+	} else {
+		sb.WriteByte('t')
+	}
+	vers := m.vers
+	if len(m.supportedVersions) > 0 {
+		vers = 0
+		for _, v := range m.supportedVersions {
+			if !isGREASEUint16(v) && v > vers {
+				vers = v
+			}
+		}
+	}
+	sb.WriteString(ja4Version(vers))
+
+	hasSNI := false
+	var ciphers, exts []uint16
+	for _, c := range m.cipherSuites {
+		if !isGREASEUint16(c) {
+			ciphers = append(ciphers, c)
+		}
+	}
+	for _, e := range m.extensions {
+		if isGREASEUint16(e) {
+			continue
+		}
+		if e == extensionServerName {
+			hasSNI = true
+		}
+		exts = append(exts, e)
+	}
+	if hasSNI {
+		sb.WriteByte('d')
+	} else {
+		sb.WriteByte('i')
+	}
+	fmt.Fprintf(&sb, "%02d%02d", min(len(ciphers), 99), min(len(exts), 99))
+	sb.WriteString(ja4ALPN(m.alpnProtocols))
+	a = sb.String()
+
+	if !originalOrder {
+		sort.Slice(ciphers, func(i, j int) bool { return ciphers[i] < ciphers[j] })
+		exts = slices.DeleteFunc(exts, func(e uint16) bool {
+			return e == extensionServerName || e == extensionALPN
+		})
+		sort.Slice(exts, func(i, j int) bool { return exts[i] < exts[j] })
+	}
+	b = ja4HexList(ciphers)
+	c = ja4HexList(exts)
+	if c != "" && len(m.supportedSignatureAlgorithms) > 0 {
+		sigAlgs := make([]uint16, len(m.supportedSignatureAlgorithms))
+		for i, s := range m.supportedSignatureAlgorithms {
+			sigAlgs[i] = uint16(s)
+		}
+		c += "_" + ja4HexList(sigAlgs)
+	}
+	return a, b, c
+}
+
+func ja4Version(vers uint16) string {
+	switch vers {
+	case VersionTLS13:
+		return "13"
+	case VersionTLS12:
+		return "12"
+	case VersionTLS11:
+		return "11"
+	case VersionTLS10:
+		return "10"
+	case VersionSSL30:
+		return "s3"
+	case 0x0002:
+		return "s2"
+	case 0xfeff:
+		return "d1"
+	case 0xfefd:
+		return "d2"
+	case 0xfefc:
+		return "d3"
+	default:
+		return "00"
+	}
+}
+
+func ja4ALPN(protos []string) string {
+	if len(protos) == 0 || protos[0] == "" {
+		return "00"
+	}
+	p := protos[0]
+	if isAlphanumeric(p[0]) && isAlphanumeric(p[len(p)-1]) {
+		return string([]byte{p[0], p[len(p)-1]})
+	}
+	h := hex.EncodeToString([]byte(p))
+	return string([]byte{h[0], h[len(h)-1]})
+}
+
+func isAlphanumeric(c byte) bool {
+	return '0' <= c && c <= '9' || 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z'
+}
+
+func ja4HexList(values []uint16) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%04x", v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func ja4Hash(s string) string {
+	if s == "" {
+		return "000000000000"
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// JA3 returns the JA3 fingerprint string of the ClientHello the spec produces
+// for a server named by a domain name. Its MD5 hash is the value JA3
+// fingerprints are usually looked up by.
+//
+// The spec is applied to a HelloCustom UConn in the process, so like with
+// ApplyPreset its extensions must not be shared with another connection.
+func (chs *ClientHelloSpec) JA3() (string, error) {
+	m, err := chs.clientHelloMsg()
+	if err != nil {
+		return "", err
+	}
+	return ja3String(m), nil
+}
+
+// JA4 is like JA3, but returns the JA4 fingerprint of a connection over TCP.
+func (chs *ClientHelloSpec) JA4() (string, error) {
+	m, err := chs.clientHelloMsg()
+	if err != nil {
+		return "", err
+	}
+	return ja4String(m, false), nil
+}
+
+// clientHelloMsg builds the ClientHello of chs.
+func (chs *ClientHelloSpec) clientHelloMsg() (*clientHelloMsg, error) {
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloCustom)
+	if err := uconn.ApplyPreset(chs); err != nil {
+		return nil, err
+	}
+	if err := uconn.BuildHandshakeState(); err != nil {
+		return nil, err
+	}
+	return uconn.clientHelloMsg()
+}
+
+// JA3 returns the JA3 fingerprint string of the ClientHello uconn sends. It
+// must be called after BuildHandshakeState or the handshake.
+func (uconn *UConn) JA3() (string, error) {
+	m, err := uconn.clientHelloMsg()
+	if err != nil {
+		return "", err
+	}
+	return ja3String(m), nil
+}
+
+// JA4 is like JA3, but returns the JA4 fingerprint, of a connection over QUIC
+// if uconn is a QUIC connection.
+func (uconn *UConn) JA4() (string, error) {
+	m, err := uconn.clientHelloMsg()
+	if err != nil {
+		return "", err
+	}
+	return ja4String(m, uconn.quic != nil), nil
+}
+
+// clientHelloMsg parses the built ClientHello of uconn.
+func (uconn *UConn) clientHelloMsg() (*clientHelloMsg, error) {
+	if uconn.clientHelloBuildStatus == NotBuilt || uconn.HandshakeState.Hello == nil {
+		return nil, errors.New("tls: ClientHello not built yet, call BuildHandshakeState first")
+	}
+	m := new(clientHelloMsg)
+	if !m.unmarshal(bytes.Clone(uconn.HandshakeState.Hello.Raw)) {
+		return nil, errors.New("tls: unable to parse ClientHello")
+	}
+	return m, nil
+}
@@ -0,0 +1,136 @@
+package tls
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SpecFromJA3 builds a ClientHelloSpec from a JA3 string, as collected by
+// passive fingerprinting tools:
+// SSLVersion,Ciphers,Extensions,EllipticCurves,EllipticCurvePointFormats.
+//
+// JA3 records the cipher suites, extension types, supported groups and point
+// formats in the order they were sent, which the spec reproduces, so that
+// the ClientHello it builds has the same JA3 fingerprint. The contents of the
+// other extensions are filled with common browser values, as by
+// JA4Spec.ClientHelloSpec: the h2 and http/1.1 protocols, the signature
+// algorithms of Chrome, a key share for the first supported group and so on.
+// TLS 1.3 is offered if the supported_versions extension is listed.
+// pre_shared_key is omitted, since it can only be sent when resuming a
+// session.
+//
+// GREASE values, which JA3 tools usually strip, are turned into GREASE
+// placeholders if present.
+func SpecFromJA3(ja3 string) (*ClientHelloSpec, error) {
+	fields := strings.Split(strings.TrimSpace(ja3), ",")
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("tls: malformed JA3 string %q", ja3)
+	}
+	vers, err := strconv.ParseUint(fields[0], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("tls: malformed version %q in JA3 string", fields[0])
+	}
+	var lists [4][]uint16
+	for i := range lists {
+		if lists[i], err = parseJA3List(fields[i+1]); err != nil {
+			return nil, err
+		}
+	}
+	ciphers, extensions, curves, points := lists[0], lists[1], lists[2], lists[3]
+	if len(ciphers) == 0 {
+		return nil, fmt.Errorf("tls: no cipher suites in JA3 string %q", ja3)
+	}
+	for _, p := range points {
+		if p > 0xff {
+			return nil, fmt.Errorf("tls: invalid point format %d in JA3 string", p)
+		}
+	}
+
+	p := &ja4RO{
+		version:    uint16(vers),
+		alpn:       "h2",
+		ciphers:    ciphers,
+		extensions: extensions,
+		sigAlgs: []uint16{
+			uint16(ECDSAWithP256AndSHA256), uint16(PSSWithSHA256), uint16(PKCS1WithSHA256),
+			uint16(ECDSAWithP384AndSHA384), uint16(PSSWithSHA384), uint16(PKCS1WithSHA384),
+			uint16(PSSWithSHA512), uint16(PKCS1WithSHA512),
+		},
+	}
+	if slices.Contains(extensions, extensionSupportedVersions) {
+		p.version = VersionTLS13
+	}
+	spec, err := p.clientHelloSpec()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, c := range spec.CipherSuites {
+		if isGREASEUint16(c) {
+			spec.CipherSuites[i] = GREASE_PLACEHOLDER
+		}
+	}
+	groups := make([]CurveID, len(curves))
+	for i, c := range curves {
+		groups[i] = CurveID(c)
+		if isGREASEUint16(c) {
+			groups[i] = GREASE_PLACEHOLDER
+		}
+	}
+	for i, ext := range spec.Extensions {
+		switch ext := ext.(type) {
+		case *SupportedCurvesExtension:
+			ext.Curves = groups
+		case *SupportedPointsExtension:
+			ext.SupportedPoints = make([]uint8, len(points))
+			for i, p := range points {
+				ext.SupportedPoints[i] = uint8(p)
+			}
+		case *KeyShareExtension:
+			ext.KeyShares = ja3KeyShares(groups)
+		case *GenericExtension:
+			if isGREASEUint16(ext.Id) {
+				spec.Extensions[i] = &UtlsGREASEExtension{}
+			}
+		}
+	}
+	return spec, nil
+}
+
+// ja3KeyShares returns the key shares a browser sends for groups: one for the
+// first group, and also for X25519 if the first group is its hybrid with
+// ML-KEM, preceded by a GREASE one if groups start with GREASE.
+func ja3KeyShares(groups []CurveID) []KeyShare {
+	var shares []KeyShare
+	for _, g := range groups {
+		if g == GREASE_PLACEHOLDER {
+			if len(shares) == 0 {
+				shares = append(shares, KeyShare{Group: GREASE_PLACEHOLDER, Data: []byte{0}})
+			}
+			continue
+		}
+		shares = append(shares, KeyShare{Group: g})
+		if g == X25519MLKEM768 && slices.Contains(groups, X25519) {
+			shares = append(shares, KeyShare{Group: X25519})
+		}
+		return shares
+	}
+	return append(shares, KeyShare{Group: X25519})
+}
+
+func parseJA3List(s string) ([]uint16, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var values []uint16
+	for _, d := range strings.Split(s, "-") {
+		v, err := strconv.ParseUint(d, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("tls: malformed value %q in JA3 string", d)
+		}
+		values = append(values, uint16(v))
+	}
+	return values, nil
+}
@@ -0,0 +1,64 @@
+package tls
+
+import (
+	"net"
+	"testing"
+)
+
+func testJA3(t *testing.T, id ClientHelloID, spec *ClientHelloSpec) string {
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, id)
+	if spec != nil {
+		if err := uconn.ApplyPreset(spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := uconn.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	m := new(clientHelloMsg)
+	if !m.unmarshal(uconn.HandshakeState.Hello.Raw) {
+		t.Fatal("failed to parse ClientHello")
+	}
+	return ja3String(m)
+}
+
+func TestSpecFromJA3(t *testing.T) {
+	for _, id := range []ClientHelloID{HelloFirefox_120, HelloOkHttp_4, HelloChrome_58} {
+		t.Run(id.Str(), func(t *testing.T) {
+			ja3 := testJA3(t, id, nil)
+			spec, err := SpecFromJA3(ja3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := testJA3(t, HelloCustom, spec); got != ja3 {
+				t.Errorf("JA3 of the spec = %s, want %s", got, ja3)
+			}
+		})
+	}
+
+	// GREASE values and a hybrid post-quantum group.
+	spec, err := SpecFromJA3("771,2570-4865-4866,2570-0-10-11-43-51,2570-4588-29-23,0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spec.Extensions[0].(*UtlsGREASEExtension); !ok {
+		t.Errorf("first extension is %T, want GREASE", spec.Extensions[0])
+	}
+	ks := spec.Extensions[5].(*KeyShareExtension).KeyShares
+	if len(ks) != 3 || ks[0].Group != GREASE_PLACEHOLDER || ks[1].Group != X25519MLKEM768 || ks[2].Group != X25519 {
+		t.Errorf("key shares = %v", ks)
+	}
+	if spec.TLSVersMax != VersionTLS13 {
+		t.Errorf("TLSVersMax = %x, want TLS 1.3", spec.TLSVersMax)
+	}
+	want := "771,4865-4866,0-10-11-43-51,4588-29-23,0"
+	if got := testJA3(t, HelloCustom, spec); got != want {
+		t.Errorf("JA3 = %s, want %s", got, want)
+	}
+
+	for _, bad := range []string{"", "771,4865,0,29", "771,,0,29,0", "771,4865,0,x,0", "771,4865,0,29,256"} {
+		if _, err := SpecFromJA3(bad); err == nil {
+			t.Errorf("SpecFromJA3(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package tls
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// JA4Spec is a client fingerprint imported from a JA4 export, see
+// ImportJA4Export.
+type JA4Spec struct {
+	// Name identifies the fingerprint in the imported library. It is the
+	// JA4 fingerprint, which is unique after deduplication.
+	Name string
+
+	// JA4 is the JA4 fingerprint, computed from JA4RO if the export
+	// didn't include it.
+	JA4 string
+
+	// JA4RO is the raw JA4 fingerprint in original order, from which the
+	// ClientHelloSpec is built.
+	JA4RO string
+
+	// Count is the number of ClientHellos of the export with this
+	// fingerprint.
+	Count int
+
+	// ServerNames are the distinct server names the fingerprint was seen
+	// with, if the export included them.
+	ServerNames []string
+}
+
+// ImportJA4Export reads the packet dissections exported by Wireshark with the
+// JA4+ plugin, either as CSV (File > Export Packet Dissections > As CSV, with
+// the plugin's fields added as columns) or as JSON (As JSON, or tshark -T json
+// or -T ek), and returns the distinct client fingerprints found, in order of
+// first appearance.
+//
+// A ClientHello can only be rebuilt from its JA4_ro fingerprint ("ja4.ja4_ro"),
+// which lists cipher suites and extensions in the order they were sent, so
+// packets without it are skipped. ClientHellos with the same JA4 fingerprint,
+// e.g. from a browser shuffling its extensions, are deduplicated, and the first
+// ordering seen is kept. Server names are read from the
+// "tls.handshake.extensions_server_name" field.
+func ImportJA4Export(r io.Reader) ([]*JA4Spec, error) {
+	br := bufio.NewReader(r)
+	var records []map[string]string
+	var err error
+	if c, ok := peekNonSpace(br); ok && (c == '[' || c == '{') {
+		records, err = readJA4JSONRecords(br)
+	} else {
+		records, err = readJA4CSVRecords(br)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var specs []*JA4Spec
+	byJA4 := make(map[string]*JA4Spec)
+	for i, record := range records {
+		ro := record["ja4_ro"]
+		if ro == "" {
+			continue
+		}
+		p, err := parseJA4RO(ro)
+		if err != nil {
+			return nil, fmt.Errorf("tls: record %d: %w", i+1, err)
+		}
+		ja4 := record["ja4"]
+		if ja4 == "" {
+			ja4 = p.ja4()
+		}
+		s, ok := byJA4[ja4]
+		if !ok {
+			s = &JA4Spec{Name: ja4, JA4: ja4, JA4RO: ro}
+			byJA4[ja4] = s
+			specs = append(specs, s)
+		}
+		s.Count++
+		if sni := record["server_name"]; sni != "" && !slices.Contains(s.ServerNames, sni) {
+			s.ServerNames = append(s.ServerNames, sni)
+		}
+	}
+	return specs, nil
+}
+
+// ClientHelloSpec builds a new ClientHelloSpec from the fingerprint.
+//
+// JA4 only records extension types, signature algorithms and the first ALPN
+// protocol, so the contents of the other extensions are filled with common
+// browser values: X25519, P-256 and P-384 groups with an X25519 key share,
+// Brotli certificate compression, BoringSSL-style padding and so on. GREASE
+// values are not part of JA4 and are not added. pre_shared_key is omitted,
+// since it can only be sent when resuming a session. Extensions unknown to this
+// package are sent empty.
+func (s *JA4Spec) ClientHelloSpec() (*ClientHelloSpec, error) {
+	p, err := parseJA4RO(s.JA4RO)
+	if err != nil {
+		return nil, err
+	}
+	return p.clientHelloSpec()
+}
+
+// ja4RO is a parsed JA4_ro fingerprint.
+type ja4RO struct {
+	a          string
+	version    uint16
+	alpn       string
+	ciphers    []uint16
+	extensions []uint16
+	sigAlgs    []uint16
+}
+
+func parseJA4RO(ro string) (*ja4RO, error) {
+	parts := strings.Split(ro, "_")
+	if len(parts) != 3 && len(parts) != 4 {
+		return nil, fmt.Errorf("tls: malformed JA4_ro fingerprint %q", ro)
+	}
+	p := &ja4RO{a: parts[0]}
+	a := parts[0]
+	if len(a) != 10 || (a[0] != 't' && a[0] != 'q') {
+		return nil, fmt.Errorf("tls: malformed JA4_ro fingerprint %q", ro)
+	}
+	switch a[1:3] {
+	case "13":
+		p.version = VersionTLS13
+	case "12":
+		p.version = VersionTLS12
+	case "11":
+		p.version = VersionTLS11
+	case "10":
+		p.version = VersionTLS10
+	default:
+		return nil, fmt.Errorf("tls: unsupported version %q in JA4_ro fingerprint", a[1:3])
+	}
+	p.alpn = a[8:10]
+
+	var err error
+	if p.ciphers, err = parseJA4HexList(parts[1]); err != nil {
+		return nil, err
+	}
+	if p.extensions, err = parseJA4HexList(parts[2]); err != nil {
+		return nil, err
+	}
+	if len(parts) == 4 {
+		if p.sigAlgs, err = parseJA4HexList(parts[3]); err != nil {
+			return nil, err
+		}
+	}
+	if len(p.ciphers) == 0 {
+		return nil, fmt.Errorf("tls: no cipher suites in JA4_ro fingerprint %q", ro)
+	}
+	if n, err := strconv.Atoi(a[4:6]); err != nil || n != min(len(p.ciphers), 99) {
+		return nil, fmt.Errorf("tls: cipher suite count mismatch in JA4_ro fingerprint %q", ro)
+	}
+	if n, err := strconv.Atoi(a[6:8]); err != nil || n != min(len(p.extensions), 99) {
+		return nil, fmt.Errorf("tls: extension count mismatch in JA4_ro fingerprint %q", ro)
+	}
+	return p, nil
+}
+
+func parseJA4HexList(s string) ([]uint16, error) {
+	if s == "" {
+		return nil, nil
+	}
+	var values []uint16
+	for _, h := range strings.Split(s, ",") {
+		v, err := strconv.ParseUint(h, 16, 16)
+		if err != nil || len(h) != 4 {
+			return nil, fmt.Errorf("tls: malformed value %q in JA4_ro fingerprint", h)
+		}
+		values = append(values, uint16(v))
+	}
+	return values, nil
+}
+
+// ja4 returns the JA4 fingerprint matching p.
+func (p *ja4RO) ja4() string {
+	ciphers := slices.Clone(p.ciphers)
+	slices.Sort(ciphers)
+	exts := slices.DeleteFunc(slices.Clone(p.extensions), func(e uint16) bool {
+		return e == extensionServerName || e == extensionALPN
+	})
+	slices.Sort(exts)
+	c := ja4HexList(exts)
+	if c != "" && len(p.sigAlgs) > 0 {
+		c += "_" + ja4HexList(p.sigAlgs)
+	}
+	return p.a + "_" + ja4Hash(ja4HexList(ciphers)) + "_" + ja4Hash(c)
+}
+
+// alpnProtocols returns the ALPN protocols for the JA4 ALPN code, which only
+// records the first and last characters of the first protocol.
+func (p *ja4RO) alpnProtocols() ([]string, error) {
+	switch p.alpn {
+	case "h2":
+		return []string{"h2", "http/1.1"}, nil
+	case "h1":
+		return []string{"http/1.1"}, nil
+	case "h3":
+		return []string{"h3"}, nil
+	case "00":
+		return nil, errors.New("tls: JA4_ro fingerprint has an ALPN extension but no protocol")
+	default:
+		return nil, fmt.Errorf("tls: unknown ALPN protocol %q in JA4_ro fingerprint", p.alpn)
+	}
+}
+
+func (p *ja4RO) clientHelloSpec() (*ClientHelloSpec, error) {
+	spec := &ClientHelloSpec{
+		CipherSuites:       slices.Clone(p.ciphers),
+		CompressionMethods: []uint8{compressionNone},
+		TLSVersMax:         p.version,
+		TLSVersMin:         min(p.version, VersionTLS12),
+	}
+	sigAlgs := make([]SignatureScheme, len(p.sigAlgs))
+	for i, s := range p.sigAlgs {
+		sigAlgs[i] = SignatureScheme(s)
+	}
+	alpn := []string{"http/1.1"}
+	if slices.Contains(p.extensions, extensionALPN) {
+		var err error
+		if alpn, err = p.alpnProtocols(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range p.extensions {
+		var ext TLSExtension
+		switch id {
+		case extensionPreSharedKey:
+			continue
+		case extensionSupportedCurves:
+			ext = &SupportedCurvesExtension{Curves: []CurveID{X25519, CurveP256, CurveP384}}
+		case extensionSupportedPoints:
+			ext = &SupportedPointsExtension{SupportedPoints: []byte{pointFormatUncompressed}}
+		case extensionSignatureAlgorithms:
+			ext = &SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: sigAlgs}
+		case extensionSignatureAlgorithmsCert:
+			ext = &SignatureAlgorithmsCertExtension{SupportedSignatureAlgorithms: sigAlgs}
+		case fakeExtensionDelegatedCredentials:
+			ext = &FakeDelegatedCredentialsExtension{SupportedSignatureAlgorithms: sigAlgs}
+		case extensionALPN:
+			ext = &ALPNExtension{AlpnProtocols: alpn}
+		case utlsExtensionApplicationSettings:
+			ext = &ApplicationSettingsExtension{SupportedProtocols: alpn[:1]}
+		case utlsExtensionApplicationSettingsNew:
+			ext = &ApplicationSettingsExtensionNew{SupportedProtocols: alpn[:1]}
+		case extensionSupportedVersions:
+			versions := []uint16{p.version}
+			if p.version == VersionTLS13 {
+				versions = append(versions, VersionTLS12)
+			}
+			ext = &SupportedVersionsExtension{Versions: versions}
+		case extensionKeyShare:
+			ext = &KeyShareExtension{KeyShares: []KeyShare{{Group: X25519}}}
+		case extensionPSKModes:
+			ext = &PSKKeyExchangeModesExtension{Modes: []uint8{PskModeDHE}}
+		case utlsExtensionCompressCertificate:
+			ext = &UtlsCompressCertExtension{Algorithms: []CertCompressionAlgo{CertCompressionBrotli}}
+		case fakeRecordSizeLimit:
+			ext = &FakeRecordSizeLimitExtension{Limit: 0x4001}
+		case utlsExtensionPadding:
+			ext = &UtlsPaddingExtension{GetPaddingLen: BoringPaddingStyle}
+		case extensionRenegotiationInfo:
+			ext = &RenegotiationInfoExtension{Renegotiation: RenegotiateOnceAsClient}
+		case utlsExtensionECH:
+			ext = BoringGREASEECH()
+		default:
+			ext = ExtensionFromID(id)
+			if ext == nil {
+				ext = &GenericExtension{Id: id}
+			}
+		}
+		spec.Extensions = append(spec.Extensions, ext)
+	}
+	return spec, nil
+}
+
+// peekNonSpace returns the first byte of br that is not white space, without
+// consuming it.
+func peekNonSpace(br *bufio.Reader) (byte, bool) {
+	for {
+		c, err := br.ReadByte()
+		if err != nil {
+			return 0, false
+		}
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			br.UnreadByte()
+			return c, true
+		}
+	}
+}
+
+// ja4RecordKey returns the key under which an exported field is recorded, or ""
+// if the field is not used. Field names vary between exports: "ja4.ja4_ro" in
+// JSON, "ja4_ja4_ro" with tshark -T ek, and column titles in CSV.
+func ja4RecordKey(field string) string {
+	f := strings.ToLower(strings.TrimSpace(field))
+	f = strings.NewReplacer(".", "_", " ", "_", "-", "_").Replace(f)
+	switch {
+	case f == "ja4_ro" || strings.HasSuffix(f, "_ja4_ro"):
+		return "ja4_ro"
+	case f == "ja4" || strings.HasSuffix(f, "_ja4"):
+		return "ja4"
+	case f == "sni" || f == "server_name" || strings.HasSuffix(f, "extensions_server_name"):
+		return "server_name"
+	}
+	return ""
+}
+
+func readJA4CSVRecords(r io.Reader) ([]map[string]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("tls: reading JA4 CSV export: %w", err)
+	}
+	keys := make([]string, len(header))
+	found := false
+	for i, field := range header {
+		keys[i] = ja4RecordKey(field)
+		found = found || keys[i] == "ja4_ro"
+	}
+	if !found {
+		return nil, errors.New("tls: JA4 CSV export has no JA4_ro column")
+	}
+
+	var records []map[string]string
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tls: reading JA4 CSV export: %w", err)
+		}
+		record := make(map[string]string)
+		for i, v := range row {
+			if i < len(keys) && keys[i] != "" && v != "" {
+				record[keys[i]] = v
+			}
+		}
+		records = append(records, record)
+	}
+}
+
+// readJA4JSONRecords reads a JSON array of packets, or a sequence of JSON
+// objects as written by tshark -T ek, and returns one record per packet.
+func readJA4JSONRecords(r io.Reader) ([]map[string]string, error) {
+	var records []map[string]string
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	for {
+		var v any
+		if err := dec.Decode(&v); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("tls: reading JA4 JSON export: %w", err)
+		}
+		packets, ok := v.([]any)
+		if !ok {
+			packets = []any{v}
+		}
+		for _, packet := range packets {
+			record := make(map[string]string)
+			collectJA4Fields(packet, record)
+			if len(record) > 0 {
+				records = append(records, record)
+			}
+		}
+	}
+}
+
+// collectJA4Fields records the fields used by ImportJA4Export found anywhere
+// in a JSON packet dissection. Fields that appear more than once, as server
+// names might in some dissections, keep one of their values.
+func collectJA4Fields(v any, record map[string]string) {
+	switch v := v.(type) {
+	case map[string]any:
+		for field, value := range v {
+			// Layers may be named like fields, e.g. "ja4".
+			if key, s := ja4RecordKey(field), firstJSONString(value); key != "" && s != "" {
+				if record[key] == "" {
+					record[key] = s
+				}
+				continue
+			}
+			collectJA4Fields(value, record)
+		}
+	case []any:
+		for _, e := range v {
+			collectJA4Fields(e, record)
+		}
+	}
+}
+
+func firstJSONString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []any:
+		if len(v) > 0 {
+			return firstJSONString(v[0])
+		}
+	}
+	return ""
+}
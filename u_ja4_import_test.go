@@ -0,0 +1,118 @@
+package tls
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func testJA4RO(t *testing.T, id ClientHelloID, spec *ClientHelloSpec) (ja4, ro string) {
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, id)
+	if spec != nil {
+		if err := uconn.ApplyPreset(spec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := uconn.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	m := new(clientHelloMsg)
+	if !m.unmarshal(uconn.HandshakeState.Hello.Raw) {
+		t.Fatal("failed to parse ClientHello")
+	}
+	return ja4String(m, false), ja4RawString(m, false, true)
+}
+
+func TestImportJA4Export(t *testing.T) {
+	chromeJA4, chromeRO := testJA4RO(t, HelloChrome_120, nil)
+	_, chromeRO2 := testJA4RO(t, HelloChrome_120, nil)
+	firefoxJA4, firefoxRO := testJA4RO(t, HelloFirefox_120, nil)
+
+	csvExport := fmt.Sprintf(`"No.","Time","Source","Destination","Protocol","Length","Server Name","JA4","JA4_ro"
+"1","0.000","10.0.0.1","10.0.0.2","TLSv1.3","517","a.example","%s","%s"
+"2","0.100","10.0.0.1","10.0.0.2","TLSv1.3","300","","",""
+"3","0.200","10.0.0.1","10.0.0.3","TLSv1.3","517","b.example","","%s"
+"4","0.300","10.0.0.1","10.0.0.4","TLSv1.3","517","a.example","%s","%s"
+`, chromeJA4, chromeRO, chromeRO2, firefoxJA4, firefoxRO)
+
+	jsonExport := fmt.Sprintf(`[
+  {"_source": {"layers": {
+    "tls": {"tls.record": {"tls.handshake": {"tls.handshake.extensions_server_name": "a.example"}}},
+    "ja4": {"ja4.ja4": "%s", "ja4.ja4_r": "ignored", "ja4.ja4_ro": "%s"}}}},
+  {"_source": {"layers": {"tcp": {"tcp.len": "0"}}}},
+  {"_source": {"layers": {
+    "tls": {"tls.handshake.extensions_server_name": ["b.example"]},
+    "ja4": {"ja4.ja4_ro": "%s"}}}},
+  {"_source": {"layers": {
+    "tls": {"tls.handshake.extensions_server_name": "a.example"},
+    "ja4": {"ja4.ja4": "%s", "ja4.ja4_ro": "%s"}}}}
+]`, chromeJA4, chromeRO, chromeRO2, firefoxJA4, firefoxRO)
+
+	ekExport := fmt.Sprintf(`{"index": {"_index": "packets-2024-01-01"}}
+{"layers": {"tls": {"tls_tls_handshake_extensions_server_name": "a.example"}, "ja4": {"ja4_ja4_ro": "%s"}}}
+{"index": {"_index": "packets-2024-01-01"}}
+{"layers": {"tls": {"tls_tls_handshake_extensions_server_name": "b.example"}, "ja4": {"ja4_ja4_ro": "%s"}}}
+{"index": {"_index": "packets-2024-01-01"}}
+{"layers": {"tls": {"tls_tls_handshake_extensions_server_name": "a.example"}, "ja4": {"ja4_ja4_ro": "%s"}}}
+`, chromeRO, chromeRO2, firefoxRO)
+
+	for name, export := range map[string]string{"csv": csvExport, "json": jsonExport, "ek": ekExport} {
+		specs, err := ImportJA4Export(strings.NewReader(export))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(specs) != 2 {
+			t.Fatalf("%s: got %d specs, want 2", name, len(specs))
+		}
+		chrome, firefox := specs[0], specs[1]
+		if chrome.Name != chromeJA4 || chrome.JA4RO != chromeRO || chrome.Count != 2 ||
+			strings.Join(chrome.ServerNames, " ") != "a.example b.example" {
+			t.Errorf("%s: unexpected Chrome spec %+v", name, chrome)
+		}
+		if firefox.Name != firefoxJA4 || firefox.Count != 1 || strings.Join(firefox.ServerNames, " ") != "a.example" {
+			t.Errorf("%s: unexpected Firefox spec %+v", name, firefox)
+		}
+	}
+
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, ro := range []string{chromeRO, firefoxRO} {
+		spec, err := (&JA4Spec{JA4RO: ro}).ClientHelloSpec()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, got := testJA4RO(t, HelloCustom, spec); got != ro {
+			t.Errorf("rebuilt ClientHello has JA4_ro\n%s, want\n%s", got, ro)
+		}
+
+		spec, _ = (&JA4Spec{JA4RO: ro}).ClientHelloSpec()
+		c := NewUnstartedUTLSClient(s, nil, HelloCustom)
+		if err := c.ApplyPreset(spec); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Start(); err != nil {
+			t.Errorf("handshake with rebuilt spec: %v", err)
+			continue
+		}
+		c.Close()
+	}
+}
+
+func TestImportJA4ExportErrors(t *testing.T) {
+	for _, export := range []string{
+		"No.,Time,JA4\n1,0,t13d0101h2_abc_def\n",
+		"JA4_ro\nt13d0102h2_1301_0010\n",
+		"JA4_ro\nt13d0201h2_1301_0010\n",
+		"JA4_ro\nt13d0101h2_13_0010\n",
+		`[{"ja4.ja4_ro": "x"}]`,
+		`[{"ja4.ja4_ro": "t13d0101h2_1301_0010"`,
+	} {
+		if _, err := ImportJA4Export(strings.NewReader(export)); err == nil {
+			t.Errorf("no error importing %q", export)
+		}
+	}
+}
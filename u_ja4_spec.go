@@ -0,0 +1,128 @@
+package tls
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ja4Dictionary resolves the hashes of JA4 fingerprints to the lists they
+// were computed from, for the ClientHellos of the presets and of the embedded
+// fingerprint database.
+type ja4Dictionary struct {
+	// byJA4 maps full JA4 fingerprints to the client producing them.
+	byJA4 map[string]ClientHelloID
+
+	// ciphers maps JA4_b hashes to cipher suites, in the order sent.
+	ciphers map[string][]uint16
+
+	// extensions maps JA4_c hashes to extensions, in the order sent and
+	// including server_name and ALPN, and signature algorithms.
+	extensions map[string]ja4Extensions
+}
+
+type ja4Extensions struct {
+	extensions []uint16
+	sigAlgs    []uint16
+}
+
+var (
+	ja4DictionaryOnce sync.Once
+	ja4DictionaryData *ja4Dictionary
+)
+
+// loadJA4Dictionary builds the dictionary once, from the known ClientHellos.
+func loadJA4Dictionary() *ja4Dictionary {
+	ja4DictionaryOnce.Do(func() {
+		d := &ja4Dictionary{
+			byJA4:      make(map[string]ClientHelloID),
+			ciphers:    make(map[string][]uint16),
+			extensions: make(map[string]ja4Extensions),
+		}
+		for _, k := range loadKnownClientHellos() {
+			d.add(k.id, k.msg)
+		}
+		ja4DictionaryData = d
+	})
+	return ja4DictionaryData
+}
+
+func (d *ja4Dictionary) add(id ClientHelloID, m *clientHelloMsg) {
+	ja4 := ja4String(m, false)
+	if _, ok := d.byJA4[ja4]; !ok {
+		d.byJA4[ja4] = id
+	}
+	_, b, c := ja4Parts(m, false, false)
+	_, rb, rc := ja4Parts(m, false, true)
+	if _, ok := d.ciphers[ja4Hash(b)]; !ok {
+		ciphers, _ := parseJA4HexList(rb)
+		d.ciphers[ja4Hash(b)] = ciphers
+	}
+	if _, ok := d.extensions[ja4Hash(c)]; !ok {
+		var e ja4Extensions
+		exts, sigAlgs, _ := strings.Cut(rc, "_")
+		e.extensions, _ = parseJA4HexList(exts)
+		e.sigAlgs, _ = parseJA4HexList(sigAlgs)
+		d.extensions[ja4Hash(c)] = e
+	}
+}
+
+// SpecFromJA4 builds a ClientHelloSpec from a JA4 fingerprint, as computed by
+// TLS over TCP.
+//
+// JA4 only records hashes of the cipher suites and extensions, which are
+// resolved against a dictionary of the ClientHellos of the presets of this
+// package and of the embedded fingerprint database. If a client of the
+// dictionary has the fingerprint, its spec is returned. Otherwise the spec is
+// assembled from the cipher suites and the extensions of possibly different
+// clients, with the TLS version, presence of server_name and ALPN protocol of
+// the fingerprint, and its extension contents filled as by
+// JA4Spec.ClientHelloSpec. An error is returned if a hash is not in the
+// dictionary.
+func SpecFromJA4(ja4 string) (*ClientHelloSpec, error) {
+	parts := strings.Split(strings.TrimSpace(ja4), "_")
+	if len(parts) != 3 || len(parts[0]) != 10 || len(parts[1]) != 12 || len(parts[2]) != 12 {
+		return nil, fmt.Errorf("tls: malformed JA4 fingerprint %q", ja4)
+	}
+	d := loadJA4Dictionary()
+	if id, ok := d.byJA4[ja4]; ok {
+		spec, err := UTLSIdToSpec(id)
+		if err != nil {
+			return nil, err
+		}
+		return &spec, nil
+	}
+
+	a := parts[0]
+	ciphers, ok := d.ciphers[parts[1]]
+	if !ok {
+		return nil, fmt.Errorf("tls: unknown cipher suites hash %s in JA4 fingerprint", parts[1])
+	}
+	e, ok := d.extensions[parts[2]]
+	if !ok {
+		return nil, fmt.Errorf("tls: unknown extensions hash %s in JA4 fingerprint", parts[2])
+	}
+
+	// server_name and ALPN are not part of the hash, and are taken from
+	// JA4_a instead.
+	exts := slices.DeleteFunc(slices.Clone(e.extensions), func(e uint16) bool {
+		return e == extensionServerName || e == extensionALPN
+	})
+	if a[3] == 'd' {
+		exts = slices.Insert(exts, 0, extensionServerName)
+	}
+	if a[8:10] != "00" {
+		i := slices.Index(e.extensions, extensionALPN)
+		exts = slices.Insert(exts, min(max(i, 0), len(exts)), extensionALPN)
+	}
+	ro := a + "_" + ja4HexList(ciphers) + "_" + ja4HexList(exts)
+	if len(e.sigAlgs) > 0 {
+		ro += "_" + ja4HexList(e.sigAlgs)
+	}
+	p, err := parseJA4RO(ro)
+	if err != nil {
+		return nil, err
+	}
+	return p.clientHelloSpec()
+}
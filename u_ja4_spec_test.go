@@ -0,0 +1,40 @@
+package tls
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSpecFromJA4(t *testing.T) {
+	firefox, _ := testJA4RO(t, HelloFirefox_120, nil)
+	spec, err := SpecFromJA4(firefox)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := testJA4RO(t, HelloCustom, spec); got != firefox {
+		t.Errorf("JA4 of the spec = %s, want %s", got, firefox)
+	}
+
+	// Without server_name, and with the cipher suites of Firefox and the
+	// extensions of Chrome.
+	chrome, _ := testJA4RO(t, HelloChrome_120, nil)
+	fa, fb, _ := strings.Cut(firefox, "_")
+	ca := strings.Split(chrome, "_")
+	n := 0
+	fmt.Sscanf(ca[0][6:8], "%d", &n)
+	want := fmt.Sprintf("%si%s%02d%s_%s_%s", fa[:3], fa[4:6], n-1, ca[0][8:], fb[:12], ca[2])
+	spec, err = SpecFromJA4(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := testJA4RO(t, HelloCustom, spec); got != want {
+		t.Errorf("JA4 of the spec = %s, want %s", got, want)
+	}
+
+	for _, bad := range []string{"", "t13d1516h2_8daaf6152771", "t13d1516h2_000000000001_e5627efa2ab1", firefox[:11] + "000000000001" + firefox[23:]} {
+		if _, err := SpecFromJA4(bad); err == nil {
+			t.Errorf("SpecFromJA4(%q) succeeded", bad)
+		}
+	}
+}
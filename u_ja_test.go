@@ -0,0 +1,49 @@
+package tls
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestClientHelloFingerprints(t *testing.T) {
+	const wantJA4 = "t13d1717h2_5b57614c22b0_3cbfd9057e0d" // Firefox 133
+	spec, err := UTLSIdToSpec(HelloFirefox_133)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ja4, err := spec.JA4()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ja4 != wantJA4 {
+		t.Errorf("spec JA4 = %s, want %s", ja4, wantJA4)
+	}
+	spec, err = UTLSIdToSpec(HelloFirefox_133)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ja3, err := spec.JA3()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(ja3, "771,4865-4867-4866-") {
+		t.Errorf("spec JA3 = %s", ja3)
+	}
+
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloFirefox_133)
+	if _, err := uconn.JA4(); err == nil {
+		t.Error("JA4 succeeded before the ClientHello was built")
+	}
+	if err := uconn.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	// Firefox doesn't shuffle its extensions, so the fingerprints are
+	// those of the spec.
+	if got, err := uconn.JA3(); err != nil || got != ja3 {
+		t.Errorf("UConn JA3 = %s, %v; want %s", got, err, ja3)
+	}
+	if got, err := uconn.JA4(); err != nil || got != wantJA4 {
+		t.Errorf("UConn JA4 = %s, %v; want %s", got, err, wantJA4)
+	}
+}
@@ -0,0 +1,91 @@
+package tls
+
+import "errors"
+
+// KeyExchange is the key exchange family of a cipher suite.
+type KeyExchange uint8
+
+const (
+	// KeyExchangeUnspecified is the absence of a preference in
+	// ClientHelloSpec.KeyExchangePreference, and the family of unknown
+	// cipher suites.
+	KeyExchangeUnspecified KeyExchange = iota
+
+	// KeyExchangeECDHE is the ephemeral elliptic curve Diffie-Hellman key
+	// exchange, used by all TLS 1.3 cipher suites.
+	KeyExchangeECDHE
+
+	// KeyExchangeRSA is the static RSA key exchange, where the client
+	// encrypts the premaster secret to the server certificate.
+	KeyExchangeRSA
+)
+
+func (kx KeyExchange) String() string {
+	switch kx {
+	case KeyExchangeECDHE:
+		return "ECDHE"
+	case KeyExchangeRSA:
+		return "RSA"
+	default:
+		return "unspecified"
+	}
+}
+
+// CipherSuiteKeyExchange returns the key exchange family of the cipher suite
+// id, or KeyExchangeUnspecified if it's not implemented by this package.
+func CipherSuiteKeyExchange(id uint16) KeyExchange {
+	if cipherSuiteTLS13ByID(id) != nil {
+		return KeyExchangeECDHE
+	}
+	suite := cipherSuiteByID(id)
+	if suite == nil {
+		return KeyExchangeUnspecified
+	}
+	if suite.flags&suiteECDHE != 0 {
+		return KeyExchangeECDHE
+	}
+	return KeyExchangeRSA
+}
+
+// KeyExchange returns the key exchange family of the negotiated cipher suite,
+// once the handshake is complete. Resumed TLS 1.2 sessions report the family
+// of the handshake that established them.
+func (cs *ConnectionState) KeyExchange() KeyExchange {
+	if !cs.HandshakeComplete {
+		return KeyExchangeUnspecified
+	}
+	return CipherSuiteKeyExchange(cs.CipherSuite)
+}
+
+// preferKeyExchange reorders the TLS 1.2 cipher suites of the given family
+// ahead of the others in place, see ClientHelloSpec.KeyExchangePreference.
+func preferKeyExchange(suites []uint16, preference KeyExchange) error {
+	switch preference {
+	case KeyExchangeUnspecified:
+		return nil
+	case KeyExchangeECDHE, KeyExchangeRSA:
+	default:
+		return errors.New("tls: invalid KeyExchangePreference")
+	}
+
+	var positions []int
+	var preferred, others []uint16
+	for i, id := range suites {
+		if cipherSuiteTLS13ByID(id) != nil {
+			continue
+		}
+		switch CipherSuiteKeyExchange(id) {
+		case KeyExchangeUnspecified:
+			continue
+		case preference:
+			preferred = append(preferred, id)
+		default:
+			others = append(others, id)
+		}
+		positions = append(positions, i)
+	}
+	for i, id := range append(preferred, others...) {
+		suites[positions[i]] = id
+	}
+	return nil
+}
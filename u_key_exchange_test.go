@@ -0,0 +1,86 @@
+package tls
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestKeyExchangePreference(t *testing.T) {
+	spec, err := UTLSIdToSpec(HelloChrome_Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.KeyExchangePreference = KeyExchangeRSA
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloCustom)
+	if err := uconn.ApplyPreset(&spec); err != nil {
+		t.Fatal(err)
+	}
+	suites := uconn.HandshakeState.Hello.CipherSuites
+	if len(suites) != len(spec.CipherSuites) {
+		t.Fatalf("got %d cipher suites, want %d", len(suites), len(spec.CipherSuites))
+	}
+	var families []KeyExchange
+	for i, id := range suites {
+		if isGREASEUint16(spec.CipherSuites[i]) || cipherSuiteTLS13ByID(spec.CipherSuites[i]) != nil {
+			if isGREASEUint16(id) != isGREASEUint16(spec.CipherSuites[i]) || !isGREASEUint16(id) && id != spec.CipherSuites[i] {
+				t.Errorf("cipher suite %#04x at position %d moved", spec.CipherSuites[i], i)
+			}
+			continue
+		}
+		families = append(families, CipherSuiteKeyExchange(id))
+	}
+	if i := slices.Index(families, KeyExchangeECDHE); i < 0 || slices.Contains(families[i:], KeyExchangeRSA) {
+		t.Errorf("cipher suites %x don't start with the RSA key exchange", suites)
+	}
+	// The order within each family is kept.
+	var wantRSA, gotRSA []uint16
+	for _, id := range spec.CipherSuites {
+		if CipherSuiteKeyExchange(id) == KeyExchangeRSA {
+			wantRSA = append(wantRSA, id)
+		}
+	}
+	for _, id := range suites {
+		if CipherSuiteKeyExchange(id) == KeyExchangeRSA {
+			gotRSA = append(gotRSA, id)
+		}
+	}
+	if len(wantRSA) == 0 || !slices.Equal(gotRSA, wantRSA) {
+		t.Errorf("RSA cipher suites %x, want %x", gotRSA, wantRSA)
+	}
+
+	spec.KeyExchangePreference = KeyExchangeRSA + 1
+	if err := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloCustom).ApplyPreset(&spec); err == nil {
+		t.Error("ApplyPreset accepted an invalid KeyExchangePreference")
+	}
+}
+
+func TestConnectionStateKeyExchange(t *testing.T) {
+	for _, test := range []struct {
+		version uint16
+		suites  []uint16
+		want    KeyExchange
+	}{
+		{VersionTLS12, []uint16{TLS_RSA_WITH_AES_128_GCM_SHA256}, KeyExchangeRSA},
+		{VersionTLS12, []uint16{TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, KeyExchangeECDHE},
+		{VersionTLS13, nil, KeyExchangeECDHE},
+	} {
+		serverConfig := testConfig.Clone()
+		serverConfig.MaxVersion = test.version
+		serverConfig.CipherSuites = test.suites
+		s, err := NewUTLSServer(serverConfig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := NewUTLSClient(s, &Config{InsecureSkipVerify: true}, HelloChrome_Auto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cs := c.ConnectionState()
+		if kx := cs.KeyExchange(); kx != test.want {
+			t.Errorf("%x %x: got key exchange %v, want %v", test.version, test.suites, kx, test.want)
+		}
+		c.Close()
+		s.Close()
+	}
+}
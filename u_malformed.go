@@ -0,0 +1,118 @@
+// Copyright 2024 uTLS Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/refraction-networking/utls/tlsbytes"
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// Malformations is a set of deviations from the TLS specifications that a
+// ClientHelloSpec may opt into, to emulate buggy clients seen in the wild.
+// They only relax what UConn sends: ClientHellos with malformations are still
+// rejected when received by a server of this package.
+type Malformations uint8
+
+const (
+	// MalformDuplicateExtensions allows an extension type to appear more
+	// than once in the ClientHello (RFC 8446, Section 4.2). Only the first
+	// padding extension is sized automatically, later ones are sent as
+	// configured.
+	MalformDuplicateExtensions Malformations = 1 << iota
+
+	// MalformExtraGREASEExtensions allows more than two GREASE extensions.
+	// The extra extensions get GREASE values not used by the others, for as
+	// long as there are any left.
+	MalformExtraGREASEExtensions
+)
+
+// checkExtensionTypes returns an error if the extension types of a ClientHello
+// include malformations that are not allowed.
+func (m Malformations) checkExtensionTypes(extTypes []uint16) error {
+	seen := make(map[uint16]bool, len(extTypes))
+	for _, extType := range extTypes {
+		if seen[extType] && m&MalformDuplicateExtensions == 0 {
+			return fmt.Errorf("tls: extension %d is present more than once in the ClientHello, which requires ClientHelloSpec.Malformations to include MalformDuplicateExtensions", extType)
+		}
+		seen[extType] = true
+	}
+	return nil
+}
+
+// malformationsOf returns the malformations needed to send a ClientHello with
+// the given extension types.
+func malformationsOf(extTypes []uint16) Malformations {
+	var m Malformations
+	greaseExts := 0
+	seen := make(map[uint16]bool, len(extTypes))
+	for _, extType := range extTypes {
+		if isGREASEUint16(extType) {
+			// GREASE values are reassigned by ApplyPreset, so repeated
+			// GREASE values don't need to be kept.
+			greaseExts++
+			continue
+		}
+		if seen[extType] {
+			m |= MalformDuplicateExtensions
+		}
+		seen[extType] = true
+	}
+	if greaseExts > 2 {
+		m |= MalformExtraGREASEExtensions
+	}
+	return m
+}
+
+// readExtensionTypes returns the types of the extensions in a ClientHello
+// extensions block.
+func readExtensionTypes(extensions cryptobyte.String) ([]uint16, bool) {
+	var extTypes []uint16
+	for !extensions.Empty() {
+		var extType uint16
+		var extData cryptobyte.String
+		if !extensions.ReadUint16(&extType) || !extensions.ReadUint16LengthPrefixed(&extData) {
+			return nil, false
+		}
+		extTypes = append(extTypes, extType)
+	}
+	return extTypes, true
+}
+
+// clientHelloExtensionTypes returns the types of the extensions of a marshaled
+// ClientHello handshake message.
+func clientHelloExtensionTypes(raw []byte) ([]uint16, bool) {
+	s := cryptobyte.String(raw)
+	var sessionID, compressionMethods []byte
+	var cipherSuites, extensions cryptobyte.String
+	if !s.Skip(4+2+32) || // message header, legacy_version and random
+		!readUint8LengthPrefixed(&s, &sessionID) ||
+		!s.ReadUint16LengthPrefixed(&cipherSuites) ||
+		!readUint8LengthPrefixed(&s, &compressionMethods) {
+		return nil, false
+	}
+	if s.Empty() {
+		return nil, true
+	}
+	if !s.ReadUint16LengthPrefixed(&extensions) || !s.Empty() {
+		return nil, false
+	}
+	return readExtensionTypes(extensions)
+}
+
+// extraGREASEValue returns a GREASE value for an extension past the first two,
+// avoiding the values in used if possible.
+func (uconn *UConn) extraGREASEValue(used []uint16) uint16 {
+	v := GetBoringGREASEValue(uconn.greaseSeed, ssl_grease_extension2)
+	for range 16 {
+		v = tlsbytes.GREASEValue(v + 0x10)
+		if !slices.Contains(used, v) {
+			break
+		}
+	}
+	return v
+}
@@ -0,0 +1,112 @@
+package tls
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+func malformedTestSpec(t *testing.T, malformations Malformations, extra ...TLSExtension) *ClientHelloSpec {
+	spec, err := UTLSIdToSpec(HelloChrome_Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.Extensions = append(spec.Extensions, extra...)
+	spec.Malformations = malformations
+	return &spec
+}
+
+func helloExtensionTypes(t *testing.T, uconn *UConn) []uint16 {
+	extTypes, ok := clientHelloExtensionTypes(uconn.HandshakeState.Hello.Raw)
+	if !ok {
+		t.Fatal("failed to parse ClientHello extensions")
+	}
+	return extTypes
+}
+
+func TestUTLSMalformDuplicateExtensions(t *testing.T) {
+	duplicate := &ALPNExtension{AlpnProtocols: []string{"http/1.1"}}
+
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloCustom)
+	if err := uconn.ApplyPreset(malformedTestSpec(t, 0, duplicate)); err != nil {
+		t.Fatal(err)
+	}
+	if err := uconn.BuildHandshakeState(); err == nil {
+		t.Fatal("expected an error building a ClientHello with a duplicate extension")
+	}
+
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c := NewUnstartedUTLSClient(s, nil, HelloCustom)
+	if err := c.ApplyPreset(malformedTestSpec(t, MalformDuplicateExtensions, duplicate)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	extTypes := helloExtensionTypes(t, c.UConn)
+	if n := len(slices.DeleteFunc(slices.Clone(extTypes), func(e uint16) bool { return e != extensionALPN })); n != 2 {
+		t.Errorf("ClientHello has %d ALPN extensions, want 2", n)
+	}
+
+	// Servers still reject ClientHellos with duplicate extensions.
+	if err := c.Start(); err == nil {
+		t.Error("server accepted a ClientHello with a duplicate extension")
+	}
+
+	var spec ClientHelloSpec
+	record := append([]byte{byte(recordTypeHandshake), 3, 1, 0, 0}, c.HandshakeState.Hello.Raw...)
+	if err := spec.FromRaw(record, true); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Malformations != MalformDuplicateExtensions {
+		t.Errorf("FromRaw read Malformations %d, want %d", spec.Malformations, MalformDuplicateExtensions)
+	}
+}
+
+func TestUTLSMalformExtraGREASEExtensions(t *testing.T) {
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloCustom)
+	if err := uconn.ApplyPreset(malformedTestSpec(t, 0, &UtlsGREASEExtension{})); err == nil {
+		t.Fatal("expected an error applying a spec with three GREASE extensions")
+	}
+
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c := NewUnstartedUTLSClient(s, nil, HelloCustom)
+	if err := c.ApplyPreset(malformedTestSpec(t, MalformExtraGREASEExtensions, &UtlsGREASEExtension{}, &UtlsGREASEExtension{})); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	var grease []uint16
+	for _, e := range helloExtensionTypes(t, c.UConn) {
+		if isGREASEUint16(e) && !slices.Contains(grease, e) {
+			grease = append(grease, e)
+		}
+	}
+	if len(grease) != 4 {
+		t.Errorf("ClientHello has distinct GREASE extensions %x, want 4", grease)
+	}
+
+	// Distinct GREASE extensions are not duplicates, so the handshake succeeds.
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	var spec ClientHelloSpec
+	record := append([]byte{byte(recordTypeHandshake), 3, 1, 0, 0}, c.HandshakeState.Hello.Raw...)
+	if err := spec.FromRaw(record, true); err != nil {
+		t.Fatal(err)
+	}
+	if spec.Malformations != MalformExtraGREASEExtensions {
+		t.Errorf("FromRaw read Malformations %d, want %d", spec.Malformations, MalformExtraGREASEExtensions)
+	}
+}
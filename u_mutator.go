@@ -0,0 +1,288 @@
+// Copyright 2024 uTLS Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// MutationKind identifies the kind of change a SpecMutation makes to a base
+// ClientHelloSpec.
+type MutationKind int
+
+const (
+	// MutationNone leaves the base spec untouched. It is always generated first
+	// so that probes have a control sample to compare the variants against.
+	MutationNone MutationKind = iota
+	// MutationDropExtension removes the extension at Index.
+	MutationDropExtension
+	// MutationSwapExtensions swaps the extensions at Index and Index+1.
+	MutationSwapExtensions
+	// MutationDropCipherSuite removes the cipher suite at Index.
+	MutationDropCipherSuite
+	// MutationReplaceCipherSuite replaces the cipher suite at Index with CipherSuite.
+	MutationReplaceCipherSuite
+)
+
+func (k MutationKind) String() string {
+	switch k {
+	case MutationNone:
+		return "none"
+	case MutationDropExtension:
+		return "drop-extension"
+	case MutationSwapExtensions:
+		return "swap-extensions"
+	case MutationDropCipherSuite:
+		return "drop-cipher-suite"
+	case MutationReplaceCipherSuite:
+		return "replace-cipher-suite"
+	default:
+		return fmt.Sprintf("MutationKind(%d)", int(k))
+	}
+}
+
+// SpecMutation describes a single, systematic change to a base ClientHelloSpec.
+type SpecMutation struct {
+	Kind MutationKind
+
+	// Index is the position in the base spec's Extensions or CipherSuites the
+	// mutation applies to.
+	Index int
+
+	// CipherSuite is the replacement value for MutationReplaceCipherSuite.
+	CipherSuite uint16
+}
+
+func (m SpecMutation) String() string {
+	switch m.Kind {
+	case MutationNone:
+		return m.Kind.String()
+	case MutationReplaceCipherSuite:
+		return fmt.Sprintf("%s[%d]=0x%04x", m.Kind, m.Index, m.CipherSuite)
+	default:
+		return fmt.Sprintf("%s[%d]", m.Kind, m.Index)
+	}
+}
+
+// MutationResult is the outcome of probing a single variant.
+type MutationResult struct {
+	Mutation SpecMutation
+	Err      error // error returned by the probe, or by building the variant
+}
+
+// SpecMutator generates variants of a base ClientHelloSpec that differ from it in
+// exactly one field, and runs a user-supplied probe against each of them. It is
+// meant to automate "which field triggers the block" experiments.
+type SpecMutator struct {
+	// NewBaseSpec must return a fresh copy of the base spec every time it is called,
+	// since TLSExtensions carry state that must not be shared across connections.
+	// The copies must be identical, so that the indices of the mutations refer to
+	// the spec mutated by Variant: UTLSIdToSpec isn't suitable for presets that
+	// shuffle their extensions, use NewSpecMutatorFromID instead.
+	NewBaseSpec func() (ClientHelloSpec, error)
+
+	// ReplacementCipherSuites, if set, are substituted one at a time for every
+	// cipher suite of the base spec.
+	ReplacementCipherSuites []uint16
+
+	// SkipGREASE excludes GREASE extensions and cipher suites from mutation.
+	SkipGREASE bool
+}
+
+// NewSpecMutatorFromID returns a SpecMutator using the spec of a built-in
+// ClientHelloID as base. The spec is built once and copied for each variant,
+// so that the extension order of presets that shuffle it is the same in all of
+// them.
+func NewSpecMutatorFromID(id ClientHelloID) *SpecMutator {
+	base, err := UTLSIdToSpec(id)
+	return &SpecMutator{
+		NewBaseSpec: func() (ClientHelloSpec, error) {
+			if err != nil {
+				return ClientHelloSpec{}, err
+			}
+			return cloneClientHelloSpec(&base), nil
+		},
+	}
+}
+
+// Mutations enumerates every single-field mutation of the base spec, starting
+// with MutationNone.
+func (m *SpecMutator) Mutations() ([]SpecMutation, error) {
+	if m.NewBaseSpec == nil {
+		return nil, errors.New("tls: SpecMutator.NewBaseSpec is nil")
+	}
+	base, err := m.NewBaseSpec()
+	if err != nil {
+		return nil, err
+	}
+
+	mutations := []SpecMutation{{Kind: MutationNone}}
+	for i, ext := range base.Extensions {
+		if m.SkipGREASE && isGREASEExtension(ext) {
+			continue
+		}
+		mutations = append(mutations, SpecMutation{Kind: MutationDropExtension, Index: i})
+	}
+	for i := 0; i+1 < len(base.Extensions); i++ {
+		if m.SkipGREASE && (isGREASEExtension(base.Extensions[i]) || isGREASEExtension(base.Extensions[i+1])) {
+			continue
+		}
+		mutations = append(mutations, SpecMutation{Kind: MutationSwapExtensions, Index: i})
+	}
+	for i, suite := range base.CipherSuites {
+		if m.SkipGREASE && isGREASEUint16(suite) {
+			continue
+		}
+		mutations = append(mutations, SpecMutation{Kind: MutationDropCipherSuite, Index: i})
+		for _, replacement := range m.ReplacementCipherSuites {
+			if replacement == suite {
+				continue
+			}
+			mutations = append(mutations, SpecMutation{Kind: MutationReplaceCipherSuite, Index: i, CipherSuite: replacement})
+		}
+	}
+	return mutations, nil
+}
+
+// Variant returns a fresh copy of the base spec with mutation applied.
+func (m *SpecMutator) Variant(mutation SpecMutation) (ClientHelloSpec, error) {
+	if m.NewBaseSpec == nil {
+		return ClientHelloSpec{}, errors.New("tls: SpecMutator.NewBaseSpec is nil")
+	}
+	spec, err := m.NewBaseSpec()
+	if err != nil {
+		return ClientHelloSpec{}, err
+	}
+
+	outOfRange := func(n int) error {
+		return fmt.Errorf("tls: mutation %s out of range for spec with %d elements", mutation, n)
+	}
+
+	switch mutation.Kind {
+	case MutationNone:
+	case MutationDropExtension:
+		if mutation.Index < 0 || mutation.Index >= len(spec.Extensions) {
+			return ClientHelloSpec{}, outOfRange(len(spec.Extensions))
+		}
+		spec.Extensions = append(spec.Extensions[:mutation.Index:mutation.Index], spec.Extensions[mutation.Index+1:]...)
+	case MutationSwapExtensions:
+		if mutation.Index < 0 || mutation.Index+1 >= len(spec.Extensions) {
+			return ClientHelloSpec{}, outOfRange(len(spec.Extensions))
+		}
+		spec.Extensions[mutation.Index], spec.Extensions[mutation.Index+1] = spec.Extensions[mutation.Index+1], spec.Extensions[mutation.Index]
+	case MutationDropCipherSuite:
+		if mutation.Index < 0 || mutation.Index >= len(spec.CipherSuites) {
+			return ClientHelloSpec{}, outOfRange(len(spec.CipherSuites))
+		}
+		spec.CipherSuites = append(spec.CipherSuites[:mutation.Index:mutation.Index], spec.CipherSuites[mutation.Index+1:]...)
+	case MutationReplaceCipherSuite:
+		if mutation.Index < 0 || mutation.Index >= len(spec.CipherSuites) {
+			return ClientHelloSpec{}, outOfRange(len(spec.CipherSuites))
+		}
+		spec.CipherSuites = append([]uint16(nil), spec.CipherSuites...)
+		spec.CipherSuites[mutation.Index] = mutation.CipherSuite
+	default:
+		return ClientHelloSpec{}, fmt.Errorf("tls: unknown mutation kind %s", mutation.Kind)
+	}
+	return spec, nil
+}
+
+// Run builds every variant returned by Mutations and calls probe with it, in
+// order. The probe typically dials a server with a HelloCustom UConn, applies
+// the spec and reports whether the handshake succeeded.
+func (m *SpecMutator) Run(probe func(spec *ClientHelloSpec, mutation SpecMutation) error) ([]MutationResult, error) {
+	mutations, err := m.Mutations()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]MutationResult, 0, len(mutations))
+	for _, mutation := range mutations {
+		spec, err := m.Variant(mutation)
+		if err == nil {
+			err = probe(&spec, mutation)
+		}
+		results = append(results, MutationResult{Mutation: mutation, Err: err})
+	}
+	return results, nil
+}
+
+func isGREASEExtension(ext TLSExtension) bool {
+	_, ok := ext.(*UtlsGREASEExtension)
+	return ok
+}
+
+// cloneClientHelloSpec returns a deep copy of spec, which must not have been
+// applied to a connection: the unexported state of its extensions, which is
+// only set when they are, is copied as is, and so are funcs.
+func cloneClientHelloSpec(spec *ClientHelloSpec) ClientHelloSpec {
+	clone := *spec
+	deepCopyReferences(reflect.ValueOf(&clone).Elem())
+	return clone
+}
+
+// deepCopyReferences replaces the slices, maps, pointers and interfaces
+// reachable from v through exported fields by copies.
+func deepCopyReferences(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		// Exported fields of unexported embedded structs are settable.
+		for i := 0; i < v.NumField(); i++ {
+			deepCopyReferences(v.Field(i))
+		}
+		return
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			deepCopyReferences(v.Index(i))
+		}
+		return
+	}
+	if !v.CanSet() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return
+		}
+		p := reflect.New(v.Type().Elem())
+		p.Elem().Set(v.Elem())
+		deepCopyReferences(p.Elem())
+		v.Set(p)
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		e := reflect.New(v.Elem().Type()).Elem()
+		e.Set(v.Elem())
+		deepCopyReferences(e)
+		v.Set(e)
+	case reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+		s := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(s, v)
+		for i := 0; i < s.Len(); i++ {
+			deepCopyReferences(s.Index(i))
+		}
+		v.Set(s)
+	case reflect.Map:
+		if v.IsNil() {
+			return
+		}
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			e := reflect.New(iter.Value().Type()).Elem()
+			e.Set(iter.Value())
+			deepCopyReferences(e)
+			m.SetMapIndex(iter.Key(), e)
+		}
+		v.Set(m)
+	}
+}
@@ -0,0 +1,146 @@
+package tls
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestSpecMutator(t *testing.T) {
+	newBase := func() (ClientHelloSpec, error) {
+		return ClientHelloSpec{
+			CipherSuites: []uint16{GREASE_PLACEHOLDER, TLS_AES_128_GCM_SHA256, TLS_AES_256_GCM_SHA384},
+			Extensions: []TLSExtension{
+				&UtlsGREASEExtension{},
+				&SNIExtension{},
+				&SupportedCurvesExtension{[]CurveID{X25519}},
+			},
+		}, nil
+	}
+
+	m := &SpecMutator{
+		NewBaseSpec:             newBase,
+		ReplacementCipherSuites: []uint16{TLS_CHACHA20_POLY1305_SHA256},
+		SkipGREASE:              true,
+	}
+
+	mutations, err := m.Mutations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// none + 2 drops + 1 swap + 2 cipher drops + 2 cipher replacements
+	if len(mutations) != 8 {
+		t.Fatalf("got %d mutations, want 8: %v", len(mutations), mutations)
+	}
+	if mutations[0].Kind != MutationNone {
+		t.Errorf("first mutation is %s, want %s", mutations[0], MutationNone)
+	}
+
+	spec, err := m.Variant(SpecMutation{Kind: MutationSwapExtensions, Index: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spec.Extensions[1].(*SupportedCurvesExtension); !ok {
+		t.Errorf("extensions were not swapped: %T", spec.Extensions[1])
+	}
+
+	spec, err = m.Variant(SpecMutation{Kind: MutationReplaceCipherSuite, Index: 2, CipherSuite: TLS_CHACHA20_POLY1305_SHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.CipherSuites[2] != TLS_CHACHA20_POLY1305_SHA256 {
+		t.Errorf("cipher suite was not replaced: %x", spec.CipherSuites)
+	}
+
+	if _, err := m.Variant(SpecMutation{Kind: MutationDropExtension, Index: 3}); err == nil {
+		t.Error("expected error for out of range mutation")
+	}
+
+	errBlocked := errors.New("blocked")
+	results, err := m.Run(func(spec *ClientHelloSpec, mutation SpecMutation) error {
+		for _, ext := range spec.Extensions {
+			if _, ok := ext.(*SNIExtension); ok {
+				return errBlocked
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		dropsSNI := result.Mutation.Kind == MutationDropExtension && result.Mutation.Index == 1
+		if dropsSNI != (result.Err == nil) {
+			t.Errorf("mutation %s: unexpected probe result %v", result.Mutation, result.Err)
+		}
+	}
+}
+
+func TestSpecMutatorFromID(t *testing.T) {
+	m := NewSpecMutatorFromID(HelloChrome_120)
+	mutations, err := m.Mutations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, mutation := range mutations {
+		if _, err := m.Variant(mutation); err != nil {
+			t.Errorf("mutation %s: %v", mutation, err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		spec, err := m.Variant(SpecMutation{Kind: MutationNone})
+		if err != nil {
+			t.Fatal(err)
+		}
+		uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloCustom)
+		if err := uconn.ApplyPreset(&spec); err != nil {
+			t.Fatal(err)
+		}
+		if err := uconn.BuildHandshakeState(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Chrome shuffles its extensions, but the variants share the order of
+	// the base spec, and don't share its extensions.
+	base, err := m.Variant(SpecMutation{Kind: MutationNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		v, err := m.Variant(SpecMutation{Kind: MutationSwapExtensions, Index: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := range v.Extensions {
+			k := j
+			switch j {
+			case 1:
+				k = 2
+			case 2:
+				k = 1
+			}
+			if reflect.TypeOf(v.Extensions[j]) != reflect.TypeOf(base.Extensions[k]) {
+				t.Fatalf("variant extension %d is %T, want %T", j, v.Extensions[j], base.Extensions[k])
+			}
+			// Pointers to zero-size values may be equal.
+			if reflect.TypeOf(v.Extensions[j]).Elem().Size() > 0 && v.Extensions[j] == base.Extensions[k] {
+				t.Fatalf("variant extension %d is shared with another variant", j)
+			}
+		}
+	}
+	isCurves := func(e TLSExtension) bool {
+		_, ok := e.(*SupportedCurvesExtension)
+		return ok
+	}
+	base.Extensions[slices.IndexFunc(base.Extensions, isCurves)].(*SupportedCurvesExtension).Curves[0] = 0
+	again, err := m.Variant(SpecMutation{Kind: MutationNone})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Extensions[slices.IndexFunc(again.Extensions, isCurves)].(*SupportedCurvesExtension).Curves[0] == 0 {
+		t.Error("variants share the slices of their extensions")
+	}
+}
@@ -0,0 +1,88 @@
+package tls
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// connectionIDLength is the length of the identifiers returned by
+// ConnectionState.ConnectionID.
+const connectionIDLength = 16
+
+// ConnectionID returns a 16-byte identifier of the connection derived from its
+// exporter, which both peers compute identically without exchanging it, e.g. to
+// tag the sessions of a stream multiplexer. It is unique to the connection and
+// reveals nothing about its keys.
+//
+// Like ExportKeyingMaterial, it fails for TLS 1.2 connections with
+// renegotiation enabled, as parrots of browsers are.
+func (cs *ConnectionState) ConnectionID() ([]byte, error) {
+	return cs.ExportNamespacedKey("uTLS", "connection id", connectionIDLength)
+}
+
+// errHalfCloseTLS12 is returned by MuxConn.CloseWrite for TLS 1.2 connections.
+var errHalfCloseTLS12 = errors.New("tls: CloseWrite requires TLS 1.3, as TLS 1.2 peers may close the connection on close_notify")
+
+// MuxConn adapts a UConn for stream multiplexers such as yamux and smux, which
+// take ownership of the connection they run on.
+//
+// The handshake is completed by NewMuxConn, so that the connection ID is
+// available before the multiplexer starts. Close may be called by both the
+// multiplexer and the application: only the first call closes the connection.
+// CloseWrite sends close_notify while still allowing reads, which TLS 1.3
+// defines as a half-close.
+type MuxConn struct {
+	*UConn
+
+	id []byte
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewMuxConn completes the handshake of uconn and returns a MuxConn owning it.
+// If the client False Started, the server's Finished is read first, as the
+// ConnectionID depends on it. uconn is closed if NewMuxConn fails, including
+// if its ConnectionID can't be derived.
+func NewMuxConn(ctx context.Context, uconn *UConn) (*MuxConn, error) {
+	if err := uconn.HandshakeContext(ctx); err != nil {
+		uconn.Close()
+		return nil, err
+	}
+	if err := uconn.waitFalseStart(); err != nil {
+		uconn.Close()
+		return nil, err
+	}
+	cs := uconn.ConnectionState()
+	id, err := cs.ConnectionID()
+	if err != nil {
+		uconn.Close()
+		return nil, err
+	}
+	return &MuxConn{UConn: uconn, id: id}, nil
+}
+
+// ID returns the ConnectionID of the connection.
+func (c *MuxConn) ID() []byte {
+	return append([]byte(nil), c.id...)
+}
+
+// CloseWrite sends close_notify without closing the connection, so that the
+// peer reads EOF while data can still be read from it. It returns an error
+// without sending anything for TLS 1.2 connections, whose peers may close the
+// connection when they receive close_notify.
+func (c *MuxConn) CloseWrite() error {
+	if c.UConn.ConnectionState().Version != VersionTLS13 {
+		return errHalfCloseTLS12
+	}
+	return c.UConn.CloseWrite()
+}
+
+// Close closes the connection. Calls after the first return the same error.
+func (c *MuxConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.UConn.Close()
+	})
+	return c.closeErr
+}
@@ -0,0 +1,98 @@
+package tls
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+)
+
+func TestMuxConn(t *testing.T) {
+	for _, test := range []struct {
+		version   uint16
+		id        ClientHelloID
+		halfClose bool
+	}{
+		{VersionTLS13, HelloChrome_120, true},
+		// HelloChrome_120 enables renegotiation, which disables the exporter
+		// in TLS 1.2.
+		{VersionTLS12, HelloGolang, false},
+	} {
+		serverIDs := make(chan []byte, 1)
+		s, err := NewUTLSServer(&Config{MaxVersion: test.version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Handler = func(c *Conn) {
+			cs := c.ConnectionState()
+			id, err := cs.ConnectionID()
+			if err != nil {
+				t.Error(err)
+			}
+			serverIDs <- id
+			// Echo until the client half-closes, then say goodbye.
+			io.Copy(c, c)
+			c.Write([]byte("bye"))
+		}
+
+		c := NewUnstartedUTLSClient(s, nil, test.id)
+		if err := c.Start(); err != nil {
+			t.Fatal(err)
+		}
+		mc, err := NewMuxConn(context.Background(), c.UConn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id := <-serverIDs; len(mc.ID()) != 16 || !bytes.Equal(mc.ID(), id) {
+			t.Errorf("%x: client ID %x, server ID %x", test.version, mc.ID(), id)
+		}
+
+		if _, err := mc.Write([]byte("ping")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.ReadFull(mc, make([]byte, 4)); err != nil {
+			t.Fatal(err)
+		}
+		err = mc.CloseWrite()
+		if (err == nil) != test.halfClose {
+			t.Errorf("%x: CloseWrite error %v", test.version, err)
+		}
+		if test.halfClose {
+			if b, err := io.ReadAll(mc); err != nil || string(b) != "bye" {
+				t.Errorf("%x: read %q, %v after CloseWrite", test.version, b, err)
+			}
+		}
+
+		if err := mc.Close(); err != nil {
+			t.Errorf("%x: Close: %v", test.version, err)
+		}
+		if err := mc.Close(); err != nil {
+			t.Errorf("%x: second Close: %v", test.version, err)
+		}
+		s.Close()
+	}
+}
+
+func TestMuxConnDistinctIDs(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var ids [][]byte
+	for i := 0; i < 2; i++ {
+		c := NewUnstartedUTLSClient(s, nil, HelloGolang)
+		if err := c.Start(); err != nil {
+			t.Fatal(err)
+		}
+		mc, err := NewMuxConn(context.Background(), c.UConn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, mc.ID())
+		mc.Close()
+	}
+	if bytes.Equal(ids[0], ids[1]) {
+		t.Error("two connections have the same ID")
+	}
+}
@@ -0,0 +1,61 @@
+package tls
+
+import (
+	"errors"
+	"net"
+)
+
+// SwapNetConn replaces the underlying connection of c with conn, keeping the
+// TLS state, and returns the previous one, which is not closed. It lets a
+// client migrate a connection to another network path, e.g. when a mobile
+// device changes interfaces, provided the peer resumes the byte stream on
+// conn where it stopped on the previous connection.
+//
+// The swap is only allowed between records, after the handshake, and while
+// no Read or Write is in progress. A blocked Read can be interrupted with
+// SetReadDeadline, which leaves c usable, unless part of a record was read.
+// Any other error of c, including a failed Write, is returned, and so is
+// net.ErrClosed once Close was called.
+func (c *Conn) SwapNetConn(conn net.Conn) (net.Conn, error) {
+	if !c.in.TryLock() {
+		return nil, errors.New("tls: SwapNetConn called during a Read or handshake")
+	}
+	defer c.in.Unlock()
+	if !c.out.TryLock() {
+		return nil, errors.New("tls: SwapNetConn called during a Write")
+	}
+	defer c.out.Unlock()
+
+	switch {
+	case c.quic != nil:
+		return nil, errors.New("tls: SwapNetConn called on a QUIC connection")
+	case !c.isHandshakeComplete.Load():
+		return nil, errors.New("tls: SwapNetConn called before the handshake")
+	case c.in.err != nil:
+		return nil, c.in.err
+	case c.out.err != nil:
+		return nil, c.out.err
+	case c.rawInput.Len() > 0 || c.hand.Len() > 0:
+		return nil, errors.New("tls: SwapNetConn called in the middle of a record or handshake message")
+	}
+	// Close marks c as closed before it takes connMu to get the connection to
+	// close, so it either closes conn or makes the swap fail.
+	c.utls.connMu.Lock()
+	defer c.utls.connMu.Unlock()
+	if c.activeCall.Load()&1 != 0 {
+		return nil, net.ErrClosed
+	}
+	old := c.conn
+	c.conn = conn
+	return old, nil
+}
+
+// closeNetConn closes the underlying connection, unless it was already closed
+// by closeNetConn, e.g. after a decryption error, in which case it returns
+// nil so that Close still succeeds.
+func (c *Conn) closeNetConn() error {
+	if c.utls.netConnClosed.Swap(true) {
+		return nil
+	}
+	return c.NetConn().Close()
+}
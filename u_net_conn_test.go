@@ -0,0 +1,111 @@
+package tls
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSwapNetConn(t *testing.T) {
+	c1, s1 := net.Pipe()
+	client := Client(c1, testConfig.Clone())
+	server := Server(s1, testConfig.Clone())
+	defer client.Close()
+	defer server.Close()
+
+	if _, err := client.SwapNetConn(c1); err == nil {
+		t.Error("SwapNetConn succeeded before the handshake")
+	}
+
+	exchange := func(msg string) {
+		t.Helper()
+		errc := make(chan error, 1)
+		go func() {
+			_, err := client.Write([]byte(msg))
+			errc <- err
+		}()
+		buf := make([]byte, len(msg))
+		if _, err := io.ReadFull(server, buf); err != nil {
+			t.Fatal(err)
+		}
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != msg {
+			t.Fatalf("read %q, want %q", buf, msg)
+		}
+	}
+	exchange("before")
+
+	c2, s2 := net.Pipe()
+	// Close the pipes first, so that Close doesn't wait to send close_notify.
+	defer c1.Close()
+	defer c2.Close()
+	if old, err := client.SwapNetConn(c2); err != nil || old != c1 {
+		t.Fatalf("SwapNetConn = %v, %v", old, err)
+	}
+	if _, err := server.SwapNetConn(s2); err != nil {
+		t.Fatal(err)
+	}
+	if client.NetConn() != c2 {
+		t.Error("NetConn doesn't return the new connection")
+	}
+	exchange("after")
+
+	// A Read interrupted in the middle of a record prevents swapping.
+	go c2.Write([]byte{byte(recordTypeApplicationData), 3, 3})
+	server.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, err := server.Read(make([]byte, 1)); err == nil {
+		t.Fatal("Read of a partial record succeeded")
+	}
+	if _, err := server.SwapNetConn(s1); err == nil {
+		t.Error("SwapNetConn succeeded in the middle of a record")
+	}
+}
+
+func TestSwapNetConnConcurrent(t *testing.T) {
+	c1, s1 := net.Pipe()
+	client := Client(c1, testConfig.Clone())
+	server := Server(s1, testConfig.Clone())
+	defer server.Close()
+	go server.Handshake()
+	if err := client.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The net.Conn methods of client may be called while it's swapped, and
+	// see either connection. Run with -race.
+	c2, _ := net.Pipe()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			client.SetDeadline(time.Now().Add(time.Minute))
+			client.LocalAddr()
+			client.RemoteAddr()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		next := c2
+		if i%2 == 1 {
+			next = c1
+		}
+		if _, err := client.SwapNetConn(next); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wg.Wait()
+
+	// Closing the connections first, so that Close doesn't wait to send
+	// close_notify.
+	c1.Close()
+	c2.Close()
+	client.Close()
+	if _, err := client.SwapNetConn(c1); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("SwapNetConn after Close = %v, want net.ErrClosed", err)
+	}
+}
@@ -0,0 +1,83 @@
+package tls
+
+import (
+	"bytes"
+	"errors"
+	"time"
+)
+
+// NewSessionTicket is a NewSessionTicket handshake message, in the format of
+// RFC 5077 for TLS 1.2 and earlier, or of RFC 8446, Section 4.6.1 for TLS 1.3.
+type NewSessionTicket struct {
+	// Version selects the format of the message: VersionTLS13, or
+	// VersionTLS12 for all earlier versions.
+	Version uint16
+
+	// Lifetime is the ticket_lifetime of a TLS 1.3 ticket, or the
+	// ticket_lifetime_hint of a TLS 1.2 ticket, for which zero means
+	// unspecified.
+	Lifetime time.Duration
+
+	// Ticket is the opaque ticket, sent back by the client to resume the
+	// session.
+	Ticket []byte
+
+	// AgeAdd, Nonce and MaxEarlyData are only used in TLS 1.3. MaxEarlyData
+	// is zero if the early_data extension is absent.
+	AgeAdd       uint32
+	Nonce        []byte
+	MaxEarlyData uint32
+}
+
+// ParseNewSessionTicket parses a NewSessionTicket handshake message, including
+// its four-byte header, sent in a connection of the given version. Unknown
+// TLS 1.3 extensions are ignored.
+func ParseNewSessionTicket(raw []byte, version uint16) (*NewSessionTicket, error) {
+	if len(raw) == 0 || raw[0] != typeNewSessionTicket {
+		return nil, errors.New("tls: not a NewSessionTicket message")
+	}
+	if version == VersionTLS13 {
+		var m newSessionTicketMsgTLS13
+		if !m.unmarshal(raw) {
+			return nil, errors.New("tls: malformed TLS 1.3 NewSessionTicket message")
+		}
+		return newSessionTicketFromTLS13(&m), nil
+	}
+	var m newSessionTicketMsg
+	if !m.unmarshal(raw) {
+		return nil, errors.New("tls: malformed NewSessionTicket message")
+	}
+	return newSessionTicketFromTLS12(&m), nil
+}
+
+func newSessionTicketFromTLS13(m *newSessionTicketMsgTLS13) *NewSessionTicket {
+	return &NewSessionTicket{
+		Version:      VersionTLS13,
+		Lifetime:     time.Duration(m.lifetime) * time.Second,
+		Ticket:       bytes.Clone(m.label),
+		AgeAdd:       m.ageAdd,
+		Nonce:        bytes.Clone(m.nonce),
+		MaxEarlyData: m.maxEarlyData,
+	}
+}
+
+func newSessionTicketFromTLS12(m *newSessionTicketMsg) *NewSessionTicket {
+	return &NewSessionTicket{
+		Version:  VersionTLS12,
+		Lifetime: time.Duration(m.lifetimeHint) * time.Second,
+		Ticket:   bytes.Clone(m.ticket),
+	}
+}
+
+// onNewSessionTicket calls Config.OnNewSessionTicket, and reports whether cs
+// should be stored in Config.ClientSessionCache.
+func (c *Conn) onNewSessionTicket(ticket *NewSessionTicket, cs *ClientSessionState) (bool, error) {
+	if c.config.OnNewSessionTicket == nil {
+		return c.config.ClientSessionCache != nil, nil
+	}
+	store, err := c.config.OnNewSessionTicket(c.clientSessionCacheKey(), ticket, cs)
+	if err != nil {
+		return false, err
+	}
+	return store && c.config.ClientSessionCache != nil, nil
+}
@@ -0,0 +1,126 @@
+package tls
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestParseNewSessionTicket(t *testing.T) {
+	m13 := &newSessionTicketMsgTLS13{
+		lifetime:     7200,
+		ageAdd:       0x01020304,
+		nonce:        []byte{0},
+		label:        []byte("ticket"),
+		maxEarlyData: 16384,
+	}
+	raw, err := m13.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket, err := ParseNewSessionTicket(raw, VersionTLS13)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.Version != VersionTLS13 || ticket.Lifetime != 2*time.Hour || ticket.AgeAdd != m13.ageAdd ||
+		!bytes.Equal(ticket.Nonce, m13.nonce) || !bytes.Equal(ticket.Ticket, m13.label) || ticket.MaxEarlyData != m13.maxEarlyData {
+		t.Errorf("unexpected TLS 1.3 ticket %+v", ticket)
+	}
+
+	m12 := &newSessionTicketMsg{lifetimeHint: 300, ticket: []byte("ticket")}
+	raw, err = m12.marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ticket, err = ParseNewSessionTicket(raw, VersionTLS12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.Version != VersionTLS12 || ticket.Lifetime != 5*time.Minute || !bytes.Equal(ticket.Ticket, m12.ticket) {
+		t.Errorf("unexpected TLS 1.2 ticket %+v", ticket)
+	}
+
+	if _, err := ParseNewSessionTicket(raw[:len(raw)-1], VersionTLS12); err == nil {
+		t.Error("parsed a truncated message")
+	}
+	if _, err := ParseNewSessionTicket(raw, VersionTLS13); err == nil {
+		t.Error("parsed a TLS 1.2 message as TLS 1.3")
+	}
+	if _, err := ParseNewSessionTicket([]byte{typeFinished, 0, 0, 0}, VersionTLS13); err == nil {
+		t.Error("parsed a Finished message")
+	}
+}
+
+func TestOnNewSessionTicket(t *testing.T) {
+	for _, version := range []uint16{VersionTLS12, VersionTLS13} {
+		s, err := NewUTLSServer(&Config{MaxVersion: version})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var tickets []*NewSessionTicket
+		var sessions []*ClientSessionState
+		store := false
+		config := &Config{
+			OnNewSessionTicket: func(key string, ticket *NewSessionTicket, session *ClientSessionState) (bool, error) {
+				if key != "localhost" {
+					t.Errorf("%x: unexpected cache key %q", version, key)
+				}
+				tickets = append(tickets, ticket)
+				sessions = append(sessions, session)
+				return store, nil
+			},
+		}
+		connect := func() *UTLSTestClient {
+			c, err := NewUTLSClient(s, config, HelloGolang)
+			if err != nil {
+				t.Fatalf("%x: %v", version, err)
+			}
+			defer c.Close()
+			// Read the echo, and the TLS 1.3 session ticket sent before it.
+			if _, err := c.Write([]byte{0}); err != nil {
+				t.Fatalf("%x: %v", version, err)
+			}
+			if _, err := io.ReadFull(c, make([]byte, 1)); err != nil {
+				t.Fatalf("%x: %v", version, err)
+			}
+			return c
+		}
+
+		// Without a ClientSessionCache, the hook can store sessions itself.
+		connect()
+		if len(tickets) != 1 || tickets[0].Version != version || len(tickets[0].Ticket) == 0 {
+			t.Fatalf("%x: unexpected tickets %+v", version, tickets)
+		}
+		config.ClientSessionCache = &testSessionCache{session: sessions[0]}
+		if c := connect(); !c.ConnectionState().DidResume {
+			t.Errorf("%x: didn't resume with the session passed to the hook", version)
+		}
+
+		cache := NewLRUClientSessionCache(1)
+		config.ClientSessionCache = cache
+		tickets = nil
+		connect()
+		if len(tickets) != 1 {
+			t.Errorf("%x: hook called %d times, want 1", version, len(tickets))
+		}
+		if _, ok := cache.Get("localhost"); ok {
+			t.Errorf("%x: session stored although the hook returned false", version)
+		}
+		store = true
+		connect()
+		if _, ok := cache.Get("localhost"); !ok {
+			t.Errorf("%x: session not stored although the hook returned true", version)
+		}
+		s.Close()
+	}
+}
+
+// testSessionCache always returns the same session.
+type testSessionCache struct {
+	session *ClientSessionState
+}
+
+func (c *testSessionCache) Get(string) (*ClientSessionState, bool) { return c.session, true }
+func (c *testSessionCache) Put(string, *ClientSessionState)        {}
@@ -0,0 +1,79 @@
+package tls
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// OriginBoundTokenExtension emits the origin-bound token extension a given
+// Chrome major version sent in its ClientHello, so that historical Chrome
+// fingerprints can be reproduced from a version number, e.g. when generating
+// datasets:
+//
+//   - before Chrome 72: channel_id (30032), the successor of TLS origin-bound
+//     certificates, as in HelloChrome_58 to HelloChrome_70;
+//   - Chrome 72 to 90: nothing, the extension is zero-length;
+//   - Chrome 91 to 132: application_settings (17513);
+//   - Chrome 133 and later: application_settings on its new codepoint (17613).
+//
+// Like FakeChannelIDExtension and ApplicationSettingsExtension, it is not
+// negotiated: uTLS ignores the server's response to it.
+type OriginBoundTokenExtension struct {
+	// ChromeVersion is the Chrome major version to emulate, e.g. 96.
+	ChromeVersion int
+
+	// SupportedProtocols is the payload of application_settings. If empty,
+	// Chrome's []string{"h2"} is used.
+	SupportedProtocols []string
+}
+
+// extension returns the extension ChromeVersion sent, or nil.
+func (e *OriginBoundTokenExtension) extension() TLSExtension {
+	protocols := e.SupportedProtocols
+	if len(protocols) == 0 {
+		protocols = []string{"h2"}
+	}
+	switch {
+	case e.ChromeVersion < 72:
+		return &FakeChannelIDExtension{}
+	case e.ChromeVersion < 91:
+		return nil
+	case e.ChromeVersion < 133:
+		return &ApplicationSettingsExtension{SupportedProtocols: protocols}
+	default:
+		return &ApplicationSettingsExtensionNew{SupportedProtocols: protocols}
+	}
+}
+
+func (e *OriginBoundTokenExtension) writeToUConn(uc *UConn) error {
+	return nil
+}
+
+func (e *OriginBoundTokenExtension) Len() int {
+	if ext := e.extension(); ext != nil {
+		return ext.Len()
+	}
+	return 0
+}
+
+func (e *OriginBoundTokenExtension) Read(b []byte) (int, error) {
+	ext := e.extension()
+	if ext == nil {
+		return 0, io.EOF
+	}
+	return ext.Read(b)
+}
+
+func (e *OriginBoundTokenExtension) UnmarshalJSON(data []byte) error {
+	var originBoundToken struct {
+		ChromeVersion      int      `json:"chrome_version"`
+		SupportedProtocols []string `json:"supported_protocols"`
+	}
+	if err := json.Unmarshal(data, &originBoundToken); err != nil {
+		return err
+	}
+
+	e.ChromeVersion = originBoundToken.ChromeVersion
+	e.SupportedProtocols = originBoundToken.SupportedProtocols
+	return nil
+}
@@ -0,0 +1,59 @@
+package tls
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestOriginBoundTokenExtension(t *testing.T) {
+	for _, test := range []struct {
+		version int
+		want    TLSExtension
+	}{
+		{58, &FakeChannelIDExtension{}},
+		{83, nil},
+		{96, &ApplicationSettingsExtension{SupportedProtocols: []string{"h2"}}},
+		{133, &ApplicationSettingsExtensionNew{SupportedProtocols: []string{"h2"}}},
+	} {
+		var want []byte
+		if test.want != nil {
+			want, _ = io.ReadAll(test.want)
+		}
+		got, err := io.ReadAll(&OriginBoundTokenExtension{ChromeVersion: test.version})
+		if err != nil {
+			t.Fatalf("Chrome %d: %v", test.version, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("Chrome %d: got %x, want %x", test.version, got, want)
+		}
+	}
+}
+
+func TestOriginBoundTokenExtensionHandshake(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, version := range []int{70, 83} {
+		spec, err := UTLSIdToSpec(HelloChrome_120)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, ext := range spec.Extensions {
+			if _, ok := ext.(*ApplicationSettingsExtension); ok {
+				spec.Extensions[i] = &OriginBoundTokenExtension{ChromeVersion: version}
+			}
+		}
+		c := NewUnstartedUTLSClient(s, nil, HelloCustom)
+		if err := c.ApplyPreset(&spec); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Start(); err != nil {
+			t.Fatalf("Chrome %d: %v", version, err)
+		}
+		c.Close()
+	}
+}
@@ -0,0 +1,199 @@
+package tls
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// WritePacing limits the rate at which a Conn writes records to the underlying
+// connection with a token bucket, so that tunnels can shape the throughput of
+// each connection without wrapping the net.Conn, which would hide its
+// optimized write paths. Set it in Config, or per connection with
+// Conn.SetWritePacing.
+//
+// Writes are split into records, and each one waits, without holding the
+// connection's locks, until the records written before it are paid for, but
+// no longer than the write deadline; they then fail with
+// os.ErrDeadlineExceeded. A waiting Write notices a new deadline or pacing
+// right away, and fails with net.ErrClosed if the Conn is closed. Handshake
+// records and alerts are counted, but not delayed. Paced Writes don't
+// interleave with each other.
+type WritePacing struct {
+	// BytesPerSecond is the sustained rate, counting the records as
+	// written, headers and encryption overhead included. If not positive,
+	// writes are not paced.
+	BytesPerSecond int
+
+	// Burst is the number of bytes that can be written without delay after
+	// the connection has been idle. If zero, it's the size of one maximum
+	// size record.
+	Burst int
+}
+
+// writePacer is the token bucket of a Conn.
+type writePacer struct {
+	mu       sync.Mutex
+	rate     float64 // bytes per second, or zero if not pacing
+	burst    float64
+	tokens   float64
+	last     time.Time
+	deadline time.Time
+	closed   bool
+
+	// wake, if not nil, is closed to wake up the Writes waiting in wait when
+	// the deadline or pacing changes, or the Conn is closed.
+	wake chan struct{}
+
+	// explicit is set once SetWritePacing is called, after which Config
+	// doesn't apply anymore.
+	explicit bool
+}
+
+// SetWritePacing paces the records c writes from now on, or stops pacing them
+// if p is nil. It overrides Config.WritePacing and may be called at any time,
+// including before or during the handshake.
+func (c *Conn) SetWritePacing(p *WritePacing) {
+	c.utls.pacer.set(p, true)
+}
+
+// set replaces the pacing of p, unless it was set explicitly and explicit is
+// false. Tokens accumulated under the previous pacing are kept, up to the new
+// burst.
+func (p *writePacer) set(pacing *WritePacing, explicit bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.explicit && !explicit {
+		return
+	}
+	p.explicit = p.explicit || explicit
+	p.wakeLocked()
+	if pacing == nil || pacing.BytesPerSecond <= 0 {
+		p.rate = 0
+		return
+	}
+	p.rate = float64(pacing.BytesPerSecond)
+	p.burst = float64(pacing.Burst)
+	if pacing.Burst <= 0 {
+		p.burst = recordHeaderLen + maxCiphertext
+	}
+	if p.last.IsZero() {
+		p.tokens = p.burst
+		p.last = time.Now()
+	}
+	p.tokens = min(p.tokens, p.burst)
+}
+
+func (p *writePacer) setDeadline(t time.Time) {
+	p.mu.Lock()
+	p.deadline = t
+	p.wakeLocked()
+	p.mu.Unlock()
+}
+
+// close makes the current and future waits fail with net.ErrClosed.
+func (p *writePacer) close() {
+	p.mu.Lock()
+	p.closed = true
+	p.wakeLocked()
+	p.mu.Unlock()
+}
+
+// wakeLocked wakes up the waiting Writes, so that they recheck the deadline,
+// the pacing and whether the Conn is closed.
+func (p *writePacer) wakeLocked() {
+	if p.wake != nil {
+		close(p.wake)
+		p.wake = nil
+	}
+}
+
+// active reports whether writes are paced.
+func (p *writePacer) active() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.rate != 0
+}
+
+// refillLocked adds the tokens earned since the last call.
+func (p *writePacer) refillLocked(now time.Time) {
+	p.tokens = min(p.burst, p.tokens+now.Sub(p.last).Seconds()*p.rate)
+	p.last = now
+}
+
+// spend takes the tokens for n bytes being written, going into debt if there
+// aren't enough. It's called with the output lock held, and doesn't block.
+func (p *writePacer) spend(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.rate == 0 {
+		return
+	}
+	p.refillLocked(time.Now())
+	p.tokens -= float64(n)
+}
+
+// wait blocks until the debt of previous writes is paid, or fails at the
+// write deadline or once the Conn is closed. The delay is computed under p.mu,
+// which is released while waiting, and no other lock may be held.
+func (p *writePacer) wait() error {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return net.ErrClosed
+		}
+		if p.rate == 0 {
+			p.mu.Unlock()
+			return nil
+		}
+		now := time.Now()
+		p.refillLocked(now)
+		if p.tokens >= 0 {
+			p.mu.Unlock()
+			return nil
+		}
+		delay := time.Duration(-p.tokens / p.rate * float64(time.Second))
+		if !p.deadline.IsZero() {
+			if !now.Before(p.deadline) {
+				p.mu.Unlock()
+				return os.ErrDeadlineExceeded
+			}
+			delay = min(delay, p.deadline.Sub(now))
+		}
+		if p.wake == nil {
+			p.wake = make(chan struct{})
+		}
+		wake := p.wake
+		p.mu.Unlock()
+
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-wake:
+			t.Stop()
+		}
+	}
+}
+
+// pacedWrite implements Conn.Write while writes are paced: b is written
+// record by record, each one after waiting, without the output lock, for the
+// pacing of the previous ones.
+func (c *Conn) pacedWrite(b []byte) (int, error) {
+	c.utls.pacedWriteMu.Lock()
+	defer c.utls.pacedWriteMu.Unlock()
+	var n int
+	for len(b) > 0 {
+		if err := c.utls.pacer.wait(); err != nil {
+			return n, err
+		}
+		m, err := c.writeUnpaced(b[:min(len(b), maxPlaintext)])
+		n += m
+		if err != nil {
+			return n, err
+		}
+		b = b[m:]
+	}
+	return n, nil
+}
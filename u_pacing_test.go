@@ -0,0 +1,163 @@
+package tls
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestWritePacing(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := NewUTLSClient(s, &Config{
+		WritePacing: &WritePacing{BytesPerSecond: 200_000, Burst: 20_000},
+	}, HelloChrome_131)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	// The handshake used part of the burst, so the remaining 80 KB take at
+	// least 300ms.
+	start := time.Now()
+	data := make([]byte, 100_000)
+	go io.ReadFull(c, make([]byte, len(data)))
+	if _, err := c.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 300*time.Millisecond {
+		t.Errorf("writing %d bytes at 200 KB/s took %v", len(data), d)
+	}
+
+	c.SetWritePacing(nil)
+	start = time.Now()
+	go io.ReadFull(c, make([]byte, len(data)))
+	if _, err := c.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 200*time.Millisecond {
+		t.Errorf("writing without pacing took %v", d)
+	}
+}
+
+func TestWritePacingDeadline(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := NewUTLSClient(s, nil, HelloChrome_131)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	c.SetWritePacing(&WritePacing{BytesPerSecond: 10_000})
+	c.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+	start := time.Now()
+	_, err = c.Write(make([]byte, 100_000))
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Write = %v, want a deadline error", err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Write returned after %v", d)
+	}
+}
+
+func TestWritePacingDoesNotBlockCloseWrite(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := NewUTLSClient(s, nil, HelloChrome_131)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first record uses the burst, the second one waits for seconds.
+	c.SetWritePacing(&WritePacing{BytesPerSecond: 1000})
+	c.SetWriteDeadline(time.Now().Add(time.Second))
+	written := make(chan error, 1)
+	go func() {
+		_, err := c.Write(make([]byte, 100_000))
+		written <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// CloseWrite needs the output lock, which the Write doesn't hold while
+	// it waits.
+	start := time.Now()
+	if err := c.CloseWrite(); err != nil {
+		t.Error(err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("CloseWrite waited %v for a paced Write", d)
+	}
+	if err := <-written; err == nil {
+		t.Error("paced Write succeeded after CloseWrite")
+	}
+	c.Close()
+}
+
+func TestWritePacingClone(t *testing.T) {
+	config := &Config{WritePacing: &WritePacing{BytesPerSecond: 1000}}
+	clone := config.Clone()
+	clone.WritePacing.BytesPerSecond = 2000
+	if config.WritePacing.BytesPerSecond != 1000 {
+		t.Error("WritePacing shared between a Config and its clone")
+	}
+}
+
+func TestWritePacingWaitIsInterrupted(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	tests := []struct {
+		name    string
+		stop    func(c *UTLSTestClient)
+		wantErr error
+	}{
+		{"Close", func(c *UTLSTestClient) { c.Close() }, net.ErrClosed},
+		{"SetWriteDeadline", func(c *UTLSTestClient) { c.SetWriteDeadline(time.Now()) }, os.ErrDeadlineExceeded},
+		{"SetWritePacing", func(c *UTLSTestClient) { c.SetWritePacing(nil) }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := NewUTLSClient(s, nil, HelloChrome_131)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			// The first records use the burst, the next one would wait for
+			// hours.
+			c.SetWritePacing(&WritePacing{BytesPerSecond: 1})
+			written := make(chan error, 1)
+			go func() {
+				_, err := c.Write(make([]byte, 4*maxPlaintext))
+				written <- err
+			}()
+			time.Sleep(50 * time.Millisecond)
+
+			tt.stop(c)
+			select {
+			case err := <-written:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Write = %v, want %v", err, tt.wantErr)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("paced Write still waiting")
+			}
+		})
+	}
+}
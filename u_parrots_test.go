@@ -0,0 +1,391 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func compressionTestSpec(methods []uint8) *ClientHelloSpec {
+	return &ClientHelloSpec{
+		TLSVersMin:         VersionTLS10,
+		TLSVersMax:         VersionTLS12,
+		CipherSuites:       []uint16{TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		CompressionMethods: methods,
+		Extensions: []TLSExtension{
+			&SNIExtension{},
+			&SupportedCurvesExtension{[]CurveID{X25519, CurveP256}},
+			&SupportedPointsExtension{SupportedPoints: []byte{pointFormatUncompressed}},
+			&SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: []SignatureScheme{ECDSAWithP256AndSHA256, PSSWithSHA256, PKCS1WithSHA256}},
+			&RenegotiationInfoExtension{Renegotiation: RenegotiateOnceAsClient},
+		},
+	}
+}
+
+func TestApplyPresetCompressionMethods(t *testing.T) {
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloCustom)
+	if err := uconn.ApplyPreset(compressionTestSpec([]uint8{CompressionDeflate})); err == nil {
+		t.Error("applied a spec without null compression")
+	}
+
+	s, err := NewUTLSServer(&Config{MaxVersion: VersionTLS12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c := NewUnstartedUTLSClient(s, nil, HelloCustom)
+	methods := []uint8{CompressionDeflate, CompressionNone}
+	if err := c.ApplyPreset(compressionTestSpec(methods)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	m := new(clientHelloMsg)
+	if !m.unmarshal(c.HandshakeState.Hello.Raw) || !bytes.Equal(m.compressionMethods, methods) {
+		t.Errorf("ClientHello offers compression methods %v, want %v", m.compressionMethods, methods)
+	}
+	// The server picks null compression.
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+}
+
+func TestServerSelectedOfferedCompression(t *testing.T) {
+	clientEnd, serverEnd := memPipe()
+	defer serverEnd.Close()
+	uconn := UClient(clientEnd, &Config{ServerName: "example.com", RootCAs: x509.NewCertPool()}, HelloCustom)
+	if err := uconn.ApplyPreset(compressionTestSpec([]uint8{CompressionDeflate, CompressionNone})); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		// Read the ClientHello, and answer selecting DEFLATE.
+		hdr := make([]byte, recordHeaderLen)
+		if _, err := serverEnd.Read(hdr); err != nil {
+			return
+		}
+		serverHello, err := (&serverHelloMsg{
+			vers:              VersionTLS12,
+			random:            make([]byte, 32),
+			cipherSuite:       TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			compressionMethod: CompressionDeflate,
+		}).marshal()
+		if err != nil {
+			return
+		}
+		record := append([]byte{byte(recordTypeHandshake), 3, 3, byte(len(serverHello) >> 8), byte(len(serverHello))}, serverHello...)
+		serverEnd.Write(record)
+	}()
+
+	err := uconn.Handshake()
+	if err == nil || !strings.Contains(err.Error(), "compression method 1") {
+		t.Errorf("expected an error about the compression method, got %v", err)
+	}
+}
+
+func greaseALPNTestSpec(t *testing.T, protos ...string) *ClientHelloSpec {
+	spec, err := UTLSIdToSpec(HelloChrome_Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range spec.Extensions {
+		if alpn, ok := e.(*ALPNExtension); ok {
+			alpn.AlpnProtocols = protos
+		}
+	}
+	return &spec
+}
+
+func TestApplyPresetGREASEALPN(t *testing.T) {
+	s, err := NewUTLSServer(&Config{NextProtos: []string{"h2", "http/1.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	c := NewUnstartedUTLSClient(s, nil, HelloCustom)
+	if err := c.ApplyPreset(greaseALPNTestSpec(t, GREASE_ALPN_PLACEHOLDER, "http/1.1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	m := new(clientHelloMsg)
+	if !m.unmarshal(c.HandshakeState.Hello.Raw) || len(m.alpnProtocols) != 2 || !isGREASEALPN(m.alpnProtocols[0]) {
+		t.Fatalf("ClientHello offers ALPN protocols %q, want a GREASE value first", m.alpnProtocols)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if proto := c.ConnectionState().NegotiatedProtocol; proto != "http/1.1" {
+		t.Errorf("negotiated %q, want http/1.1", proto)
+	}
+
+	var spec ClientHelloSpec
+	record := append([]byte{byte(recordTypeHandshake), 3, 1, 0, 0}, c.HandshakeState.Hello.Raw...)
+	if err := spec.FromRaw(record, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range spec.Extensions {
+		if alpn, ok := e.(*ALPNExtension); ok && alpn.AlpnProtocols[0] != GREASE_ALPN_PLACEHOLDER {
+			t.Errorf("FromRaw read ALPN protocols %q, want GREASE_ALPN_PLACEHOLDER first", alpn.AlpnProtocols)
+		}
+	}
+
+	// A GREASE value alone doesn't request ALPN, so the server doesn't
+	// reject the client for not supporting its protocols.
+	c = NewUnstartedUTLSClient(s, nil, HelloCustom)
+	if err := c.ApplyPreset(greaseALPNTestSpec(t, GREASE_ALPN_PLACEHOLDER)); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if proto := c.ConnectionState().NegotiatedProtocol; proto != "" {
+		t.Errorf("negotiated %q with only a GREASE protocol", proto)
+	}
+}
+
+func TestCheckALPNRejectsGREASE(t *testing.T) {
+	grease := "\x1a\x1a"
+	err := checkALPN([]string{grease, "h2"}, grease, false)
+	var gerr *GREASESelectedError
+	if !errors.As(err, &gerr) || gerr.Field != GREASEALPNProtocol || gerr.Value != 0x1a1a {
+		t.Errorf("expected a GREASESelectedError for a server selecting a GREASE protocol, got %v", err)
+	}
+	if err := checkALPN([]string{grease, "h2"}, "h2", false); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNonBrowserPresets(t *testing.T) {
+	s, err := NewUTLSServer(&Config{NextProtos: []string{"http/1.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, tt := range []struct {
+		id      ClientHelloID
+		version uint16
+	}{
+		{HelloCurl_Auto, VersionTLS13},
+		{HelloWget_Auto, VersionTLS13},
+		{HelloJava_11, VersionTLS13},
+		{HelloJava_17, VersionTLS13},
+		{HelloPythonRequests_Auto, VersionTLS13},
+		{HelloDotNet_Auto, VersionTLS12},
+		{HelloOkHttp_Auto, VersionTLS13},
+		{HelloConscrypt, VersionTLS13},
+	} {
+		t.Run(tt.id.Str(), func(t *testing.T) {
+			c, err := NewUTLSClient(s, nil, tt.id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			if v := c.ConnectionState().Version; v != tt.version {
+				t.Errorf("negotiated version %x, want %x", v, tt.version)
+			}
+		})
+	}
+}
+
+func TestHelloOkHttp4(t *testing.T) {
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloOkHttp_4)
+	if err := uconn.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	hello := new(clientHelloMsg)
+	if !hello.unmarshal(uconn.HandshakeState.Hello.Raw) {
+		t.Fatal("unable to parse the ClientHello")
+	}
+	// The JA3 of OkHttp 4 on Android 10 and later.
+	want := "771,4865-4866-4867-49195-49196-52393-49199-49200-52392-49171-49172-156-157-47-53,0-23-65281-10-11-35-16-5-13-51-45-43-21,29-23-24,0"
+	if ja3 := ja3String(hello); ja3 != want {
+		t.Errorf("JA3 = %s, want %s", ja3, want)
+	}
+}
+
+func TestHelloFirefox133(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := NewUTLSClient(s, nil, HelloFirefox_133)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.curveID != X25519MLKEM768 {
+		t.Errorf("negotiated %v, want %v", c.curveID, X25519MLKEM768)
+	}
+	hello := new(clientHelloMsg)
+	if !hello.unmarshal(c.HandshakeState.Hello.Raw) {
+		t.Fatal("unable to parse the ClientHello")
+	}
+	// The JA4 published for Firefox 133.
+	if ja4 := ja4String(hello, false); ja4 != "t13d1717h2_5b57614c22b0_3cbfd9057e0d" {
+		t.Errorf("JA4 = %s, want t13d1717h2_5b57614c22b0_3cbfd9057e0d", ja4)
+	}
+}
+
+func TestHelloSafari18(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// iOS 18 and macOS Sequoia share most of their TLS stack.
+	for _, id := range []ClientHelloID{HelloSafari_18, HelloIOS_18} {
+		c, err := NewUTLSClient(s, nil, id)
+		if err != nil {
+			t.Fatalf("%v: %v", id, err)
+		}
+		c.Close()
+
+		hello := new(clientHelloMsg)
+		if !hello.unmarshal(c.HandshakeState.Hello.Raw) {
+			t.Fatalf("%v: unable to parse the ClientHello", id)
+		}
+		if slices.Contains(hello.supportedSignatureAlgorithms, ECDSAWithSHA1) {
+			t.Errorf("%v offers ecdsa_sha1", id)
+		}
+		if slices.Contains(hello.extensions, utlsExtensionApplicationSettings) || slices.Contains(hello.extensions, utlsExtensionApplicationSettingsNew) {
+			t.Errorf("%v offers application_settings", id)
+		}
+		// The JA4 published for Safari 18.
+		if ja4 := ja4String(hello, false); ja4 != "t13d2014h2_a09f3c656075_e42f34c56612" {
+			t.Errorf("%v: JA4 = %s, want t13d2014h2_a09f3c656075_e42f34c56612", id, ja4)
+		}
+	}
+}
+
+func TestHelloIOS18(t *testing.T) {
+	groups := func(id ClientHelloID) []CurveID {
+		t.Helper()
+		spec, err := UTLSIdToSpec(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ext := range spec.Extensions {
+			if sc, ok := ext.(*SupportedCurvesExtension); ok {
+				return sc.Curves
+			}
+		}
+		t.Fatalf("%v has no supported_groups", id)
+		return nil
+	}
+	safari, ios := groups(HelloSafari_18), groups(HelloIOS_18)
+	if slices.Equal(safari, ios) {
+		t.Errorf("iOS 18 offers the supported_groups of Safari 18: %v", ios)
+	}
+	if !slices.Contains(safari, CurveP521) || slices.Contains(ios, CurveP521) {
+		t.Errorf("secp521r1 is offered by Safari 18: %v, iOS 18: %v", safari, ios)
+	}
+}
+
+func TestHelloEdge130(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := NewUTLSClient(s, nil, HelloEdge_130)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	hello := new(clientHelloMsg)
+	if !hello.unmarshal(c.HandshakeState.Hello.Raw) {
+		t.Fatal("unable to parse the ClientHello")
+	}
+	if len(hello.keyShares) != 3 || hello.keyShares[1].group != X25519Kyber768Draft00 {
+		t.Errorf("Edge 130 doesn't offer a %v key share", X25519Kyber768Draft00)
+	}
+	if !slices.Contains(hello.extensions, utlsExtensionApplicationSettingsNew) || slices.Contains(hello.extensions, utlsExtensionApplicationSettings) {
+		t.Error("Edge 130 doesn't offer application_settings on the new code point only")
+	}
+	if slices.Contains(hello.extensions, utlsExtensionECH) {
+		t.Error("Edge 130 offers GREASE encrypted_client_hello")
+	}
+
+	// Chrome 130 is Chrome 131 with X25519Kyber768Draft00 instead of
+	// X25519MLKEM768: it sends ALPS on the old code point and GREASE ECH.
+	spec, err := UTLSIdToSpec(HelloChrome_131)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ext := range spec.Extensions {
+		switch ext := ext.(type) {
+		case *SupportedCurvesExtension:
+			ext.Curves[slices.Index(ext.Curves, X25519MLKEM768)] = X25519Kyber768Draft00
+		case *KeyShareExtension:
+			ext.KeyShares[slices.IndexFunc(ext.KeyShares, func(ks KeyShare) bool { return ks.Group == X25519MLKEM768 })].Group = X25519Kyber768Draft00
+		}
+	}
+	chromium := NewUnstartedUTLSClient(s, nil, HelloCustom)
+	if err := chromium.ApplyPreset(&spec); err != nil {
+		t.Fatal(err)
+	}
+	if err := chromium.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	chromiumHello := new(clientHelloMsg)
+	if !chromiumHello.unmarshal(chromium.HandshakeState.Hello.Raw) {
+		t.Fatal("unable to parse the Chrome 130 ClientHello")
+	}
+	if !slices.Contains(chromiumHello.extensions, utlsExtensionApplicationSettings) || !slices.Contains(chromiumHello.extensions, utlsExtensionECH) {
+		t.Fatal("the Chrome 130 spec doesn't send ALPS on the old code point and GREASE ECH")
+	}
+	if ja4, chromiumJA4 := ja4String(hello, false), ja4String(chromiumHello, false); ja4 == chromiumJA4 {
+		t.Errorf("Edge 130 has the JA4 of Chrome 130: %s", ja4)
+	}
+
+	m, ok := HelloEdge_130.Metadata()
+	if !ok || m.Source == sourceCapture {
+		t.Errorf("HelloEdge_130 metadata claims a capture: %+v", m)
+	}
+}
+
+// TestChromiumWithoutFieldTrials tests the Chromium presets that don't send
+// the extensions enabled by Chrome's field trials.
+func TestChromiumWithoutFieldTrials(t *testing.T) {
+	s, err := NewUTLSServer(&Config{NextProtos: []string{"h2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, id := range []ClientHelloID{HelloElectron_33, HelloChrome_Headless} {
+		t.Run(id.Str(), func(t *testing.T) {
+			c, err := NewUTLSClient(s, nil, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			if p := c.ConnectionState().NegotiatedProtocol; p != "h2" {
+				t.Errorf("negotiated %q, want h2", p)
+			}
+			hello := new(clientHelloMsg)
+			if !hello.unmarshal(c.HandshakeState.Hello.Raw) {
+				t.Fatal("unable to parse the ClientHello")
+			}
+			for _, ext := range []uint16{utlsExtensionApplicationSettings, utlsExtensionECH} {
+				if slices.Contains(hello.extensions, ext) {
+					t.Errorf("offered extension %d", ext)
+				}
+			}
+		})
+	}
+}
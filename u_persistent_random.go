@@ -0,0 +1,100 @@
+package tls
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+var persistentRandomizedSeed struct {
+	sync.Mutex
+	seed *PRNGSeed
+}
+
+// SetPersistentRandomizedSeed sets the seed from which PersistentRandomizedID
+// derives the seeds of randomized ClientHelloIDs. By default, a random seed is
+// generated once per process. Storing and restoring it keeps the randomized
+// ClientHellos the same across restarts.
+//
+// It only affects IDs derived afterwards.
+func SetPersistentRandomizedSeed(seed *PRNGSeed) {
+	persistentRandomizedSeed.Lock()
+	defer persistentRandomizedSeed.Unlock()
+	persistentRandomizedSeed.seed = seed
+}
+
+// PersistentRandomizedSeed returns the seed used by PersistentRandomizedID,
+// generating it if needed.
+func PersistentRandomizedSeed() (*PRNGSeed, error) {
+	persistentRandomizedSeed.Lock()
+	defer persistentRandomizedSeed.Unlock()
+	if persistentRandomizedSeed.seed == nil {
+		seed, err := NewPRNGSeed()
+		if err != nil {
+			return nil, err
+		}
+		persistentRandomizedSeed.seed = seed
+	}
+	return persistentRandomizedSeed.seed, nil
+}
+
+// PersistentRandomizedID returns a copy of id, one of the HelloRandomized IDs,
+// whose Seed is derived from key and PersistentRandomizedSeed. Connections using
+// the returned ID all send the same randomized ClientHello, whereas
+// HelloRandomized without a Seed generates a new one for each connection,
+// which is in itself an anomaly observers can detect.
+//
+// Different keys, such as persona names, get independent randomized
+// ClientHellos. An explicitly set Seed is kept as is.
+func PersistentRandomizedID(id ClientHelloID, key string) (ClientHelloID, error) {
+	if !isRandomizedClient(id.Client) {
+		return id, fmt.Errorf("tls: %s is not a randomized ClientHelloID", id.Str())
+	}
+	if id.Seed != nil {
+		return id, nil
+	}
+	seed, err := PersistentRandomizedSeed()
+	if err != nil {
+		return id, err
+	}
+	id.Seed, err = newSaltedPRNGSeed(seed, "persistent randomized "+id.Client+"\x00"+key)
+	if err != nil {
+		return id, err
+	}
+	return id, nil
+}
+
+// perHostRandomizedID returns a copy of id, one of the HelloRandomized IDs,
+// whose Seed is derived from host and the Seed of id, or
+// PersistentRandomizedSeed if it has none.
+func perHostRandomizedID(id ClientHelloID, host string) (ClientHelloID, error) {
+	seed, err := id.Seed, error(nil)
+	if seed == nil {
+		if seed, err = PersistentRandomizedSeed(); err != nil {
+			return id, err
+		}
+	}
+	id.Seed, err = newSaltedPRNGSeed(seed, "per-host randomized "+id.Client+"\x00"+strings.ToLower(host))
+	return id, err
+}
+
+func isRandomizedClient(client string) bool {
+	switch client {
+	case helloRandomized, helloRandomizedALPN, helloRandomizedNoALPN:
+		return true
+	}
+	return false
+}
+
+// HelloRandomizedSeeded returns a HelloRandomized ClientHelloID whose random
+// choices are all derived from seed: cipher suites, extensions and their
+// order, padding, and the GREASE values unless Config.GREASESeed is set.
+// Connections using the same seed send the same fingerprint across runs and
+// machines, e.g. to reproduce a randomized fingerprint in a test or to share
+// one between distributed clients.
+func HelloRandomizedSeeded(seed int64) ClientHelloID {
+	prngSeed := new(PRNGSeed)
+	binary.BigEndian.PutUint64(prngSeed[:], uint64(seed))
+	return ClientHelloID{helloRandomized, helloAutoVers, prngSeed, nil}
+}
@@ -0,0 +1,173 @@
+package tls
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func randomizedHelloShape(t *testing.T, id ClientHelloID) ([]uint16, []uint16) {
+	t.Helper()
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, id)
+	if err := uconn.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	var m clientHelloMsg
+	if !m.unmarshal(uconn.HandshakeState.Hello.Raw) {
+		t.Fatal("failed to parse ClientHello")
+	}
+	return m.cipherSuites, m.extensions
+}
+
+func TestPersistentRandomizedID(t *testing.T) {
+	if _, err := PersistentRandomizedID(HelloChrome_120, "a"); err == nil {
+		t.Error("expected an error for a non-randomized ClientHelloID")
+	}
+
+	id, err := PersistentRandomizedID(HelloRandomized, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Seed == nil || id.Client != HelloRandomized.Client {
+		t.Fatalf("unexpected ID %+v", id)
+	}
+	again, err := PersistentRandomizedID(HelloRandomized, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *again.Seed != *id.Seed {
+		t.Error("the same key produced different seeds")
+	}
+
+	suites, exts := randomizedHelloShape(t, id)
+	for i := 0; i < 5; i++ {
+		s, e := randomizedHelloShape(t, again)
+		if !reflect.DeepEqual(s, suites) || !reflect.DeepEqual(e, exts) {
+			t.Fatalf("ClientHello changed between connections: %v %v, then %v %v", suites, exts, s, e)
+		}
+	}
+
+	seeds := map[PRNGSeed]bool{*id.Seed: true}
+	for _, key := range []string{"b", "c"} {
+		other, err := PersistentRandomizedID(HelloRandomized, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seeds[*other.Seed] = true
+	}
+	alpn, err := PersistentRandomizedID(HelloRandomizedALPN, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeds[*alpn.Seed] = true
+	if len(seeds) != 4 {
+		t.Error("different keys or IDs produced the same seed")
+	}
+
+	explicit := HelloRandomized
+	explicit.Seed = &PRNGSeed{1}
+	if kept, _ := PersistentRandomizedID(explicit, "a"); kept.Seed != explicit.Seed {
+		t.Error("explicit Seed was replaced")
+	}
+}
+
+func TestSetPersistentRandomizedSeed(t *testing.T) {
+	saved, err := PersistentRandomizedSeed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer SetPersistentRandomizedSeed(saved)
+
+	SetPersistentRandomizedSeed(&PRNGSeed{42})
+	first, err := PersistentRandomizedID(HelloRandomizedNoALPN, "persona")
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetPersistentRandomizedSeed(&PRNGSeed{43})
+	SetPersistentRandomizedSeed(&PRNGSeed{42})
+	second, err := PersistentRandomizedID(HelloRandomizedNoALPN, "persona")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *first.Seed != *second.Seed {
+		t.Error("restoring the seed didn't restore the derived ID")
+	}
+}
+
+func TestIdentityManagerPersistentRandomized(t *testing.T) {
+	p := &Persona{Name: "random", ClientHelloID: HelloRandomized}
+	if _, err := NewIdentityManager(p); err != nil {
+		t.Fatal(err)
+	}
+	want, err := PersistentRandomizedID(HelloRandomized, "random")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ClientHelloID.Seed == nil || *p.ClientHelloID.Seed != *want.Seed {
+		t.Errorf("persona was not given a persistent seed")
+	}
+}
+
+func TestHelloRandomizedSeeded(t *testing.T) {
+	fingerprint := func(seed int64) string {
+		uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloRandomizedSeeded(seed))
+		if err := uconn.BuildHandshakeState(); err != nil {
+			t.Fatal(err)
+		}
+		hello := uconn.HandshakeState.Hello
+		msg := new(clientHelloMsg)
+		if !msg.unmarshal(hello.Raw) {
+			t.Fatal("unable to parse the ClientHello")
+		}
+		// The raw extension IDs keep GREASE values, which ja3String drops.
+		return fmt.Sprint(ja3String(msg), msg.extensions, len(hello.Raw), uconn.greaseSeed)
+	}
+	if a, b := fingerprint(7), fingerprint(7); a != b {
+		t.Errorf("same seed, different ClientHellos:\n%s\n%s", a, b)
+	}
+	seen := map[string]bool{}
+	for seed := int64(0); seed < 8; seed++ {
+		seen[fingerprint(seed)] = true
+	}
+	if len(seen) < 2 {
+		t.Error("different seeds all produced the same ClientHello")
+	}
+}
+
+func TestRandomizedPerHost(t *testing.T) {
+	fingerprint := func(id ClientHelloID, serverName string) string {
+		uconn := UClient(&net.TCPConn{}, &Config{ServerName: serverName, RandomizedPerHost: true}, id)
+		if err := uconn.BuildHandshakeState(); err != nil {
+			t.Fatal(err)
+		}
+		msg := new(clientHelloMsg)
+		if !msg.unmarshal(uconn.HandshakeState.Hello.Raw) {
+			t.Fatal("unable to parse the ClientHello")
+		}
+		return fmt.Sprint(ja3String(msg), msg.extensions)
+	}
+
+	if a, b := fingerprint(HelloRandomized, "a.example"), fingerprint(HelloRandomized, "A.example"); a != b {
+		t.Errorf("same host, different ClientHellos:\n%s\n%s", a, b)
+	}
+	seen := map[string]bool{}
+	for _, host := range []string{"a.example", "b.example", "c.example", "d.example", "e.example", "f.example"} {
+		seen[fingerprint(HelloRandomized, host)] = true
+	}
+	if len(seen) < 2 {
+		t.Error("different hosts all got the same ClientHello")
+	}
+
+	// An explicit seed is combined with the host.
+	if fingerprint(HelloRandomizedSeeded(1), "a.example") != fingerprint(HelloRandomizedSeeded(1), "a.example") {
+		t.Error("same seed and host, different ClientHellos")
+	}
+	id, err := perHostRandomizedID(HelloRandomizedSeeded(1), "a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other, _ := perHostRandomizedID(HelloRandomizedSeeded(2), "a.example"); *other.Seed == *id.Seed {
+		t.Error("the seed of the ID was ignored")
+	}
+}
@@ -1,9 +1,13 @@
 package tls
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"math/rand/v2"
+	"time"
 
 	"golang.org/x/crypto/cryptobyte"
 )
@@ -484,3 +488,57 @@ var (
 )
 
 // type ExternalPreSharedKeyExtension struct{} // TODO: wait for whoever cares about external PSK to implement it
+
+// maxPskIdentityLength is the longest identity that fits in the identities
+// list of a pre_shared_key extension, along with its length and ticket age.
+const maxPskIdentityLength = 1<<16 - 1 - 2 - 4
+
+// TicketAgeJitter returns a Config.TicketAge function that adds a random
+// offset of up to maxJitter, in either direction, to ticket ages.
+func TicketAgeJitter(maxJitter time.Duration) func(age time.Duration) time.Duration {
+	return func(age time.Duration) time.Duration {
+		if maxJitter <= 0 {
+			return age
+		}
+		return age + time.Duration(rand.Int64N(int64(2*maxJitter)+1)) - maxJitter
+	}
+}
+
+// obfuscatedTicketAge returns the obfuscated_ticket_age of the identity
+// offered for session, see RFC 8446, Section 4.2.11.1. The age is adjusted by
+// Config.TicketAge, with the same offset for every ClientHello of the
+// connection.
+func (c *Conn) obfuscatedTicketAge(session *SessionState) uint32 {
+	age := c.config.time().Sub(time.Unix(int64(session.createdAt), 0))
+	if c.config.TicketAge != nil {
+		if !c.utls.ticketAgeAdjusted {
+			c.utls.ticketAgeAdjustment = c.config.TicketAge(age) - age
+			c.utls.ticketAgeAdjusted = true
+		}
+		age = max(age+c.utls.ticketAgeAdjustment, 0)
+	}
+	return uint32(age/time.Millisecond) + session.ageAdd
+}
+
+// transformPskIdentity applies Config.TransformPskIdentity to the identity of
+// the pre_shared_key extension offered for session.
+func (c *Conn) transformPskIdentity(identity pskIdentity, session *SessionState) (pskIdentity, error) {
+	if c.config.TransformPskIdentity == nil {
+		return identity, nil
+	}
+	transformed, err := c.config.TransformPskIdentity(PskIdentity{
+		Label:               bytes.Clone(identity.label),
+		ObfuscatedTicketAge: identity.obfuscatedTicketAge,
+	}, session)
+	if err != nil {
+		return pskIdentity{}, err
+	}
+	if len(transformed.Label) == 0 || len(transformed.Label) > maxPskIdentityLength {
+		return pskIdentity{}, fmt.Errorf("tls: TransformPskIdentity returned an identity of invalid length %d", len(transformed.Label))
+	}
+	c.utls.pskAgeTransform = transformed.ObfuscatedTicketAge - identity.obfuscatedTicketAge
+	return pskIdentity{
+		label:               transformed.Label,
+		obfuscatedTicketAge: transformed.ObfuscatedTicketAge,
+	}, nil
+}
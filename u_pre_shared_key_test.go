@@ -0,0 +1,200 @@
+package tls
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestTransformPskIdentity(t *testing.T) {
+	prefix := []byte("covert:")
+	var signals int
+	serverConfig := &Config{}
+	serverConfig.UnwrapSession = func(identity []byte, cs ConnectionState) (*SessionState, error) {
+		if bytes.HasPrefix(identity, prefix) {
+			signals++
+			identity = identity[len(prefix):]
+		}
+		return serverConfig.DecryptTicket(identity, cs)
+	}
+	s, err := NewUTLSServer(serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, id := range []ClientHelloID{HelloChrome_112_PSK_Shuf, HelloGolang} {
+		signals = 0
+		clientConfig := &Config{
+			ClientSessionCache: NewLRUClientSessionCache(1),
+			OmitEmptyPsk:       true,
+			TransformPskIdentity: func(identity PskIdentity, session *SessionState) (PskIdentity, error) {
+				if session == nil {
+					t.Error("TransformPskIdentity called without a session")
+				}
+				identity.Label = append(bytes.Clone(prefix), identity.Label...)
+				return identity, nil
+			},
+		}
+		for i := 0; i < 2; i++ {
+			c, err := NewUTLSClient(s, clientConfig, id)
+			if err != nil {
+				t.Fatalf("%s: connection %d: %v", id.Str(), i, err)
+			}
+			// Read the echo, and with it the session ticket.
+			if _, err := c.Write([]byte("ping")); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := io.ReadFull(c, make([]byte, 4)); err != nil {
+				t.Fatal(err)
+			}
+			if resumed := c.ConnectionState().DidResume; resumed != (i == 1) {
+				t.Errorf("%s: connection %d: DidResume = %v", id.Str(), i, resumed)
+			}
+			c.Close()
+		}
+		if signals != 1 {
+			t.Errorf("%s: server saw %d transformed identities, want 1", id.Str(), signals)
+		}
+	}
+
+	clientConfig := &Config{
+		ClientSessionCache: NewLRUClientSessionCache(1),
+		TransformPskIdentity: func(identity PskIdentity, session *SessionState) (PskIdentity, error) {
+			return PskIdentity{}, errors.New("refused")
+		},
+	}
+	c, err := NewUTLSClient(s, clientConfig, HelloGolang)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Write([]byte("ping"))
+	io.ReadFull(c, make([]byte, 4))
+	c.Close()
+	if _, err := NewUTLSClient(s, clientConfig, HelloGolang); err == nil {
+		t.Error("handshake succeeded with a failing TransformPskIdentity")
+	}
+}
+
+func TestTransformPskIdentityHelloRetryRequest(t *testing.T) {
+	// The server prefers a group the client has no key share for.
+	s, err := NewUTLSServer(&Config{CurvePreferences: []CurveID{CurveP256}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	now := time.Now()
+	config := &Config{
+		Time:               func() time.Time { return now },
+		ClientSessionCache: NewLRUClientSessionCache(1),
+		TransformPskIdentity: func(identity PskIdentity, session *SessionState) (PskIdentity, error) {
+			identity.ObfuscatedTicketAge += 1000
+			return identity, nil
+		},
+	}
+	c, err := NewUTLSClient(s, config, HelloGolang)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Write([]byte("ping"))
+	io.ReadFull(c, make([]byte, 4))
+	c.Close()
+
+	c, err = NewUTLSClient(s, config, HelloGolang)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if !c.ConnectionState().DidResume {
+		t.Error("session was not resumed")
+	}
+
+	var ages []uint32
+	flight := c.ClientFlight()
+	for len(flight) >= recordHeaderLen {
+		n := int(flight[3])<<8 | int(flight[4])
+		record := flight[recordHeaderLen : recordHeaderLen+n]
+		if recordType(flight[0]) == recordTypeHandshake && record[0] == typeClientHello {
+			var hello clientHelloMsg
+			if !hello.unmarshal(record) || len(hello.pskIdentities) != 1 {
+				t.Fatal("failed to parse ClientHello with a pre_shared_key")
+			}
+			ages = append(ages, hello.pskIdentities[0].obfuscatedTicketAge)
+		}
+		flight = flight[recordHeaderLen+n:]
+	}
+	if len(ages) != 2 {
+		t.Fatalf("got %d ClientHellos, want 2", len(ages))
+	}
+	if ages[0] != ages[1] {
+		t.Errorf("ticket age after HelloRetryRequest is %d, want %d", ages[1], ages[0])
+	}
+}
+
+func TestTicketAge(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// Sessions record their creation time in seconds.
+	now := time.Now().Truncate(time.Second)
+	var sentAge uint32
+	config := &Config{
+		Time:               func() time.Time { return now },
+		ClientSessionCache: NewLRUClientSessionCache(1),
+		OmitEmptyPsk:       true,
+		TransformPskIdentity: func(identity PskIdentity, session *SessionState) (PskIdentity, error) {
+			sentAge = identity.ObfuscatedTicketAge
+			return identity, nil
+		},
+	}
+	c, err := NewUTLSClient(s, config, HelloChrome_112_PSK_Shuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Read the echo, and with it the session ticket.
+	c.Write([]byte("ping"))
+	io.ReadFull(c, make([]byte, 4))
+	c.Close()
+
+	// The connections don't read, so the session and its age_add are kept.
+	now = now.Add(5 * time.Second)
+	resume := func(ticketAge func(time.Duration) time.Duration) uint32 {
+		t.Helper()
+		config.TicketAge = ticketAge
+		c, err := NewUTLSClient(s, config, HelloChrome_112_PSK_Shuf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		if !c.ConnectionState().DidResume {
+			t.Error("session was not resumed")
+		}
+		return sentAge
+	}
+	exact := resume(nil)
+	if got := resume(func(age time.Duration) time.Duration { return age + time.Hour }); got-exact != 3600000 {
+		t.Errorf("age shifted by an hour differs by %d ms", got-exact)
+	}
+	if got := resume(func(age time.Duration) time.Duration { return -time.Hour }); exact-got != 5000 {
+		t.Errorf("negative age differs by %d ms from a 5s age, want 5000", exact-got)
+	}
+
+	jitter := TicketAgeJitter(time.Second)
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		age := jitter(time.Minute)
+		if age < time.Minute-time.Second || age > time.Minute+time.Second {
+			t.Fatalf("jittered age %v is out of range", age)
+		}
+		seen[age] = true
+	}
+	if len(seen) < 2 {
+		t.Error("TicketAgeJitter doesn't jitter")
+	}
+}
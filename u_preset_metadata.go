@@ -0,0 +1,155 @@
+package tls
+
+import (
+	"sort"
+	"time"
+)
+
+// PresetMetadata describes where a parroted ClientHelloID preset came from, so
+// that operators can programmatically avoid fingerprints that went stale.
+type PresetMetadata struct {
+	ID ClientHelloID
+
+	// BrowserVersion is the version of the client the preset was captured
+	// from, e.g. "Chrome 120" or "iOS 14 Safari".
+	BrowserVersion string
+
+	// CaptureDate is the (approximate, month precision) date at which the
+	// fingerprint was captured. It is zero if unknown.
+	CaptureDate time.Time
+
+	// Source is where the fingerprint was captured, and Notes lists
+	// deviations from the real client that users should be aware of.
+	Source string
+	Notes  string
+}
+
+// Age returns how old the captured fingerprint is at now.
+// It returns 0 if the capture date is unknown.
+func (m PresetMetadata) Age(now time.Time) time.Duration {
+	if m.CaptureDate.IsZero() {
+		return 0
+	}
+	return now.Sub(m.CaptureDate)
+}
+
+func captureMonth(year int, month time.Month) time.Time {
+	return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+const (
+	sourceTLSFingerprint = "tlsfingerprint.io"
+	sourceCapture        = "packet capture"
+	sourceLibDefaults    = "library defaults"
+	sourceDerived        = "derived from another preset"
+)
+
+// presetMetadata must be updated whenever a parrot is added to u_parrots.go.
+var presetMetadata = []PresetMetadata{
+	{HelloFirefox_55, "Firefox 55", captureMonth(2017, time.August), sourceTLSFingerprint, ""},
+	{HelloFirefox_56, "Firefox 56", captureMonth(2017, time.September), sourceTLSFingerprint, ""},
+	{HelloFirefox_63, "Firefox 63", captureMonth(2018, time.October), sourceTLSFingerprint, ""},
+	{HelloFirefox_65, "Firefox 65", captureMonth(2019, time.January), sourceTLSFingerprint, ""},
+	{HelloFirefox_99, "Firefox 99", captureMonth(2022, time.April), sourceTLSFingerprint, ""},
+	{HelloFirefox_102, "Firefox 102", captureMonth(2022, time.June), sourceTLSFingerprint, ""},
+	{HelloFirefox_105, "Firefox 105", captureMonth(2022, time.September), sourceTLSFingerprint, ""},
+	{HelloFirefox_120, "Firefox 120", captureMonth(2023, time.November), sourceTLSFingerprint, ""},
+	{HelloFirefox_133, "Firefox 133", captureMonth(2024, time.November), sourceCapture, "X25519MLKEM768 key share; JA4 t13d1717h2_5b57614c22b0_3cbfd9057e0d"},
+
+	{HelloChrome_58, "Chrome 58", captureMonth(2017, time.April), sourceTLSFingerprint, ""},
+	{HelloChrome_62, "Chrome 62", captureMonth(2017, time.October), sourceTLSFingerprint, ""},
+	{HelloChrome_70, "Chrome 70", captureMonth(2018, time.October), sourceTLSFingerprint, ""},
+	{HelloChrome_72, "Chrome 72", captureMonth(2019, time.January), sourceTLSFingerprint, ""},
+	{HelloChrome_83, "Chrome 83", captureMonth(2020, time.May), sourceTLSFingerprint, ""},
+	{HelloChrome_87, "Chrome 87", captureMonth(2020, time.November), sourceTLSFingerprint, ""},
+	{HelloChrome_96, "Chrome 96", captureMonth(2021, time.November), sourceTLSFingerprint, ""},
+	{HelloChrome_100, "Chrome 100", captureMonth(2022, time.March), sourceTLSFingerprint, ""},
+	{HelloChrome_102, "Chrome 102", captureMonth(2022, time.May), sourceTLSFingerprint, ""},
+	{HelloChrome_106_Shuffle, "Chrome 106", captureMonth(2022, time.October), sourceTLSFingerprint, "extension order is shuffled per connection"},
+	{HelloChrome_100_PSK, "Chrome 100", captureMonth(2022, time.March), sourceCapture, "resumption ClientHello; PSK support is beta"},
+	{HelloChrome_112_PSK_Shuf, "Chrome 112", captureMonth(2023, time.April), sourceCapture, "resumption ClientHello; PSK support is beta"},
+	{HelloChrome_114_Padding_PSK_Shuf, "Chrome 114", captureMonth(2023, time.June), sourceCapture, "resumption ClientHello; PSK support is beta"},
+	{HelloChrome_115_PQ, "Chrome 115", captureMonth(2023, time.August), sourceCapture, "X25519Kyber768 key share"},
+	{HelloChrome_115_PQ_PSK, "Chrome 115", captureMonth(2023, time.August), sourceCapture, "X25519Kyber768 key share; PSK support is beta"},
+	{HelloChrome_120, "Chrome 120", captureMonth(2023, time.December), sourceCapture, "GREASE ECH"},
+	{HelloChrome_120_PQ, "Chrome 120", captureMonth(2023, time.December), sourceCapture, "GREASE ECH and X25519Kyber768 key share"},
+	{HelloChrome_131, "Chrome 131", captureMonth(2024, time.November), sourceCapture, "X25519MLKEM768 key share"},
+	{HelloChrome_133, "Chrome 133", captureMonth(2025, time.February), sourceCapture, "new ALPS codepoint"},
+	{HelloChrome_Headless, "Headless Chrome 131", captureMonth(2024, time.November), sourceCapture, "chrome-headless-shell; no ALPS or GREASE ECH"},
+
+	{HelloIOS_11_1, "iOS 11.1 Safari", captureMonth(2017, time.November), sourceTLSFingerprint, ""},
+	{HelloIOS_12_1, "iOS 12.1 Safari", captureMonth(2018, time.November), sourceTLSFingerprint, ""},
+	{HelloIOS_13, "iOS 13 Safari", captureMonth(2019, time.September), sourceTLSFingerprint, ""},
+	{HelloIOS_14, "iOS 14 Safari", captureMonth(2020, time.September), sourceTLSFingerprint, ""},
+	{HelloIOS_18, "iOS 18 Safari", captureMonth(2024, time.September), sourceCapture, "Safari 18 on macOS without secp521r1 in supported_groups; no ALPS"},
+
+	{HelloAndroid_11_OkHttp, "Android 11 OkHttp", captureMonth(2020, time.September), sourceTLSFingerprint, ""},
+
+	{HelloEdge_85, "Edge 85", captureMonth(2020, time.August), sourceTLSFingerprint, ""},
+	{HelloEdge_106, "Edge 106", captureMonth(2022, time.October), sourceTLSFingerprint, "may be incompatible with this library"},
+	{HelloEdge_130, "Edge 130", captureMonth(2024, time.October), sourceDerived, "Chromium 130 with ALPS on the new code point and no GREASE ECH; not verified against an Edge capture"},
+
+	{HelloSafari_16_0, "Safari 16.0", captureMonth(2022, time.September), sourceTLSFingerprint, ""},
+	{HelloSafari_18, "Safari 18 (macOS 15)", captureMonth(2024, time.September), sourceCapture, "no ecdsa_sha1 signature algorithm; JA4 t13d2014h2_a09f3c656075_e42f34c56612"},
+
+	{HelloElectron_33, "Electron 33 (Chromium 130)", captureMonth(2024, time.October), sourceLibDefaults, "Chromium 130 without ALPS and GREASE ECH"},
+
+	{Hello360_7_5, "360 Browser 7.5", captureMonth(2019, time.January), sourceTLSFingerprint, ""},
+	{Hello360_11_0, "360 Browser 11.0", captureMonth(2020, time.June), sourceTLSFingerprint, "may be incompatible with this library"},
+
+	{HelloQQ_11_1, "QQ Browser 11.1", captureMonth(2021, time.October), sourceTLSFingerprint, ""},
+
+	{HelloCurl_8, "curl 8 (OpenSSL 3)", captureMonth(2023, time.July), sourceLibDefaults, "reconstructed from OpenSSL 3 defaults; ALPN assumes an HTTP/2 enabled build"},
+	{HelloWget_1_21, "wget 1.21 (GnuTLS 3.7)", captureMonth(2022, time.February), sourceLibDefaults, "reconstructed from the GnuTLS NORMAL priority string"},
+	{HelloJava_11, "Java 11 HttpClient", captureMonth(2021, time.January), sourceLibDefaults, "reconstructed from OpenJDK 11 defaults"},
+	{HelloJava_17, "Java 17 HttpClient", captureMonth(2022, time.January), sourceLibDefaults, "reconstructed from OpenJDK 17 defaults"},
+	{HelloPythonRequests_2_31, "Python requests 2.31 (urllib3 2, OpenSSL 3)", captureMonth(2023, time.May), sourceLibDefaults, "reconstructed from Python ssl and OpenSSL 3 defaults"},
+	{HelloDotNet_6, ".NET 6 HttpClient (Windows 10 SChannel)", captureMonth(2022, time.March), sourceLibDefaults, "reconstructed from SChannel defaults; TLS 1.2 only"},
+	{HelloOkHttp_4, "OkHttp 4 (Android, Conscrypt)", captureMonth(2021, time.June), sourceLibDefaults, "reconstructed from the OkHttp MODERN_TLS connection spec and Conscrypt defaults; no GREASE"},
+	{HelloConscrypt, "Conscrypt 2 (Android)", captureMonth(2021, time.June), sourceLibDefaults, "reconstructed from Conscrypt defaults; no ALPN or session tickets"},
+}
+
+// Metadata returns provenance metadata for a parroted preset or a fingerprint
+// of the embedded database. It returns false for randomized, custom and Golang
+// ClientHelloIDs.
+func (p *ClientHelloID) Metadata() (PresetMetadata, bool) {
+	id := ClientHelloID{Client: p.Client, Version: p.Version}
+	for _, m := range presetMetadata {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	if id.Client == helloCaptured {
+		if fp, err := capturedFingerprint(id.Version); err == nil {
+			return fp.PresetMetadata, true
+		}
+	}
+	return PresetMetadata{}, false
+}
+
+// PresetsByFreshness returns metadata of all parroted presets, most recently
+// captured first. Presets captured at the same date are sorted by ID.
+func PresetsByFreshness() []PresetMetadata {
+	presets := make([]PresetMetadata, len(presetMetadata))
+	copy(presets, presetMetadata)
+	sort.SliceStable(presets, func(i, j int) bool {
+		if !presets[i].CaptureDate.Equal(presets[j].CaptureDate) {
+			return presets[i].CaptureDate.After(presets[j].CaptureDate)
+		}
+		return presets[i].ID.Str() < presets[j].ID.Str()
+	})
+	return presets
+}
+
+// PresetsCapturedSince returns metadata of parroted presets captured at or
+// after t, most recently captured first.
+func PresetsCapturedSince(t time.Time) []PresetMetadata {
+	var presets []PresetMetadata
+	for _, m := range PresetsByFreshness() {
+		if m.CaptureDate.Before(t) {
+			break
+		}
+		presets = append(presets, m)
+	}
+	return presets
+}
@@ -0,0 +1,54 @@
+package tls
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPresetMetadata(t *testing.T) {
+	for _, m := range presetMetadata {
+		if _, err := UTLSIdToSpec(m.ID); err != nil {
+			t.Errorf("%s: metadata for unknown preset: %v", m.ID.Str(), err)
+		}
+		if m.BrowserVersion == "" || m.CaptureDate.IsZero() {
+			t.Errorf("%s: incomplete metadata", m.ID.Str())
+		}
+	}
+
+	m, ok := HelloChrome_Auto.Metadata()
+	if !ok || m.ID != HelloChrome_Auto {
+		t.Fatalf("missing metadata for %s", HelloChrome_Auto.Str())
+	}
+	seeded := HelloChrome_120
+	seeded.Seed = &PRNGSeed{}
+	if m, ok := seeded.Metadata(); !ok || m.ID != HelloChrome_120 {
+		t.Errorf("metadata lookup should ignore the seed")
+	}
+	for _, id := range []ClientHelloID{HelloGolang, HelloCustom, HelloRandomized} {
+		if _, ok := id.Metadata(); ok {
+			t.Errorf("%s: unexpected metadata", id.Str())
+		}
+	}
+}
+
+func TestPresetsByFreshness(t *testing.T) {
+	presets := PresetsByFreshness()
+	if len(presets) != len(presetMetadata) {
+		t.Fatalf("got %d presets, want %d", len(presets), len(presetMetadata))
+	}
+	for i := 1; i < len(presets); i++ {
+		if presets[i].CaptureDate.After(presets[i-1].CaptureDate) {
+			t.Errorf("%s sorted after older %s", presets[i].ID.Str(), presets[i-1].ID.Str())
+		}
+	}
+
+	since := captureMonth(2023, time.December)
+	for _, m := range PresetsCapturedSince(since) {
+		if m.CaptureDate.Before(since) {
+			t.Errorf("%s captured before %v", m.ID.Str(), since)
+		}
+	}
+	if len(PresetsCapturedSince(time.Now().AddDate(10, 0, 0))) != 0 {
+		t.Error("expected no presets captured in the future")
+	}
+}
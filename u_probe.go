@@ -0,0 +1,187 @@
+package tls
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	defaultProbeConcurrency      = 4
+	defaultProbeDialTimeout      = 10 * time.Second
+	defaultProbeHandshakeTimeout = 15 * time.Second
+)
+
+// ProbeResult is the outcome of connecting to a host with a single
+// ClientHelloID.
+type ProbeResult struct {
+	ClientHelloID ClientHelloID
+
+	// DialErr is the error of the TCP dial, if any. If it is set, the
+	// handshake was not attempted.
+	DialErr error
+
+	// HandshakeErr is the error of the TLS handshake, if any.
+	HandshakeErr error
+
+	// ConnectionState is the state of the connection after a successful
+	// handshake.
+	ConnectionState ConnectionState
+
+	DialDuration      time.Duration
+	HandshakeDuration time.Duration
+}
+
+// Reachable reports whether the TCP connection could be established.
+func (r *ProbeResult) Reachable() bool {
+	return r.DialErr == nil
+}
+
+// OK reports whether the TLS handshake completed.
+func (r *ProbeResult) OK() bool {
+	return r.DialErr == nil && r.HandshakeErr == nil
+}
+
+// Prober connects to a host with several ClientHelloIDs concurrently and
+// reports which of them complete a handshake. It is intended for measuring
+// fingerprint-based blocking: a host that is reachable but only fails the
+// handshake for some fingerprints is likely filtered on the ClientHello.
+//
+// Unlike Roller, which stops at the first working ClientHelloID, a Prober
+// always tries all of them and closes the resulting connections.
+type Prober struct {
+	// HelloIDs are the ClientHelloIDs to probe with.
+	HelloIDs []ClientHelloID
+
+	// Config is used for every connection, with ServerName overridden by
+	// the serverName passed to Probe if not empty. If nil, an empty Config
+	// is used.
+	Config *Config
+
+	// Concurrency bounds the number of simultaneous probes. If zero, 4 is
+	// used.
+	Concurrency int
+
+	// Stagger is the delay between starting successive probes, so that the
+	// connections don't all arrive at once.
+	Stagger time.Duration
+
+	// DialTimeout and HandshakeTimeout bound each probe. If zero, 10 and 15
+	// seconds are used respectively.
+	DialTimeout      time.Duration
+	HandshakeTimeout time.Duration
+
+	// DialContext, if set, is used to establish TCP connections instead of
+	// a net.Dialer.
+	DialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// NewProber returns a Prober for the given ClientHelloIDs with default
+// settings.
+func NewProber(helloIDs ...ClientHelloID) *Prober {
+	return &Prober{HelloIDs: helloIDs}
+}
+
+// Probe connects to addr once per ClientHelloID and returns the results in the
+// order of HelloIDs. Probes that haven't started when ctx is done report the
+// context error as DialErr.
+func (p *Prober) Probe(ctx context.Context, network, addr, serverName string) []ProbeResult {
+	results := make([]ProbeResult, len(p.HelloIDs))
+	concurrency := p.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultProbeConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, id := range p.HelloIDs {
+		results[i].ClientHelloID = id
+		if i > 0 && p.Stagger > 0 {
+			t := time.NewTimer(p.Stagger)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+			}
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if err := ctx.Err(); err != nil {
+			results[i].DialErr = err
+			continue
+		}
+		wg.Add(1)
+		go func(r *ProbeResult) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			p.probe(ctx, r, network, addr, serverName)
+		}(&results[i])
+	}
+	wg.Wait()
+	return results
+}
+
+func (p *Prober) probe(ctx context.Context, r *ProbeResult, network, addr, serverName string) {
+	if uconn := p.connect(ctx, r, network, addr, serverName); uconn != nil {
+		uconn.Close()
+	}
+}
+
+// connect is like probe, but returns the connection if the handshake
+// completed.
+func (p *Prober) connect(ctx context.Context, r *ProbeResult, network, addr, serverName string) *UConn {
+	dialTimeout := p.DialTimeout
+	if dialTimeout == 0 {
+		dialTimeout = defaultProbeDialTimeout
+	}
+	handshakeTimeout := p.HandshakeTimeout
+	if handshakeTimeout == 0 {
+		handshakeTimeout = defaultProbeHandshakeTimeout
+	}
+
+	start := time.Now()
+	dialCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	var conn net.Conn
+	if p.DialContext != nil {
+		conn, r.DialErr = p.DialContext(dialCtx, network, addr)
+	} else {
+		var d net.Dialer
+		conn, r.DialErr = d.DialContext(dialCtx, network, addr)
+	}
+	cancel()
+	r.DialDuration = time.Since(start)
+	if r.DialErr != nil {
+		return nil
+	}
+	if conn == nil {
+		r.DialErr = errors.New("tls: DialContext returned no connection")
+		return nil
+	}
+
+	var config *Config
+	if p.Config == nil {
+		config = &Config{}
+	} else {
+		config = p.Config.Clone()
+	}
+	if serverName != "" {
+		config.ServerName = serverName
+	}
+
+	start = time.Now()
+	hsCtx, cancel := context.WithTimeout(ctx, handshakeTimeout)
+	defer cancel()
+	uconn := UClient(conn, config, r.ClientHelloID)
+	r.HandshakeErr = uconn.HandshakeContext(hsCtx)
+	r.HandshakeDuration = time.Since(start)
+	if r.HandshakeErr != nil {
+		uconn.Close()
+		return nil
+	}
+	r.ConnectionState = uconn.ConnectionState()
+	return uconn
+}
@@ -0,0 +1,81 @@
+package tls
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProber(t *testing.T) {
+	// Block ClientHellos offering certificate compression, like a filter
+	// matching on a browser fingerprint would.
+	serverConfig := &Config{
+		GetConfigForClient: func(chi *ClientHelloInfo) (*Config, error) {
+			if slices.Contains(chi.Extensions, utlsExtensionCompressCertificate) {
+				return nil, errors.New("blocked")
+			}
+			return nil, nil
+		},
+	}
+	s, err := NewClientHelloEchoServer(serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate)
+	p := NewProber(HelloChrome_120, HelloGolang, HelloFirefox_120)
+	p.Config = &Config{RootCAs: roots}
+	p.Concurrency = 2
+	p.Stagger = time.Millisecond
+
+	results := p.Probe(context.Background(), "tcp", s.Addr().String(), "localhost")
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	for i, want := range []bool{false, true, true} {
+		r := results[i]
+		if r.ClientHelloID != p.HelloIDs[i] {
+			t.Errorf("result %d is for %s, want %s", i, r.ClientHelloID.Str(), p.HelloIDs[i].Str())
+		}
+		if !r.Reachable() {
+			t.Errorf("%s: dial failed: %v", r.ClientHelloID.Str(), r.DialErr)
+		}
+		if r.OK() != want {
+			t.Errorf("%s: OK() = %v, want %v (handshake error: %v)", r.ClientHelloID.Str(), r.OK(), want, r.HandshakeErr)
+		}
+		if r.OK() && (!r.ConnectionState.HandshakeComplete || r.ConnectionState.ServerName != "localhost") {
+			t.Errorf("%s: unexpected connection state %+v", r.ClientHelloID.Str(), r.ConnectionState)
+		}
+	}
+}
+
+func TestProberUnreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	p := NewProber(HelloChrome_Auto, HelloFirefox_Auto)
+	results := p.Probe(context.Background(), "tcp", addr, "localhost")
+	for _, r := range results {
+		if r.Reachable() || r.OK() || r.HandshakeErr != nil {
+			t.Errorf("%s: expected a dial error, got %+v", r.ClientHelloID.Str(), r)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, r := range p.Probe(ctx, "tcp", addr, "localhost") {
+		if !errors.Is(r.DialErr, context.Canceled) {
+			t.Errorf("%s: DialErr = %v, want context.Canceled", r.ClientHelloID.Str(), r.DialErr)
+		}
+	}
+}
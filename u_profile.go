@@ -0,0 +1,136 @@
+package tls
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// Profile bundles a ClientHello with the record layer, transport and timing
+// behaviors of the client it imitates, so that they are applied to a UConn in
+// one call with Apply, and stored as a unit, e.g. as JSON. Mixing parts of
+// different profiles is itself a fingerprint.
+type Profile struct {
+	// Name identifies the profile, e.g. "chrome-131-windows".
+	Name string `json:"name"`
+
+	// ClientHello is the ClientHello in the text format of
+	// ExportTLSClientHelloText. A new ClientHelloSpec is imported from it
+	// for each connection, as extensions can't be shared.
+	ClientHello string `json:"client_hello"`
+
+	// ALPN, if not nil, replaces the protocols offered in the ALPN
+	// extension of ClientHello, e.g. []string{"http/1.1"} for a client
+	// without HTTP/2 support. An empty non-nil list removes the extension.
+	ALPN []string `json:"alpn,omitempty"`
+
+	// FalseStart sets ClientHelloSpec.FalseStart, which the text format
+	// doesn't carry.
+	FalseStart bool `json:"false_start,omitempty"`
+
+	// DynamicRecordSizingDisabled and ExplicitNonce set the record sizing
+	// and nonce policies of the connection, see the Config fields of the
+	// same names.
+	DynamicRecordSizingDisabled bool                `json:"dynamic_record_sizing_disabled,omitempty"`
+	ExplicitNonce               ExplicitNoncePolicy `json:"explicit_nonce,omitempty"`
+
+	// Transport, if not nil, shapes the segments carrying the ClientHello,
+	// see ShapeConn.
+	Transport *TransportShaping `json:"transport,omitempty"`
+
+	// HandshakeJitter, if not zero, delays the ClientHello by a random
+	// duration of up to HandshakeJitter, so that the timing between
+	// connecting and sending it varies like for a real client.
+	HandshakeJitter time.Duration `json:"handshake_jitter,omitempty"`
+}
+
+// NewProfile returns a Profile named name with the ClientHello of id, and
+// default record layer and transport behaviors.
+func NewProfile(name string, id ClientHelloID) (*Profile, error) {
+	spec, err := UTLSIdToSpec(id)
+	if err != nil {
+		return nil, err
+	}
+	text, err := spec.ExportTLSClientHelloText()
+	if err != nil {
+		return nil, err
+	}
+	return &Profile{Name: name, ClientHello: string(text), FalseStart: spec.FalseStart}, nil
+}
+
+// Spec returns a new ClientHelloSpec of the ClientHello, ALPN and FalseStart of
+// p.
+func (p *Profile) Spec() (ClientHelloSpec, error) {
+	var spec ClientHelloSpec
+	if err := spec.ImportTLSClientHelloFromText([]byte(p.ClientHello)); err != nil {
+		return spec, err
+	}
+	spec.FalseStart = p.FalseStart
+	if p.ALPN == nil {
+		return spec, nil
+	}
+	for i, ext := range spec.Extensions {
+		if alpn, ok := ext.(*ALPNExtension); ok {
+			if len(p.ALPN) == 0 {
+				spec.Extensions = slices.Delete(spec.Extensions, i, i+1)
+			} else {
+				alpn.AlpnProtocols = slices.Clone(p.ALPN)
+			}
+			return spec, nil
+		}
+	}
+	if len(p.ALPN) != 0 {
+		return spec, errors.New("tls: profile sets ALPN, but its ClientHello has no ALPN extension")
+	}
+	return spec, nil
+}
+
+// Apply applies p to uconn, which must be a HelloCustom UConn whose handshake
+// hasn't started. The Config of uconn is cloned before it's modified.
+func (p *Profile) Apply(uconn *UConn) error {
+	if uconn.ClientHelloID != HelloCustom {
+		return errors.New("tls: profiles can only be applied to HelloCustom connections")
+	}
+	spec, err := p.Spec()
+	if err != nil {
+		return err
+	}
+	config := uconn.config.Clone()
+	config.DynamicRecordSizingDisabled = p.DynamicRecordSizingDisabled
+	config.ExplicitNonce = p.ExplicitNonce
+	uconn.config = config
+	if err := uconn.ApplyPreset(&spec); err != nil {
+		return err
+	}
+
+	conn := uconn.GetUnderlyingConn()
+	if p.Transport != nil {
+		if conn, err = ShapeConn(conn, p.Transport); err != nil {
+			return err
+		}
+	}
+	if p.HandshakeJitter > 0 {
+		uconn.utls.handshakeJitter.Store(int64(rand.N(p.HandshakeJitter + 1)))
+	}
+	uconn.SetUnderlyingConn(conn)
+	return nil
+}
+
+// waitHandshakeJitter waits for the delay set by Profile.HandshakeJitter, if
+// any, before the handshake takes any lock, or until ctx is done.
+func (c *UConn) waitHandshakeJitter(ctx context.Context) error {
+	delay := time.Duration(c.utls.handshakeJitter.Swap(0))
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,83 @@
+package tls
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProfile(t *testing.T) {
+	p, err := NewProfile("firefox-http1", HelloFirefox_120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.ALPN = []string{"http/1.1"}
+	p.ExplicitNonce = ExplicitNonceRandom
+	p.Transport = &TransportShaping{SplitServerName: true}
+	p.HandshakeJitter = time.Millisecond
+
+	b, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Profile
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewUTLSServer(&Config{NextProtos: []string{"h2", "http/1.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c := NewUnstartedUTLSClient(s, nil, HelloCustom)
+	if err := decoded.Apply(c.UConn); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if proto := c.ConnectionState().NegotiatedProtocol; proto != "http/1.1" {
+		t.Errorf("negotiated %q, want http/1.1", proto)
+	}
+	if c.config.ExplicitNonce != ExplicitNonceRandom {
+		t.Error("profile didn't set ExplicitNonce")
+	}
+	spec, err := UTLSIdToSpec(HelloFirefox_120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(c.HandshakeState.Hello.CipherSuites, spec.CipherSuites) {
+		t.Errorf("cipher suites = %x, want those of Firefox 120", c.HandshakeState.Hello.CipherSuites)
+	}
+
+	if err := decoded.Apply(UClient(nil, nil, HelloChrome_Auto)); err == nil {
+		t.Error("profile applied to a connection with a preset ClientHelloID")
+	}
+}
+
+func TestProfileHandshakeJitterContext(t *testing.T) {
+	p, err := NewProfile("slow", HelloChrome_120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.HandshakeJitter = time.Hour
+	rec := &writeRecorder{}
+	uconn := UClient(rec, &Config{ServerName: "example.com"}, HelloCustom)
+	if err := p.Apply(uconn); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := uconn.HandshakeContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("HandshakeContext = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if len(rec.writes) != 0 {
+		t.Error("ClientHello written before the jitter delay")
+	}
+}
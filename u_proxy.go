@@ -0,0 +1,428 @@
+package tls
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultProxyDialTimeout      = 10 * time.Second
+	defaultProxyHandshakeTimeout = 15 * time.Second
+	proxyRequestTimeout          = 30 * time.Second
+)
+
+// ErrProxyClosed is returned by ProxyServer.Serve after Close is called.
+var ErrProxyClosed = errors.New("tls: proxy server closed")
+
+// SOCKS5 constants, see RFC 1928.
+const (
+	socks5Version = 5
+
+	socks5AuthNone         = 0
+	socks5AuthNoAcceptable = 0xff
+
+	socks5CmdConnect = 1
+
+	socks5AddrIPv4   = 1
+	socks5AddrDomain = 3
+	socks5AddrIPv6   = 4
+
+	socks5Succeeded           = 0
+	socks5GeneralFailure      = 1
+	socks5HostUnreachable     = 4
+	socks5CmdNotSupported     = 7
+	socks5AddrTypeUnsupported = 8
+)
+
+// ProxyServer is a local proxy that accepts SOCKS5 and HTTP CONNECT requests
+// from applications speaking plaintext, and connects to the requested
+// destination with a uTLS client. The application then talks to the
+// destination through the proxy as if it had made the TLS connection itself,
+// with the fingerprint chosen by the proxy.
+//
+// Only the SOCKS5 CONNECT command without authentication is supported. Both
+// protocols are served on the same listener, told apart by the first byte.
+type ProxyServer struct {
+	// ClientHelloID is used for upstream connections if Identities is nil.
+	// If zero, HelloChrome_Auto is used.
+	ClientHelloID ClientHelloID
+
+	// Identities, if not nil, picks the persona of every upstream
+	// connection from its destination host, see IdentityManager.UClient.
+	Identities *IdentityManager
+
+	// Config is used for upstream connections, with ServerName set to the
+	// requested host. If nil, an empty Config is used.
+	Config *Config
+
+	// DialTimeout and HandshakeTimeout bound each upstream connection. If
+	// zero, 10 and 15 seconds are used respectively.
+	DialTimeout      time.Duration
+	HandshakeTimeout time.Duration
+
+	// NetDialContext, if set, is used to establish upstream TCP connections
+	// instead of a net.Dialer.
+	NetDialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// OnError, if set, is called with the error of every proxied connection
+	// that failed before relaying data, e.g. a malformed request or a failed
+	// upstream handshake.
+	OnError func(client net.Addr, destination string, err error)
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// ListenAndServe listens on the TCP address addr and calls Serve.
+func (s *ProxyServer) ListenAndServe(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l and proxies them until l fails or Close is
+// called, in which case it returns ErrProxyClosed. l is closed on return.
+func (s *ProxyServer) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrProxyClosed
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	s.listeners[l] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		l.Close()
+	}()
+
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrProxyClosed
+			}
+			return err
+		}
+		if !s.track(c, true) {
+			c.Close()
+			return ErrProxyClosed
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.track(c, false)
+			defer c.Close()
+			s.handle(c)
+		}()
+	}
+}
+
+// Close stops all listeners, closes all proxied connections and waits for
+// them to finish.
+func (s *ProxyServer) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	for l := range s.listeners {
+		if cerr := l.Close(); err == nil {
+			err = cerr
+		}
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// track adds or removes c from the connections closed by Close, and reports
+// false if the server is already closed.
+func (s *ProxyServer) track(c net.Conn, add bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !add {
+		delete(s.conns, c)
+		return true
+	}
+	if s.closed {
+		return false
+	}
+	if s.conns == nil {
+		s.conns = make(map[net.Conn]struct{})
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *ProxyServer) handle(c net.Conn) {
+	c.SetDeadline(time.Now().Add(proxyRequestTimeout))
+	br := bufio.NewReader(c)
+	first, err := br.Peek(1)
+	if err != nil {
+		return
+	}
+	// Bytes sent by the client after its request are buffered in br.
+	client := &bufferedConn{Conn: c, r: br}
+	var dest string
+	var upstream *UConn
+	if first[0] == socks5Version {
+		dest, upstream, err = s.handleSOCKS5(client, br)
+	} else {
+		dest, upstream, err = s.handleConnect(client, br)
+	}
+	if err != nil {
+		if s.OnError != nil {
+			s.OnError(c.RemoteAddr(), dest, err)
+		}
+		return
+	}
+	defer upstream.Close()
+	if !s.track(upstream, true) {
+		return
+	}
+	defer s.track(upstream, false)
+	c.SetDeadline(time.Time{})
+	relay(client, upstream)
+}
+
+func (s *ProxyServer) handleSOCKS5(c net.Conn, br *bufio.Reader) (string, *UConn, error) {
+	// Method selection.
+	var hdr [2]byte
+	if _, err := io.ReadFull(br, hdr[:]); err != nil {
+		return "", nil, err
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(br, methods); err != nil {
+		return "", nil, err
+	}
+	method := byte(socks5AuthNoAcceptable)
+	for _, m := range methods {
+		if m == socks5AuthNone {
+			method = socks5AuthNone
+		}
+	}
+	if _, err := c.Write([]byte{socks5Version, method}); err != nil {
+		return "", nil, err
+	}
+	if method == socks5AuthNoAcceptable {
+		return "", nil, errors.New("tls: SOCKS5 client doesn't support connecting without authentication")
+	}
+
+	// Request.
+	var req [4]byte
+	if _, err := io.ReadFull(br, req[:]); err != nil {
+		return "", nil, err
+	}
+	if req[0] != socks5Version {
+		return "", nil, fmt.Errorf("tls: unexpected SOCKS version %d", req[0])
+	}
+	var host string
+	switch req[3] {
+	case socks5AddrIPv4, socks5AddrIPv6:
+		ip := make(net.IP, net.IPv4len)
+		if req[3] == socks5AddrIPv6 {
+			ip = make(net.IP, net.IPv6len)
+		}
+		if _, err := io.ReadFull(br, ip); err != nil {
+			return "", nil, err
+		}
+		host = ip.String()
+	case socks5AddrDomain:
+		n, err := br.ReadByte()
+		if err != nil {
+			return "", nil, err
+		}
+		name := make([]byte, n)
+		if _, err := io.ReadFull(br, name); err != nil {
+			return "", nil, err
+		}
+		host = string(name)
+	default:
+		writeSOCKS5Reply(c, socks5AddrTypeUnsupported)
+		return "", nil, fmt.Errorf("tls: unsupported SOCKS5 address type %d", req[3])
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(br, port[:]); err != nil {
+		return "", nil, err
+	}
+	dest := net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:]))))
+	if req[1] != socks5CmdConnect {
+		writeSOCKS5Reply(c, socks5CmdNotSupported)
+		return dest, nil, fmt.Errorf("tls: unsupported SOCKS5 command %d", req[1])
+	}
+
+	upstream, dialErr, err := s.dial(host, dest)
+	if dialErr != nil {
+		writeSOCKS5Reply(c, socks5HostUnreachable)
+		return dest, nil, dialErr
+	}
+	if err != nil {
+		writeSOCKS5Reply(c, socks5GeneralFailure)
+		return dest, nil, err
+	}
+	if err := writeSOCKS5Reply(c, socks5Succeeded); err != nil {
+		upstream.Close()
+		return dest, nil, err
+	}
+	return dest, upstream, nil
+}
+
+// writeSOCKS5Reply writes a reply with an unspecified bound address, which
+// clients of a CONNECT proxy don't need.
+func writeSOCKS5Reply(c net.Conn, rep byte) error {
+	_, err := c.Write([]byte{socks5Version, rep, 0, socks5AddrIPv4, 0, 0, 0, 0, 0, 0})
+	return err
+}
+
+func (s *ProxyServer) handleConnect(c net.Conn, br *bufio.Reader) (string, *UConn, error) {
+	req, err := http.ReadRequest(br)
+	if err != nil {
+		return "", nil, err
+	}
+	if req.Method != http.MethodConnect {
+		io.WriteString(c, "HTTP/1.1 405 Method Not Allowed\r\nAllow: CONNECT\r\nContent-Length: 0\r\n\r\n")
+		return "", nil, fmt.Errorf("tls: unsupported HTTP proxy method %s", req.Method)
+	}
+	dest := req.Host
+	host, _, err := net.SplitHostPort(dest)
+	if err != nil {
+		io.WriteString(c, "HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n")
+		return dest, nil, err
+	}
+
+	upstream, dialErr, err := s.dial(host, dest)
+	if dialErr != nil {
+		err = dialErr
+	}
+	if err != nil {
+		io.WriteString(c, "HTTP/1.1 502 Bad Gateway\r\nContent-Length: 0\r\n\r\n")
+		return dest, nil, err
+	}
+	if _, err := io.WriteString(c, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		upstream.Close()
+		return dest, nil, err
+	}
+	return dest, upstream, nil
+}
+
+// dial connects to dest and completes a uTLS handshake with host as the
+// server name. It returns the dial error separately from the handshake error,
+// as proxy protocols report them differently.
+func (s *ProxyServer) dial(host, dest string) (uconn *UConn, dialErr, err error) {
+	dialTimeout := s.DialTimeout
+	if dialTimeout == 0 {
+		dialTimeout = defaultProxyDialTimeout
+	}
+	handshakeTimeout := s.HandshakeTimeout
+	if handshakeTimeout == 0 {
+		handshakeTimeout = defaultProxyHandshakeTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	var conn net.Conn
+	if s.NetDialContext != nil {
+		conn, dialErr = s.NetDialContext(ctx, "tcp", dest)
+	} else {
+		var d net.Dialer
+		conn, dialErr = d.DialContext(ctx, "tcp", dest)
+	}
+	cancel()
+	if dialErr != nil {
+		return nil, dialErr, nil
+	}
+	if conn == nil {
+		return nil, errors.New("tls: NetDialContext returned no connection"), nil
+	}
+
+	var config *Config
+	if s.Config == nil {
+		config = &Config{}
+	} else {
+		config = s.Config.Clone()
+	}
+	config.ServerName = host
+	if s.Identities != nil {
+		uconn, _, err = s.Identities.UClient(conn, config, host)
+		if err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+	} else {
+		id := s.ClientHelloID
+		if id == (ClientHelloID{}) {
+			id = HelloChrome_Auto
+		}
+		uconn = UClient(conn, config, id)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	if err := uconn.HandshakeContext(ctx); err != nil {
+		uconn.Close()
+		return nil, nil, err
+	}
+	return uconn, nil, nil
+}
+
+// relay copies data between client and upstream in both directions until
+// both are done. EOF from either side is forwarded with CloseWrite.
+func relay(client net.Conn, upstream *UConn) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		io.Copy(client, upstream)
+		if cw, ok := client.(interface{ CloseWrite() error }); ok {
+			cw.CloseWrite()
+		} else {
+			client.Close()
+		}
+	}()
+	if _, err := io.Copy(upstream, client); err == nil {
+		upstream.CloseWrite()
+	} else {
+		upstream.Close()
+	}
+	<-done
+}
+
+// bufferedConn is a net.Conn whose reads are served by r first, so that data
+// buffered while parsing a proxy request isn't lost.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+func (c *bufferedConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return c.Conn.Close()
+}
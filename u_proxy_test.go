@@ -0,0 +1,182 @@
+package tls
+
+import (
+	"bufio"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func startTestProxy(t *testing.T, p *ProxyServer) net.Addr {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- p.Serve(l) }()
+	t.Cleanup(func() {
+		p.Close()
+		if err := <-serveErr; !errors.Is(err, ErrProxyClosed) {
+			t.Errorf("Serve returned %v, want ErrProxyClosed", err)
+		}
+	})
+	return l.Addr()
+}
+
+func socks5Connect(t *testing.T, proxy net.Addr, host string, port int) (net.Conn, byte) {
+	c, err := net.Dial("tcp", proxy.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := []byte{socks5Version, 1, socks5AuthNone, socks5Version, socks5CmdConnect, 0, socks5AddrDomain, byte(len(host))}
+	req = append(req, host...)
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := c.Write(req); err != nil {
+		t.Fatal(err)
+	}
+	var reply [12]byte
+	if _, err := io.ReadFull(c, reply[:]); err != nil {
+		t.Fatal(err)
+	}
+	if reply[0] != socks5Version || reply[1] != socks5AuthNone || reply[2] != socks5Version {
+		t.Fatalf("unexpected SOCKS5 reply %x", reply)
+	}
+	return c, reply[3]
+}
+
+func httpConnect(t *testing.T, proxy net.Addr, dest string) (net.Conn, *bufio.Reader, int) {
+	c, err := net.Dial("tcp", proxy.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(c, "CONNECT "+dest+" HTTP/1.1\r\nHost: "+dest+"\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(c)
+	resp, err := http.ReadResponse(br, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, br, resp.StatusCode
+}
+
+// fetchClientHelloReport sends an HTTP request to a ClientHelloEchoServer
+// through c and returns the report.
+func fetchClientHelloReport(t *testing.T, c net.Conn, br *bufio.Reader) *ClientHelloReport {
+	if _, err := io.WriteString(c, "GET / HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var report ClientHelloReport
+	if err := json.NewDecoder(resp.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	return &report
+}
+
+func TestProxyServer(t *testing.T) {
+	echo, err := NewClientHelloEchoServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	port := echo.Addr().(*net.TCPAddr).Port
+	roots := x509.NewCertPool()
+	roots.AddCert(echo.Certificate)
+
+	expected, err := UTLSIdToSpec(HelloFirefox_Auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &ProxyServer{ClientHelloID: HelloFirefox_Auto, Config: &Config{RootCAs: roots}}
+	proxy := startTestProxy(t, p)
+
+	c, rep := socks5Connect(t, proxy, "localhost", port)
+	defer c.Close()
+	if rep != socks5Succeeded {
+		t.Fatalf("SOCKS5 CONNECT failed with reply %d", rep)
+	}
+	report := fetchClientHelloReport(t, c, bufio.NewReader(c))
+	if report.ServerName != "localhost" || len(report.CipherSuites) != len(expected.CipherSuites) {
+		t.Errorf("unexpected ClientHello through SOCKS5: %+v", report)
+	}
+
+	c, br, status := httpConnect(t, proxy, net.JoinHostPort("localhost", strconv.Itoa(port)))
+	defer c.Close()
+	if status != http.StatusOK {
+		t.Fatalf("HTTP CONNECT failed with status %d", status)
+	}
+	report = fetchClientHelloReport(t, c, br)
+	if report.ServerName != "localhost" || len(report.CipherSuites) != len(expected.CipherSuites) {
+		t.Errorf("unexpected ClientHello through HTTP CONNECT: %+v", report)
+	}
+}
+
+func TestProxyServerErrors(t *testing.T) {
+	echo, err := NewClientHelloEchoServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	port := echo.Addr().(*net.TCPAddr).Port
+
+	errs := make(chan error, 10)
+	// The echo server certificate is not trusted.
+	p := &ProxyServer{OnError: func(client net.Addr, dest string, err error) { errs <- err }}
+	proxy := startTestProxy(t, p)
+
+	c, rep := socks5Connect(t, proxy, "localhost", port)
+	c.Close()
+	if rep != socks5GeneralFailure {
+		t.Errorf("SOCKS5 CONNECT with a failed handshake returned reply %d, want %d", rep, socks5GeneralFailure)
+	}
+	var certErr *CertificateVerificationError
+	if err := <-errs; !errors.As(err, &certErr) {
+		t.Errorf("OnError called with %v, want a certificate verification error", err)
+	}
+
+	c, _, status := httpConnect(t, proxy, net.JoinHostPort("localhost", strconv.Itoa(port)))
+	c.Close()
+	if status != http.StatusBadGateway {
+		t.Errorf("HTTP CONNECT with a failed handshake returned status %d, want %d", status, http.StatusBadGateway)
+	}
+	<-errs
+
+	// Nothing listens on the port of a closed listener.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+	c, rep = socks5Connect(t, proxy, "127.0.0.1", l.Addr().(*net.TCPAddr).Port)
+	c.Close()
+	if rep != socks5HostUnreachable {
+		t.Errorf("SOCKS5 CONNECT to a closed port returned reply %d, want %d", rep, socks5HostUnreachable)
+	}
+	<-errs
+
+	c, err = net.Dial("tcp", proxy.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(c, "GET http://localhost/ HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(c), nil)
+	c.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("plain HTTP request returned status %d, want %d", resp.StatusCode, http.StatusMethodNotAllowed)
+	}
+	<-errs
+}
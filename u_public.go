@@ -5,6 +5,7 @@
 package tls
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ecdh"
 	"crypto/mlkem"
@@ -741,10 +742,47 @@ func (css *ClientSessionState) VerifiedChains() [][]*x509.Certificate {
 	return css.session.verifiedChains
 }
 
+// SessionState returns the state necessary to resume the session, or nil,
+// e.g. to serialize it with SessionState.Bytes.
+func (css *ClientSessionState) SessionState() *SessionState {
+	return css.session
+}
+
+// Session ID the client resumes the session with, see SessionState.SessionID
+func (css *ClientSessionState) SessionID() []byte {
+	if css.session == nil {
+		return nil
+	}
+	return css.session.SessionID()
+}
+
 func (css *ClientSessionState) SetSessionTicket(SessionTicket []uint8) {
+	if css.session == nil {
+		css.session = &SessionState{}
+	}
 	css.session.ticket = SessionTicket
 }
 
+// SetSessionState sets the state necessary to resume the session, e.g. one
+// returned by ParseSessionState, to import a session obtained by another
+// process without a handshake. A ticket previously set with SetSessionTicket
+// is kept if state has none.
+func (css *ClientSessionState) SetSessionState(state *SessionState) {
+	if css.session != nil && state != nil && state.ticket == nil {
+		state.ticket = css.session.ticket
+	}
+	css.session = state
+}
+
+// SetSessionID sets the session ID the client resumes the session with, and
+// makes it resume the session by session ID rather than by ticket.
+func (css *ClientSessionState) SetSessionID(sessionID []byte) {
+	if css.session == nil {
+		css.session = &SessionState{}
+	}
+	SetSessionExtraFields(css.session, &UTLSSessionData{ResumeType: ResumeSessionID, SessionID: bytes.Clone(sessionID)})
+}
+
 func (css *ClientSessionState) SetVers(Vers uint16) {
 	if css.session == nil {
 		css.session = &SessionState{}
@@ -7,7 +7,6 @@ package tls
 import (
 	"context"
 	"errors"
-	"fmt"
 )
 
 // A UQUICConn represents a connection which uses a QUIC implementation as the underlying
@@ -51,6 +50,9 @@ func (q *UQUICConn) Start(ctx context.Context) error {
 	if q.conn.config.MinVersion < VersionTLS13 {
 		return quicError(errors.New("tls: Config MinVersion must be at least TLS 1.13"))
 	}
+	if q.conn.config.NoBackgroundTasks {
+		return quicError(errQUICBackgroundTasks)
+	}
 	go q.conn.HandshakeContext(ctx)
 	if _, ok := <-q.conn.quic.blockedc; !ok {
 		return q.conn.handshakeErr
@@ -116,8 +118,8 @@ func (q *UQUICConn) HandleData(level QUICEncryptionLevel, data []byte) error {
 	for q.conn.hand.Len() >= 4 && q.conn.handshakeErr == nil {
 		b := q.conn.hand.Bytes()
 		n := int(b[1])<<16 | int(b[2])<<8 | int(b[3])
-		if n > maxHandshake {
-			q.conn.handshakeErr = fmt.Errorf("tls: handshake message of length %d bytes exceeds maximum of %d bytes", n, maxHandshake)
+		if limit := q.conn.maxHandshakeSize(b[0]); n > limit { // [uTLS]
+			q.conn.handshakeErr = &HandshakeSizeError{Kind: LimitMessageSize, MsgType: b[0], Size: n, Limit: limit}
 			break
 		}
 		if len(b) < 4+n {
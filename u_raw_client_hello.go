@@ -0,0 +1,119 @@
+package tls
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ApplyClientHelloBytes makes uconn send raw, a complete ClientHello handshake
+// message in the format of HandshakeState.Hello.Raw, instead of building one,
+// and drive the rest of the handshake as usual. It's meant for tools that
+// generate ClientHellos outside of uTLS.
+//
+// keys are the private keys of the key shares in raw, as ApplyPreset would
+// generate them: Ecdhe for the first ECDHE key share, and Mlkem and
+// MlkemEcdhe for a X25519MLKEM768 or X25519Kyber768Draft00 key share. Key
+// shares without a private key can't be selected by the server. keys may be
+// nil if raw has no key shares.
+//
+// raw is sent byte for byte, except in response to a HelloRetryRequest, when
+// the ClientHello is rebuilt from a spec parsed from raw. Sessions are not
+// resumed, so raw must not offer pre_shared_key, and an encrypted_client_hello
+// extension is sent as GREASE. The server certificate is verified against the
+// Config, not the server_name of raw. Connections using it can't be
+// renegotiated.
+//
+// It must be called before the handshake, instead of ApplyPreset and
+// BuildHandshakeState.
+func (uconn *UConn) ApplyClientHelloBytes(raw []byte, keys *KeySharePrivateKeys) error {
+	if uconn.clientHelloBuildStatus != NotBuilt {
+		return errors.New("tls: ApplyClientHelloBytes called after the ClientHello was built")
+	}
+	hello := new(clientHelloMsg)
+	if !hello.unmarshal(bytes.Clone(raw)) {
+		return errors.New("tls: unable to parse ClientHello")
+	}
+	if len(hello.pskIdentities) > 0 {
+		return errors.New("tls: ClientHello bytes offering pre_shared_key are not supported")
+	}
+	if err := checkKeySharePrivateKeys(hello.keyShares, keys); err != nil {
+		return err
+	}
+
+	// Apply a spec parsed from raw, so that the extensions are configured
+	// and a HelloRetryRequest can be answered.
+	record := append([]byte{byte(recordTypeHandshake), 3, 1, byte(len(raw) >> 8), byte(len(raw))}, raw...)
+	var spec ClientHelloSpec
+	if err := spec.FromRaw(record, true); err != nil {
+		return err
+	}
+	if err := uconn.ApplyPreset(&spec); err != nil {
+		return err
+	}
+	if err := uconn.ApplyConfig(); err != nil {
+		return err
+	}
+
+	uconn.HandshakeState.Hello = hello.getPublicPtr()
+	state := &KeySharePrivateKeys{}
+	if keys != nil {
+		*state = *keys
+	}
+	if state.Ecdhe == nil {
+		// The TLS 1.3 handshake requires an ECDHE key, which is otherwise
+		// the X25519 part of the hybrid key share.
+		state.Ecdhe = state.MlkemEcdhe
+	}
+	uconn.HandshakeState.State13.KeyShareKeys = state
+	uconn.HandshakeState.State13.EcdheKey = nil
+	// No session is loaded into raw.
+	uconn.sessionController.locked = true
+	uconn.clientHelloBuildStatus = BuildByUtls
+	uconn.rawClientHello = true
+	return nil
+}
+
+// checkKeySharePrivateKeys checks that each of keys matches a key share.
+func checkKeySharePrivateKeys(keyShares []keyShare, keys *KeySharePrivateKeys) error {
+	var ecdheMatched, hybridMatched, offered bool
+	for _, ks := range keyShares {
+		if isGREASEUint16(uint16(ks.group)) {
+			continue
+		}
+		offered = true
+		if keys == nil {
+			break
+		}
+		switch ks.group {
+		case X25519MLKEM768, X25519Kyber768Draft00:
+			if keys.Mlkem == nil || keys.MlkemEcdhe == nil {
+				continue
+			}
+			want := append(keys.Mlkem.EncapsulationKey().Bytes(), keys.MlkemEcdhe.PublicKey().Bytes()...)
+			if ks.group == X25519Kyber768Draft00 {
+				want = append(keys.MlkemEcdhe.PublicKey().Bytes(), keys.Mlkem.EncapsulationKey().Bytes()...)
+			}
+			hybridMatched = hybridMatched || bytes.Equal(ks.data, want)
+		default:
+			if keys.Ecdhe == nil {
+				continue
+			}
+			if curve, ok := curveForCurveID(ks.group); ok && curve == keys.Ecdhe.Curve() {
+				ecdheMatched = ecdheMatched || bytes.Equal(ks.data, keys.Ecdhe.PublicKey().Bytes())
+			}
+		}
+	}
+	if !offered {
+		return nil
+	}
+	if keys == nil || keys.Ecdhe == nil && keys.MlkemEcdhe == nil {
+		return errors.New("tls: ClientHello offers key shares, but no private keys were provided")
+	}
+	if keys.Ecdhe != nil && !ecdheMatched {
+		return errors.New("tls: ECDHE private key doesn't match a key share of the ClientHello")
+	}
+	if (keys.Mlkem != nil || keys.MlkemEcdhe != nil) && !hybridMatched {
+		return errors.New("tls: hybrid private keys don't match a key share of the ClientHello")
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package tls
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"net"
+	"testing"
+)
+
+func TestApplyClientHelloBytes(t *testing.T) {
+	s, err := NewClientHelloEchoServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	reports := make(chan *ClientHelloReport, 1)
+	s.OnReport = func(r *ClientHelloReport) { reports <- r }
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate)
+
+	// Build the ClientHello as an external generator would.
+	generator := UClient(nil, &Config{ServerName: "127.0.0.1"}, HelloChrome_131)
+	if err := generator.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	raw := generator.HandshakeState.Hello.Raw
+	keys := generator.HandshakeState.State13.KeyShareKeys
+
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	uconn := UClient(conn, &Config{ServerName: "127.0.0.1", RootCAs: roots}, HelloCustom)
+	defer uconn.Close()
+	if err := uconn.ApplyClientHelloBytes(raw, keys); err != nil {
+		t.Fatal(err)
+	}
+	if err := uconn.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+	if r := <-reports; r.Raw != hex.EncodeToString(raw) {
+		t.Error("server received a different ClientHello than the supplied bytes")
+	}
+	if got := uconn.curveID; got != X25519MLKEM768 {
+		t.Errorf("negotiated %v, want %v", got, X25519MLKEM768)
+	}
+
+	other := UClient(nil, &Config{ServerName: "127.0.0.1"}, HelloChrome_131)
+	if err := other.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	if err := UClient(nil, nil, HelloCustom).ApplyClientHelloBytes(raw, other.HandshakeState.State13.KeyShareKeys); err == nil {
+		t.Error("ApplyClientHelloBytes accepted the keys of another ClientHello")
+	}
+	if err := UClient(nil, nil, HelloCustom).ApplyClientHelloBytes(raw, nil); err == nil {
+		t.Error("ApplyClientHelloBytes accepted key shares without private keys")
+	}
+}
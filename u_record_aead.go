@@ -0,0 +1,62 @@
+package tls
+
+import (
+	"crypto/cipher"
+	"errors"
+)
+
+// RecordAEADProvider supplies the AEAD implementations protecting the records
+// of TLS 1.2 AEAD cipher suites and TLS 1.3, so that alternative
+// implementations, such as AWS-LC, BoringCrypto or hardware offload, can be
+// used for throughput-critical connections. Set it in Config.
+//
+// uTLS keeps constructing the per-record nonces from the IV and the sequence
+// number; the provider only implements the cipher.
+type RecordAEADProvider interface {
+	// RecordAEAD returns an AEAD with a 12-byte nonce for the cipher suite
+	// with the given ID, keyed with key, or nil to use the built-in
+	// implementation, e.g. for unsupported cipher suites. It's called once
+	// per direction and key update.
+	RecordAEAD(suite uint16, key []byte) cipher.AEAD
+}
+
+// errRecordAEADNonceSize is returned by handshakes whose RecordAEADProvider
+// returned an AEAD with an unsupported nonce size.
+var errRecordAEADNonceSize = errors.New("tls: RecordAEADProvider returned an AEAD with a nonce size other than 12")
+
+// recordAEAD returns the AEAD of the cipher suite with the given ID for key
+// and iv, from the provider of hc if it has one, or from newAEAD.
+func (hc *halfConn) recordAEAD(suite uint16, newAEAD func(key, iv []byte) aead, key, iv []byte) (aead, error) {
+	if hc.aeadProvider == nil {
+		return newAEAD(key, iv), nil
+	}
+	a := hc.aeadProvider.RecordAEAD(suite, key)
+	if a == nil {
+		return newAEAD(key, iv), nil
+	}
+	if a.NonceSize() != aeadNonceLength {
+		return nil, errRecordAEADNonceSize
+	}
+	switch len(iv) {
+	case noncePrefixLength:
+		// TLS 1.2 AES-GCM, with an explicit nonce.
+		ret := &prefixNonceAEAD{aead: a}
+		copy(ret.nonce[:], iv)
+		return ret, nil
+	case aeadNonceLength:
+		ret := &xorNonceAEAD{aead: a}
+		copy(ret.nonceMask[:], iv)
+		return ret, nil
+	default:
+		return nil, errors.New("tls: internal error: wrong nonce length")
+	}
+}
+
+// configureRecordLayer makes the record layer of c use the RecordAEADProvider,
+// ExplicitNonce and WritePacing of its Config.
+func (c *Conn) configureRecordLayer() {
+	c.in.aeadProvider = c.config.RecordAEADProvider
+	c.out.aeadProvider = c.config.RecordAEADProvider
+	c.out.explicitNonce = c.config.ExplicitNonce
+	c.utls.pacer.set(c.config.WritePacing, false)
+}
@@ -0,0 +1,144 @@
+package tls
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// testRecordAEADProvider implements the AES-GCM and ChaCha20-Poly1305 cipher
+// suites with the standard library, and counts the records it seals.
+type testRecordAEADProvider struct {
+	mu     sync.Mutex
+	suites []uint16
+	sealed int
+}
+
+func (p *testRecordAEADProvider) RecordAEAD(suite uint16, key []byte) cipher.AEAD {
+	var a cipher.AEAD
+	switch suite {
+	case TLS_AES_128_GCM_SHA256, TLS_AES_256_GCM_SHA384, TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			panic(err)
+		}
+		if a, err = cipher.NewGCM(block); err != nil {
+			panic(err)
+		}
+	case TLS_CHACHA20_POLY1305_SHA256, TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305, TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305:
+		var err error
+		if a, err = chacha20poly1305.New(key); err != nil {
+			panic(err)
+		}
+	default:
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.suites = append(p.suites, suite)
+	return &countingAEAD{AEAD: a, p: p}
+}
+
+type countingAEAD struct {
+	cipher.AEAD
+	p *testRecordAEADProvider
+}
+
+func (a *countingAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	a.p.mu.Lock()
+	a.p.sealed++
+	a.p.mu.Unlock()
+	return a.AEAD.Seal(dst, nonce, plaintext, additionalData)
+}
+
+func TestRecordAEADProvider(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		version uint16
+		suite   uint16
+	}{
+		// TLS 1.3 cipher suites are not configurable.
+		{"TLS13", VersionTLS13, 0},
+		{"TLS12-AES", VersionTLS12, TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		{"TLS12-ChaCha20", VersionTLS12, TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var suites []uint16
+			if tt.suite != 0 {
+				suites = []uint16{tt.suite}
+			}
+			server := &testRecordAEADProvider{}
+			s, err := NewUTLSServer(&Config{
+				CipherSuites:       suites,
+				RecordAEADProvider: server,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			client := &testRecordAEADProvider{}
+			c, err := NewUTLSClient(s, &Config{
+				MaxVersion:         tt.version,
+				CipherSuites:       suites,
+				RecordAEADProvider: client,
+			}, HelloGolang)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			if _, err := c.Write([]byte("hello")); err != nil {
+				t.Fatal(err)
+			}
+			buf := make([]byte, 5)
+			if _, err := io.ReadFull(c, buf); err != nil || string(buf) != "hello" {
+				t.Fatalf("echo = %q, %v", buf, err)
+			}
+
+			cs := c.ConnectionState()
+			if cs.Version != tt.version || tt.suite != 0 && cs.CipherSuite != tt.suite {
+				t.Fatalf("negotiated %x with %x", cs.Version, cs.CipherSuite)
+			}
+			for _, p := range []*testRecordAEADProvider{client, server} {
+				p.mu.Lock()
+				if len(p.suites) == 0 || p.suites[0] != cs.CipherSuite || p.sealed == 0 {
+					t.Errorf("provider used for %x and %d records", p.suites, p.sealed)
+				}
+				p.mu.Unlock()
+			}
+		})
+	}
+}
+
+// badNonceAEADProvider returns AEADs with a 24-byte nonce.
+type badNonceAEADProvider struct{}
+
+func (badNonceAEADProvider) RecordAEAD(suite uint16, key []byte) cipher.AEAD {
+	a, err := chacha20poly1305.NewX(make([]byte, chacha20poly1305.KeySize))
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func TestRecordAEADProviderNonceSize(t *testing.T) {
+	for _, version := range []uint16{VersionTLS12, VersionTLS13} {
+		s, err := NewUTLSServer(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewUTLSClient(s, &Config{
+			MaxVersion:         version,
+			RecordAEADProvider: badNonceAEADProvider{},
+		}, HelloGolang)
+		if !errors.Is(err, errRecordAEADNonceSize) {
+			t.Errorf("version %x: handshake = %v, want %v", version, err, errRecordAEADNonceSize)
+		}
+		s.Close()
+	}
+}
@@ -0,0 +1,206 @@
+package tls
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// ReplayResponse is how a server responded to a replayed ClientHello.
+type ReplayResponse int
+
+const (
+	// ReplaySilent means the server sent nothing and kept the connection
+	// open until the timeout, like a probe-resistant server waiting for
+	// the client to give up.
+	ReplaySilent ReplayResponse = iota
+
+	// ReplayClosed means the server closed or reset the connection without
+	// sending anything.
+	ReplayClosed
+
+	// ReplayAlert means the server rejected the ClientHello with an alert.
+	ReplayAlert
+
+	// ReplayServerHello means the server continued the handshake.
+	ReplayServerHello
+
+	// ReplayHelloRetryRequest means the server asked for another key share.
+	ReplayHelloRetryRequest
+
+	// ReplayNonTLS means the server sent something other than a TLS
+	// handshake or alert record, e.g. the response of a decoy service.
+	ReplayNonTLS
+)
+
+func (r ReplayResponse) String() string {
+	switch r {
+	case ReplaySilent:
+		return "silent"
+	case ReplayClosed:
+		return "closed"
+	case ReplayAlert:
+		return "alert"
+	case ReplayServerHello:
+		return "ServerHello"
+	case ReplayHelloRetryRequest:
+		return "HelloRetryRequest"
+	case ReplayNonTLS:
+		return "non-TLS"
+	default:
+		return "unknown"
+	}
+}
+
+// ReplayResult describes the response of a server to a replayed ClientHello.
+type ReplayResult struct {
+	Response ReplayResponse
+
+	// Alert is the alert sent by the server, if Response is ReplayAlert.
+	Alert AlertError
+
+	// Data is what the server sent, up to the end of its first record.
+	Data []byte
+
+	// Err is the error that ended reading the response, if the first record
+	// wasn't read completely, e.g. io.EOF or os.ErrDeadlineExceeded.
+	Err error
+
+	// ServerErr is the error returned by the handshake of the server. It's
+	// only set by UTLSTestServer.Replay.
+	ServerErr error
+}
+
+// ReplayClientHello sends clientHello on conn, a new connection to a server,
+// and reports how the server responds within timeout. It's meant to check in
+// CI how a probe-resistant server reacts to ClientHellos captured from real
+// clients or scanners, or replayed by an active prober.
+//
+// clientHello is either the records carrying a ClientHello, as returned by
+// UTLSTestClient.ClientFlight or CapturedFingerprint.ClientHello, or a
+// ClientHello handshake message, as in HandshakeState.Hello.Raw. Only the
+// first record sent by the server is read; conn is not closed.
+func ReplayClientHello(conn net.Conn, clientHello []byte, timeout time.Duration) (*ReplayResult, error) {
+	records, err := replayRecords(clientHello)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+	defer conn.SetDeadline(time.Time{})
+	if _, err := conn.Write(records); err != nil {
+		return nil, err
+	}
+	return readReplayResponse(conn), nil
+}
+
+// Replay is like ReplayClientHello, but replays clientHello against s over an
+// in-memory connection, and also reports the error of the server handshake,
+// which it waits for: Config callbacks of s must return eventually.
+func (s *UTLSTestServer) Replay(clientHello []byte, timeout time.Duration) (*ReplayResult, error) {
+	s.mu.Lock()
+	if !s.started || s.closed {
+		s.mu.Unlock()
+		return nil, errors.New("tls: UTLSTestServer not running")
+	}
+	s.wg.Add(1)
+	s.mu.Unlock()
+	defer s.wg.Done()
+
+	clientEnd, serverEnd := memPipe()
+	server := Server(serverEnd, s.Config)
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.Handshake()
+	}()
+
+	r, err := ReplayClientHello(clientEnd, clientHello, timeout)
+	// Closing the client end fails a server waiting for the next flight.
+	clientEnd.Close()
+	handshakeErr := <-serverErr
+	server.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.ServerErr = handshakeErr
+	return r, nil
+}
+
+// replayRecords returns clientHello as records.
+func replayRecords(clientHello []byte) ([]byte, error) {
+	if len(clientHello) == 0 {
+		return nil, errors.New("tls: replayed bytes are empty")
+	}
+	switch clientHello[0] {
+	case byte(recordTypeHandshake):
+		return clientHello, nil
+	case typeClientHello:
+		var records []byte
+		for msg := clientHello; len(msg) > 0; {
+			n := min(len(msg), maxPlaintext)
+			records = append(records, byte(recordTypeHandshake), 3, 1, byte(n>>8), byte(n))
+			records = append(records, msg[:n]...)
+			msg = msg[n:]
+		}
+		return records, nil
+	default:
+		return nil, errors.New("tls: replayed bytes are not a ClientHello")
+	}
+}
+
+// readReplayResponse reads the first record sent by the server on conn and
+// classifies it.
+func readReplayResponse(conn net.Conn) *ReplayResult {
+	r := &ReplayResult{}
+	hdr := make([]byte, recordHeaderLen)
+	n, err := io.ReadFull(conn, hdr)
+	r.Data = hdr[:n]
+	if err != nil {
+		r.Err = err
+		switch {
+		case n > 0:
+			r.Response = ReplayNonTLS
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			r.Response = ReplaySilent
+		default:
+			// io.EOF, net.ErrClosed and connection resets alike.
+			r.Response = ReplayClosed
+		}
+		return r
+	}
+
+	typ := recordType(hdr[0])
+	length := int(hdr[3])<<8 | int(hdr[4])
+	if typ != recordTypeAlert && typ != recordTypeHandshake || hdr[1] != 3 || length > maxCiphertext {
+		r.Response = ReplayNonTLS
+		return r
+	}
+	body := make([]byte, length)
+	n, err = io.ReadFull(conn, body)
+	r.Data = append(r.Data, body[:n]...)
+	if err != nil {
+		r.Err = err
+		r.Response = ReplayNonTLS
+		return r
+	}
+
+	switch {
+	case typ == recordTypeAlert && length == 2:
+		r.Response = ReplayAlert
+		r.Alert = AlertError(body[1])
+	case typ == recordTypeHandshake && length >= 38 && body[0] == typeServerHello:
+		// The random follows the message header and the legacy version.
+		if bytes.Equal(body[6:38], helloRetryRequestRandom) {
+			r.Response = ReplayHelloRetryRequest
+		} else {
+			r.Response = ReplayServerHello
+		}
+	default:
+		r.Response = ReplayNonTLS
+	}
+	return r
+}
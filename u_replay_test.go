@@ -0,0 +1,137 @@
+package tls
+
+import (
+	"errors"
+	"net"
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestReplayClientHello(t *testing.T) {
+	s, err := NewUTLSServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := NewUTLSClient(s, nil, HelloChrome_131)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	// Both the records and the bare handshake message can be replayed.
+	for _, hello := range [][]byte{c.ClientFlight(), c.HandshakeState.Hello.Raw} {
+		r, err := s.Replay(hello, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Response != ReplayServerHello || r.Err != nil {
+			t.Errorf("Response = %v, %v; want ServerHello", r.Response, r.Err)
+		}
+		// The server handshake fails when the connection is closed.
+		if r.ServerErr == nil {
+			t.Error("expected a server handshake error")
+		}
+	}
+
+	hrr, err := NewUTLSServer(&Config{CurvePreferences: []CurveID{CurveP384}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hrr.Close()
+	if r, err := hrr.Replay(c.ClientFlight(), time.Second); err != nil || r.Response != ReplayHelloRetryRequest {
+		t.Errorf("Replay against a server preferring P-384: %v, %v; want HelloRetryRequest", r, err)
+	}
+
+	if _, err := s.Replay([]byte("GET / HTTP/1.1\r\n\r\n"), time.Second); err == nil {
+		t.Error("replaying non-ClientHello bytes succeeded")
+	}
+}
+
+func TestReplayClientHelloRejected(t *testing.T) {
+	// A server that only accepts ClientHellos without certificate
+	// compression, and stalls ones offering ALPN until the client gives up.
+	s, err := NewUTLSServer(&Config{
+		GetConfigForClient: func(chi *ClientHelloInfo) (*Config, error) {
+			if len(chi.SupportedProtos) > 0 {
+				time.Sleep(100 * time.Millisecond)
+			}
+			if slices.Contains(chi.Extensions, utlsExtensionCompressCertificate) {
+				return nil, errors.New("blocked")
+			}
+			return nil, nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	chrome := NewUnstartedUTLSClient(s, nil, HelloChrome_131)
+	if err := chrome.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := s.Replay(chrome.HandshakeState.Hello.Raw, 10*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Response != ReplaySilent || !errors.Is(r.Err, os.ErrDeadlineExceeded) {
+		t.Errorf("Response = %v, %v; want silent", r.Response, r.Err)
+	}
+
+	r, err = s.Replay(chrome.HandshakeState.Hello.Raw, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Response != ReplayAlert || r.Alert != AlertError(alertInternalError) || r.ServerErr == nil {
+		t.Errorf("got %v (%v), server error %v; want an internal_error alert", r.Response, r.Alert, r.ServerErr)
+	}
+}
+
+func TestReplayClientHelloNetwork(t *testing.T) {
+	hello := NewUnstartedUTLSClient(NewUnstartedUTLSServer(nil), nil, HelloFirefox_133)
+	if err := hello.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		name   string
+		answer func(net.Conn)
+		want   ReplayResponse
+	}{
+		{"closed", func(c net.Conn) {}, ReplayClosed},
+		{"decoy", func(c net.Conn) { c.Write([]byte("HTTP/1.1 400 Bad Request\r\n\r\n")) }, ReplayNonTLS},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+			go func() {
+				c, err := l.Accept()
+				if err != nil {
+					return
+				}
+				c.Read(make([]byte, 1))
+				test.answer(c)
+				c.Close()
+			}()
+
+			conn, err := net.Dial("tcp", l.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			r, err := ReplayClientHello(conn, hello.HandshakeState.Hello.Raw, 5*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Response != test.want {
+				t.Errorf("Response = %v, want %v", r.Response, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package tls
+
+import (
+	"crypto/x509"
+	"sync/atomic"
+	"time"
+)
+
+// ResumptionMonitor watches the session resumptions attempted by clients, and
+// reports servers that complete a full handshake instead of resuming a session
+// they issued. Servers legitimately do so after rotating their ticket keys or
+// restarting, but a server that always does, or that presents a different
+// certificate, may be an impersonator that can't decrypt the tickets, or a
+// prober replaying them. Set Config.ResumptionMonitor to enable it.
+//
+// A ResumptionMonitor is safe for concurrent use and may be shared between
+// Configs.
+type ResumptionMonitor struct {
+	// OnRejected, if not nil, is called when a server rejects a session,
+	// once its Finished message has been verified. If it returns an error,
+	// the handshake is aborted with it and the session is dropped from the
+	// ClientSessionCache.
+	OnRejected func(*ResumptionRejection) error
+
+	attempts, resumed, rejected atomic.Uint64
+}
+
+// ResumptionRejection describes a session rejected by a server, as reported to
+// ResumptionMonitor.OnRejected.
+type ResumptionRejection struct {
+	// ServerName is the server name of the connection.
+	ServerName string
+
+	// Version is the version of the full handshake, and SessionVersion the
+	// version of the rejected session.
+	Version        uint16
+	SessionVersion uint16
+
+	// SessionAge is the time since the session was established.
+	SessionAge time.Duration
+
+	// PeerCertificates are the certificates sent in the full handshake, and
+	// SessionCertificates those of the rejected session. SameCertificate
+	// reports whether their leaves are identical.
+	PeerCertificates    []*x509.Certificate
+	SessionCertificates []*x509.Certificate
+	SameCertificate     bool
+}
+
+// ResumptionMonitorStats are the counters of a ResumptionMonitor, as returned
+// by ResumptionMonitor.Stats. Attempts only counts the handshakes that reached
+// the server's Finished message.
+type ResumptionMonitorStats struct {
+	Attempts uint64
+	Resumed  uint64
+	Rejected uint64
+}
+
+// NewResumptionMonitor returns a ResumptionMonitor calling onRejected, which
+// may be nil.
+func NewResumptionMonitor(onRejected func(*ResumptionRejection) error) *ResumptionMonitor {
+	return &ResumptionMonitor{OnRejected: onRejected}
+}
+
+// Stats returns the counters of the monitor.
+func (m *ResumptionMonitor) Stats() ResumptionMonitorStats {
+	return ResumptionMonitorStats{
+		Attempts: m.attempts.Load(),
+		Resumed:  m.resumed.Load(),
+		Rejected: m.rejected.Load(),
+	}
+}
+
+// checkResumption reports the outcome of offering session to
+// Config.ResumptionMonitor. It must be called once the server's Finished
+// message has been verified.
+func (c *Conn) checkResumption(session *SessionState) error {
+	m := c.config.ResumptionMonitor
+	if m == nil || session == nil {
+		return nil
+	}
+	m.attempts.Add(1)
+	if c.didResume {
+		m.resumed.Add(1)
+		return nil
+	}
+	m.rejected.Add(1)
+	if m.OnRejected == nil {
+		return nil
+	}
+
+	r := &ResumptionRejection{
+		ServerName:          c.serverName,
+		Version:             c.vers,
+		SessionVersion:      session.version,
+		SessionAge:          c.config.time().Sub(time.Unix(int64(session.createdAt), 0)),
+		PeerCertificates:    c.peerCertificates,
+		SessionCertificates: session.peerCertificates,
+	}
+	if len(c.peerCertificates) > 0 && len(session.peerCertificates) > 0 {
+		r.SameCertificate = c.peerCertificates[0].Equal(session.peerCertificates[0])
+	}
+	if err := m.OnRejected(r); err != nil {
+		c.sendAlert(alertHandshakeFailure)
+		return err
+	}
+	return nil
+}
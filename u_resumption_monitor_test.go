@@ -0,0 +1,79 @@
+package tls
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestResumptionMonitor(t *testing.T) {
+	for _, version := range []uint16{VersionTLS12, VersionTLS13} {
+		s, err := NewUTLSServer(&Config{MaxVersion: version})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		errAbort := errors.New("abort")
+		var rejections []*ResumptionRejection
+		var abort bool
+		monitor := NewResumptionMonitor(func(r *ResumptionRejection) error {
+			rejections = append(rejections, r)
+			if abort {
+				return errAbort
+			}
+			return nil
+		})
+		cache := NewLRUClientSessionCache(1)
+		config := &Config{ClientSessionCache: cache, ResumptionMonitor: monitor}
+		connect := func() error {
+			c, err := NewUTLSClient(s, config, HelloGolang)
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			// Read the echo, and the TLS 1.3 session ticket sent before it.
+			if _, err := c.Write([]byte{0}); err != nil {
+				return err
+			}
+			_, err = io.ReadFull(c, make([]byte, 1))
+			return err
+		}
+
+		for i := 0; i < 2; i++ {
+			if err := connect(); err != nil {
+				t.Fatalf("%x: %v", version, err)
+			}
+		}
+		if stats := monitor.Stats(); stats != (ResumptionMonitorStats{Attempts: 1, Resumed: 1}) {
+			t.Errorf("%x: unexpected stats after resuming: %+v", version, stats)
+		}
+
+		// The server can't decrypt the tickets anymore.
+		s.Config.SetSessionTicketKeys([][32]byte{{1}})
+		if err := connect(); err != nil {
+			t.Fatalf("%x: %v", version, err)
+		}
+		if len(rejections) != 1 {
+			t.Fatalf("%x: %d rejections reported, want 1", version, len(rejections))
+		}
+		r := rejections[0]
+		if r.Version != version || r.SessionVersion != version || !r.SameCertificate || r.ServerName != "localhost" || r.SessionAge < 0 {
+			t.Errorf("%x: unexpected rejection %+v", version, r)
+		}
+
+		abort = true
+		s.Config.SetSessionTicketKeys([][32]byte{{2}})
+		// NewUTLSClient doesn't wrap errors.
+		if err := connect(); err == nil || !strings.Contains(err.Error(), "client: "+errAbort.Error()) {
+			t.Errorf("%x: handshake with an aborting OnRejected returned %v", version, err)
+		}
+		if _, ok := cache.Get("localhost"); ok {
+			t.Errorf("%x: rejected session was kept", version)
+		}
+		if stats := monitor.Stats(); stats != (ResumptionMonitorStats{Attempts: 3, Resumed: 1, Rejected: 2}) {
+			t.Errorf("%x: unexpected stats: %+v", version, stats)
+		}
+		s.Close()
+	}
+}
@@ -0,0 +1,231 @@
+package tls
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// ErrGateRejected is returned by ServerGate.Server for connections that
+// didn't pass the gate and were handed to the masquerade backend instead.
+var ErrGateRejected = errors.New("tls: connection rejected by the server gate")
+
+// ServerGate makes a server resistant to active probing: it reads the whole
+// ClientHello of a connection before sending a single byte, and only answers
+// with a ServerHello if the ClientHello passes its checks. Other connections,
+// including ones that don't speak TLS at all, are relayed to a masquerade
+// backend, e.g. a real web server, so that probes see the backend's response
+// instead of the gated server's.
+type ServerGate struct {
+	// MinLength is the minimum length of the ClientHello handshake message,
+	// e.g. to require the padding of a known client. Zero means no minimum.
+	MinLength int
+
+	// Allow, if not nil, reports whether the ClientHello may be answered,
+	// e.g. by checking its JA4 against an allowlist or a token in its
+	// SessionID.
+	Allow func(hello *ClientHelloReport) bool
+
+	// Masquerade, if not nil, dials the backend that rejected connections are
+	// relayed to. The bytes read from the client so far are written to it
+	// first. If nil, rejected connections are closed.
+	Masquerade func(ctx context.Context) (net.Conn, error)
+
+	// Timeout bounds the time to receive the ClientHello. Zero means 10s.
+	Timeout time.Duration
+}
+
+// Server reads the ClientHello from conn without writing anything and, if it
+// passes the gate, returns a server Conn that will handshake with it using
+// config. Otherwise the connection is relayed to the masquerade backend until
+// either side closes it, and Server returns ErrGateRejected, or the error that
+// prevented the relay. In both cases, conn is closed by the time Server
+// returns an error.
+func (g *ServerGate) Server(ctx context.Context, conn net.Conn, config *Config) (*Conn, error) {
+	timeout := g.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+	rc := &clientHelloRecorder{Conn: conn}
+	buf := make([]byte, 4096)
+	for !rc.done {
+		if _, err := rc.Read(buf); err != nil {
+			break
+		}
+		if len(rc.buf) > 0 && recordType(rc.buf[0]) != recordTypeHandshake {
+			break // not TLS, no need to wait for more
+		}
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	if g.allow(rc.clientHello()) {
+		r := bufio.NewReader(io.MultiReader(bytes.NewReader(rc.buf), conn))
+		return Server(&bufferedConn{Conn: conn, r: r}, config), nil
+	}
+	return nil, g.masquerade(ctx, conn, rc.buf)
+}
+
+func (g *ServerGate) allow(clientHello []byte) bool {
+	if clientHello == nil || len(clientHello) < g.MinLength {
+		return false
+	}
+	if g.Allow == nil {
+		return true
+	}
+	report, err := NewClientHelloReport(clientHello)
+	if err != nil {
+		return false
+	}
+	return g.Allow(report)
+}
+
+// masquerade relays conn to the masquerade backend, starting with the
+// already consumed bytes in prefix.
+func (g *ServerGate) masquerade(ctx context.Context, conn net.Conn, prefix []byte) error {
+	defer conn.Close()
+	if g.Masquerade == nil {
+		return ErrGateRejected
+	}
+	backend, err := g.Masquerade(ctx)
+	if err != nil {
+		return err
+	}
+	defer backend.Close()
+	if _, err := backend.Write(prefix); err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		io.Copy(conn, backend)
+		closeWrite(conn)
+	}()
+	io.Copy(backend, conn)
+	closeWrite(backend)
+	<-done
+	return ErrGateRejected
+}
+
+// MasqueradeOrigin returns a ServerGate.Masquerade function that dials the
+// origin at addr, e.g. a real web server for the same domain, so that probes
+// see an authentic website.
+func MasqueradeOrigin(network, addr string) func(ctx context.Context) (net.Conn, error) {
+	return func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+}
+
+// gatedListener is a net.Listener that only returns the connections that
+// passed its gate. The others are relayed to the masquerade backend in the
+// background.
+type gatedListener struct {
+	net.Listener
+	config *Config
+	gate   *ServerGate
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	conns  chan *Conn
+	errc   chan error
+}
+
+// NewGatedListener creates a Listener which accepts connections from an inner
+// Listener, checks their ClientHello with gate, and returns the ones that
+// passed it as a [Server] connection using config. Rejected connections are
+// never returned by Accept: they are transparently relayed to the masquerade
+// backend of gate, including their ClientHello.
+//
+// Closing the Listener doesn't interrupt the connections being relayed.
+func NewGatedListener(inner net.Listener, config *Config, gate *ServerGate) net.Listener {
+	l := &gatedListener{
+		Listener: inner,
+		config:   config,
+		gate:     gate,
+		conns:    make(chan *Conn),
+		errc:     make(chan error, 1),
+	}
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	go l.serve()
+	return l
+}
+
+// serve accepts connections until the inner Listener fails permanently or is
+// closed. Like net/http, temporary Accept errors, e.g. running out of file
+// descriptors, are retried with an exponential backoff capped at one second.
+func (l *gatedListener) serve() {
+	var tempDelay time.Duration
+	for {
+		c, err := l.Listener.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() && l.ctx.Err() == nil {
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else {
+					tempDelay *= 2
+				}
+				if max := 1 * time.Second; tempDelay > max {
+					tempDelay = max
+				}
+				t := time.NewTimer(tempDelay)
+				select {
+				case <-t.C:
+					continue
+				case <-l.ctx.Done():
+					t.Stop()
+				}
+			}
+			l.errc <- err
+			return
+		}
+		tempDelay = 0
+		go func() {
+			conn, err := l.gate.Server(l.ctx, c, l.config)
+			if err != nil {
+				return
+			}
+			select {
+			case l.conns <- conn:
+			case <-l.ctx.Done():
+				conn.Close()
+			}
+		}()
+	}
+}
+
+// Accept waits for and returns the next incoming connection that passed the
+// gate. The returned connection is of type *Conn.
+func (l *gatedListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errc:
+		l.errc <- err // keep failing on the following calls
+		return nil, err
+	}
+}
+
+func (l *gatedListener) Close() error {
+	l.cancel()
+	return l.Listener.Close()
+}
+
+// closeWrite shuts down the writing side of c if it supports it, and closes
+// it otherwise.
+func closeWrite(c net.Conn) {
+	if cw, ok := c.(interface{ CloseWrite() error }); ok {
+		cw.CloseWrite()
+	} else {
+		c.Close()
+	}
+}
@@ -0,0 +1,283 @@
+package tls
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServerGate(t *testing.T) {
+	cert, err := selfSignedTestCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConfig := &Config{Certificates: []Certificate{cert}}
+
+	backendReply := []byte("HTTP/1.1 404 Not Found\r\nContent-Length: 0\r\n\r\n")
+	received := make(chan []byte, 1)
+	gate := &ServerGate{
+		Allow: func(hello *ClientHelloReport) bool {
+			return hello.ServerName == "allowed.example" && hello.JA4 != ""
+		},
+		Masquerade: func(context.Context) (net.Conn, error) {
+			c, backend := net.Pipe()
+			go func() {
+				defer backend.Close()
+				buf := make([]byte, 16384)
+				n, _ := backend.Read(buf)
+				received <- buf[:n]
+				backend.Write(backendReply)
+			}()
+			return c, nil
+		},
+	}
+
+	serve := func() (net.Conn, chan error) {
+		client, server := net.Pipe()
+		errc := make(chan error, 1)
+		go func() {
+			conn, err := gate.Server(context.Background(), server, serverConfig)
+			if err == nil {
+				err = conn.Handshake()
+				conn.Close()
+			}
+			errc <- err
+		}()
+		return client, errc
+	}
+
+	t.Run("Allowed", func(t *testing.T) {
+		c, errc := serve()
+		uconn := UClient(c, &Config{ServerName: "allowed.example", InsecureSkipVerify: true}, HelloChrome_Auto)
+		if err := uconn.Handshake(); err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+		if err := <-errc; err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("WrongServerName", func(t *testing.T) {
+		c, errc := serve()
+		uconn := UClient(c, &Config{ServerName: "probe.example", InsecureSkipVerify: true}, HelloChrome_Auto)
+		if err := uconn.Handshake(); err == nil {
+			t.Fatal("handshake succeeded past the gate")
+		}
+		c.Close()
+		if err := <-errc; !errors.Is(err, ErrGateRejected) {
+			t.Fatalf("got %v, want ErrGateRejected", err)
+		}
+		if got := <-received; len(got) == 0 || got[0] != byte(recordTypeHandshake) {
+			t.Errorf("backend received %x, want the ClientHello record", got)
+		}
+	})
+
+	t.Run("NotTLS", func(t *testing.T) {
+		c, errc := serve()
+		probe := []byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+		go c.Write(probe)
+		reply, err := io.ReadAll(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(reply, backendReply) {
+			t.Errorf("probe got %q, want the backend reply %q", reply, backendReply)
+		}
+		if err := <-errc; !errors.Is(err, ErrGateRejected) {
+			t.Fatalf("got %v, want ErrGateRejected", err)
+		}
+		if got := <-received; !bytes.Equal(got, probe) {
+			t.Errorf("backend received %q, want %q", got, probe)
+		}
+	})
+
+	t.Run("TooShort", func(t *testing.T) {
+		g := &ServerGate{MinLength: 1 << 16}
+		client, server := net.Pipe()
+		errc := make(chan error, 1)
+		go func() {
+			_, err := g.Server(context.Background(), server, serverConfig)
+			errc <- err
+		}()
+		uconn := UClient(client, &Config{ServerName: "allowed.example", InsecureSkipVerify: true}, HelloChrome_Auto)
+		if err := uconn.Handshake(); err == nil {
+			t.Fatal("handshake succeeded past the gate")
+		}
+		if err := <-errc; !errors.Is(err, ErrGateRejected) {
+			t.Fatalf("got %v, want ErrGateRejected", err)
+		}
+	})
+}
+
+func TestGatedListener(t *testing.T) {
+	cert, err := selfSignedTestCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	origin, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer origin.Close()
+	originReply := []byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n")
+	originReceived := make(chan []byte, 2)
+	go func() {
+		for {
+			c, err := origin.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				buf := make([]byte, 16384)
+				n, _ := c.Read(buf)
+				originReceived <- buf[:n]
+				c.Write(originReply)
+			}()
+		}
+	}()
+
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewGatedListener(inner, &Config{Certificates: []Certificate{cert}}, &ServerGate{
+		Allow: func(hello *ClientHelloReport) bool {
+			return hello.ServerName == "allowed.example"
+		},
+		Masquerade: MasqueradeOrigin("tcp", origin.Addr().String()),
+	})
+	defer l.Close()
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				c.Write([]byte("gated"))
+			}()
+		}
+	}()
+
+	// A rejected client is proxied to the origin, ClientHello included, and
+	// sees the origin's reply instead of a ServerHello.
+	probe, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	uconn := UClient(probe, &Config{ServerName: "probe.example", InsecureSkipVerify: true}, HelloChrome_Auto)
+	if err := uconn.Handshake(); err == nil {
+		t.Fatal("handshake succeeded past the gate")
+	}
+	probe.Close()
+	if got := <-originReceived; len(got) == 0 || got[0] != byte(recordTypeHandshake) {
+		t.Errorf("origin received %x, want the ClientHello record", got)
+	}
+
+	probe, err = net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	probe.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+	reply, err := io.ReadAll(probe)
+	probe.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(reply, originReply) {
+		t.Errorf("probe got %q, want the origin reply %q", reply, originReply)
+	}
+	<-originReceived
+
+	// An allowed client reaches the gated server.
+	c, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	uconn = UClient(c, &Config{ServerName: "allowed.example", InsecureSkipVerify: true}, HelloChrome_Auto)
+	got, err := io.ReadAll(uconn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "gated" {
+		t.Errorf("allowed client got %q, want %q", got, "gated")
+	}
+
+	l.Close()
+	if _, err := l.Accept(); err == nil {
+		t.Error("Accept succeeded after Close")
+	}
+}
+
+// temporaryError is a net.Error that reports itself as temporary.
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "temporary accept error" }
+func (temporaryError) Timeout() bool   { return false }
+func (temporaryError) Temporary() bool { return true }
+
+// flakyListener fails its first n Accept calls with a temporary error.
+type flakyListener struct {
+	net.Listener
+	n int
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	if l.n > 0 {
+		l.n--
+		return nil, temporaryError{}
+	}
+	return l.Listener.Accept()
+}
+
+func TestGatedListenerTemporaryError(t *testing.T) {
+	cert, err := selfSignedTestCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewGatedListener(&flakyListener{Listener: inner, n: 3}, &Config{Certificates: []Certificate{cert}}, &ServerGate{})
+	defer l.Close()
+
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		c.Write([]byte("gated"))
+	}()
+
+	c, err := net.Dial("tcp", inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	uconn := UClient(c, &Config{ServerName: "example.com", InsecureSkipVerify: true}, HelloChrome_Auto)
+	got, err := io.ReadAll(uconn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "gated" {
+		t.Errorf("client got %q, want %q", got, "gated")
+	}
+
+	l.Close()
+	if _, err := l.Accept(); err == nil {
+		t.Error("Accept succeeded after Close")
+	}
+}
@@ -0,0 +1,135 @@
+package tls
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// sessionCacheSnapshotVersion is the version of the encoding written by
+// Snapshot. Restore rejects other versions.
+const sessionCacheSnapshotVersion uint8 = 1
+
+// PersistentClientSessionCache is a ClientSessionCache whose sessions can be
+// saved and loaded again, e.g. so that a command line tool can resume
+// sessions between invocations. The cache returned by NewLRUClientSessionCache
+// implements it.
+//
+// Snapshots contain the secrets of the sessions, which are critical to the
+// security of future and possibly past connections, and must be stored
+// accordingly.
+type PersistentClientSessionCache interface {
+	ClientSessionCache
+
+	// Snapshot writes the sessions of the cache to w.
+	Snapshot(w io.Writer) error
+
+	// Restore reads sessions written by Snapshot from r and adds them to
+	// the cache. No session is added if r is malformed.
+	Restore(r io.Reader) error
+}
+
+// Snapshot writes the sessions of the cache to w, least recently used first,
+// so that Restore preserves their order. Expired TLS 1.3 sessions are skipped.
+// Sessions are encoded with SessionState.Bytes, which includes their Extra
+// fields.
+func (c *lruSessionCache) Snapshot(w io.Writer) error {
+	type snapshotEntry struct {
+		key string
+		cs  *ClientSessionState
+	}
+	c.Lock()
+	entries := make([]snapshotEntry, 0, c.q.Len())
+	for elem := c.q.Back(); elem != nil; elem = elem.Prev() {
+		entry := elem.Value.(*lruSessionCacheEntry)
+		entries = append(entries, snapshotEntry{entry.sessionKey, entry.state})
+	}
+	c.Unlock()
+
+	now := uint64(time.Now().Unix())
+	var b cryptobyte.Builder
+	b.AddUint8(sessionCacheSnapshotVersion)
+	for _, e := range entries {
+		ticket, state, err := e.cs.ResumptionState()
+		if err != nil {
+			return err
+		}
+		if state == nil || state.version >= VersionTLS13 && state.useBy != 0 && state.useBy < now {
+			continue
+		}
+		stateBytes, err := state.Bytes()
+		if err != nil {
+			return err
+		}
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes([]byte(e.key))
+		})
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(ticket)
+		})
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+			b.AddBytes(stateBytes)
+		})
+	}
+	out, err := b.Bytes()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// Restore reads sessions written by Snapshot from r and adds them to the
+// cache. If the snapshot holds more sessions than the capacity of the cache,
+// the least recently used ones are evicted.
+func (c *lruSessionCache) Restore(r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s := cryptobyte.String(data)
+	var version uint8
+	if !s.ReadUint8(&version) {
+		return errors.New("tls: malformed session cache snapshot")
+	}
+	if version != sessionCacheSnapshotVersion {
+		return errors.New("tls: unsupported session cache snapshot version")
+	}
+
+	type restoredEntry struct {
+		key string
+		cs  *ClientSessionState
+	}
+	var entries []restoredEntry
+	for !s.Empty() {
+		var key, ticket, stateBytes cryptobyte.String
+		if !s.ReadUint16LengthPrefixed(&key) ||
+			!s.ReadUint24LengthPrefixed(&ticket) ||
+			!s.ReadUint24LengthPrefixed(&stateBytes) {
+			return errors.New("tls: malformed session cache snapshot")
+		}
+		state, err := ParseSessionState(stateBytes)
+		if err != nil {
+			return err
+		}
+		if !state.isClient {
+			return errors.New("tls: session cache snapshot contains a server session")
+		}
+		var t []byte
+		if len(ticket) > 0 {
+			t = []byte(ticket)
+		}
+		cs, err := NewResumptionState(t, state)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, restoredEntry{string(key), cs})
+	}
+
+	for _, e := range entries {
+		c.Put(e.key, e.cs)
+	}
+	return nil
+}
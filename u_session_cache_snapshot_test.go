@@ -0,0 +1,95 @@
+package tls
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestSessionCacheSnapshot(t *testing.T) {
+	for _, version := range []uint16{VersionTLS12, VersionTLS13} {
+		s, err := NewUTLSServer(&Config{MaxVersion: version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+
+		connect := func(cache ClientSessionCache) *UTLSTestClient {
+			c, err := NewUTLSClient(s, &Config{ClientSessionCache: cache}, HelloGolang)
+			if err != nil {
+				t.Fatalf("%x: %v", version, err)
+			}
+			defer c.Close()
+			// Read the echo, and the TLS 1.3 session ticket sent before it.
+			if _, err := c.Write([]byte{0}); err != nil {
+				t.Fatalf("%x: %v", version, err)
+			}
+			if _, err := io.ReadFull(c, make([]byte, 1)); err != nil {
+				t.Fatalf("%x: %v", version, err)
+			}
+			return c
+		}
+
+		saved, ok := NewLRUClientSessionCache(0).(PersistentClientSessionCache)
+		if !ok {
+			t.Fatal("NewLRUClientSessionCache doesn't return a PersistentClientSessionCache")
+		}
+		connect(saved)
+		var snapshot bytes.Buffer
+		if err := saved.Snapshot(&snapshot); err != nil {
+			t.Fatalf("%x: %v", version, err)
+		}
+
+		restored := NewLRUClientSessionCache(0).(PersistentClientSessionCache)
+		if err := restored.Restore(bytes.NewReader(snapshot.Bytes())); err != nil {
+			t.Fatalf("%x: %v", version, err)
+		}
+		if c := connect(restored); !c.ConnectionState().DidResume {
+			t.Errorf("%x: didn't resume with a restored session", version)
+		}
+
+		empty := NewLRUClientSessionCache(0).(PersistentClientSessionCache)
+		truncated := snapshot.Bytes()[:snapshot.Len()-1]
+		if err := empty.Restore(bytes.NewReader(truncated)); err == nil {
+			t.Errorf("%x: restored a truncated snapshot", version)
+		}
+		if _, ok := empty.Get("localhost"); ok {
+			t.Errorf("%x: session added from a malformed snapshot", version)
+		}
+	}
+}
+
+func TestSessionCacheSnapshotOrder(t *testing.T) {
+	s, err := NewUTLSServer(&Config{MaxVersion: VersionTLS12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	cache := NewLRUClientSessionCache(2).(PersistentClientSessionCache)
+	for _, name := range []string{"a.example", "b.example", "c.example"} {
+		config := &Config{ClientSessionCache: cache, ServerName: name, InsecureServerNameToVerify: "localhost"}
+		c, err := NewUTLSClient(s, config, HelloGolang)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.Close()
+	}
+	var snapshot bytes.Buffer
+	if err := cache.Snapshot(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+
+	// Restoring into a cache of capacity one keeps the most recently used
+	// session.
+	small := NewLRUClientSessionCache(1).(PersistentClientSessionCache)
+	if err := small.Restore(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := small.Get("c.example"); !ok {
+		t.Error("most recently used session not restored")
+	}
+	if _, ok := small.Get("b.example"); ok {
+		t.Error("least recently used session not evicted")
+	}
+}
@@ -193,12 +193,10 @@ func (s *sessionController) setSessionTicketToUConn() {
 	s.uconnRef.HandshakeState.Session = session
 	
 	// [uTLS] Support both SessionID and Session Ticket resumption
-	// [uTLS] Check if session has extra fields for TLS 1.2 resumption
 	if session != nil {
-		utlsData := GetSessionExtraFields(session)
-		if utlsData != nil && utlsData.ResumeType == ResumeSessionID {
+		if session.resumeType == ResumeSessionID {
 			// SessionID resumption: use sessionId field
-			s.uconnRef.HandshakeState.Hello.SessionId = utlsData.SessionID
+			s.uconnRef.HandshakeState.Hello.SessionId = session.sessionId
 			s.uconnRef.HandshakeState.Hello.SessionTicket = nil
 		} else {
 			// Session Ticket resumption: use ticket field
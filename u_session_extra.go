@@ -1,6 +1,7 @@
 package tls
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -17,6 +18,40 @@ const (
 // SessionExtraVersion is the version number for extension fields
 const SessionExtraVersion uint8 = 0x01
 
+// SessionExtraDecoder decodes an Extra entry of SessionState written in a given
+// SessionExtraVersion, including its version byte.
+type SessionExtraDecoder func(extra []byte) (*UTLSSessionData, error)
+
+// sessionExtraDecoders maps each readable SessionExtraVersion to its decoder.
+// When SessionExtraVersion is bumped, the decoder of the previous version
+// stays registered, so that sessions persisted by a ClientSessionCache before
+// an upgrade keep their extension fields instead of silently losing them.
+var sessionExtraDecoders = map[uint8]SessionExtraDecoder{
+	0x01: unmarshalSessionExtraV1,
+}
+
+// RegisterSessionExtraDecoder registers the decoder of Extra entries written in
+// version, e.g. by a fork or an older release of uTLS. It replaces any decoder
+// of that version, except the one of the current SessionExtraVersion, which
+// can't be replaced. It must be called before sessions are parsed, e.g. from
+// an init function.
+func RegisterSessionExtraDecoder(version uint8, decoder SessionExtraDecoder) {
+	if version == SessionExtraVersion {
+		panic("tls: the decoder of the current SessionExtraVersion can't be replaced")
+	}
+	sessionExtraDecoders[version] = decoder
+}
+
+// isSessionExtra reports whether extraItem is an Extra entry of ours, in any
+// readable version.
+func isSessionExtra(extraItem []byte) bool {
+	if len(extraItem) == 0 {
+		return false
+	}
+	_, ok := sessionExtraDecoders[extraItem[0]]
+	return ok
+}
+
 // UTLSSessionData encapsulates uTLS-specific session resumption data
 type UTLSSessionData struct {
 	ResumeType ResumeMechanism
@@ -159,26 +194,28 @@ func marshalSessionExtra(data *UTLSSessionData) []byte {
 	return result
 }
 
-// unmarshalSessionExtra deserializes extension data from the Extra field
+// unmarshalSessionExtra deserializes extension data from the Extra field, with
+// the decoder of its version. Data in an unknown version, e.g. written by a
+// newer release, is ignored.
 func unmarshalSessionExtra(extraData []byte) (*UTLSSessionData, error) {
 	if len(extraData) == 0 {
 		return nil, nil // no extension data
 	}
+	decoder, ok := sessionExtraDecoders[extraData[0]]
+	if !ok {
+		return nil, nil
+	}
+	return decoder(extraData)
+}
 
+// unmarshalSessionExtraV1 deserializes extension data in version 0x01.
+func unmarshalSessionExtraV1(extraData []byte) (*UTLSSessionData, error) {
 	if len(extraData) < 3 {
 		return nil, errors.New("invalid extra data: too short")
 	}
 
-	offset := 0
-
-	// read version number
-	version := extraData[offset]
-	offset++
-
-	if version != SessionExtraVersion {
-		// version mismatch, might be future version or other data, ignore
-		return nil, nil
-	}
+	// skip version number
+	offset := 1
 
 	// read field count
 	if offset+2 > len(extraData) {
@@ -215,7 +252,7 @@ func unmarshalSessionExtra(extraData []byte) (*UTLSSessionData, error) {
 		offset += int(dataLength)
 
 		// handle known fields
-		if fieldVersion == SessionExtraVersion {
+		if fieldVersion == 0x01 {
 			switch fieldID {
 			case SessionExtraUTLSData:
 				// unmarshal UTLS session data
@@ -235,9 +272,9 @@ func HasSessionExtra(s *SessionState) bool {
 		return false
 	}
 
-	// find our extension data: iterate through all Extra entries to find ones starting with our version
+	// find our extension data: iterate through all Extra entries to find ones starting with a readable version
 	for _, extraItem := range s.Extra {
-		if len(extraItem) >= 1 && extraItem[0] == SessionExtraVersion {
+		if isSessionExtra(extraItem) {
 			return true
 		}
 	}
@@ -253,7 +290,7 @@ func GetSessionExtraFields(s *SessionState) *UTLSSessionData {
 
 	// find our extension data
 	for _, extraItem := range s.Extra {
-		if len(extraItem) >= 1 && extraItem[0] == SessionExtraVersion {
+		if isSessionExtra(extraItem) {
 			utlsData, err := unmarshalSessionExtra(extraItem)
 			if err != nil {
 				// parsing failed, continue to next item
@@ -266,7 +303,8 @@ func GetSessionExtraFields(s *SessionState) *UTLSSessionData {
 	return nil
 }
 
-// SetSessionExtraFields sets extension fields in SessionState
+// SetSessionExtraFields sets extension fields in SessionState, as returned by
+// SessionState.ResumeType and SessionState.SessionID, and encodes them in Extra
 func SetSessionExtraFields(s *SessionState, data *UTLSSessionData) {
 	extraData := marshalSessionExtra(data)
 	if extraData == nil {
@@ -280,17 +318,75 @@ func SetSessionExtraFields(s *SessionState, data *UTLSSessionData) {
 
 	// add new extension data
 	s.Extra = append(s.Extra, extraData)
+	s.resumeType = data.ResumeType
+	s.sessionId = data.SessionID
 }
 
 // ClearSessionExtraFields clears extension fields from SessionState
 func ClearSessionExtraFields(s *SessionState) {
-	// remove all extension data starting with our version number
+	s.resumeType = ResumeUnknown
+	s.sessionId = nil
+
+	// remove all extension data starting with a readable version number
 	var newExtra [][]byte
 	for _, extraItem := range s.Extra {
-		if len(extraItem) == 0 || extraItem[0] != SessionExtraVersion {
+		if !isSessionExtra(extraItem) {
 			// keep extension data that is not ours
 			newExtra = append(newExtra, extraItem)
 		}
 	}
 	s.Extra = newExtra
 }
+
+// loadSessionExtraFields sets the extension fields of s from Extra, after
+// parsing.
+func (s *SessionState) loadSessionExtraFields() {
+	if data := GetSessionExtraFields(s); data != nil {
+		s.resumeType = data.ResumeType
+		s.sessionId = data.SessionID
+	}
+}
+
+// MigrateSessionExtraFields rewrites the extension fields of s in the current
+// SessionExtraVersion if they were read from an older version, and reports
+// whether it did. A ClientSessionCache can call it on loaded sessions to
+// store them back in the current format.
+func MigrateSessionExtraFields(s *SessionState) bool {
+	for _, extraItem := range s.Extra {
+		if isSessionExtra(extraItem) && extraItem[0] != SessionExtraVersion {
+			data := GetSessionExtraFields(s)
+			if data == nil {
+				return false
+			}
+			SetSessionExtraFields(s, data)
+			return true
+		}
+	}
+	return false
+}
+
+// ResumeType returns how the client resumes the session, for TLS 1.0–1.2
+// sessions, so that ClientSessionCache implementations can make decisions on
+// it, e.g. to expire session ID based sessions sooner. It is ResumeUnknown for
+// TLS 1.3 sessions.
+func (s *SessionState) ResumeType() ResumeMechanism {
+	return s.resumeType
+}
+
+// SessionID returns the session ID the client resumes the session with, if
+// ResumeType is ResumeSessionID. It must not be modified.
+func (s *SessionState) SessionID() []byte {
+	return s.sessionId
+}
+
+// SetResumeType sets how the client resumes the session, e.g. ResumeSessionTicket
+// to ignore a session ID stored along with a ticket.
+func (s *SessionState) SetResumeType(resumeType ResumeMechanism) {
+	SetSessionExtraFields(s, &UTLSSessionData{ResumeType: resumeType, SessionID: s.sessionId})
+}
+
+// SetSessionID sets the session ID the client resumes the session with if
+// ResumeType is ResumeSessionID.
+func (s *SessionState) SetSessionID(sessionID []byte) {
+	SetSessionExtraFields(s, &UTLSSessionData{ResumeType: s.resumeType, SessionID: bytes.Clone(sessionID)})
+}
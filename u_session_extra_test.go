@@ -0,0 +1,66 @@
+package tls
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSessionExtraVersionMigration(t *testing.T) {
+	// A made-up legacy format: version, resume type, then the session ID.
+	const legacyVersion = 0x7f
+	RegisterSessionExtraDecoder(legacyVersion, func(extra []byte) (*UTLSSessionData, error) {
+		return &UTLSSessionData{ResumeType: ResumeMechanism(extra[1]), SessionID: bytes.Clone(extra[2:])}, nil
+	})
+	t.Cleanup(func() { delete(sessionExtraDecoders, legacyVersion) })
+
+	session := &SessionState{
+		version:     VersionTLS12,
+		cipherSuite: TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		secret:      make([]byte, 48),
+		Extra: [][]byte{
+			[]byte("other layer"),
+			{legacyVersion, byte(ResumeSessionID), 1, 2, 3},
+			{0x7e, 1, 2}, // unknown version, not ours
+		},
+	}
+	b, err := session.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseSessionState(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.ResumeType() != ResumeSessionID || !bytes.Equal(parsed.SessionID(), []byte{1, 2, 3}) {
+		t.Fatalf("legacy fields not read: %v, %x", parsed.ResumeType(), parsed.SessionID())
+	}
+
+	if !MigrateSessionExtraFields(parsed) {
+		t.Fatal("legacy fields not migrated")
+	}
+	if MigrateSessionExtraFields(parsed) {
+		t.Error("current fields migrated again")
+	}
+	var current int
+	for _, extra := range parsed.Extra {
+		switch extra[0] {
+		case legacyVersion:
+			t.Error("legacy entry kept after migration")
+		case SessionExtraVersion:
+			current++
+		}
+	}
+	if current != 1 || len(parsed.Extra) != 3 {
+		t.Errorf("unexpected Extra after migration: %x", parsed.Extra)
+	}
+	if data := GetSessionExtraFields(parsed); data == nil || data.ResumeType != ResumeSessionID || !bytes.Equal(data.SessionID, []byte{1, 2, 3}) {
+		t.Errorf("migrated fields = %+v", data)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("replacing the current decoder didn't panic")
+		}
+	}()
+	RegisterSessionExtraDecoder(SessionExtraVersion, nil)
+}
@@ -0,0 +1,168 @@
+package tls
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// ShadowReport compares the handshake of a connection made with a ShadowDialer
+// to the shadow handshake made with its control ClientHelloID.
+type ShadowReport struct {
+	Network    string
+	Addr       string
+	ServerName string
+
+	// Primary is the handshake returned to the caller of DialContext.
+	Primary ProbeResult
+
+	// Control is the shadow handshake made with ControlHelloID.
+	Control ProbeResult
+}
+
+// Divergent reports whether the server treated the two handshakes
+// differently: only one of them completed, or they failed with different
+// alerts, or only one of them timed out.
+func (r *ShadowReport) Divergent() bool {
+	if r.Primary.OK() != r.Control.OK() {
+		return true
+	}
+	return shadowFailureOf(&r.Primary) != shadowFailureOf(&r.Control)
+}
+
+// shadowFailure is the part of a handshake failure compared by Divergent.
+type shadowFailure struct {
+	dial        bool
+	remoteAlert alert
+	timeout     bool
+}
+
+func shadowFailureOf(r *ProbeResult) shadowFailure {
+	var f shadowFailure
+	err := r.HandshakeErr
+	if r.DialErr != nil {
+		f.dial = true
+		err = r.DialErr
+	}
+	if err == nil {
+		return f
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "remote error" {
+		if a, ok := opErr.Err.(alert); ok {
+			f.remoteAlert = a
+		}
+	}
+	f.timeout = errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded)
+	return f
+}
+
+// ShadowDialer is a diagnostic dialer for detecting fingerprint-based
+// discrimination in the wild. It dials with ClientHelloID like a regular
+// dialer, and for a sample of the connections it makes an additional
+// handshake in the background with ControlHelloID, typically a fingerprint
+// no one filters on, and reports how the server behaved with both.
+//
+// Shadow handshakes double the connections made to sampled servers, so
+// SampleRate should be kept low outside of measurements.
+type ShadowDialer struct {
+	// ClientHelloID is used for the connections returned by DialContext.
+	ClientHelloID ClientHelloID
+
+	// ControlHelloID is used for shadow handshakes. If zero, HelloGolang
+	// is used.
+	ControlHelloID ClientHelloID
+
+	// Config is used for both handshakes, with ServerName set from the
+	// address if empty. If nil, an empty Config is used.
+	Config *Config
+
+	// SampleRate is the fraction of connections, between 0 and 1, for
+	// which a shadow handshake is made.
+	SampleRate float64
+
+	// DialTimeout and HandshakeTimeout bound each handshake. If zero, 10
+	// and 15 seconds are used respectively.
+	DialTimeout      time.Duration
+	HandshakeTimeout time.Duration
+
+	// NetDialContext, if set, is used to establish TCP connections instead
+	// of a net.Dialer.
+	NetDialContext func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// Report is called with the result of every shadow handshake, from
+	// the goroutine that made it. If nil, nothing is reported.
+	Report func(*ShadowReport)
+
+	wg sync.WaitGroup
+}
+
+// Dial is like DialContext with a background context.
+func (d *ShadowDialer) Dial(network, addr string) (*UConn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// DialContext connects to addr and completes a handshake with ClientHelloID.
+// If the connection is sampled, a shadow handshake with ControlHelloID is
+// then started in the background, even if the first handshake failed, unless
+// addr was not reachable at all. The shadow handshake doesn't depend on ctx.
+func (d *ShadowDialer) DialContext(ctx context.Context, network, addr string) (*UConn, error) {
+	serverName := ""
+	if d.Config == nil || d.Config.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		serverName = host
+	}
+
+	p := d.prober()
+	r := ProbeResult{ClientHelloID: d.ClientHelloID}
+	uconn := p.connect(ctx, &r, network, addr, serverName)
+	if r.Reachable() && d.Report != nil && d.SampleRate > 0 && rand.Float64() < d.SampleRate {
+		report := &ShadowReport{
+			Network:    network,
+			Addr:       addr,
+			ServerName: serverName,
+			Primary:    r,
+			Control:    ProbeResult{ClientHelloID: d.ControlHelloID},
+		}
+		if report.Control.ClientHelloID == (ClientHelloID{}) {
+			report.Control.ClientHelloID = HelloGolang
+		}
+		if report.ServerName == "" {
+			report.ServerName = d.Config.ServerName
+		}
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			p.probe(context.Background(), &report.Control, network, addr, serverName)
+			d.Report(report)
+		}()
+	}
+	if r.DialErr != nil {
+		return nil, r.DialErr
+	}
+	if r.HandshakeErr != nil {
+		return nil, r.HandshakeErr
+	}
+	return uconn, nil
+}
+
+// Wait waits for the shadow handshakes in progress to be reported.
+func (d *ShadowDialer) Wait() {
+	d.wg.Wait()
+}
+
+func (d *ShadowDialer) prober() *Prober {
+	return &Prober{
+		Config:           d.Config,
+		DialTimeout:      d.DialTimeout,
+		HandshakeTimeout: d.HandshakeTimeout,
+		DialContext:      d.NetDialContext,
+	}
+}
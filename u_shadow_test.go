@@ -0,0 +1,102 @@
+package tls
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestShadowDialer(t *testing.T) {
+	serverConfig := &Config{
+		GetConfigForClient: func(chi *ClientHelloInfo) (*Config, error) {
+			if slices.Contains(chi.Extensions, utlsExtensionCompressCertificate) {
+				return nil, errors.New("blocked")
+			}
+			return nil, nil
+		},
+	}
+	s, err := NewClientHelloEchoServer(serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate)
+
+	reports := make(chan *ShadowReport, 4)
+	d := &ShadowDialer{
+		Config:     &Config{RootCAs: roots, ServerName: "localhost"},
+		SampleRate: 1,
+		Report:     func(r *ShadowReport) { reports <- r },
+	}
+	for _, test := range []struct {
+		id        ClientHelloID
+		divergent bool
+	}{
+		{HelloChrome_120, true},
+		{HelloFirefox_120, false},
+	} {
+		d.ClientHelloID = test.id
+		uconn, err := d.Dial("tcp", s.Addr().String())
+		if (err == nil) == test.divergent {
+			t.Errorf("%s: unexpected dial error %v", test.id.Str(), err)
+		}
+		if uconn != nil {
+			uconn.Close()
+		}
+		d.Wait()
+		r := <-reports
+		if r.Primary.ClientHelloID != test.id || r.Control.ClientHelloID != HelloGolang {
+			t.Errorf("%s: report for %s and %s", test.id.Str(), r.Primary.ClientHelloID.Str(), r.Control.ClientHelloID.Str())
+		}
+		if r.ServerName != "localhost" || r.Addr != s.Addr().String() {
+			t.Errorf("%s: report for %q at %s", test.id.Str(), r.ServerName, r.Addr)
+		}
+		if !r.Control.OK() {
+			t.Errorf("%s: control handshake failed: %v", test.id.Str(), r.Control.HandshakeErr)
+		}
+		if r.Divergent() != test.divergent {
+			t.Errorf("%s: Divergent() = %v, want %v", test.id.Str(), r.Divergent(), test.divergent)
+		}
+	}
+
+	d.SampleRate = 0
+	d.ClientHelloID = HelloGolang
+	uconn, err := d.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	uconn.Close()
+	d.Wait()
+	select {
+	case r := <-reports:
+		t.Errorf("unsampled connection reported: %+v", r)
+	default:
+	}
+}
+
+func TestShadowReportDivergent(t *testing.T) {
+	remote := func(a alert) error { return &net.OpError{Op: "remote error", Err: a} }
+	for _, test := range []struct {
+		primary, control error
+		divergent        bool
+	}{
+		{nil, nil, false},
+		{remote(alertHandshakeFailure), nil, true},
+		{remote(alertHandshakeFailure), remote(alertHandshakeFailure), false},
+		{remote(alertHandshakeFailure), remote(alertProtocolVersion), true},
+		{context.DeadlineExceeded, remote(alertHandshakeFailure), true},
+		{context.DeadlineExceeded, context.DeadlineExceeded, false},
+	} {
+		r := &ShadowReport{
+			Primary: ProbeResult{HandshakeErr: test.primary},
+			Control: ProbeResult{HandshakeErr: test.control},
+		}
+		if r.Divergent() != test.divergent {
+			t.Errorf("Divergent() with %v and %v = %v, want %v", test.primary, test.control, r.Divergent(), test.divergent)
+		}
+	}
+}
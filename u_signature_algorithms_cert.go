@@ -0,0 +1,86 @@
+package tls
+
+import (
+	"crypto/x509"
+	"fmt"
+	"slices"
+)
+
+// certSignatureAlgorithms returns the signature algorithms the client accepts
+// in certificates: signature_algorithms_cert if sent, and signature_algorithms
+// otherwise (RFC 8446, Section 4.2.3).
+func (m *clientHelloMsg) certSignatureAlgorithms() []SignatureScheme {
+	if len(m.supportedSignatureAlgorithmsCert) > 0 {
+		return m.supportedSignatureAlgorithmsCert
+	}
+	return m.supportedSignatureAlgorithms
+}
+
+// x509SignatureSchemes returns the TLS signature schemes corresponding to a
+// certificate signature algorithm. RSA-PSS certificate signatures may be
+// offered as rsa_pss_rsae or rsa_pss_pss schemes.
+func x509SignatureSchemes(alg x509.SignatureAlgorithm) []SignatureScheme {
+	switch alg {
+	case x509.SHA1WithRSA:
+		return []SignatureScheme{PKCS1WithSHA1}
+	case x509.SHA256WithRSA:
+		return []SignatureScheme{PKCS1WithSHA256}
+	case x509.SHA384WithRSA:
+		return []SignatureScheme{PKCS1WithSHA384}
+	case x509.SHA512WithRSA:
+		return []SignatureScheme{PKCS1WithSHA512}
+	case x509.SHA256WithRSAPSS:
+		return []SignatureScheme{PSSWithSHA256, 0x0809}
+	case x509.SHA384WithRSAPSS:
+		return []SignatureScheme{PSSWithSHA384, 0x080a}
+	case x509.SHA512WithRSAPSS:
+		return []SignatureScheme{PSSWithSHA512, 0x080b}
+	case x509.ECDSAWithSHA1:
+		return []SignatureScheme{ECDSAWithSHA1}
+	case x509.ECDSAWithSHA256:
+		return []SignatureScheme{ECDSAWithP256AndSHA256}
+	case x509.ECDSAWithSHA384:
+		return []SignatureScheme{ECDSAWithP384AndSHA384}
+	case x509.ECDSAWithSHA512:
+		return []SignatureScheme{ECDSAWithP521AndSHA512}
+	case x509.PureEd25519:
+		return []SignatureScheme{Ed25519}
+	}
+	return nil
+}
+
+// chainSignatureAlgorithmsOffered returns the first certificate of chain whose
+// signature uses an algorithm not in offered, or nil. The signature of the
+// trust anchor at the end of the chain is not checked.
+func chainSignatureAlgorithmsOffered(chain []*x509.Certificate, offered []SignatureScheme) *x509.Certificate {
+	for _, cert := range chain[:max(len(chain)-1, 0)] {
+		if !slices.ContainsFunc(x509SignatureSchemes(cert.SignatureAlgorithm), func(s SignatureScheme) bool {
+			return slices.Contains(offered, s)
+		}) {
+			return cert
+		}
+	}
+	return nil
+}
+
+// checkCertSignatureAlgorithms implements Config.VerifyCertSignatureAlgorithms,
+// dropping the verified chains with signatures the ClientHello didn't offer.
+func (c *Conn) checkCertSignatureAlgorithms() error {
+	if !c.config.VerifyCertSignatureAlgorithms || len(c.verifiedChains) == 0 || c.utls.certSignatureAlgorithms == nil {
+		return nil
+	}
+	var unoffered *x509.Certificate
+	chains := slices.DeleteFunc(slices.Clone(c.verifiedChains), func(chain []*x509.Certificate) bool {
+		cert := chainSignatureAlgorithmsOffered(chain, c.utls.certSignatureAlgorithms)
+		if cert != nil && unoffered == nil {
+			unoffered = cert
+		}
+		return cert != nil
+	})
+	if len(chains) == 0 {
+		c.sendAlert(alertBadCertificate)
+		return fmt.Errorf("tls: server certificate chain is signed with %v, which was not offered in signature_algorithms_cert", unoffered.SignatureAlgorithm)
+	}
+	c.verifiedChains = chains
+	return nil
+}
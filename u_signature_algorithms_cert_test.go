@@ -0,0 +1,113 @@
+package tls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSignatureAlgorithmsCert(t *testing.T) {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, _ := x509.ParseCertificate(rootDER)
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, root, &leafKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewUTLSServer(&Config{Certificates: []Certificate{{Certificate: [][]byte{leafDER}, PrivateKey: leafKey}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+
+	sigAlgs := []SignatureScheme{ECDSAWithP256AndSHA256, PSSWithSHA256, PKCS1WithSHA256}
+	for _, test := range []struct {
+		name     string
+		certAlgs []SignatureScheme
+		verify   bool
+		ok       bool
+	}{
+		{"offered", []SignatureScheme{ECDSAWithP256AndSHA256}, true, true},
+		{"not offered", []SignatureScheme{Ed25519, PSSWithSHA256}, true, false},
+		{"not offered, not verified", []SignatureScheme{Ed25519}, false, true},
+		{"signature_algorithms", nil, true, true},
+	} {
+		extensions := []TLSExtension{
+			&SNIExtension{},
+			&SupportedCurvesExtension{[]CurveID{X25519}},
+			&SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: sigAlgs},
+		}
+		if test.certAlgs != nil {
+			extensions = append(extensions, &SignatureAlgorithmsCertExtension{SupportedSignatureAlgorithms: test.certAlgs})
+		}
+		extensions = append(extensions,
+			&SupportedVersionsExtension{[]uint16{VersionTLS13}},
+			&KeyShareExtension{[]KeyShare{{Group: X25519}}})
+
+		c := NewUnstartedUTLSClient(s, &Config{
+			ServerName:                    "example.com",
+			RootCAs:                       roots,
+			VerifyCertSignatureAlgorithms: test.verify,
+		}, HelloCustom)
+		if err := c.ApplyPreset(&ClientHelloSpec{
+			CipherSuites:       []uint16{TLS_AES_128_GCM_SHA256},
+			CompressionMethods: []uint8{compressionNone},
+			Extensions:         extensions,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.BuildHandshakeState(); err != nil {
+			t.Fatal(err)
+		}
+		m := new(clientHelloMsg)
+		if !m.unmarshal(c.HandshakeState.Hello.Raw) {
+			t.Fatal("failed to parse ClientHello")
+		}
+		if !slices.Equal(m.supportedSignatureAlgorithms, sigAlgs) || !slices.Equal(m.supportedSignatureAlgorithmsCert, test.certAlgs) {
+			t.Errorf("%s: ClientHello offers %v and %v for certificates", test.name, m.supportedSignatureAlgorithms, m.supportedSignatureAlgorithmsCert)
+		}
+
+		err := c.Start()
+		if (err == nil) != test.ok {
+			t.Errorf("%s: handshake error %v, want success %v", test.name, err, test.ok)
+		}
+		if err == nil {
+			c.Close()
+		}
+	}
+}
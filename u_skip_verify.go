@@ -0,0 +1,34 @@
+// Copyright 2024 uTLS Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tls
+
+import "strings"
+
+// skipVerify reports whether a client should skip verifying the server
+// certificate, because of InsecureSkipVerify or because ServerName is allowed
+// by InsecureSkipVerifyHosts or InsecureSkipVerifyHost.
+func (c *Config) skipVerify() bool {
+	if c.InsecureSkipVerify {
+		return true
+	}
+	if c.ServerName == "" {
+		return false
+	}
+	if c.InsecureSkipVerifyHost != nil && c.InsecureSkipVerifyHost(c.ServerName) {
+		return true
+	}
+	name := strings.ToLower(strings.TrimSuffix(c.ServerName, "."))
+	for _, host := range c.InsecureSkipVerifyHosts {
+		host = strings.ToLower(strings.TrimSuffix(host, "."))
+		if suffix, ok := strings.CutPrefix(host, "*."); ok {
+			if strings.HasSuffix(name, "."+suffix) {
+				return true
+			}
+		} else if name == host {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package tls
+
+import (
+	"crypto/x509"
+	"testing"
+)
+
+func TestSkipVerifyHostsMatching(t *testing.T) {
+	hosts := []string{"internal.example", "*.corp.example."}
+	for _, test := range []struct {
+		serverName string
+		skip       bool
+	}{
+		{"internal.example", true},
+		{"Internal.Example.", true},
+		{"www.internal.example", false},
+		{"a.corp.example", true},
+		{"a.b.corp.example", true},
+		{"corp.example", false},
+		{"xcorp.example", false},
+		{"example.com", false},
+		{"", false},
+	} {
+		config := &Config{ServerName: test.serverName, InsecureSkipVerifyHosts: hosts}
+		if skip := config.skipVerify(); skip != test.skip {
+			t.Errorf("%q: skipVerify() = %v, want %v", test.serverName, skip, test.skip)
+		}
+	}
+}
+
+func TestUTLSSkipVerifyHosts(t *testing.T) {
+	for _, version := range []uint16{VersionTLS12, VersionTLS13} {
+		s, err := NewUTLSServer(&Config{MaxVersion: version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		testSkipVerifyHosts(t, s, version)
+		s.Close()
+	}
+}
+
+func testSkipVerifyHosts(t *testing.T, s *UTLSTestServer, version uint16) {
+	for _, test := range []struct {
+		name   string
+		config *Config
+		ok     bool
+	}{
+		{"none", &Config{}, false},
+		{"other host", &Config{InsecureSkipVerifyHosts: []string{"example.com", "*.localhost"}}, false},
+		{"listed", &Config{InsecureSkipVerifyHosts: []string{"example.com", "LOCALHOST"}}, true},
+		{"callback", &Config{InsecureSkipVerifyHost: func(serverName string) bool { return serverName == "localhost" }}, true},
+	} {
+		// An empty pool doesn't trust the server certificate.
+		test.config.RootCAs = x509.NewCertPool()
+		c, err := NewUTLSClient(s, test.config, HelloChrome_Auto)
+		if !test.ok {
+			if err == nil {
+				c.Close()
+				t.Errorf("%s, %x: expected the untrusted certificate to be rejected", test.name, version)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s, %x: %v", test.name, version, err)
+			continue
+		}
+		if cs := c.ConnectionState(); len(cs.PeerCertificates) == 0 || len(cs.VerifiedChains) != 0 {
+			t.Errorf("%s, %x: got %d peer certificates and %d verified chains", test.name, version, len(cs.PeerCertificates), len(cs.VerifiedChains))
+		}
+		c.Close()
+	}
+}
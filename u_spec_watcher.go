@@ -0,0 +1,243 @@
+package tls
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultSpecWatchInterval is the polling interval used by WatchSpecs if none
+// is given.
+const DefaultSpecWatchInterval = 5 * time.Second
+
+// SpecWatcher watches a ClientHelloSpec JSON file, or a directory of them, and
+// atomically swaps the specs handed to new connections whenever the files
+// change, so that long-running processes can update fingerprints without
+// restarts.
+//
+// Files use the format of ClientHelloSpecJSONUnmarshaler. Each spec is named
+// after its file name without the ".json" extension. A file that fails to
+// parse does not replace the previously loaded version of the spec.
+//
+// Connections already established or in progress are not affected by reloads.
+type SpecWatcher struct {
+	path     string
+	interval time.Duration
+
+	// reloadMu serializes reloads, readers only use specs.
+	reloadMu sync.Mutex
+	specs    atomic.Pointer[map[string]*watchedSpec]
+	onError  atomic.Pointer[func(name string, err error)]
+
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+type watchedSpec struct {
+	raw     []byte
+	modTime time.Time
+	size    int64
+}
+
+// WatchSpecs loads the spec JSON file, or all "*.json" files in the directory,
+// at path and starts polling them for changes every interval. If interval is
+// zero, DefaultSpecWatchInterval is used.
+//
+// It returns an error if the initial load fails or no spec is found. Close must
+// be called to stop watching.
+func WatchSpecs(path string, interval time.Duration) (*SpecWatcher, error) {
+	if interval <= 0 {
+		interval = DefaultSpecWatchInterval
+	}
+	w := &SpecWatcher{
+		path:     path,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+	w.specs.Store(&map[string]*watchedSpec{})
+	if err := w.Reload(); err != nil {
+		return nil, err
+	}
+	if len(w.Names()) == 0 {
+		return nil, fmt.Errorf("tls: no ClientHelloSpec found at %s", path)
+	}
+	go w.watch()
+	return w, nil
+}
+
+// OnError sets a function called with errors encountered while reloading in
+// the background, e.g. to log them. name is empty for errors not specific to a
+// spec file.
+func (w *SpecWatcher) OnError(f func(name string, err error)) {
+	w.onError.Store(&f)
+}
+
+// Names returns the sorted names of the currently loaded specs.
+func (w *SpecWatcher) Names() []string {
+	specs := *w.specs.Load()
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Spec returns a new ClientHelloSpec built from the currently loaded version of
+// the named spec. Every call returns a fresh spec that may be applied to a
+// single connection.
+func (w *SpecWatcher) Spec(name string) (ClientHelloSpec, error) {
+	s, ok := (*w.specs.Load())[name]
+	if !ok {
+		return ClientHelloSpec{}, fmt.Errorf("tls: unknown ClientHelloSpec %q", name)
+	}
+	return parseSpecJSON(s.raw)
+}
+
+// UClient returns a new uTLS client using the currently loaded version of the
+// named spec.
+func (w *SpecWatcher) UClient(conn net.Conn, config *Config, name string) (*UConn, error) {
+	spec, err := w.Spec(name)
+	if err != nil {
+		return nil, err
+	}
+	uconn := UClient(conn, config, HelloCustom)
+	if err := uconn.ApplyPreset(&spec); err != nil {
+		return nil, err
+	}
+	return uconn, nil
+}
+
+// Reload checks the watched files for changes immediately. Changed specs are
+// swapped in atomically, specs whose file was removed are dropped. The first
+// error encountered is returned, but valid changes are applied regardless.
+func (w *SpecWatcher) Reload() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+
+	files, err := w.specFiles()
+	if err != nil {
+		w.reportError("", err)
+		return err
+	}
+
+	old := *w.specs.Load()
+	specs := make(map[string]*watchedSpec, len(files))
+	changed := len(old) != len(files)
+	var firstErr error
+	for name, path := range files {
+		s, err := loadWatchedSpec(path, old[name])
+		if err != nil {
+			w.reportError(name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			s = old[name]
+		}
+		if s == nil {
+			changed = true
+			continue
+		}
+		if s != old[name] {
+			changed = true
+		}
+		specs[name] = s
+	}
+	if changed {
+		w.specs.Store(&specs)
+	}
+	return firstErr
+}
+
+// Close stops watching for changes. Specs loaded so far remain available.
+func (w *SpecWatcher) Close() error {
+	w.closeOnce.Do(func() { close(w.done) })
+	return nil
+}
+
+func (w *SpecWatcher) watch() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			w.Reload()
+		}
+	}
+}
+
+func (w *SpecWatcher) reportError(name string, err error) {
+	if f := w.onError.Load(); f != nil {
+		(*f)(name, err)
+	}
+}
+
+// specFiles maps spec names to the paths of the files to load them from.
+func (w *SpecWatcher) specFiles() (map[string]string, error) {
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return map[string]string{specName(w.path): w.path}, nil
+	}
+
+	entries, err := os.ReadDir(w.path)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]string)
+	for _, e := range entries {
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(e.Name()), ".json") {
+			continue
+		}
+		files[specName(e.Name())] = filepath.Join(w.path, e.Name())
+	}
+	return files, nil
+}
+
+func specName(path string) string {
+	base := filepath.Base(path)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// loadWatchedSpec returns old if the file is unchanged, or the new version of
+// the spec otherwise.
+func loadWatchedSpec(path string, old *watchedSpec) (*watchedSpec, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if old != nil && fi.ModTime().Equal(old.modTime) && fi.Size() == old.size {
+		return old, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parseSpecJSON(raw); err != nil {
+		return nil, fmt.Errorf("tls: invalid ClientHelloSpec in %s: %w", path, err)
+	}
+	return &watchedSpec{raw: raw, modTime: fi.ModTime(), size: fi.Size()}, nil
+}
+
+func parseSpecJSON(raw []byte) (ClientHelloSpec, error) {
+	var chsju ClientHelloSpecJSONUnmarshaler
+	if err := json.Unmarshal(raw, &chsju); err != nil {
+		return ClientHelloSpec{}, err
+	}
+	if chsju.CipherSuites == nil || chsju.CompressionMethods == nil || chsju.Extensions == nil {
+		return ClientHelloSpec{}, errors.New("cipher_suites, compression_methods and extensions are required")
+	}
+	return chsju.ClientHelloSpec(), nil
+}
@@ -0,0 +1,112 @@
+package tls
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func copySpecFile(t *testing.T, src, dst string, modTime time.Time) {
+	t.Helper()
+	b, err := os.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dst, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(dst, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSpecWatcher(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	copySpecFile(t, "testdata/ClientHello-JSON-Chrome102.json", filepath.Join(dir, "active.json"), now)
+
+	w, err := WatchSpecs(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	var reported []string
+	w.OnError(func(name string, err error) { reported = append(reported, name) })
+
+	if names := w.Names(); !reflect.DeepEqual(names, []string{"active"}) {
+		t.Fatalf("got names %v", names)
+	}
+	chrome, _ := UTLSIdToSpec(HelloChrome_102)
+	spec, err := w.Spec("active")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(spec.CipherSuites, chrome.CipherSuites) {
+		t.Errorf("got cipher suites %x, want %x", spec.CipherSuites, chrome.CipherSuites)
+	}
+
+	// Swap in a new fingerprint and add a second spec.
+	copySpecFile(t, "testdata/ClientHello-JSON-Firefox105.json", filepath.Join(dir, "active.json"), now.Add(time.Second))
+	copySpecFile(t, "testdata/ClientHello-JSON-iOS14.json", filepath.Join(dir, "ios.json"), now)
+	if err := w.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	firefox, _ := UTLSIdToSpec(HelloFirefox_105)
+	spec, err = w.Spec("active")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(spec.CipherSuites, firefox.CipherSuites) {
+		t.Errorf("spec was not reloaded: got cipher suites %x, want %x", spec.CipherSuites, firefox.CipherSuites)
+	}
+	if names := w.Names(); !reflect.DeepEqual(names, []string{"active", "ios"}) {
+		t.Errorf("got names %v", names)
+	}
+
+	// A broken file keeps the previous version.
+	if err := os.WriteFile(filepath.Join(dir, "active.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Reload(); err == nil {
+		t.Error("expected error reloading invalid spec")
+	}
+	if !reflect.DeepEqual(reported, []string{"active"}) {
+		t.Errorf("got reported errors for %v", reported)
+	}
+	if spec, err = w.Spec("active"); err != nil || !reflect.DeepEqual(spec.CipherSuites, firefox.CipherSuites) {
+		t.Errorf("invalid file replaced the loaded spec: %v", err)
+	}
+
+	uconn, err := w.UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, "ios")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := uconn.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Spec("missing"); err == nil {
+		t.Error("expected error for unknown spec")
+	}
+
+	// Removed files are dropped.
+	if err := os.Remove(filepath.Join(dir, "ios.json")); err != nil {
+		t.Fatal(err)
+	}
+	w.Reload()
+	if names := w.Names(); !reflect.DeepEqual(names, []string{"active"}) {
+		t.Errorf("got names %v after removal", names)
+	}
+}
+
+func TestSpecWatcherInvalid(t *testing.T) {
+	if _, err := WatchSpecs(t.TempDir(), 0); err == nil {
+		t.Error("expected error for empty directory")
+	}
+	if _, err := WatchSpecs(filepath.Join(t.TempDir(), "missing.json"), 0); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
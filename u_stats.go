@@ -0,0 +1,87 @@
+package tls
+
+import "sync/atomic"
+
+// ConnStats are the counters of a connection, as returned by Conn.Stats.
+type ConnStats struct {
+	RecordsRead    uint64
+	RecordsWritten uint64
+
+	// BytesRead and BytesWritten count the bytes of TLS records, including
+	// record headers.
+	BytesRead    uint64
+	BytesWritten uint64
+
+	// PaddingBytes counts the padding added to outgoing records by CBC
+	// cipher suites. For a UConn, it also includes the padding extension of
+	// the ClientHello.
+	PaddingBytes uint64
+
+	KeyUpdatesReceived uint64
+	KeyUpdatesSent     uint64
+
+	// HelloRetryRequests counts the HelloRetryRequests sent or received,
+	// after which the client sends a second ClientHello.
+	HelloRetryRequests uint64
+
+	// Renegotiations counts the completed TLS 1.2 renegotiations, which
+	// don't include the initial handshake.
+	Renegotiations uint64
+}
+
+// connStats holds the counters of a Conn. They are updated atomically so that
+// Stats doesn't block on in-flight reads and writes.
+type connStats struct {
+	recordsRead, recordsWritten        atomic.Uint64
+	bytesRead, bytesWritten            atomic.Uint64
+	paddingBytes                       atomic.Uint64
+	keyUpdatesReceived, keyUpdatesSent atomic.Uint64
+	helloRetryRequests                 atomic.Uint64
+	renegotiations                     atomic.Uint64
+}
+
+// Stats returns the counters of the connection. It is safe to call
+// concurrently with any other method.
+func (c *Conn) Stats() ConnStats {
+	s := &c.utls.stats
+	return ConnStats{
+		RecordsRead:        s.recordsRead.Load(),
+		RecordsWritten:     s.recordsWritten.Load(),
+		BytesRead:          s.bytesRead.Load(),
+		BytesWritten:       s.bytesWritten.Load(),
+		PaddingBytes:       s.paddingBytes.Load(),
+		KeyUpdatesReceived: s.keyUpdatesReceived.Load(),
+		KeyUpdatesSent:     s.keyUpdatesSent.Load(),
+		HelloRetryRequests: s.helloRetryRequests.Load(),
+		Renegotiations:     s.renegotiations.Load(),
+	}
+}
+
+// countRecordWritten records an outgoing record of payloadLen bytes, encrypted
+// into record.
+func (c *Conn) countRecordWritten(record []byte, payloadLen int) {
+	s := &c.utls.stats
+	s.recordsWritten.Add(1)
+	s.bytesWritten.Add(uint64(len(record)))
+	if _, ok := c.out.cipher.(cbcMode); ok {
+		padding := len(record) - recordHeaderLen - c.out.explicitNonceLen() - payloadLen - c.out.mac.Size()
+		if padding > 0 {
+			s.paddingBytes.Add(uint64(padding))
+		}
+	}
+}
+
+// Stats is like Conn.Stats, but also counts the padding extension of the
+// ClientHello.
+func (c *UConn) Stats() ConnStats {
+	stats := c.Conn.Stats()
+	if c.clientHelloBuildStatus != BuildByUtls {
+		return stats
+	}
+	for _, ext := range c.Extensions {
+		if p, ok := ext.(*UtlsPaddingExtension); ok && p.WillPad {
+			stats.PaddingBytes += uint64(p.PaddingLen)
+		}
+	}
+	return stats
+}
@@ -0,0 +1,95 @@
+package tls
+
+import (
+	"testing"
+)
+
+func TestConnStats(t *testing.T) {
+	serverConfig := testConfig.Clone()
+	serverConfig.CurvePreferences = []CurveID{CurveP256} // force a HelloRetryRequest
+	s, err := NewUTLSServer(serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := NewUTLSClient(s, &Config{InsecureSkipVerify: true}, HelloChrome_120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if c.ConnectionState().Version != VersionTLS13 {
+		t.Fatal("expected TLS 1.3")
+	}
+
+	// Send a KeyUpdate requesting one back, then round trip some data.
+	c.out.Lock()
+	msg, err := (&keyUpdateMsg{updateRequested: true}).marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.writeRecordLocked(recordTypeHandshake, msg); err != nil {
+		t.Fatal(err)
+	}
+	suite := cipherSuiteTLS13ByID(c.cipherSuite)
+	c.out.setTrafficSecret(suite, QUICEncryptionLevelInitial, suite.nextTrafficSecret(c.out.trafficSecret))
+	c.out.Unlock()
+
+	go c.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := c.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	client, server := c.Stats(), c.ServerConn.Stats()
+	if client.BytesWritten != server.BytesRead || client.RecordsWritten != server.RecordsRead {
+		t.Errorf("client wrote %d records, %d bytes, server read %d records, %d bytes",
+			client.RecordsWritten, client.BytesWritten, server.RecordsRead, server.BytesRead)
+	}
+	if client.RecordsWritten < 4 || client.RecordsRead < 4 || client.BytesRead == 0 {
+		t.Errorf("unexpected client stats %+v", client)
+	}
+	if server.KeyUpdatesReceived != 1 || server.KeyUpdatesSent != 1 || client.KeyUpdatesReceived != 1 {
+		t.Errorf("unexpected key update counts: client %+v, server %+v", client, server)
+	}
+	if client.HelloRetryRequests != 1 || server.HelloRetryRequests != 1 {
+		t.Errorf("unexpected HelloRetryRequest counts: client %d, server %d", client.HelloRetryRequests, server.HelloRetryRequests)
+	}
+	if server.PaddingBytes != 0 {
+		t.Errorf("TLS 1.3 server reported %d bytes of padding", server.PaddingBytes)
+	}
+}
+
+func TestConnStatsCBCPadding(t *testing.T) {
+	serverConfig := testConfig.Clone()
+	serverConfig.MaxVersion = VersionTLS12
+	serverConfig.CipherSuites = []uint16{TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA}
+	s, err := NewUTLSServer(serverConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	c, err := NewUTLSClient(s, &Config{InsecureSkipVerify: true}, HelloChrome_120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	go c.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := c.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	// Finished and the echo are each padded to the AES block size.
+	if p := c.ServerConn.Stats().PaddingBytes; p == 0 || p > 2*16 {
+		t.Errorf("server reported %d bytes of padding", p)
+	}
+	var helloPadding uint64
+	for _, ext := range c.Extensions {
+		if p, ok := ext.(*UtlsPaddingExtension); ok && p.WillPad {
+			helloPadding += uint64(p.PaddingLen)
+		}
+	}
+	if got, want := c.Stats().PaddingBytes, c.Conn.Stats().PaddingBytes+helloPadding; got != want || c.Conn.Stats().PaddingBytes == 0 {
+		t.Errorf("client reported %d bytes of padding, want %d", got, want)
+	}
+}
@@ -0,0 +1,53 @@
+package tls
+
+import (
+	"io"
+	"time"
+)
+
+// defaultTarpitMaxBytes is the default of HandshakeTarpit.MaxBytes.
+const defaultTarpitMaxBytes = 1 << 20
+
+// HandshakeTarpit configures how a server holds the connection of a failed
+// handshake, see Config.HandshakeTarpit. Like a server that doesn't speak TLS,
+// it keeps reading without answering, so that active probes can't tell a
+// TLS server apart by its alerts or by how fast it closes the connection.
+type HandshakeTarpit struct {
+	// Duration is the longest time the connection is held. If zero, it's
+	// held until the read deadline of the connection, the client closes it
+	// or MaxBytes are read.
+	Duration time.Duration
+
+	// MaxBytes is the most bytes discarded before giving up. If zero, it's
+	// 1 MiB.
+	MaxBytes int64
+}
+
+// tarpitting reports whether the alerts of c are held back because its
+// handshake failed on a server with a HandshakeTarpit.
+func (c *Conn) tarpitting() bool {
+	return !c.isClient && c.quic == nil && c.config.HandshakeTarpit != nil && !c.isHandshakeComplete.Load()
+}
+
+// tarpit reads and discards data from the connection of a failed handshake
+// within the limits of the HandshakeTarpit, if tarpitting. The handshake
+// error is then returned as usual, and the connection isn't usable anymore.
+func (c *Conn) tarpit() {
+	if !c.tarpitting() {
+		return
+	}
+	t := c.config.HandshakeTarpit
+	if t.Duration > 0 {
+		// The connection failed, so its deadline is not restored.
+		c.NetConn().SetReadDeadline(time.Now().Add(t.Duration))
+	}
+	max := t.MaxBytes
+	if max <= 0 {
+		max = defaultTarpitMaxBytes
+	}
+	max -= int64(c.rawInput.Len())
+	c.rawInput.Reset()
+	if max > 0 {
+		io.Copy(io.Discard, io.LimitReader(c.NetConn(), max))
+	}
+}
@@ -0,0 +1,77 @@
+package tls
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestHandshakeTarpit(t *testing.T) {
+	cert, err := selfSignedTestCertificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An HTTP request, and a handshake record of an unexpected type.
+	for _, probe := range [][]byte{
+		[]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"),
+		{byte(recordTypeHandshake), 3, 1, 0, 4, typeServerHello, 0, 0, 0},
+	} {
+		client, server := net.Pipe()
+		serverConfig := &Config{
+			Certificates:    []Certificate{cert},
+			HandshakeTarpit: &HandshakeTarpit{Duration: 100 * time.Millisecond},
+		}
+		done := make(chan error, 1)
+		start := time.Now()
+		go func() {
+			done <- Server(server, serverConfig).Handshake()
+		}()
+
+		if _, err := client.Write(probe); err != nil {
+			t.Fatal(err)
+		}
+		// The server keeps reading what is sent, without answering.
+		if _, err := client.Write(bytes.Repeat([]byte{'x'}, 100)); err != nil {
+			t.Fatalf("tarpit doesn't read: %v", err)
+		}
+		client.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+		if n, err := client.Read(make([]byte, 10)); !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("server answered %d bytes, %v", n, err)
+		}
+		if err := <-done; err == nil {
+			t.Error("handshake succeeded")
+		}
+		if d := time.Since(start); d < 100*time.Millisecond {
+			t.Errorf("connection held for %v, want the tarpit duration", d)
+		}
+		client.Close()
+		server.Close()
+	}
+
+	// The tarpit gives up after MaxBytes.
+	client, server := net.Pipe()
+	defer client.Close()
+	serverConfig := &Config{
+		Certificates:    []Certificate{cert},
+		HandshakeTarpit: &HandshakeTarpit{MaxBytes: 100},
+	}
+	done := make(chan error, 1)
+	go func() {
+		err := Server(server, serverConfig).Handshake()
+		server.Close()
+		done <- err
+	}()
+	client.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+	client.Write(bytes.Repeat([]byte{'x'}, 200))
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("handshake succeeded")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("tarpit didn't stop after MaxBytes")
+	}
+}
@@ -0,0 +1,345 @@
+package tls
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// UTLSTestServer is the server side of in-memory uTLS connections for tests,
+// in the spirit of net/http/httptest. Connections to it are created with
+// NewUnstartedUTLSClient and run over an in-memory pipe, so no network is
+// involved.
+type UTLSTestServer struct {
+	// Config is the server configuration. It may be modified until Start
+	// is called.
+	Config *Config
+
+	// Certificate is the certificate served if Config has none, to be trusted
+	// by clients. It is set by Start.
+	Certificate *x509.Certificate
+
+	// Handler, if set, is called with every connection after a successful
+	// handshake. If nil, connections echo back whatever they read.
+	// The connection is closed when Handler returns.
+	Handler func(*Conn)
+
+	mu      sync.Mutex
+	started bool
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// NewUnstartedUTLSServer returns a new UTLSTestServer with the given config,
+// which may be nil. Start must be called before connecting clients.
+func NewUnstartedUTLSServer(config *Config) *UTLSTestServer {
+	if config == nil {
+		config = &Config{}
+	}
+	return &UTLSTestServer{Config: config}
+}
+
+// NewUTLSServer is like NewUnstartedUTLSServer, but starts the server.
+func NewUTLSServer(config *Config) (*UTLSTestServer, error) {
+	s := NewUnstartedUTLSServer(config)
+	if err := s.Start(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Start finalizes the configuration of the server. If Config has no
+// certificate, a self-signed certificate for "localhost" is generated.
+func (s *UTLSTestServer) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return errors.New("tls: UTLSTestServer already started")
+	}
+	if len(s.Config.Certificates) == 0 && s.Config.GetCertificate == nil && s.Config.GetConfigForClient == nil {
+		cert, err := selfSignedTestCertificate()
+		if err != nil {
+			return err
+		}
+		s.Config.Certificates = []Certificate{cert}
+	}
+	if len(s.Config.Certificates) > 0 {
+		if leaf, err := x509.ParseCertificate(s.Config.Certificates[0].Certificate[0]); err == nil {
+			s.Certificate = leaf
+		}
+	}
+	s.started = true
+	return nil
+}
+
+// Close waits for the handlers of all connections to return.
+func (s *UTLSTestServer) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// UTLSTestClient is a client connection to a UTLSTestServer. The embedded UConn
+// may be modified, e.g. with ApplyPreset, until Start is called.
+type UTLSTestClient struct {
+	*UConn
+
+	// ServerConn is the server end of the connection.
+	ServerConn *Conn
+
+	server       *UTLSTestServer
+	clientRecord *flightRecorder
+	serverRecord *flightRecorder
+}
+
+// NewUnstartedUTLSClient returns a new client connected to s in memory,
+// using clientHelloID. config may be nil. If config doesn't specify RootCAs and
+// ServerName, the server certificate and "localhost" are used.
+func NewUnstartedUTLSClient(s *UTLSTestServer, config *Config, clientHelloID ClientHelloID) *UTLSTestClient {
+	if config == nil {
+		config = &Config{}
+	} else {
+		config = config.Clone()
+	}
+	if config.RootCAs == nil && s.Certificate != nil {
+		config.RootCAs = x509.NewCertPool()
+		config.RootCAs.AddCert(s.Certificate)
+	}
+	if config.ServerName == "" {
+		config.ServerName = "localhost"
+	}
+
+	clientEnd, serverEnd := memPipe()
+	c := &UTLSTestClient{
+		server:       s,
+		clientRecord: &flightRecorder{Conn: clientEnd},
+		serverRecord: &flightRecorder{Conn: serverEnd},
+	}
+	c.UConn = UClient(c.clientRecord, config, clientHelloID)
+	c.ServerConn = Server(c.serverRecord, s.Config)
+	return c
+}
+
+// NewUTLSClient is like NewUnstartedUTLSClient, but also completes the
+// handshake.
+func NewUTLSClient(s *UTLSTestServer, config *Config, clientHelloID ClientHelloID) (*UTLSTestClient, error) {
+	c := NewUnstartedUTLSClient(s, config, clientHelloID)
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Start runs the client and server handshakes. On success, the server's
+// Handler is started for the connection.
+func (c *UTLSTestClient) Start() error {
+	s := c.server
+	s.mu.Lock()
+	if !s.started || s.closed {
+		s.mu.Unlock()
+		return errors.New("tls: UTLSTestServer not running")
+	}
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		err := c.ServerConn.Handshake()
+		c.serverRecord.stop()
+		if err != nil {
+			// Unblock the client, which may be waiting on the pipe.
+			c.ServerConn.Close()
+		}
+		serverErr <- err
+	}()
+
+	clientErr := c.UConn.Handshake()
+	c.clientRecord.stop()
+	if clientErr != nil {
+		c.UConn.Close()
+	}
+	if err := <-serverErr; err != nil || clientErr != nil {
+		s.wg.Done()
+		if clientErr != nil {
+			// Wrap the client error, so that tests can inspect it.
+			return fmt.Errorf("tls: test handshake failed: client: %w, server: %v", clientErr, err)
+		}
+		return fmt.Errorf("tls: test handshake failed: client: %v, server: %v", clientErr, err)
+	}
+
+	go func() {
+		defer s.wg.Done()
+		defer c.ServerConn.Close()
+		if s.Handler != nil {
+			s.Handler(c.ServerConn)
+		} else {
+			io.Copy(c.ServerConn, c.ServerConn)
+		}
+	}()
+	return nil
+}
+
+// ClientFlight returns the raw records written by the client during the
+// handshake, starting with the ClientHello.
+func (c *UTLSTestClient) ClientFlight() []byte {
+	return c.clientRecord.bytes()
+}
+
+// ServerFlight returns the raw records written by the server during the
+// handshake, starting with the ServerHello.
+func (c *UTLSTestClient) ServerFlight() []byte {
+	return c.serverRecord.bytes()
+}
+
+// flightRecorder records the bytes written to a connection until stopped.
+type flightRecorder struct {
+	net.Conn
+
+	mu      sync.Mutex
+	buf     []byte
+	stopped bool
+}
+
+func (r *flightRecorder) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	if !r.stopped {
+		r.buf = append(r.buf, b...)
+	}
+	r.mu.Unlock()
+	return r.Conn.Write(b)
+}
+
+func (r *flightRecorder) stop() {
+	r.mu.Lock()
+	r.stopped = true
+	r.mu.Unlock()
+}
+
+func (r *flightRecorder) bytes() []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]byte(nil), r.buf...)
+}
+
+// memPipe returns the two ends of an in-memory connection. Unlike net.Pipe,
+// writes are buffered like on a network connection, so that both ends can write
+// at the same time, as they do during some handshakes, and data written before
+// Close can still be read by the peer.
+func memPipe() (net.Conn, net.Conn) {
+	a, b := newMemBuffer(), newMemBuffer()
+	return &memConn{r: a, w: b}, &memConn{r: b, w: a}
+}
+
+// memBuffer is one direction of a memPipe.
+type memBuffer struct {
+	mu           sync.Mutex
+	cond         *sync.Cond
+	buf          []byte
+	writerClosed bool
+	readerClosed bool
+	deadline     time.Time
+	timer        *time.Timer
+}
+
+func newMemBuffer() *memBuffer {
+	b := &memBuffer{}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+type memConn struct {
+	r, w *memBuffer
+}
+
+func (c *memConn) Read(p []byte) (int, error) {
+	b := c.r
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		switch {
+		case b.readerClosed:
+			return 0, net.ErrClosed
+		case len(b.buf) > 0:
+			n := copy(p, b.buf)
+			b.buf = b.buf[n:]
+			return n, nil
+		case b.writerClosed:
+			return 0, io.EOF
+		case !b.deadline.IsZero() && !time.Now().Before(b.deadline):
+			return 0, os.ErrDeadlineExceeded
+		}
+		b.cond.Wait()
+	}
+}
+
+func (c *memConn) Write(p []byte) (int, error) {
+	b := c.w
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.writerClosed {
+		return 0, net.ErrClosed
+	}
+	if b.readerClosed {
+		return 0, io.ErrClosedPipe
+	}
+	b.buf = append(b.buf, p...)
+	b.cond.Broadcast()
+	return len(p), nil
+}
+
+func (c *memConn) Close() error {
+	for _, b := range []*memBuffer{c.r, c.w} {
+		b.mu.Lock()
+		if b == c.r {
+			b.readerClosed = true
+		} else {
+			b.writerClosed = true
+		}
+		b.cond.Broadcast()
+		b.mu.Unlock()
+	}
+	return nil
+}
+
+func (c *memConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *memConn) SetReadDeadline(t time.Time) error {
+	b := c.r
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.deadline = t
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if !t.IsZero() {
+		b.timer = time.AfterFunc(time.Until(t), func() {
+			b.mu.Lock()
+			b.cond.Broadcast()
+			b.mu.Unlock()
+		})
+	}
+	b.cond.Broadcast()
+	return nil
+}
+
+// SetWriteDeadline is a no-op, as writes never block.
+func (c *memConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *memConn) LocalAddr() net.Addr  { return memAddr{} }
+func (c *memConn) RemoteAddr() net.Addr { return memAddr{} }
+
+type memAddr struct{}
+
+func (memAddr) Network() string { return "mem" }
+func (memAddr) String() string  { return "mem" }
@@ -0,0 +1,73 @@
+package tls
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestUTLSTestServer(t *testing.T) {
+	s := NewUnstartedUTLSServer(nil)
+	s.Config.MaxVersion = VersionTLS13
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, id := range []ClientHelloID{HelloChrome_Auto, HelloFirefox_Auto, HelloIOS_Auto, HelloGolang} {
+		c := NewUnstartedUTLSClient(s, nil, id)
+		if err := c.Start(); err != nil {
+			t.Fatalf("%s: %v", id.Str(), err)
+		}
+
+		flight := c.ClientFlight()
+		if len(flight) < recordHeaderLen+4 || flight[0] != byte(recordTypeHandshake) || flight[recordHeaderLen] != typeClientHello {
+			t.Errorf("%s: client flight doesn't start with a ClientHello", id.Str())
+		}
+		if !bytes.Contains(flight, c.HandshakeState.Hello.Raw) {
+			t.Errorf("%s: client flight doesn't contain the ClientHello", id.Str())
+		}
+		flight = c.ServerFlight()
+		if len(flight) < recordHeaderLen+4 || flight[recordHeaderLen] != typeServerHello {
+			t.Errorf("%s: server flight doesn't start with a ServerHello", id.Str())
+		}
+
+		msg := []byte("hello")
+		if _, err := c.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+		buf := make([]byte, len(msg))
+		if _, err := io.ReadFull(c, buf); err != nil || !bytes.Equal(buf, msg) {
+			t.Errorf("%s: echo failed: %q, %v", id.Str(), buf, err)
+		}
+		c.Close()
+	}
+}
+
+func TestUTLSTestServerHandler(t *testing.T) {
+	s := NewUnstartedUTLSServer(&Config{MaxVersion: VersionTLS12})
+	s.Handler = func(c *Conn) {
+		c.Write([]byte(c.ConnectionState().ServerName))
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewUnstartedUTLSClient(s, nil, HelloChrome_Auto)
+	if err := c.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if v := c.ConnectionState().Version; v != VersionTLS12 {
+		t.Errorf("got version %x, want TLS 1.2", v)
+	}
+	b, err := io.ReadAll(c)
+	if err != nil || string(b) != "localhost" {
+		t.Errorf("got %q, %v", b, err)
+	}
+	c.Close()
+	s.Close()
+
+	if err := NewUnstartedUTLSClient(s, nil, HelloChrome_Auto).Start(); err == nil {
+		t.Error("expected error connecting to closed server")
+	}
+}
@@ -0,0 +1,70 @@
+package tls
+
+import (
+	"hash"
+
+	"github.com/refraction-networking/utls/internal/tls13"
+)
+
+// TLS13KeySchedule is the part of the TLS 1.3 key schedule (RFC 8446, Section
+// 7) that QUIC implementations need to derive packet protection keys and
+// update them (RFC 9001, Sections 5 and 6), and to construct exporters. It is
+// implemented by PubCipherSuiteTLS13.
+type TLS13KeySchedule interface {
+	// ExpandLabel implements HKDF-Expand-Label with the hash of the cipher
+	// suite. The "tls13 " prefix is added to label.
+	ExpandLabel(secret []byte, label string, context []byte, length int) []byte
+
+	// NextTrafficSecret derives the next traffic secret from the current
+	// one, as for a key update.
+	NextTrafficSecret(trafficSecret []byte) []byte
+
+	// TrafficKey derives the TLS record protection key and IV of a traffic
+	// secret.
+	TrafficKey(trafficSecret []byte) (key, iv []byte)
+
+	// ExportKeyingMaterial returns an RFC 5705 exporter for a connection
+	// with the given master secret and transcript up to the server
+	// Finished, like ConnectionState.ExportKeyingMaterial.
+	ExportKeyingMaterial(masterSecret []byte, transcript hash.Hash) func(label string, context []byte, length int) ([]byte, error)
+
+	// Exporter is like ExportKeyingMaterial, but for an already derived
+	// exporter_master_secret, e.g. the early exporter secret.
+	Exporter(exporterMasterSecret []byte) func(label string, context []byte, length int) ([]byte, error)
+}
+
+var _ TLS13KeySchedule = (*PubCipherSuiteTLS13)(nil)
+
+// CipherSuiteTLS13ByID returns the TLS 1.3 cipher suite with the given ID, or
+// nil if it is not supported.
+func CipherSuiteTLS13ByID(id uint16) *PubCipherSuiteTLS13 {
+	return cipherSuiteTLS13ByID(id).toPublic()
+}
+
+// ExpandLabel implements TLS13KeySchedule.
+func (c *PubCipherSuiteTLS13) ExpandLabel(secret []byte, label string, context []byte, length int) []byte {
+	return tls13.ExpandLabel(c.Hash.New, secret, label, context, length)
+}
+
+// NextTrafficSecret implements TLS13KeySchedule.
+func (c *PubCipherSuiteTLS13) NextTrafficSecret(trafficSecret []byte) []byte {
+	return c.toPrivate().nextTrafficSecret(trafficSecret)
+}
+
+// TrafficKey implements TLS13KeySchedule.
+func (c *PubCipherSuiteTLS13) TrafficKey(trafficSecret []byte) (key, iv []byte) {
+	return c.toPrivate().trafficKey(trafficSecret)
+}
+
+// ExportKeyingMaterial implements TLS13KeySchedule.
+func (c *PubCipherSuiteTLS13) ExportKeyingMaterial(masterSecret []byte, transcript hash.Hash) func(label string, context []byte, length int) ([]byte, error) {
+	return c.toPrivate().exportKeyingMaterial(tls13.NewMasterSecretFromSecret(c.Hash.New, masterSecret), transcript)
+}
+
+// Exporter implements TLS13KeySchedule.
+func (c *PubCipherSuiteTLS13) Exporter(exporterMasterSecret []byte) func(label string, context []byte, length int) ([]byte, error) {
+	s := tls13.NewExporterMasterSecretFromSecret(c.Hash.New, exporterMasterSecret)
+	return func(label string, context []byte, length int) ([]byte, error) {
+		return s.Exporter(label, context, length), nil
+	}
+}
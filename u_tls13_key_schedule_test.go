@@ -0,0 +1,65 @@
+package tls
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/refraction-networking/utls/internal/tls13"
+)
+
+func TestTLS13KeyScheduleQUICInitialKeys(t *testing.T) {
+	// RFC 9001, Appendix A.1.
+	c := CipherSuiteTLS13ByID(TLS_AES_128_GCM_SHA256)
+	if c == nil {
+		t.Fatal("TLS_AES_128_GCM_SHA256 not found")
+	}
+	secret, _ := hex.DecodeString("c00cf151ca5be075ed0ebfb5c80323c42d6b7db67881289af4008f1f6c357aea")
+	for _, test := range []struct {
+		label  string
+		length int
+		want   string
+	}{
+		{"quic key", 16, "1f369613dd76d5467730efcbe3b1a22d"},
+		{"quic iv", 12, "fa044b2f42a3fd3b46fb255c"},
+		{"quic hp", 16, "9f50449e04a0e810283a1e9933adedd2"},
+	} {
+		if got := hex.EncodeToString(c.ExpandLabel(secret, test.label, nil, test.length)); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.label, got, test.want)
+		}
+	}
+
+	if CipherSuiteTLS13ByID(TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256) != nil {
+		t.Error("found a TLS 1.2 cipher suite")
+	}
+}
+
+func TestTLS13KeyScheduleMatchesInternal(t *testing.T) {
+	for _, suite := range cipherSuitesTLS13 {
+		c := CipherSuiteTLS13ByID(suite.id)
+		secret := bytes.Repeat([]byte{0x42}, suite.hash.Size())
+
+		if !bytes.Equal(c.NextTrafficSecret(secret), suite.nextTrafficSecret(secret)) {
+			t.Errorf("%x: NextTrafficSecret mismatch", suite.id)
+		}
+		key, iv := c.TrafficKey(secret)
+		wantKey, wantIV := suite.trafficKey(secret)
+		if !bytes.Equal(key, wantKey) || !bytes.Equal(iv, wantIV) || len(key) != suite.keyLen {
+			t.Errorf("%x: TrafficKey mismatch", suite.id)
+		}
+
+		transcript := suite.hash.New()
+		transcript.Write([]byte("transcript"))
+		ms := tls13.NewMasterSecretFromSecret(suite.hash.New, secret)
+		want, _ := suite.exportKeyingMaterial(ms, transcript)("EXPORTER-test", []byte("context"), 32)
+		got, err := c.ExportKeyingMaterial(secret, transcript)("EXPORTER-test", []byte("context"), 32)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%x: ExportKeyingMaterial mismatch: %v", suite.id, err)
+		}
+		exporterSecret := tls13.TestingOnlyExporterSecret(ms.ExporterMasterSecret(transcript))
+		got, err = c.Exporter(exporterSecret)("EXPORTER-test", []byte("context"), 32)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("%x: Exporter mismatch: %v", suite.id, err)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"strings"
 
 	"github.com/refraction-networking/utls/dicttls"
+	"github.com/refraction-networking/utls/tlsbytes"
 	"golang.org/x/crypto/cryptobyte"
 )
 
@@ -601,7 +602,7 @@ func (e *SignatureAlgorithmsCertExtension) Write(b []byte) (int, error) {
 }
 
 func (e *SignatureAlgorithmsCertExtension) writeToUConn(uc *UConn) error {
-	uc.HandshakeState.Hello.SupportedSignatureAlgorithms = e.SupportedSignatureAlgorithms
+	uc.HandshakeState.Hello.SupportedSignatureAlgorithmsCert = e.SupportedSignatureAlgorithms
 	return nil
 }
 
@@ -679,8 +680,7 @@ func (e *ALPNExtension) Write(b []byte) (int, error) {
 		if !protoList.ReadUint8LengthPrefixed(&proto) || proto.Empty() {
 			return 0, errors.New("unable to read ALPN extension data")
 		}
-		alpnProtocols = append(alpnProtocols, string(proto))
-
+		alpnProtocols = append(alpnProtocols, unGREASEALPN(string(proto)))
 	}
 	e.AlpnProtocols = alpnProtocols
 	return fullLen, nil
@@ -979,11 +979,7 @@ func (e *UtlsGREASEExtension) writeToUConn(uc *UConn) error {
 func GetBoringGREASEValue(greaseSeed [ssl_grease_last_index]uint16, index int) uint16 {
 	// GREASE value is back from deterministic to random.
 	// https://github.com/google/boringssl/blob/a365138ac60f38b64bfc608b493e0f879845cb88/ssl/handshake_client.c#L530
-	ret := uint16(greaseSeed[index])
-	/* This generates a random value of the form 0xωaωa, for all 0 ≤ ω < 16. */
-	ret = (ret & 0xf0) | 0x0a
-	ret |= ret << 8
-	return ret
+	return tlsbytes.GREASEValue(greaseSeed[index])
 }
 
 func (e *UtlsGREASEExtension) Len() int {
@@ -1685,11 +1681,12 @@ func (e *RenegotiationInfoExtension) Write(b []byte) (int, error) {
 
 func (e *RenegotiationInfoExtension) writeToUConn(uc *UConn) error {
 	uc.config.Renegotiation = e.Renegotiation
+	// The extension is sent regardless of the renegotiation mode.
+	uc.HandshakeState.Hello.SecureRenegotiationSupported = true
 	switch e.Renegotiation {
 	case RenegotiateOnceAsClient:
 		fallthrough
 	case RenegotiateFreelyAsClient:
-		uc.HandshakeState.Hello.SecureRenegotiationSupported = true
 		// TODO: don't do backward propagation here
 		if uc.handshakes > 0 {
 			e.RenegotiatedConnection = uc.clientFinished[:]
@@ -0,0 +1,172 @@
+package tls
+
+import (
+	"net"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// TransportShaping controls how the TCP segments carrying the ClientHello of
+// a connection are shaped, so that middleboxes which inspect single segments
+// without reassembling the TCP stream can't read the server_name.
+//
+// It is applied by ShapeConn, and by FastOpenDialer to the connections it
+// dials.
+type TransportShaping struct {
+	// SplitServerName splits the write of the ClientHello in two, the
+	// first one ending in the middle of the server_name host name.
+	SplitServerName bool
+
+	// SplitDelay is how long to wait between the two writes of a split
+	// ClientHello, so that the OS doesn't send them in the same segment
+	// even if it retransmits the first one.
+	SplitDelay time.Duration
+
+	// MaxSegmentSize, if not zero, sets TCP_MAXSEG on the socket. The
+	// clamped MSS is only advertised to the server if it's set before the
+	// connection is established, as done by FastOpenDialer. It is
+	// supported on Linux, macOS and the BSDs; elsewhere, applying the
+	// shaping fails.
+	MaxSegmentSize int
+
+	// Coalesce lets the OS delay small writes to coalesce them with later
+	// ones (Nagle's algorithm). By default, TCP_NODELAY is set, as by the
+	// net package, so that the writes of a split ClientHello are sent
+	// immediately, each in its own segment.
+	Coalesce bool
+}
+
+// ShapeConn applies shaping to conn, which must not have been written to yet,
+// and returns a net.Conn to use in its place, e.g. with UClient. Socket
+// options are only set if conn is a *net.TCPConn. If the returned net.Conn
+// wraps conn, it forwards CloseWrite to it and returns it from its NetConn
+// method.
+func ShapeConn(conn net.Conn, shaping *TransportShaping) (net.Conn, error) {
+	return shaping.shape(conn, true)
+}
+
+// shape implements ShapeConn. setMSS is false if control was already called
+// before connecting.
+func (shaping *TransportShaping) shape(conn net.Conn, setMSS bool) (net.Conn, error) {
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		if err := tcpConn.SetNoDelay(!shaping.Coalesce); err != nil {
+			return nil, err
+		}
+		if setMSS && shaping.MaxSegmentSize != 0 {
+			rawConn, err := tcpConn.SyscallConn()
+			if err != nil {
+				return nil, err
+			}
+			if err := shaping.control(rawConn); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if !shaping.SplitServerName {
+		return conn, nil
+	}
+	return &shapedConn{Conn: conn, delay: shaping.SplitDelay}, nil
+}
+
+// control sets the socket options of shaping that must be set before
+// connecting.
+func (shaping *TransportShaping) control(c syscall.RawConn) error {
+	if shaping.MaxSegmentSize == 0 {
+		return nil
+	}
+	var err error
+	if cerr := c.Control(func(fd uintptr) {
+		err = setMaxSegmentSize(fd, shaping.MaxSegmentSize)
+	}); cerr != nil {
+		return cerr
+	}
+	return err
+}
+
+// shapedConn splits its first write if it's a ClientHello with a server_name.
+type shapedConn struct {
+	net.Conn
+	delay   time.Duration
+	written atomic.Bool
+}
+
+// NetConn returns the connection wrapped by c, e.g. to reach a *net.TCPConn.
+func (c *shapedConn) NetConn() net.Conn {
+	return c.Conn
+}
+
+// CloseWrite shuts down the writing side of the wrapped connection, if it
+// supports it, for UConn.CloseWrite.
+func (c *shapedConn) CloseWrite() error {
+	if cw, ok := c.Conn.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return nil
+}
+
+func (c *shapedConn) Write(b []byte) (int, error) {
+	if c.written.Swap(true) {
+		return c.Conn.Write(b)
+	}
+	at := serverNameSplitOffset(b)
+	if at == 0 {
+		return c.Conn.Write(b)
+	}
+	n, err := c.Conn.Write(b[:at])
+	if err != nil {
+		return n, err
+	}
+	if c.delay > 0 {
+		time.Sleep(c.delay)
+	}
+	m, err := c.Conn.Write(b[at:])
+	return n + m, err
+}
+
+// serverNameSplitOffset returns the offset of the middle of the server_name
+// host name in b, if b starts with a handshake record holding a ClientHello,
+// or zero.
+func serverNameSplitOffset(b []byte) int {
+	s := cryptobyte.String(b)
+	var contentType, handshakeType uint8
+	var record cryptobyte.String
+	if !s.ReadUint8(&contentType) || recordType(contentType) != recordTypeHandshake ||
+		!s.Skip(2) || // version
+		!s.ReadUint16LengthPrefixed(&record) {
+		return 0
+	}
+	var sessionID, cipherSuites, compressionMethods, extensions cryptobyte.String
+	if !record.ReadUint8(&handshakeType) || handshakeType != typeClientHello ||
+		!record.Skip(3+2+32) || // length, version and random
+		!record.ReadUint8LengthPrefixed(&sessionID) ||
+		!record.ReadUint16LengthPrefixed(&cipherSuites) ||
+		!record.ReadUint8LengthPrefixed(&compressionMethods) ||
+		!record.ReadUint16LengthPrefixed(&extensions) {
+		return 0
+	}
+	for !extensions.Empty() {
+		var extType uint16
+		var extData cryptobyte.String
+		if !extensions.ReadUint16(&extType) || !extensions.ReadUint16LengthPrefixed(&extData) {
+			return 0
+		}
+		if extType != extensionServerName {
+			continue
+		}
+		var nameList, name cryptobyte.String
+		var nameType uint8
+		if !extData.ReadUint16LengthPrefixed(&nameList) ||
+			!nameList.ReadUint8(&nameType) || nameType != 0 || // host_name
+			!nameList.ReadUint16LengthPrefixed(&name) || len(name) < 2 {
+			return 0
+		}
+		// The host name is followed by the rest of the name list, the
+		// extension data and the record.
+		end := len(b) - len(nameList) - len(extData) - len(extensions) - len(record) - len(s)
+		return end - len(name) + len(name)/2
+	}
+	return 0
+}
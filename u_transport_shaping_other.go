@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package tls
+
+import "errors"
+
+// setMaxSegmentSize sets TCP_MAXSEG on a socket.
+func setMaxSegmentSize(fd uintptr, mss int) error {
+	return errors.New("tls: TransportShaping.MaxSegmentSize is not supported on this platform")
+}
@@ -0,0 +1,107 @@
+package tls
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// writeRecorder is a net.Conn recording its writes.
+type writeRecorder struct {
+	net.Conn
+	writes      [][]byte
+	closedWrite bool
+}
+
+func (c *writeRecorder) Write(b []byte) (int, error) {
+	c.writes = append(c.writes, bytes.Clone(b))
+	return len(b), nil
+}
+
+func (c *writeRecorder) CloseWrite() error {
+	c.closedWrite = true
+	return nil
+}
+
+func TestShapeConnSplitsServerName(t *testing.T) {
+	uconn := UClient(nil, &Config{ServerName: "example.com"}, HelloChrome_120)
+	flight, err := uconn.FirstFlight()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := &writeRecorder{}
+	conn, err := ShapeConn(rec, &TransportShaping{SplitServerName: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := conn.Write(flight); n != len(flight) || err != nil {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if _, err := conn.Write([]byte("later")); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.writes) != 3 {
+		t.Fatalf("got %d writes, want the ClientHello in two and a third one", len(rec.writes))
+	}
+	if !bytes.HasSuffix(rec.writes[0], []byte("examp")) || !bytes.HasPrefix(rec.writes[1], []byte("le.com")) {
+		t.Errorf("ClientHello not split in the server name: %q | %q", rec.writes[0][len(rec.writes[0])-8:], rec.writes[1][:8])
+	}
+	if !bytes.Equal(append(rec.writes[0], rec.writes[1]...), flight) {
+		t.Error("split writes don't add up to the ClientHello")
+	}
+
+	// The wrapper is transparent to CloseWrite and can be unwrapped.
+	if cw, ok := conn.(interface{ CloseWrite() error }); !ok || cw.CloseWrite() != nil || !rec.closedWrite {
+		t.Error("CloseWrite not forwarded to the wrapped connection")
+	}
+	if nc, ok := conn.(interface{ NetConn() net.Conn }); !ok || nc.NetConn() != rec {
+		t.Error("NetConn doesn't return the wrapped connection")
+	}
+
+	// Other data is written unchanged.
+	rec = &writeRecorder{}
+	conn, _ = ShapeConn(rec, &TransportShaping{SplitServerName: true})
+	conn.Write([]byte{byte(recordTypeApplicationData), 3, 3, 0, 1, 0})
+	if len(rec.writes) != 1 {
+		t.Errorf("got %d writes of application data, want 1", len(rec.writes))
+	}
+}
+
+func TestFastOpenDialerTransportShaping(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("TCP_MAXSEG is not tested on " + runtime.GOOS)
+	}
+	s, err := NewClientHelloEchoServer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	reports := make(chan *ClientHelloReport, 1)
+	s.OnReport = func(r *ClientHelloReport) { reports <- r }
+
+	d := &FastOpenDialer{
+		NetDialer:     &net.Dialer{Timeout: 10 * time.Second},
+		Config:        &Config{ServerName: "example.com", InsecureSkipVerify: true},
+		ClientHelloID: HelloChrome_120,
+		TransportShaping: &TransportShaping{
+			SplitServerName: true,
+			SplitDelay:      10 * time.Millisecond,
+			MaxSegmentSize:  1200,
+		},
+	}
+	uconn, err := d.DialContext(context.Background(), "tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer uconn.Close()
+
+	r := <-reports
+	if want := uconn.HandshakeState.Hello.Raw; r.Raw != hex.EncodeToString(want) {
+		t.Error("server received a different ClientHello than the first flight")
+	}
+}
@@ -0,0 +1,10 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package tls
+
+import "golang.org/x/sys/unix"
+
+// setMaxSegmentSize sets TCP_MAXSEG on a socket.
+func setMaxSegmentSize(fd uintptr, mss int) error {
+	return unix.SetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_MAXSEG, mss)
+}
@@ -0,0 +1,146 @@
+package tls
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+// UnsolicitedExtensionPolicy is how a client reacts to extensions sent by the
+// server that the ClientHello didn't offer.
+//
+// RFC 8446, Section 4.2 requires aborting the handshake with an
+// unsupported_extension alert, as browsers do, while crypto/tls ignores the
+// extensions it doesn't know. A server can tell the two apart by sending an
+// unsolicited extension.
+type UnsolicitedExtensionPolicy uint8
+
+const (
+	// UnsolicitedExtensionIgnore ignores unsolicited extensions unknown to
+	// crypto/tls, like crypto/tls does. Known extensions may still be
+	// rejected, e.g. an unrequested ALPN protocol.
+	UnsolicitedExtensionIgnore UnsolicitedExtensionPolicy = iota
+
+	// UnsolicitedExtensionAbort aborts the handshake with an
+	// unsupported_extension alert, like browsers do.
+	UnsolicitedExtensionAbort
+)
+
+// UnsolicitedExtensionError is returned by a client handshake aborted because
+// of an extension the ClientHello didn't offer.
+type UnsolicitedExtensionError struct {
+	// HandshakeType is the TLS HandshakeType of the message containing
+	// the extension: 2 for ServerHello and HelloRetryRequest, 8 for
+	// EncryptedExtensions.
+	HandshakeType uint8
+
+	// Extension is the type of the unsolicited extension.
+	Extension uint16
+
+	// Err is the error returned by Config.OnUnsolicitedExtension, if any.
+	Err error
+}
+
+func (e *UnsolicitedExtensionError) Error() string {
+	msg := fmt.Sprintf("tls: server sent unsolicited extension %d in handshake message %d", e.Extension, e.HandshakeType)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *UnsolicitedExtensionError) Unwrap() error {
+	return e.Err
+}
+
+// checkUnsolicitedServerHello rejects GREASE extensions and applies the
+// unsolicited extension policy to a ServerHello or HelloRetryRequest.
+func (c *Conn) checkUnsolicitedServerHello(serverHello *serverHelloMsg, hello *clientHelloMsg, ech *echClientContext) error {
+	s := cryptobyte.String(serverHello.original)
+	var random, sessionID []byte
+	var extensions cryptobyte.String
+	if !s.Skip(4+2) || // message header and legacy_version
+		!s.ReadBytes(&random, 32) ||
+		!readUint8LengthPrefixed(&s, &sessionID) ||
+		!s.Skip(2+1) { // cipher_suite and legacy_compression_method
+		return nil
+	}
+	if s.Empty() {
+		return nil
+	}
+	if !s.ReadUint16LengthPrefixed(&extensions) {
+		return nil
+	}
+	extTypes, _ := readExtensionTypes(extensions)
+	if err := c.rejectGREASEExtensions(extTypes); err != nil {
+		return err
+	}
+	if !c.config.checksUnsolicitedExtensions() {
+		return nil
+	}
+	isHRR := bytes.Equal(random, helloRetryRequestRandom)
+	return c.checkUnsolicitedExtensions(typeServerHello, extTypes, hello, ech, func(ext uint16) bool {
+		// The cookie is sent by the server first (RFC 8446, Section 4.2.2).
+		return isHRR && ext == extensionCookie
+	})
+}
+
+// checkUnsolicitedEncryptedExtensions rejects GREASE extensions and applies the
+// unsolicited extension policy to EncryptedExtensions.
+func (c *Conn) checkUnsolicitedEncryptedExtensions(ee *encryptedExtensionsMsg, hello *clientHelloMsg, ech *echClientContext) error {
+	if err := c.rejectGREASEExtensions(ee.utls.extensions); err != nil {
+		return err
+	}
+	if !c.config.checksUnsolicitedExtensions() {
+		return nil
+	}
+	return c.checkUnsolicitedExtensions(typeEncryptedExtensions, ee.utls.extensions, hello, ech, nil)
+}
+
+func (config *Config) checksUnsolicitedExtensions() bool {
+	return config.UnsolicitedExtensions != UnsolicitedExtensionIgnore || config.OnUnsolicitedExtension != nil
+}
+
+func (c *Conn) checkUnsolicitedExtensions(handshakeType uint8, extTypes []uint16, hello *clientHelloMsg, ech *echClientContext, allowed func(uint16) bool) error {
+	hellos := []*clientHelloMsg{hello}
+	if ech != nil && ech.innerHello != nil {
+		// The server may have accepted ECH and answered the inner hello.
+		hellos = append(hellos, ech.innerHello)
+	}
+	var offered []uint16
+	scsv := false
+	for _, h := range hellos {
+		raw, err := h.marshal()
+		if err != nil {
+			return err
+		}
+		if types, ok := clientHelloExtensionTypes(raw); ok {
+			offered = append(offered, types...)
+		}
+		scsv = scsv || slices.Contains(h.cipherSuites, scsvRenegotiation)
+	}
+
+	for _, ext := range extTypes {
+		if slices.Contains(offered, ext) || (allowed != nil && allowed(ext)) {
+			continue
+		}
+		// The renegotiation_info extension answers the SCSV (RFC 5746, Section 3.6).
+		if ext == extensionRenegotiationInfo && scsv {
+			continue
+		}
+		var err error
+		if c.config.OnUnsolicitedExtension != nil {
+			err = c.config.OnUnsolicitedExtension(handshakeType, ext)
+			if err == nil {
+				continue
+			}
+		} else if c.config.UnsolicitedExtensions == UnsolicitedExtensionIgnore {
+			continue
+		}
+		c.sendAlert(alertUnsupportedExtension)
+		return &UnsolicitedExtensionError{HandshakeType: handshakeType, Extension: ext, Err: err}
+	}
+	return nil
+}
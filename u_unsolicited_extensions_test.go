@@ -0,0 +1,109 @@
+package tls
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+func rawServerHello(t *testing.T, random []byte, exts ...uint16) *serverHelloMsg {
+	var b cryptobyte.Builder
+	b.AddUint8(typeServerHello)
+	b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) {
+		b.AddUint16(VersionTLS12)
+		b.AddBytes(random)
+		b.AddUint8(0) // legacy_session_id_echo
+		b.AddUint16(TLS_AES_128_GCM_SHA256)
+		b.AddUint8(compressionNone)
+		b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+			for _, ext := range exts {
+				b.AddUint16(ext)
+				b.AddUint16(0)
+			}
+		})
+	})
+	return &serverHelloMsg{original: b.BytesOrPanic()}
+}
+
+func TestUnsolicitedExtensionPolicy(t *testing.T) {
+	uconn := UClient(nil, &Config{ServerName: "example.com"}, HelloChrome_Auto)
+	if err := uconn.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	uconn.conn, _ = memPipe()
+	hello := uconn.HandshakeState.Hello.getPrivatePtr()
+	random := make([]byte, 32)
+
+	sentinel := errors.New("sentinel")
+	var calls [][2]uint16
+	for _, test := range []struct {
+		name        string
+		msg         *serverHelloMsg
+		ee          *encryptedExtensionsMsg
+		policy      UnsolicitedExtensionPolicy
+		callback    bool
+		callbackErr error
+		wantExt     uint16 // 0 for no error
+	}{
+		{name: "offered", msg: rawServerHello(t, random, extensionALPN, extensionSupportedVersions), policy: UnsolicitedExtensionAbort},
+		{name: "ignored", msg: rawServerHello(t, random, extensionALPN, 0x1234)},
+		{name: "aborted", msg: rawServerHello(t, random, extensionALPN, 0x1234), policy: UnsolicitedExtensionAbort, wantExt: 0x1234},
+		{name: "HRR cookie", msg: rawServerHello(t, helloRetryRequestRandom, extensionCookie), policy: UnsolicitedExtensionAbort},
+		{name: "ServerHello cookie", msg: rawServerHello(t, random, extensionCookie), policy: UnsolicitedExtensionAbort, wantExt: extensionCookie},
+		{name: "EncryptedExtensions", ee: &encryptedExtensionsMsg{utls: utlsEncryptedExtensionsMsgExtraFields{extensions: []uint16{extensionALPN, 0x4321}}}, policy: UnsolicitedExtensionAbort, wantExt: 0x4321},
+		{name: "callback accepts", msg: rawServerHello(t, random, 0x1234), policy: UnsolicitedExtensionAbort, callback: true},
+		{name: "callback rejects", msg: rawServerHello(t, random, 0x1234), callback: true, callbackErr: sentinel, wantExt: 0x1234},
+	} {
+		uconn.config.UnsolicitedExtensions = test.policy
+		uconn.config.OnUnsolicitedExtension = nil
+		if test.callback {
+			uconn.config.OnUnsolicitedExtension = func(handshakeType uint8, extension uint16) error {
+				calls = append(calls, [2]uint16{uint16(handshakeType), extension})
+				return test.callbackErr
+			}
+		}
+
+		var err error
+		if test.ee != nil {
+			err = uconn.checkUnsolicitedEncryptedExtensions(test.ee, hello, nil)
+		} else {
+			err = uconn.checkUnsolicitedServerHello(test.msg, hello, nil)
+		}
+		if test.wantExt == 0 {
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+			}
+			continue
+		}
+		var unsolicitedErr *UnsolicitedExtensionError
+		if !errors.As(err, &unsolicitedErr) || unsolicitedErr.Extension != test.wantExt {
+			t.Errorf("%s: got error %v, want an UnsolicitedExtensionError for %d", test.name, err, test.wantExt)
+		}
+		if test.callbackErr != nil && !errors.Is(err, test.callbackErr) {
+			t.Errorf("%s: error doesn't wrap the callback error", test.name)
+		}
+	}
+	if len(calls) != 2 || calls[0] != [2]uint16{uint16(typeServerHello), 0x1234} {
+		t.Errorf("OnUnsolicitedExtension calls: %v", calls)
+	}
+}
+
+func TestUnsolicitedExtensionAbortHandshakes(t *testing.T) {
+	for _, version := range []uint16{VersionTLS12, VersionTLS13} {
+		s, err := NewUTLSServer(&Config{MaxVersion: version, NextProtos: []string{"h2"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []ClientHelloID{HelloChrome_Auto, HelloFirefox_Auto, HelloIOS_Auto, HelloGolang} {
+			config := &Config{UnsolicitedExtensions: UnsolicitedExtensionAbort, NextProtos: []string{"h2"}}
+			c, err := NewUTLSClient(s, config, id)
+			if err != nil {
+				t.Errorf("%s, %x: %v", id.Str(), version, err)
+				continue
+			}
+			c.Close()
+		}
+		s.Close()
+	}
+}
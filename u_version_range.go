@@ -0,0 +1,93 @@
+package tls
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// versionRangeSep separates the bounds of a version range ClientHelloID.
+const versionRangeSep = "..."
+
+// HelloChrome_120_to_131 picks one of the Chrome presets from 120 to 131 for
+// each connection.
+var HelloChrome_120_to_131 = HelloVersionRange(HelloChrome_120, HelloChrome_131)
+
+// HelloVersionRange returns a ClientHelloID that picks, for each connection,
+// one of the presets of the client of from with a version between the ones of
+// from and to, both included, so that a fleet spreads over plausible recent
+// versions instead of pinning one. Only the presets of plain releases are
+// picked, not variants such as PSK, PQ or headless ones. to must be a preset
+// of the same client as from.
+//
+// UConn.ClientHelloID is set to the picked preset when the ClientHello is
+// built.
+func HelloVersionRange(from, to ClientHelloID) ClientHelloID {
+	return ClientHelloID{Client: from.Client, Version: from.Version + versionRangeSep + to.Version}
+}
+
+// isVersionRange reports whether id was returned by HelloVersionRange.
+func isVersionRange(id ClientHelloID) bool {
+	return strings.Contains(id.Version, versionRangeSep)
+}
+
+// pickFromVersionRange returns a random preset of the range id.
+func pickFromVersionRange(id ClientHelloID) (ClientHelloID, error) {
+	from, to, _ := strings.Cut(id.Version, versionRangeSep)
+	lo, ok1 := parsePresetVersion(from)
+	hi, ok2 := parsePresetVersion(to)
+	if !ok1 || !ok2 {
+		return id, fmt.Errorf("tls: invalid version range %q", id.Version)
+	}
+
+	var candidates []ClientHelloID
+	for _, m := range presetMetadata {
+		if m.ID.Client != id.Client {
+			continue
+		}
+		if v, ok := parsePresetVersion(m.ID.Version); ok && compareVersions(lo, v) <= 0 && compareVersions(v, hi) <= 0 {
+			candidates = append(candidates, m.ID)
+		}
+	}
+	if len(candidates) == 0 {
+		return id, fmt.Errorf("tls: no %s preset with a version from %s to %s", id.Client, from, to)
+	}
+	r, err := newPRNG()
+	if err != nil {
+		return id, err
+	}
+	return candidates[r.Intn(len(candidates))], nil
+}
+
+// parsePresetVersion parses the version of a plain release preset, e.g. "131"
+// or "16.0", and reports false for variants like "120_PQ".
+func parsePresetVersion(version string) ([]int, bool) {
+	var v []int
+	for _, part := range strings.Split(version, ".") {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		v = append(v, n)
+	}
+	return v, true
+}
+
+// compareVersions compares two parsed versions, missing components counting
+// as zero.
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if c := cmp.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
@@ -0,0 +1,65 @@
+package tls
+
+import (
+	"net"
+	"testing"
+)
+
+func TestHelloVersionRange(t *testing.T) {
+	seen := map[ClientHelloID]bool{}
+	for i := 0; i < 64 && len(seen) < 2; i++ {
+		uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloChrome_120_to_131)
+		if err := uconn.BuildHandshakeState(); err != nil {
+			t.Fatal(err)
+		}
+		seen[uconn.ClientHelloID] = true
+	}
+	for id := range seen {
+		if id != HelloChrome_120 && id != HelloChrome_131 {
+			t.Errorf("picked %s, outside of the range or not a plain release", id.Str())
+		}
+	}
+	if len(seen) != 2 {
+		t.Errorf("picked only %v", seen)
+	}
+
+	uconn := UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloVersionRange(HelloSafari_16_0, HelloSafari_18))
+	if err := uconn.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+	if id := uconn.ClientHelloID; id != HelloSafari_16_0 && id != HelloSafari_18 {
+		t.Errorf("picked %s", id.Str())
+	}
+
+	uconn = UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloVersionRange(HelloChrome_58, HelloChrome_Auto))
+	if err := uconn.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+
+	uconn = UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloVersionRange(HelloChrome_133, HelloChrome_120))
+	if err := uconn.BuildHandshakeState(); err == nil {
+		t.Error("empty range accepted")
+	}
+	uconn = UClient(&net.TCPConn{}, &Config{ServerName: "example.com"}, HelloVersionRange(HelloChrome_120_PQ, HelloChrome_131))
+	if err := uconn.BuildHandshakeState(); err == nil {
+		t.Error("range bounded by a variant accepted")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"16", "16.0", 0},
+		{"16.0", "18", -1},
+		{"131", "120", 1},
+		{"11.1", "11", 1},
+	} {
+		a, _ := parsePresetVersion(tt.a)
+		b, _ := parsePresetVersion(tt.b)
+		if got := compareVersions(a, b); got != tt.want {
+			t.Errorf("compareVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package tls
+
+import "slices"
+
+// zeroize overwrites secret with zeros and reports it to ZeroizeHook.
+func (c *Config) zeroize(label string, secret []byte) {
+	clear(secret)
+	if c.ZeroizeHook != nil {
+		c.ZeroizeHook(label, secret)
+	}
+}
+
+// zeroizeTicketKey overwrites a session ticket key that is no longer in use.
+func (c *Config) zeroizeTicketKey(k *ticketKey) {
+	c.zeroize("session ticket aes key", k.aesKey[:])
+	c.zeroize("session ticket hmac key", k.hmacKey[:])
+}
+
+// shareAutoTicketKeys returns c.autoSessionTicketKeys to be held outside of
+// c, by a connection or a clone. If ZeroizeSecrets is set, it returns a copy,
+// so that ticketKeys can zeroize the keys of c once they expire without
+// racing with their users. c.mutex must be held.
+func (c *Config) shareAutoTicketKeys() []ticketKey {
+	if c.ZeroizeSecrets {
+		return slices.Clone(c.autoSessionTicketKeys)
+	}
+	return c.autoSessionTicketKeys
+}
+
+// zeroizeSecrets overwrites the secrets held by a closed connection: the TLS 1.3
+// traffic secrets and resumption secret. The keys expanded into the record
+// layer ciphers can't be reached and are left to the garbage collector.
+//
+// It waits for in-flight Reads, Writes and handshakes, which fail once the
+// underlying connection is closed.
+func (c *Conn) zeroizeSecrets() {
+	c.in.Lock()
+	defer c.in.Unlock()
+	c.out.Lock()
+	defer c.out.Unlock()
+
+	if c.in.trafficSecret != nil {
+		c.config.zeroize("read traffic secret", c.in.trafficSecret)
+	}
+	if c.out.trafficSecret != nil {
+		c.config.zeroize("write traffic secret", c.out.trafficSecret)
+	}
+	if c.resumptionSecret != nil {
+		c.config.zeroize("resumption secret", c.resumptionSecret)
+	}
+	// The ticket keys are shared with the Config and other connections.
+	c.ticketKeys = nil
+}
+
+// Close closes the connection, see Conn.Close. If Config.ZeroizeSecrets is
+// set, the master secret, early secret, binder key and traffic secret kept in
+// HandshakeState are zeroized too, and the key share private keys are
+// released. A TLS 1.2 master secret is kept if sessions may be stored in
+// Config.ClientSessionCache or handed to Config.OnNewSessionTicket, as it is
+// shared with the session for resumption.
+func (c *UConn) Close() error {
+	if c.conn == nil {
+		// Never attached, see FirstFlight.
+		return nil
+	}
+	err := c.Conn.Close()
+	if c.config.ZeroizeSecrets {
+		c.in.Lock()
+		c.HandshakeState.zeroize(c.Conn)
+		c.in.Unlock()
+	}
+	return err
+}
+
+func (chs *PubClientHandshakeState) zeroize(c *Conn) {
+	config := c.config
+	resumable := !config.SessionTicketsDisabled &&
+		(config.ClientSessionCache != nil || config.OnNewSessionTicket != nil)
+	if chs.MasterSecret != nil && (c.vers == VersionTLS13 || !resumable) {
+		config.zeroize("master secret", chs.MasterSecret)
+	}
+	s := &chs.State13
+	if s.EarlySecret != nil {
+		config.zeroize("early secret", s.EarlySecret)
+	}
+	if s.BinderKey != nil {
+		config.zeroize("binder key", s.BinderKey)
+	}
+	if s.TrafficSecret != nil {
+		config.zeroize("client application traffic secret", s.TrafficSecret)
+	}
+	if s.KeyShareKeys != nil || s.EcdheKey != nil || s.KEMKey != nil {
+		s.KeyShareKeys, s.EcdheKey, s.KEMKey = nil, nil, nil
+		config.zeroize("key share private keys", nil)
+	}
+}
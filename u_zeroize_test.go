@@ -0,0 +1,246 @@
+package tls
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// zeroizeRecorder records the labels of zeroized secrets and checks that they
+// were actually zeroed.
+type zeroizeRecorder struct {
+	t      *testing.T
+	mu     sync.Mutex
+	labels map[string]int
+}
+
+func newZeroizeRecorder(t *testing.T) *zeroizeRecorder {
+	return &zeroizeRecorder{t: t, labels: make(map[string]int)}
+}
+
+func (r *zeroizeRecorder) hook(label string, secret []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.labels[label]++
+	for _, b := range secret {
+		if b != 0 {
+			r.t.Errorf("%s was not zeroized", label)
+			return
+		}
+	}
+}
+
+func (r *zeroizeRecorder) count(label string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.labels[label]
+}
+
+func TestZeroizeSecrets(t *testing.T) {
+	for _, test := range []struct {
+		name       string
+		version    uint16
+		clientWant []string
+	}{
+		{"TLSv13", VersionTLS13, []string{"read traffic secret", "write traffic secret", "master secret", "client application traffic secret", "key share private keys"}},
+		{"TLSv12", VersionTLS12, []string{"master secret"}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			clientRec, serverRec := newZeroizeRecorder(t), newZeroizeRecorder(t)
+			serverConfig := testConfig.Clone()
+			serverConfig.MaxVersion = test.version
+			serverConfig.ZeroizeSecrets = true
+			serverConfig.ZeroizeHook = serverRec.hook
+			s, err := NewUTLSServer(serverConfig)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			clientConfig := &Config{
+				InsecureSkipVerify: true,
+				ZeroizeSecrets:     true,
+				ZeroizeHook:        clientRec.hook,
+			}
+			c, err := NewUTLSClient(s, clientConfig, HelloChrome_120)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Keep references to secrets that are zeroized in place.
+			masterSecret := c.HandshakeState.MasterSecret
+			trafficSecret := c.out.trafficSecret
+			if len(masterSecret) == 0 {
+				t.Fatal("no master secret in HandshakeState")
+			}
+			if test.version == VersionTLS13 && len(trafficSecret) == 0 {
+				t.Fatal("no traffic secret")
+			}
+			if _, err := c.Write([]byte("ping")); err != nil {
+				t.Fatal(err)
+			}
+			buf := make([]byte, 4)
+			if _, err := c.Read(buf); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := c.Close(); err != nil {
+				t.Fatal(err)
+			}
+			s.Close()
+
+			for _, label := range test.clientWant {
+				if clientRec.count(label) == 0 {
+					t.Errorf("client did not zeroize %s", label)
+				}
+			}
+			if !bytes.Equal(masterSecret, make([]byte, len(masterSecret))) ||
+				!bytes.Equal(trafficSecret, make([]byte, len(trafficSecret))) {
+				t.Error("secrets were not overwritten")
+			}
+			if c.HandshakeState.State13.KeyShareKeys != nil {
+				t.Error("key share private keys were not released")
+			}
+			if test.version == VersionTLS13 && serverRec.count("write traffic secret") == 0 {
+				t.Error("server did not zeroize its traffic secret")
+			}
+		})
+	}
+}
+
+func TestZeroizeSecretsKeepsResumableMasterSecret(t *testing.T) {
+	s, err := NewUTLSServer(&Config{MaxVersion: VersionTLS12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	rec := newZeroizeRecorder(t)
+	clientConfig := &Config{
+		InsecureSkipVerify: true,
+		ClientSessionCache: NewLRUClientSessionCache(1),
+		ZeroizeSecrets:     true,
+		ZeroizeHook:        rec.hook,
+	}
+	for i := 0; i < 2; i++ {
+		c, err := NewUTLSClient(s, clientConfig, HelloChrome_120)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resumed := c.ConnectionState().DidResume; resumed != (i == 1) {
+			t.Errorf("connection %d: DidResume = %v", i, resumed)
+		}
+		c.Close()
+	}
+	if rec.count("master secret") != 0 {
+		t.Error("master secret shared with the session cache was zeroized")
+	}
+}
+
+func TestZeroizeSecretsKeepsMasterSecretOfNewSessionTicket(t *testing.T) {
+	s, err := NewUTLSServer(&Config{MaxVersion: VersionTLS12})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	rec := newZeroizeRecorder(t)
+	var session *ClientSessionState
+	clientConfig := &Config{
+		InsecureSkipVerify: true,
+		ZeroizeSecrets:     true,
+		ZeroizeHook:        rec.hook,
+		OnNewSessionTicket: func(key string, ticket *NewSessionTicket, cs *ClientSessionState) (bool, error) {
+			session = cs
+			return false, nil
+		},
+	}
+	c, err := NewUTLSClient(s, clientConfig, HelloChrome_120)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	if session == nil {
+		t.Fatal("OnNewSessionTicket was not called")
+	}
+	if rec.count("master secret") != 0 {
+		t.Error("master secret shared with OnNewSessionTicket was zeroized")
+	}
+	_, state, err := session.ResumptionState()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(state.secret, make([]byte, len(state.secret))) {
+		t.Error("session secret was overwritten")
+	}
+}
+
+func TestZeroizeTicketKeys(t *testing.T) {
+	rec := newZeroizeRecorder(t)
+	now := time.Now()
+	config := &Config{
+		Time:           func() time.Time { return now },
+		ZeroizeSecrets: true,
+		ZeroizeHook:    rec.hook,
+	}
+	// Keys handed to connections and clones are copies, which stay intact.
+	inUse := config.ticketKeys(nil)
+	if len(inUse) != 1 {
+		t.Fatalf("got %d ticket keys, want 1", len(inUse))
+	}
+	clone := config.Clone()
+	old := config.autoSessionTicketKeys
+	if &old[0] == &inUse[0] || &old[0] == &clone.autoSessionTicketKeys[0] {
+		t.Fatal("ticket keys shared outside the Config")
+	}
+	key := inUse[0]
+
+	now = now.Add(ticketKeyLifetime + time.Hour)
+	if keys := config.ticketKeys(nil); len(keys) != 1 {
+		t.Fatalf("got %d ticket keys after expiry, want 1", len(keys))
+	}
+	if rec.count("session ticket aes key") != 1 || rec.count("session ticket hmac key") != 1 {
+		t.Errorf("expired ticket key was not zeroized: %v", rec.labels)
+	}
+	if old[0].aesKey != [16]byte{} || old[0].hmacKey != [16]byte{} {
+		t.Error("expired ticket key was not overwritten")
+	}
+	if inUse[0] != key || clone.autoSessionTicketKeys[0] != key {
+		t.Error("ticket key held by a connection or clone was overwritten")
+	}
+}
+
+func TestZeroizeSecretsCloseDuringWrite(t *testing.T) {
+	rec := newZeroizeRecorder(t)
+	c, s := net.Pipe()
+	clientConfig := testConfig.Clone()
+	clientConfig.ZeroizeSecrets = true
+	clientConfig.ZeroizeHook = rec.hook
+	client := Client(c, clientConfig)
+	server := Server(s, testConfig.Clone())
+	defer server.Close()
+	go server.Handshake()
+	if err := client.Handshake(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nobody reads from the server end, so the Write blocks until Close.
+	written := make(chan error, 1)
+	go func() {
+		_, err := client.Write([]byte("blocked"))
+		written <- err
+	}()
+	for client.activeCall.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	client.Close()
+	if err := <-written; err == nil {
+		t.Error("Write succeeded after Close")
+	}
+	for _, label := range []string{"read traffic secret", "write traffic secret"} {
+		if rec.count(label) == 0 {
+			t.Errorf("Close during a Write did not zeroize the %s", label)
+		}
+	}
+}